@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"discord-music-bot/bot/audioplayer"
+	"fmt"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// announcement is a message posted by the bot, that
+// announces the song which has started playing.
+type announcement struct {
+	channelID string
+	messageID string
+	songID    uint
+}
+
+// announcements holds the latest announcement of every guild,
+// so it may be deleted once the next song is announced.
+type announcements struct {
+	mutex    sync.Mutex
+	messages map[string]*announcement
+}
+
+// newAnnouncements constructs an empty
+// map of the guilds' announcements.
+func newAnnouncements() *announcements {
+	return &announcements{
+		mutex:    sync.Mutex{},
+		messages: make(map[string]*announcement),
+	}
+}
+
+// swap stores the provided announcement as the latest announcement
+// of the guild identified by the provided guildID, and returns the
+// one it replaces, if any. The guild's announcement is removed when
+// the provided announcement is nil.
+func (a *announcements) swap(guildID string, next *announcement) *announcement {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	prev := a.messages[guildID]
+	if next == nil {
+		delete(a.messages, guildID)
+	} else {
+		a.messages[guildID] = next
+	}
+	return prev
+}
+
+// get returns the latest announcement of the
+// guild identified by the provided guildID.
+func (a *announcements) get(guildID string) *announcement {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.messages[guildID]
+}
+
+// announce posts a message with the song that is played by the provided
+// audioplayer, when the guild identified by the provided guildID has
+// the announcements enabled. The message is posted to the configured
+// channel, or to the queue message's channel, and the guild's previous
+// announcement is deleted. When the provided onlyNew is true, the song
+// is announced only if it is not the one announced last.
+func (bot *AudioplayerEventHandler) announce(guildID string, ap *audioplayer.AudioPlayer, onlyNew bool) error {
+	settings := bot.guilds.Get(guildID).Announcements
+	if !settings.Enabled() {
+		return nil
+	}
+	state := ap.State()
+	if !state.Playing || state.Song == nil {
+		bot.clearAnnouncement(guildID)
+		return nil
+	}
+	if last := bot.announcements.get(guildID); onlyNew &&
+		last != nil && last.songID == state.Song.ID {
+		return nil
+	}
+	channelID := settings.ChannelID
+	if len(channelID) == 0 {
+		queue, err := bot.datastore.Queue().GetQueue(
+			bot.ctx,
+			bot.session.ClientID(),
+			guildID,
+		)
+		if err != nil {
+			return err
+		}
+		channelID = queue.ChannelID
+	}
+	// NOTE: the announcements are not a response to any
+	// interaction, so they are in the guild's language
+	l := bot.guildLocalizer(guildID)
+	content := l.T("songs.playing", state.Song.Name)
+	if len(state.Song.RequesterID) > 0 {
+		content = l.T(
+			"songs.playing_requested",
+			state.Song.Name,
+			fmt.Sprintf("<@%s>", state.Song.RequesterID),
+		)
+	}
+	m, err := bot.session.ChannelMessageSendComplex(
+		channelID,
+		&discordgo.MessageSend{
+			Content: content,
+			// NOTE: the requester is mentioned,
+			// but not notified
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	prev := bot.announcements.swap(guildID, &announcement{
+		channelID: m.ChannelID,
+		messageID: m.ID,
+		songID:    state.Song.ID,
+	})
+	bot.deleteAnnouncement(prev)
+	return nil
+}
+
+// clearAnnouncement deletes the latest announcement of the guild
+// identified by the provided guildID, once nothing is playing there.
+func (bot *AudioplayerEventHandler) clearAnnouncement(guildID string) {
+	bot.deleteAnnouncement(bot.announcements.swap(guildID, nil))
+}
+
+// deleteAnnouncement deletes the provided announcement's message.
+func (bot *AudioplayerEventHandler) deleteAnnouncement(a *announcement) {
+	if a == nil {
+		return
+	}
+	if err := bot.session.ChannelMessageDelete(a.channelID, a.messageID); err != nil {
+		bot.log.Tracef("Could not delete announcement: %v", err)
+	}
+}
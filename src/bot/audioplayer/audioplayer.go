@@ -3,165 +3,477 @@ package audioplayer
 import (
 	"context"
 	"discord-music-bot/model"
-	"discord-music-bot/youtube"
-	"discord-music-bot/youtube/stream"
 	"errors"
 	"io"
+	"sync"
 	"time"
 
-	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
 )
 
+var (
+	ErrStopped    = errors.New("Audioplayer has been stopped")
+	ErrNotPlaying = errors.New("Audioplayer is not playing")
+	ErrNoVoice    = errors.New("Audioplayer has no voice sink")
+)
+
+// State is a snapshot of the audioplayer's playback.
+type State struct {
+	Song     *model.Song
+	Playing  bool
+	Paused   bool
+	Position time.Duration
+}
+
+type commandType int
+
+const (
+	commandPlay commandType = iota
+	commandSkip
+	commandPrevious
+	commandPause
+	commandUnpause
+	commandSeek
+	commandStop
+)
+
+type command struct {
+	tp       commandType
+	sink     VoiceSink
+	position time.Duration
+	result   chan error
+}
+
 type AudioPlayer struct {
-	youtube         *youtube.Youtube
-	streamSession   *stream.Session
-	durationSeconds int
-	subscriptions   *Subscriptions
-	stop            bool
+	guildID   string
+	log       *log.Logger
+	queue     Queue
+	events    *EventBus
+	commands  chan *command
+	done      chan struct{}
+	state     State
+	start     time.Duration
+	stateSync sync.Mutex
+	// NOTE: the fields below are owned by
+	// the audioplayer's goroutine
+	sink      VoiceSink
+	stream    Stream
+	startedAt time.Time
+	openedAt  time.Time
+	attempts  int
+	// NOTE: the head song is played for the sum of it's streams'
+	// durations, as it may be streamed again when seeking or
+	// retrying, it is recorded once it leaves the queue's head
+	playedSong *model.Song
+	played     time.Duration
 }
 
-// NewAudioPlayer constructs an object that handles playing
-// audio in a discord's voice channel
-func NewAudioPlayer(yt *youtube.Youtube) *AudioPlayer {
+// NewAudioPlayer constructs an object that plays the songs of the
+// provided queue in a discord's voice channel.
+// NOTE: this starts a worker that owns the audioplayer's voice sink,
+// stream and queue, and processes the audioplayer's commands one at
+// a time, in the order in which they have been sent. The worker runs
+// until the audioplayer is stopped or the provided ctx is done.
+func NewAudioPlayer(ctx context.Context, guildID string, queue Queue, log *log.Logger) *AudioPlayer {
 	ap := &AudioPlayer{
-		youtube:         yt,
-		streamSession:   nil,
-		stop:            false,
-		subscriptions:   NewSubscriptions(),
-		durationSeconds: 0,
+		guildID:   guildID,
+		log:       log,
+		queue:     queue,
+		events:    NewEventBus(),
+		commands:  make(chan *command),
+		done:      make(chan struct{}),
+		state:     State{},
+		stateSync: sync.Mutex{},
 	}
+	go ap.run(ctx)
 	return ap
 }
 
-func (ap *AudioPlayer) Subscriptions() *Subscriptions {
-	return ap.subscriptions
+// Events returns the event bus to which the audioplayer
+// emits the changes of its playback.
+func (ap *AudioPlayer) Events() *EventBus {
+	return ap.events
 }
 
-// Stop stops the audioplayer's stream session
-func (ap *AudioPlayer) Stop() {
-	ap.stop = true
-	if ap.streamSession == nil {
-		return
-	}
-	ap.streamSession.Stop()
+// Done returns a channel that is closed once
+// the audioplayer has been stopped.
+func (ap *AudioPlayer) Done() <-chan struct{} {
+	return ap.done
 }
 
-// Pause pauses the audioplayer's stream session
-func (ap *AudioPlayer) Pause() {
-	if ap.streamSession == nil {
-		return
+// Stopped returns true if the audioplayer has been
+// stopped and no longer accepts commands.
+func (ap *AudioPlayer) Stopped() bool {
+	select {
+	case <-ap.done:
+		return true
+	default:
+		return false
 	}
-	ap.streamSession.SetPaused(true)
 }
 
-// Unpause unpauses the audioplayer's stream session
-func (ap *AudioPlayer) Unpause() {
-	if ap.streamSession == nil {
-		return
+// State returns the current state of the audioplayer's playback.
+// Once the playback has stopped, the position is the one at
+// which it has been stopped.
+func (ap *AudioPlayer) State() State {
+	ap.stateSync.Lock()
+	defer ap.stateSync.Unlock()
+
+	state := ap.state
+	if state.Playing && ap.stream != nil {
+		state.Position = ap.start + ap.stream.PlaybackPosition()
 	}
-	ap.streamSession.SetPaused(false)
+	return state
 }
 
-// IsPaused returns true if the audioplayer is currenthly
-// paused, false otherwise
-func (ap *AudioPlayer) IsPaused() bool {
-	if ap.streamSession == nil {
-		return false
-	}
-	return ap.streamSession.Paused()
+// Play starts playing the queue's head song in the provided voice
+// sink, from the provided position in the song. If a song is already
+// playing, only the voice sink is replaced. When the provided sink is
+// nil, the previously provided one is used.
+func (ap *AudioPlayer) Play(ctx context.Context, sink VoiceSink, position time.Duration) error {
+	return ap.send(ctx, &command{
+		tp:       commandPlay,
+		sink:     sink,
+		position: position,
+	})
+}
+
+// Skip stops the playing song and starts playing the next one.
+func (ap *AudioPlayer) Skip(ctx context.Context) error {
+	return ap.send(ctx, &command{tp: commandSkip})
+}
+
+// Previous moves the previously played song to the front of the queue,
+// and starts playing it, if the audioplayer has a voice sink.
+func (ap *AudioPlayer) Previous(ctx context.Context) error {
+	return ap.send(ctx, &command{tp: commandPrevious})
+}
+
+// Pause pauses the playing song.
+func (ap *AudioPlayer) Pause(ctx context.Context) error {
+	return ap.send(ctx, &command{tp: commandPause})
+}
+
+// Unpause unpauses the paused song.
+func (ap *AudioPlayer) Unpause(ctx context.Context) error {
+	return ap.send(ctx, &command{tp: commandUnpause})
 }
 
-// PlaybackPosition returns the duration of the currently playing
-// stream already streamed. Returns 0 if nothing
-// is playing.
-func (ap *AudioPlayer) PlaybackPosition() time.Duration {
-	if ap.streamSession == nil {
-		return 0
+// Seek restarts the playing song from the provided position.
+func (ap *AudioPlayer) Seek(ctx context.Context, position time.Duration) error {
+	return ap.send(ctx, &command{
+		tp:       commandSeek,
+		position: position,
+	})
+}
+
+// Stop stops the playing song and stops the audioplayer,
+// after which it no longer accepts commands.
+func (ap *AudioPlayer) Stop(ctx context.Context) error {
+	return ap.send(ctx, &command{tp: commandStop})
+}
+
+// send sends the provided command to the audioplayer's
+// worker and waits for the command's result.
+func (ap *AudioPlayer) send(ctx context.Context, cmd *command) error {
+	cmd.result = make(chan error, 1)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-ap.done:
+		return ErrStopped
+	case ap.commands <- cmd:
 	}
-	if ap.streamSession.Finished() {
-		return 0
+	select {
+	case err := <-cmd.result:
+		return err
+	case <-ap.done:
+		// NOTE: the worker sends the result before it
+		// exits, so it may have been sent by now
+		select {
+		case err := <-cmd.result:
+			return err
+		default:
+			return ErrStopped
+		}
 	}
-	return ap.streamSession.PlaybackPosition()
 }
 
-// Cleanup sets the audioplayer's data back to default.
-func (ap *AudioPlayer) Cleanup() {
-	ap.stop = false
-	ap.durationSeconds = 0
-	if ap.streamSession != nil {
-		ap.streamSession.Cleanup()
-		ap.streamSession = nil
+// run processes the audioplayer's commands and the finished
+// streams until the audioplayer is stopped or ctx is done.
+func (ap *AudioPlayer) run(ctx context.Context) {
+	defer ap.exit()
+
+	// NOTE: check at intervals whether the voice
+	// sink is still ready to receive the stream
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		var streamDone <-chan error
+		if ap.stream != nil {
+			streamDone = ap.stream.Done()
+		}
+		select {
+		case <-ctx.Done():
+			ap.closeStream(true)
+			ap.recordPlayed()
+			return
+		case cmd := <-ap.commands:
+			if cmd.tp == commandStop {
+				ap.closeStream(true)
+				ap.recordPlayed()
+				cmd.result <- nil
+				return
+			}
+			event, err := ap.handle(ctx, cmd)
+			cmd.result <- err
+			if err == nil {
+				ap.events.Emit(event)
+			}
+		case err := <-streamDone:
+			ap.streamFinished(ctx, err)
+		case <-ticker.C:
+			if ap.stream != nil && !ap.sink.Ready() {
+				ap.log.WithField("GuildID", ap.guildID).Trace(
+					"Voice connection closed, stopping the audioplayer",
+				)
+				ap.closeStream(true)
+				ap.recordPlayed()
+				return
+			}
+		}
 	}
 }
 
-// Play starts playing the provided song in the bot's
-// current voice channel. Returns error if the bot is not connected.
-func (ap *AudioPlayer) Play(ctx context.Context, song *model.Song, vc *discordgo.VoiceConnection) (int, error) {
-	defer ap.Cleanup()
+// handle processes a single command and returns
+// the event that should be emitted once it succeeds.
+func (ap *AudioPlayer) handle(ctx context.Context, cmd *command) (Event, error) {
+	switch cmd.tp {
+	case commandPlay:
+		if cmd.sink != nil {
+			ap.sink = cmd.sink
+		}
+		if ap.stream != nil {
+			return EventPlay, nil
+		}
+		return EventPlay, ap.playHeadSong(ctx, cmd.position)
+	case commandSkip:
+		if ap.stream == nil {
+			return EventSkip, ErrNotPlaying
+		}
+		ap.closeStream(true)
+		ap.recordPlayed()
+		if err := ap.queue.Next(ctx); err != nil {
+			return EventSkip, err
+		}
+		return EventSkip, ap.playHeadSong(ctx, 0)
+	case commandPrevious:
+		ap.closeStream(true)
+		ap.recordPlayed()
+		if err := ap.queue.Previous(ctx); err != nil {
+			return EventSkipToPrevious, err
+		}
+		if ap.sink == nil {
+			return EventSkipToPrevious, nil
+		}
+		return EventSkipToPrevious, ap.playHeadSong(ctx, 0)
+	case commandPause, commandUnpause:
+		if ap.stream == nil {
+			return EventPause, ErrNotPlaying
+		}
+		paused := cmd.tp == commandPause
+		ap.stream.SetPaused(paused)
+		ap.stateSync.Lock()
+		ap.state.Paused = paused
+		ap.stateSync.Unlock()
+		if paused {
+			return EventPause, nil
+		}
+		return EventUnpause, nil
+	case commandSeek:
+		if ap.stream == nil {
+			return EventSeek, ErrNotPlaying
+		}
+		ap.closeStream(true)
+		return EventSeek, ap.playHeadSong(ctx, cmd.position)
+	}
+	return EventPlay, errors.New("Unknown audioplayer command")
+}
 
-	vc.Speaking(true)
-	defer vc.Speaking(false)
+// streamFinished handles the stream that has finished on itself,
+// and starts playing the next song.
+func (ap *AudioPlayer) streamFinished(ctx context.Context, err error) {
+	song := ap.State().Song
+	quick := time.Since(ap.openedAt) < 3*time.Second
+	remaining := time.Duration(song.DurationSeconds)*time.Second - ap.start
+	ap.closeStream(false)
 
-	var potError error = nil
-streamingLoop:
-	// NOTE: try to run the stream 3 times in case
-	// something went wrong with encoding and the stream finished
-	// with an error in less than a second
-	for i := 0; i < 3; i++ {
-		if ap.stop {
-			ap.stop = false
-			return 3, nil
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	// NOTE: the stream finished in less than a few seconds,
+	// something may have went wrong with encoding, so retry it
+	if quick && remaining > 3*time.Second {
+		if ap.attempts < 3 {
+			ap.attempts++
+			if e := ap.openStream(song, ap.start); e == nil {
+				return
+			}
+		}
+		if err == nil {
+			err = errors.New("Ended unexpectedly")
+		}
+	}
+	ap.recordPlayed()
+	if err != nil {
+		ap.log.WithField("GuildID", ap.guildID).Tracef(
+			"Audioplayer finished with error: %v", err,
+		)
+		if e := ap.queue.Drop(ctx); e != nil {
+			ap.log.WithField("GuildID", ap.guildID).Errorf(
+				"Error when dropping the head song: %v", e,
+			)
 		}
-		streamSession, err := ap.youtube.Stream().GetSession(
-			song.Url,
-			vc,
+		ap.playHeadSong(ctx, 0)
+		ap.events.Emit(EventError)
+		return
+	}
+	ap.log.WithField("GuildID", ap.guildID).Trace(
+		"Audioplayer finished on itself",
+	)
+	if e := ap.queue.Next(ctx); e != nil {
+		ap.log.WithField("GuildID", ap.guildID).Errorf(
+			"Error when moving to the next song: %v", e,
 		)
+	}
+	ap.playHeadSong(ctx, 0)
+	ap.events.Emit(EventFinished)
+}
+
+// playHeadSong starts streaming the queue's head song from the
+// provided position. The head songs that cannot be streamed
+// are dropped from the queue. Nothing is streamed, without an
+// error, when there are no songs in the queue.
+func (ap *AudioPlayer) playHeadSong(ctx context.Context, position time.Duration) error {
+	if ap.sink == nil {
+		return ErrNoVoice
+	}
+	for {
+		song, err := ap.queue.HeadSong(ctx)
 		if err != nil {
-			potError = err
-			continue streamingLoop
-		}
-		ap.streamSession = streamSession
-
-		streamDone := ap.streamSession.StreamDone()
-
-		done := ctx.Done()
-		t := time.Now()
-
-		for {
-			select {
-			case <-done:
-				return 4, nil
-			case err = <-streamDone:
-				if err.Error() == "Voice connection closed" {
-					// NOTE: if voice connection has been closed,
-					// just stop without calling any defer functions
-					return 2, errors.New("Voice connection closed")
-				}
-				// NOTE: the stream finished, if it lasted
-				// less than a second, retry it
-				if song.DurationSeconds > 3 && time.Since(t) < 3*time.Second {
-					if ap.streamSession != nil {
-						ap.streamSession.Cleanup()
-						ap.streamSession = nil
-					}
-					potError = errors.New("Ended unexpectedly")
-					continue streamingLoop
-				}
-				if err != nil && err != io.EOF {
-					return 1, err
-				}
-				// NOTE: the stream finished successfully
-				return 0, nil
-			default:
-				if vc.Ready == false {
-					return 2, errors.New("Voice connection closed")
-				}
-				if ap.stop {
-					ap.stop = false
-					return 3, nil
-				}
-			}
+			ap.log.WithField("GuildID", ap.guildID).Tracef(
+				"No head song found, cannot start playing: %v", err,
+			)
+			return nil
+		}
+		ap.attempts = 1
+		if ap.playedSong == nil {
+			// NOTE: the song is not restarted
+			// when seeking, it keeps it's start
+			ap.startedAt = time.Now()
+		}
+		if err = ap.openStream(song, position); err == nil {
+			ap.log.WithField("GuildID", ap.guildID).Tracef(
+				"Playing song: %v", song.Name,
+			)
+			return nil
+		}
+		ap.log.WithField("GuildID", ap.guildID).Debugf(
+			"Failed to stream song %v: %v", song.Name, err,
+		)
+		ap.recordPlayed()
+		if err := ap.queue.Drop(ctx); err != nil {
+			return err
 		}
+		position = 0
 	}
-	return 1, potError
+}
+
+// openStream starts streaming the provided song to the voice sink,
+// from the provided position. Opening the stream is retried a few
+// times before giving up.
+func (ap *AudioPlayer) openStream(song *model.Song, position time.Duration) error {
+	var err error
+	for i := 0; i < 3; i++ {
+		if !ap.sink.Ready() {
+			return errors.New("Voice connection closed")
+		}
+		var stream Stream
+		if stream, err = ap.sink.Stream(song, position); err != nil {
+			continue
+		}
+		ap.sink.Speaking(true)
+		ap.openedAt = time.Now()
+
+		ap.stateSync.Lock()
+		ap.stream = stream
+		ap.start = position
+		ap.state = State{
+			Song:     song,
+			Playing:  true,
+			Paused:   false,
+			Position: position,
+		}
+		ap.stateSync.Unlock()
+		return nil
+	}
+	return err
+}
+
+// closeStream stops the stream, if any, and adds the duration for
+// which it has been played to the head song's played duration. When
+// wait is true, the stream is stopped and the closing waits until
+// the stream is done.
+func (ap *AudioPlayer) closeStream(wait bool) {
+	if ap.stream == nil {
+		return
+	}
+	stream := ap.stream
+	if wait {
+		stream.Stop()
+		select {
+		case <-stream.Done():
+		case <-time.After(5 * time.Second):
+			ap.log.WithField("GuildID", ap.guildID).Debug(
+				"Stream has not finished after being stopped",
+			)
+		}
+	}
+	played := stream.PlaybackPosition()
+
+	ap.stateSync.Lock()
+	ap.stream = nil
+	ap.state.Playing = false
+	ap.state.Paused = false
+	ap.state.Position = ap.start + played
+	song := ap.state.Song
+	ap.stateSync.Unlock()
+
+	stream.Cleanup()
+	ap.sink.Speaking(false)
+	ap.playedSong = song
+	ap.played += played
+}
+
+// recordPlayed records how long the song, that is leaving the
+// queue's head, has been played across all of it's streams.
+func (ap *AudioPlayer) recordPlayed() {
+	if ap.playedSong == nil {
+		return
+	}
+	ap.queue.Played(ap.playedSong, ap.startedAt, ap.played)
+	ap.playedSong = nil
+	ap.played = 0
+}
+
+// exit marks the audioplayer as stopped and emits the stop event,
+// after which the audioplayer's event bus is closed.
+func (ap *AudioPlayer) exit() {
+	close(ap.done)
+	stopped := ap.events.Emit(EventStop)
+	go func() {
+		<-stopped.Done()
+		ap.events.Close()
+	}()
 }
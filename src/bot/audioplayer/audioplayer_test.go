@@ -0,0 +1,361 @@
+package audioplayer_test
+
+import (
+	"context"
+	"discord-music-bot/bot/audioplayer"
+	"discord-music-bot/model"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeStream is a stream that never sends anything,
+// it finishes once it is stopped or finished by the test.
+type fakeStream struct {
+	song     *model.Song
+	start    time.Duration
+	done     chan error
+	once     sync.Once
+	mutex    sync.Mutex
+	paused   bool
+	position time.Duration
+}
+
+func (s *fakeStream) Done() <-chan error { return s.done }
+
+func (s *fakeStream) SetPaused(paused bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.paused = paused
+}
+
+func (s *fakeStream) PlaybackPosition() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.position
+}
+
+func (s *fakeStream) Stop() { s.finish(errors.New("stopped")) }
+
+func (s *fakeStream) Cleanup() {}
+
+func (s *fakeStream) finish(err error) {
+	s.once.Do(func() { s.done <- err })
+}
+
+func (s *fakeStream) setPosition(position time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.position = position
+}
+
+// fakeVoiceSink records the streams started by the audioplayer.
+type fakeVoiceSink struct {
+	mutex   sync.Mutex
+	streams []*fakeStream
+}
+
+func (sink *fakeVoiceSink) Stream(song *model.Song, start time.Duration) (audioplayer.Stream, error) {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	stream := &fakeStream{song: song, start: start, done: make(chan error, 1)}
+	sink.streams = append(sink.streams, stream)
+	return stream, nil
+}
+
+func (sink *fakeVoiceSink) Ready() bool { return true }
+
+func (sink *fakeVoiceSink) Speaking(speaking bool) {}
+
+func (sink *fakeVoiceSink) count() int {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	return len(sink.streams)
+}
+
+func (sink *fakeVoiceSink) last() *fakeStream {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	return sink.streams[len(sink.streams)-1]
+}
+
+// fakeQueue is an in-memory queue of songs, that
+// records the songs' played durations.
+type fakeQueue struct {
+	mutex   sync.Mutex
+	songs   []*model.Song
+	played  []*model.Song
+	listens []listen
+}
+
+type listen struct {
+	song   string
+	played time.Duration
+}
+
+func (q *fakeQueue) HeadSong(ctx context.Context) (*model.Song, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.songs) == 0 {
+		return nil, errors.New("no songs")
+	}
+	return q.songs[0], nil
+}
+
+func (q *fakeQueue) Next(ctx context.Context) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.songs) > 0 {
+		q.played = append(q.played, q.songs[0])
+		q.songs = q.songs[1:]
+	}
+	return nil
+}
+
+func (q *fakeQueue) Previous(ctx context.Context) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.played) == 0 {
+		return errors.New("no previous songs")
+	}
+	song := q.played[len(q.played)-1]
+	q.played = q.played[:len(q.played)-1]
+	q.songs = append([]*model.Song{song}, q.songs...)
+	return nil
+}
+
+func (q *fakeQueue) Drop(ctx context.Context) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.songs) > 0 {
+		q.songs = q.songs[1:]
+	}
+	return nil
+}
+
+func (q *fakeQueue) Played(song *model.Song, startedAt time.Time, played time.Duration) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.listens = append(q.listens, listen{song: song.Name, played: played})
+}
+
+type AudioPlayerTestSuite struct {
+	suite.Suite
+	ctx    context.Context
+	cancel context.CancelFunc
+	sink   *fakeVoiceSink
+	queue  *fakeQueue
+	ap     *audioplayer.AudioPlayer
+}
+
+// SetupTest runs before every test and creates an audioplayer
+// with a fake voice sink and a queue with a few songs.
+func (s *AudioPlayerTestSuite) SetupTest() {
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.sink = &fakeVoiceSink{}
+	s.queue = &fakeQueue{}
+	for _, name := range []string{"Song1", "Song2", "Song3"} {
+		s.queue.songs = append(s.queue.songs, &model.Song{
+			Name: name,
+			// NOTE: short songs are not retried when
+			// they finish soon after being started
+			DurationSeconds: 2,
+		})
+	}
+	s.ap = audioplayer.NewAudioPlayer(
+		s.ctx,
+		"GUILD-ID-TEST",
+		s.queue,
+		logrus.StandardLogger(),
+	)
+}
+
+// TearDownTest runs after every test and
+// stops the audioplayer.
+func (s *AudioPlayerTestSuite) TearDownTest() {
+	s.cancel()
+	<-s.ap.Done()
+}
+
+// TestUnitCommands checks that play, pause, seek, skip and stop
+// commands change the playback in the order in which they are sent.
+func (s *AudioPlayerTestSuite) TestUnitCommands() {
+	events := make(chan audioplayer.Event, 10)
+	for _, event := range audioplayer.Events {
+		e := event
+		s.ap.Events().Subscribe(e, func() error {
+			events <- e
+			return nil
+		})
+	}
+	s.ErrorIs(s.ap.Skip(s.ctx), audioplayer.ErrNotPlaying)
+	s.ErrorIs(s.ap.Play(s.ctx, nil, 0), audioplayer.ErrNoVoice)
+
+	s.NoError(s.ap.Play(s.ctx, s.sink, 0))
+	s.Equal("Song1", s.ap.State().Song.Name)
+	s.True(s.ap.State().Playing)
+
+	s.NoError(s.ap.Pause(s.ctx))
+	s.True(s.ap.State().Paused)
+	s.True(s.sink.last().paused)
+	s.NoError(s.ap.Unpause(s.ctx))
+	s.False(s.ap.State().Paused)
+
+	s.NoError(s.ap.Seek(s.ctx, time.Second))
+	s.Equal(time.Second, s.sink.last().start)
+	s.Equal(time.Second, s.ap.State().Position)
+
+	s.NoError(s.ap.Skip(s.ctx))
+	s.Equal("Song2", s.ap.State().Song.Name)
+	s.Equal("Song2", s.sink.last().song.Name)
+
+	s.NoError(s.ap.Previous(s.ctx))
+	s.Equal("Song1", s.ap.State().Song.Name)
+
+	s.NoError(s.ap.Stop(s.ctx))
+	s.True(s.ap.Stopped())
+	s.False(s.ap.State().Playing)
+	s.ErrorIs(s.ap.Play(s.ctx, s.sink, 0), audioplayer.ErrStopped)
+
+	expected := []audioplayer.Event{
+		audioplayer.EventPlay,
+		audioplayer.EventPause,
+		audioplayer.EventUnpause,
+		audioplayer.EventSeek,
+		audioplayer.EventSkip,
+		audioplayer.EventSkipToPrevious,
+		audioplayer.EventStop,
+	}
+	for _, event := range expected {
+		select {
+		case e := <-events:
+			s.Equal(event, e)
+		case <-time.After(time.Second):
+			s.Fail("Event not emitted", event.String())
+		}
+	}
+}
+
+// TestUnitFinished checks that the next song starts playing once the
+// playing song finishes, and that the playback stops at the end
+// of the queue.
+func (s *AudioPlayerTestSuite) TestUnitFinished() {
+	finished := make(chan struct{}, 10)
+	s.ap.Events().Subscribe(audioplayer.EventFinished, func() error {
+		finished <- struct{}{}
+		return nil
+	})
+	s.NoError(s.ap.Play(s.ctx, s.sink, 0))
+
+	for _, next := range []string{"Song2", "Song3", ""} {
+		s.sink.last().finish(io.EOF)
+		select {
+		case <-finished:
+		case <-time.After(time.Second):
+			s.FailNow("Finished event not emitted")
+		}
+		state := s.ap.State()
+		if next == "" {
+			s.False(state.Playing)
+			continue
+		}
+		s.True(state.Playing)
+		s.Equal(next, state.Song.Name)
+	}
+	s.Len(s.queue.played, 3)
+}
+
+// TestUnitPlayedOnce checks that a song, that is retried after
+// finishing too quickly and then seeked, is recorded as played
+// only once it finishes, for the sum of it's streams' durations.
+func (s *AudioPlayerTestSuite) TestUnitPlayedOnce() {
+	s.queue.songs[0].DurationSeconds = 60
+	finished := make(chan struct{}, 1)
+	s.ap.Events().Subscribe(audioplayer.EventFinished, func() error {
+		finished <- struct{}{}
+		return nil
+	})
+	s.NoError(s.ap.Play(s.ctx, s.sink, 0))
+
+	s.sink.last().setPosition(2 * time.Second)
+	s.sink.last().finish(io.EOF)
+	s.Eventually(func() bool {
+		return s.sink.count() == 2
+	}, time.Second, 10*time.Millisecond, "Quickly finished stream not retried")
+
+	s.sink.last().setPosition(time.Second)
+	s.NoError(s.ap.Seek(s.ctx, 58*time.Second))
+	s.sink.last().setPosition(time.Second)
+	s.queue.mutex.Lock()
+	s.Empty(s.queue.listens)
+	s.queue.mutex.Unlock()
+
+	s.sink.last().finish(io.EOF)
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		s.FailNow("Finished event not emitted")
+	}
+	s.queue.mutex.Lock()
+	defer s.queue.mutex.Unlock()
+	s.Equal([]listen{{song: "Song1", played: 4 * time.Second}}, s.queue.listens)
+}
+
+// TestUnitConcurrentCommands sends commands from many goroutines at
+// once, they should all be processed one at a time without races.
+func (s *AudioPlayerTestSuite) TestUnitConcurrentCommands() {
+	for i := 0; i < 20; i++ {
+		s.queue.songs = append(s.queue.songs, &model.Song{
+			Name:            "Song",
+			DurationSeconds: 2,
+		})
+	}
+	s.NoError(s.ap.Play(s.ctx, s.sink, 0))
+
+	wg := sync.WaitGroup{}
+	skipped := make(chan struct{}, 20)
+	for i := 0; i < 10; i++ {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			if s.ap.Skip(s.ctx) == nil {
+				skipped <- struct{}{}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			s.ap.Pause(s.ctx)
+			s.ap.State()
+		}()
+		go func() {
+			defer wg.Done()
+			s.ap.Unpause(s.ctx)
+		}()
+		go func() {
+			defer wg.Done()
+			s.ap.Seek(s.ctx, time.Second)
+			s.ap.Play(s.ctx, s.sink, 0)
+		}()
+	}
+	wg.Wait()
+	close(skipped)
+
+	count := 0
+	for range skipped {
+		count++
+	}
+	s.Equal(10, count)
+	s.Len(s.queue.played, 10)
+	s.True(s.ap.State().Playing)
+}
+
+// TestAudioPlayerTestSuite runs all tests under
+// the AudioPlayerTestSuite suite.
+func TestAudioPlayerTestSuite(t *testing.T) {
+	suite.Run(t, new(AudioPlayerTestSuite))
+}
@@ -24,6 +24,21 @@ func (apm *AudioPlayersMap) Add(guildID string, ap *AudioPlayer) {
 	apm.audiopayers[guildID] = ap
 }
 
+// GetOrAdd returns the audioplayer for the guildID from the map.
+// If there is no such audioplayer or it has been stopped, the one
+// returned by the provided create function is added and returned.
+func (apm *AudioPlayersMap) GetOrAdd(guildID string, create func() *AudioPlayer) *AudioPlayer {
+	apm.mutex.Lock()
+	defer apm.mutex.Unlock()
+
+	if ap, ok := apm.audiopayers[guildID]; ok && !ap.Stopped() {
+		return ap
+	}
+	ap := create()
+	apm.audiopayers[guildID] = ap
+	return ap
+}
+
 // Remove removes the audioplayer from the map
 // for the provided guildiD
 func (apm *AudioPlayersMap) Remove(guildID string) {
@@ -33,8 +48,9 @@ func (apm *AudioPlayersMap) Remove(guildID string) {
 	delete(apm.audiopayers, guildID)
 }
 
-// Get returns the audioplayer for the guildID from the map
-// returns nil, false if there is no such audioplayer
+// Get returns the audioplayer for the guildID from the map, even
+// if it has already been stopped, so its last state may be read.
+// Returns nil, false if there is no such audioplayer
 func (apm *AudioPlayersMap) Get(guildID string) (*AudioPlayer, bool) {
 	apm.mutex.Lock()
 	defer apm.mutex.Unlock()
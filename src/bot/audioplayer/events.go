@@ -0,0 +1,253 @@
+package audioplayer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Event identifies a change of the audioplayer's playback,
+// emitted to the audioplayer's event bus once the audioplayer
+// has processed a command or a song has finished playing.
+type Event int
+
+const (
+	EventPlay           Event = iota // A song has started playing
+	EventPause                       // The playing song has been paused
+	EventUnpause                     // The paused song has been unpaused
+	EventSeek                        // The playing song has been restarted at another position
+	EventSkip                        // The playing song has been skipped
+	EventSkipToPrevious              // The previously played song has been moved to the front
+	EventFinished                    // The playing song has finished on itself
+	EventError                       // The playing song has finished with an error
+	EventStop                        // The audioplayer has stopped and no longer accepts commands
+)
+
+// Events holds all the events emitted by the audioplayer.
+var Events = []Event{
+	EventPlay,
+	EventPause,
+	EventUnpause,
+	EventSeek,
+	EventSkip,
+	EventSkipToPrevious,
+	EventFinished,
+	EventError,
+	EventStop,
+}
+
+var eventNames = map[Event]string{
+	EventPlay:           "Play",
+	EventPause:          "Pause",
+	EventUnpause:        "Unpause",
+	EventSeek:           "Seek",
+	EventSkip:           "Skip",
+	EventSkipToPrevious: "SkipToPrevious",
+	EventFinished:       "Finished",
+	EventError:          "Error",
+	EventStop:           "Stop",
+}
+
+// String returns the name of the event.
+func (event Event) String() string {
+	if name, ok := eventNames[event]; ok {
+		return name
+	}
+	return fmt.Sprintf("Event(%d)", int(event))
+}
+
+// Handler handles a single emitted event, the returned
+// error is reported to the event bus's error handler.
+type Handler func() error
+
+type subscriber struct {
+	id      uint
+	handler Handler
+}
+
+// Subscription is a handler subscribed to an event
+// of the event bus.
+type Subscription struct {
+	bus   *EventBus
+	event Event
+	id    uint
+}
+
+// Emission is a single event emitted to the event bus.
+type Emission struct {
+	event Event
+	done  chan struct{}
+	err   error
+}
+
+type EventBus struct {
+	subscribers map[Event][]*subscriber
+	onError     func(Event, error)
+	pending     []*Emission
+	idx         uint
+	mutex       sync.Mutex
+	signal      chan struct{}
+	closed      chan struct{}
+	isClosed    bool
+}
+
+// NewEventBus constructs an object that delivers the emitted
+// events to their subscribed handlers. The events are delivered
+// one at a time in the order in which they have been emitted,
+// and the handlers of an event are called in the order in which
+// they have been subscribed.
+// NOTE: this starts a worker that delivers the events until
+// the event bus is closed.
+func NewEventBus() *EventBus {
+	bus := &EventBus{
+		subscribers: make(map[Event][]*subscriber),
+		onError:     func(Event, error) {},
+		pending:     make([]*Emission, 0),
+		idx:         0,
+		mutex:       sync.Mutex{},
+		signal:      make(chan struct{}, 1),
+		closed:      make(chan struct{}),
+	}
+	go bus.run()
+	return bus
+}
+
+// Subscribe adds the provided handler to the handlers
+// of the provided event.
+func (bus *EventBus) Subscribe(event Event, handler Handler) *Subscription {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	id := bus.idx
+	bus.idx++
+	bus.subscribers[event] = append(
+		bus.subscribers[event],
+		&subscriber{id: id, handler: handler},
+	)
+	return &Subscription{bus: bus, event: event, id: id}
+}
+
+// OnError sets the function that is called with the errors
+// returned by the handlers of the emitted events.
+func (bus *EventBus) OnError(f func(event Event, err error)) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	bus.onError = f
+}
+
+// Emit adds the provided event to the end of the queue of the
+// events to be delivered, and returns without waiting for its
+// handlers. The returned emission may be used to wait for them.
+// NOTE: a handler should not wait for an event that it emits, as
+// the events are delivered one at a time.
+func (bus *EventBus) Emit(event Event) *Emission {
+	emission := &Emission{
+		event: event,
+		done:  make(chan struct{}),
+	}
+	bus.mutex.Lock()
+	if bus.isClosed {
+		bus.mutex.Unlock()
+		// NOTE: the event bus no longer delivers events,
+		// so there is nothing to wait for
+		close(emission.done)
+		return emission
+	}
+	bus.pending = append(bus.pending, emission)
+	bus.mutex.Unlock()
+
+	select {
+	case bus.signal <- struct{}{}:
+	default:
+	}
+	return emission
+}
+
+// Close stops delivering the events. The events that have
+// not yet been delivered are dropped.
+func (bus *EventBus) Close() {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	if bus.isClosed {
+		return
+	}
+	bus.isClosed = true
+	for _, emission := range bus.pending {
+		close(emission.done)
+	}
+	bus.pending = nil
+	close(bus.closed)
+}
+
+// Unsubscribe removes the subscription's handler from the
+// handlers of its event, so it is no longer called.
+func (s *Subscription) Unsubscribe() {
+	s.bus.mutex.Lock()
+	defer s.bus.mutex.Unlock()
+
+	subscribers := s.bus.subscribers[s.event]
+	for i, sub := range subscribers {
+		if sub.id == s.id {
+			s.bus.subscribers[s.event] = append(
+				subscribers[:i:i],
+				subscribers[i+1:]...,
+			)
+			return
+		}
+	}
+}
+
+// Event returns the emitted event.
+func (emission *Emission) Event() Event {
+	return emission.event
+}
+
+// Wait blocks until all the handlers of the emitted event have
+// finished, and returns the errors that they have returned.
+func (emission *Emission) Wait() error {
+	<-emission.done
+	return emission.err
+}
+
+// Done returns a channel that is closed once all the handlers
+// of the emitted event have finished.
+func (emission *Emission) Done() <-chan struct{} {
+	return emission.done
+}
+
+// run delivers the pending events until the event bus is closed.
+func (bus *EventBus) run() {
+	for {
+		bus.mutex.Lock()
+		if len(bus.pending) == 0 {
+			bus.mutex.Unlock()
+			select {
+			case <-bus.closed:
+				return
+			case <-bus.signal:
+				continue
+			}
+		}
+		emission := bus.pending[0]
+		bus.pending = bus.pending[1:]
+		subscribers := make([]*subscriber, len(bus.subscribers[emission.event]))
+		copy(subscribers, bus.subscribers[emission.event])
+		onError := bus.onError
+		bus.mutex.Unlock()
+
+		errs := make([]string, 0)
+		for _, sub := range subscribers {
+			if err := sub.handler(); err != nil {
+				onError(emission.event, err)
+				errs = append(errs, err.Error())
+			}
+		}
+		if len(errs) > 0 {
+			emission.err = fmt.Errorf(
+				"%s: %s", emission.event, strings.Join(errs, "; "),
+			)
+		}
+		close(emission.done)
+	}
+}
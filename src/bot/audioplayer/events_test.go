@@ -0,0 +1,118 @@
+package audioplayer_test
+
+import (
+	"discord-music-bot/bot/audioplayer"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type EventBusTestSuite struct {
+	suite.Suite
+	bus *audioplayer.EventBus
+}
+
+// SetupTest runs before every test and creates
+// a new event bus.
+func (s *EventBusTestSuite) SetupTest() {
+	s.bus = audioplayer.NewEventBus()
+}
+
+// TearDownTest runs after every test and
+// closes the event bus.
+func (s *EventBusTestSuite) TearDownTest() {
+	s.bus.Close()
+}
+
+// TestUnitEmitInOrder checks that the events are delivered in the
+// order in which they have been emitted.
+func (s *EventBusTestSuite) TestUnitEmitInOrder() {
+	mutex := sync.Mutex{}
+	delivered := make([]audioplayer.Event, 0)
+	for _, e := range []audioplayer.Event{
+		audioplayer.EventStop,
+		audioplayer.EventSkip,
+		audioplayer.EventFinished,
+	} {
+		event := e
+		s.bus.Subscribe(event, func() error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			delivered = append(delivered, event)
+			return nil
+		})
+	}
+	s.bus.Emit(audioplayer.EventSkip)
+	s.bus.Emit(audioplayer.EventStop)
+	s.bus.Emit(audioplayer.EventFinished)
+	s.NoError(s.bus.Emit(audioplayer.EventSkip).Wait())
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	s.Equal([]audioplayer.Event{
+		audioplayer.EventSkip,
+		audioplayer.EventStop,
+		audioplayer.EventFinished,
+		audioplayer.EventSkip,
+	}, delivered)
+}
+
+// TestUnitUnsubscribe checks that an unsubscribed
+// handler is no longer called.
+func (s *EventBusTestSuite) TestUnitUnsubscribe() {
+	calls := make([]string, 0)
+	first := s.bus.Subscribe(audioplayer.EventPause, func() error {
+		calls = append(calls, "first")
+		return nil
+	})
+	s.bus.Subscribe(audioplayer.EventPause, func() error {
+		calls = append(calls, "second")
+		return nil
+	})
+	s.NoError(s.bus.Emit(audioplayer.EventPause).Wait())
+	s.Equal([]string{"first", "second"}, calls)
+
+	first.Unsubscribe()
+	s.NoError(s.bus.Emit(audioplayer.EventPause).Wait())
+	s.Equal([]string{"first", "second", "second"}, calls)
+}
+
+// TestUnitHandlerErrors checks that the errors returned by the handlers
+// are reported to the error handler and returned when waiting.
+func (s *EventBusTestSuite) TestUnitHandlerErrors() {
+	reported := make([]audioplayer.Event, 0)
+	s.bus.OnError(func(event audioplayer.Event, err error) {
+		reported = append(reported, event)
+	})
+	s.bus.Subscribe(audioplayer.EventError, func() error {
+		return errors.New("handler failed")
+	})
+	s.bus.Subscribe(audioplayer.EventError, func() error {
+		return nil
+	})
+	err := s.bus.Emit(audioplayer.EventError).Wait()
+	s.Error(err)
+	s.Contains(err.Error(), "handler failed")
+	s.Equal([]audioplayer.Event{audioplayer.EventError}, reported)
+}
+
+// TestUnitClose checks that waiting for the events
+// emitted to a closed event bus does not block.
+func (s *EventBusTestSuite) TestUnitClose() {
+	called := false
+	s.bus.Subscribe(audioplayer.EventStop, func() error {
+		called = true
+		return nil
+	})
+	s.bus.Close()
+	s.NoError(s.bus.Emit(audioplayer.EventStop).Wait())
+	s.False(called)
+}
+
+// TestEventBusTestSuite runs all tests under
+// the EventBusTestSuite suite.
+func TestEventBusTestSuite(t *testing.T) {
+	suite.Run(t, new(EventBusTestSuite))
+}
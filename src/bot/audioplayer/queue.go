@@ -0,0 +1,27 @@
+package audioplayer
+
+import (
+	"context"
+	"discord-music-bot/model"
+	"time"
+)
+
+// Queue is the music queue of a guild, from
+// which the audioplayer plays the songs.
+type Queue interface {
+	// HeadSong returns the song that should currently be played,
+	// or an error if there are no songs in the queue.
+	HeadSong(ctx context.Context) (*model.Song, error)
+	// Next moves the queue past its head song,
+	// once the head song has finished or has been skipped.
+	Next(ctx context.Context) error
+	// Previous moves the previously played
+	// song to the front of the queue.
+	Previous(ctx context.Context) error
+	// Drop removes the queue's head song, that
+	// could not be played, from the queue.
+	Drop(ctx context.Context) error
+	// Played records that the provided song, started at the
+	// provided time, has been played for the provided duration.
+	Played(song *model.Song, startedAt time.Time, played time.Duration)
+}
@@ -0,0 +1,104 @@
+package audioplayer
+
+import (
+	"discord-music-bot/model"
+	"discord-music-bot/youtube"
+	"discord-music-bot/youtube/stream"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// VoiceSink is a voice channel in which
+// the audioplayer plays the songs.
+type VoiceSink interface {
+	// Stream starts streaming the provided song
+	// from the provided position in the song.
+	Stream(song *model.Song, start time.Duration) (Stream, error)
+	// Ready returns true while the songs
+	// may be streamed to the voice sink.
+	Ready() bool
+	// Speaking marks whether the songs are
+	// currently streamed to the voice sink.
+	Speaking(speaking bool)
+}
+
+// Stream is a song that is being streamed to a voice sink.
+// NOTE: all the methods may be called concurrently with
+// the streaming.
+type Stream interface {
+	// Done returns a channel that receives a single value once
+	// the streaming has finished, io.EOF if the whole song
+	// has been streamed.
+	Done() <-chan error
+	// SetPaused pauses or unpauses the streaming.
+	SetPaused(paused bool)
+	// PlaybackPosition returns the duration of the song
+	// that has already been streamed.
+	PlaybackPosition() time.Duration
+	// Stop stops the streaming, the Done channel still
+	// receives a value once it has stopped.
+	Stop()
+	// Cleanup releases the stream's resources,
+	// once it has finished.
+	Cleanup()
+}
+
+type discordVoiceSink struct {
+	youtube *youtube.Youtube
+	vc      *discordgo.VoiceConnection
+}
+
+type discordStream struct {
+	session *stream.Session
+}
+
+// NewDiscordVoiceSink constructs a voice sink that streams the
+// songs from youtube to the provided discord voice connection.
+func NewDiscordVoiceSink(yt *youtube.Youtube, vc *discordgo.VoiceConnection) VoiceSink {
+	return &discordVoiceSink{
+		youtube: yt,
+		vc:      vc,
+	}
+}
+
+func (sink *discordVoiceSink) Stream(song *model.Song, start time.Duration) (Stream, error) {
+	session, err := sink.youtube.Stream().GetSession(song.Url, sink.vc, start)
+	if err != nil {
+		return nil, err
+	}
+	return &discordStream{session}, nil
+}
+
+func (sink *discordVoiceSink) Ready() bool {
+	sink.vc.RLock()
+	defer sink.vc.RUnlock()
+	return sink.vc.Ready
+}
+
+func (sink *discordVoiceSink) Speaking(speaking bool) {
+	sink.vc.Speaking(speaking)
+}
+
+func (s *discordStream) Done() <-chan error {
+	return s.session.StreamDone()
+}
+
+func (s *discordStream) SetPaused(paused bool) {
+	s.session.SetPaused(paused)
+}
+
+func (s *discordStream) PlaybackPosition() time.Duration {
+	return s.session.PlaybackPosition()
+}
+
+func (s *discordStream) Stop() {
+	s.session.Stop()
+	// NOTE: a paused streaming session does not report that
+	// it has finished, so it is unpaused once it is stopped
+	s.session.SetPaused(false)
+}
+
+func (s *discordStream) Cleanup() {
+	s.session.Cleanup()
+}
@@ -3,14 +3,17 @@ package bot
 import (
 	"context"
 	"discord-music-bot/bot/audioplayer"
-	"discord-music-bot/bot/blocked_command"
 	"discord-music-bot/bot/modal"
 	"discord-music-bot/bot/slash_command"
 	"discord-music-bot/bot/transaction"
 	"discord-music-bot/builder"
 	"discord-music-bot/datastore"
+	"discord-music-bot/i18n"
 	"discord-music-bot/service"
+	"discord-music-bot/settings"
 	"discord-music-bot/youtube"
+	"discord-music-bot/youtube/client"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -18,60 +21,96 @@ import (
 )
 
 type Bot struct {
-	log             *log.Logger
-	ctx             context.Context
-	ready           bool
-	_ready          bool
-	service         *service.Service
-	builder         *builder.Builder
-	datastore       *datastore.Datastore
-	youtube         *youtube.Youtube
-	audioplayers    *audioplayer.AudioPlayersMap
-	transactions    *transaction.Transactions
-	blockedCommands *blocked_command.BlockedCommands
-	session         *discordgo.Session
-	config          *Configuration
-	helpContent     string
+	log           *log.Logger
+	ctx           context.Context
+	ready         *flag
+	_ready        *flag
+	service       *service.Service
+	builder       *builder.Builder
+	datastore     *datastore.Datastore
+	audio         AudioSource
+	audioplayers  *audioplayer.AudioPlayersMap
+	transactions  *transaction.Transactions
+	session       Session
+	guilds        *settings.GuildSettings
+	announcements *announcements
+	stickyCounts  *messageCounter
+	translator    *i18n.Translator
+	config        *Configuration
+	helpContent   string
 }
 
 type Configuration struct {
-	LogLevel      log.Level                          `yaml:"LogLevel" validate:"required"`
-	DiscordToken  string                             `yaml:"DiscordToken" validate:"required"`
-	Datastore     *datastore.Configuration           `yaml:"Datastore" validate:"required"`
-	Builder       *builder.Configuration             `yaml:"Builder" validate:"required"`
-	SlashCommands *slash_command.SlashCommandsConfig `yaml:"SlashCommands" validate:"required"`
-	Modals        *modal.ModalsConfig                `yaml:"Modals"`
-	MaxAloneTime  time.Duration                      `yaml:"MaxAloneTime" validate:"required"`
+	LogLevel        log.Level                            `yaml:"LogLevel" validate:"required"`
+	DiscordToken    string                               `yaml:"DiscordToken" validate:"required"`
+	Datastore       *datastore.Configuration             `yaml:"Datastore" validate:"required"`
+	Builder         *builder.Configuration               `yaml:"Builder" validate:"required"`
+	SlashCommands   *slash_command.SlashCommandsConfig   `yaml:"SlashCommands" validate:"required"`
+	MessageCommands *slash_command.MessageCommandsConfig `yaml:"MessageCommands" validate:"required"`
+	Modals          *modal.ModalsConfig                  `yaml:"Modals"`
+	MaxAloneTime    time.Duration                        `yaml:"MaxAloneTime" validate:"required"`
+	Youtube         *client.Configuration                `yaml:"Youtube"`
+	Guilds          *settings.Configuration              `yaml:"Guilds"`
+	I18n            *i18n.Configuration                  `yaml:"I18n"`
+}
+
+// Option replaces one of the bot's default dependencies.
+type Option func(bot *Bot)
+
+// WithSession replaces the discord session, that is otherwise
+// created from the configured token once the bot is run.
+func WithSession(session Session) Option {
+	return func(bot *Bot) {
+		bot.session = session
+	}
+}
+
+// WithAudioSource replaces the source from which the songs
+// are found and streamed, youtube by default.
+func WithAudioSource(source AudioSource) Option {
+	return func(bot *Bot) {
+		bot.audio = source
+	}
 }
 
 // NewBot constructs an object that connects the logic in the
 // service module with the discord api and the datastore.
-func NewBot(ctx context.Context, config *Configuration, help string) *Bot {
+func NewBot(ctx context.Context, config *Configuration, help string, options ...Option) *Bot {
 	l := log.New()
 	l.SetLevel(config.LogLevel)
 	l.Debug("Creating Discord music bot ...")
 
+	guilds := settings.NewGuildSettings(config.Guilds)
+	translator := i18n.NewTranslator()
 	bot := &Bot{
-		ctx:             ctx,
-		log:             l,
-		ready:           false,
-		_ready:          false,
-		service:         service.NewService(),
-		builder:         builder.NewBuilder(config.Builder),
-		datastore:       datastore.NewDatastore(config.Datastore),
-		youtube:         youtube.NewYoutube(),
-		config:          config,
-		audioplayers:    audioplayer.NewAudioPlayersMap(),
-		blockedCommands: blocked_command.NewBlockedCommands(),
-		session:         nil,
-		helpContent:     help,
+		ctx:           ctx,
+		log:           l,
+		ready:         newFlag(),
+		_ready:        newFlag(),
+		service:       service.NewService(),
+		builder:       builder.NewBuilder(config.Builder, guilds, translator),
+		datastore:     datastore.NewDatastore(config.Datastore),
+		audio:         NewYoutubeAudioSource(youtube.NewYoutube(config.Youtube)),
+		guilds:        guilds,
+		announcements: newAnnouncements(),
+		stickyCounts:  newMessageCounter(),
+		translator:    translator,
+		config:        config,
+		audioplayers:  audioplayer.NewAudioPlayersMap(),
+		session:       nil,
+		helpContent:   help,
+	}
+	for _, option := range options {
+		option(bot)
 	}
 	bot.transactions = transaction.NewTransactions(
-		func() *discordgo.Session { return bot.session },
+		ctx,
+		func() transaction.Session { return bot.session },
 		bot.log,
 		bot.datastore,
 		bot.builder,
-		func() bool { return bot._ready },
+		bot._ready.get,
+		bot.playbackPosition,
 	)
 	l.Info("Discord music bot created")
 	return bot
@@ -84,6 +123,11 @@ func NewBot(ctx context.Context, config *Configuration, help string) *Bot {
 func (bot *Bot) Init() error {
 	bot.log.Debug("Initializing the bot ...")
 
+	if bot.config.I18n != nil && len(bot.config.I18n.Path) > 0 {
+		if err := bot.translator.Load(bot.config.I18n.Path); err != nil {
+			return err
+		}
+	}
 	if err := bot.datastore.Connect(); err != nil {
 		return err
 	}
@@ -97,15 +141,23 @@ func (bot *Bot) Init() error {
 // Run is a long lived worker that creates a new discord session,
 // verifies it, adds required intents and discord event handlers,
 // then runs while the context is alive.
+// NOTE: a new session is created only if none has been
+// provided when constructing the bot.
 func (bot *Bot) Run() {
 	done := bot.ctx.Done()
 
-	bot.log.Info("Creating new Discord session...")
-	session, err := discordgo.New("Bot " + bot.config.DiscordToken)
-	if err != nil {
-		bot.log.Panic(err)
+	if bot.session == nil {
+		bot.log.Info("Creating new Discord session...")
+		session, err := discordgo.New("Bot " + bot.config.DiscordToken)
+		if err != nil {
+			bot.log.Panic(err)
+		}
+		// NOTE: discord's 429 responses are returned as errors,
+		// so the queue messages' renderer backs off on it's own,
+		// instead of discordgo blocking it while retrying
+		session.ShouldRetryOnRateLimit = false
+		bot.session = NewDiscordSession(session)
 	}
-	bot.session = session
 
 	// Set intents required by the bot
 	intentsHandler := &DiscordIntentsHandler{bot}
@@ -115,15 +167,17 @@ func (bot *Bot) Run() {
 	eventHandler := &DiscordEventHandler{bot}
 	eventHandler.setHandlers()
 
-	if err := session.Open(); err != nil {
+	if err := bot.session.Open(); err != nil {
 		bot.log.Panic(err)
 	}
 
-	// Register slash commands required by the bot
-	bot.log.Debug("Registering global slash commands ...")
+	// Register slash and message commands required by the bot
+	bot.log.Debug("Registering global application commands ...")
 	if err := slash_command.Register(
 		bot.session,
 		bot.config.SlashCommands,
+		bot.config.MessageCommands,
+		bot.translator,
 	); err != nil {
 		bot.log.Warn(err)
 	}
@@ -131,8 +185,9 @@ func (bot *Bot) Run() {
 	util := &Util{bot}
 
 	defer func() {
-		bot.ready = false
-		bot._ready = false
+		bot.ready.set(false)
+		bot._ready.set(false)
+		util.saveResumeStates()
 		util.cleanDiscordMusicQueues()
 		bot.log.Info("Closing discord session ... ")
 		bot.session.Close()
@@ -150,7 +205,7 @@ func (bot *Bot) Run() {
 		case <-done:
 			return
 		case <-ticker.C:
-			for guildID, vc := range bot.session.VoiceConnections {
+			for guildID := range bot.session.VoiceConnections() {
 				if util.hasListeners(guildID) {
 					delete(noListeners, guildID)
 					continue
@@ -169,14 +224,78 @@ func (bot *Bot) Run() {
 					)
 
 					if ap, ok := bot.audioplayers.Get(guildID); ok {
-						ap.Subscriptions().Emit("stop")
-						time.Sleep(100 * time.Millisecond)
+						ap.Stop(bot.ctx)
 					}
 
-					vc.Disconnect()
+					bot.session.VoiceDisconnect(guildID)
 					delete(noListeners, guildID)
 				}
 			}
 		}
 	}
 }
+
+// localizer returns an object that translates the messages sent in
+// response to the provided transaction's interaction. They are
+// translated to the language of the interaction's guild, or to the
+// language of the user that created the interaction, if the guild
+// has no language set.
+func (bot *Bot) localizer(t *transaction.Transaction) *i18n.Localizer {
+	return bot.translator.Localizer(
+		bot.guilds.Get(t.GuildID()).Language,
+		string(t.Interaction().Locale),
+	)
+}
+
+// guildLocalizer returns an object that translates the messages
+// that are not a response to any interaction, to the language
+// of the guild identified by the provided guildID.
+func (bot *Bot) guildLocalizer(guildID string) *i18n.Localizer {
+	return bot.translator.Localizer(bot.guilds.Get(guildID).Language)
+}
+
+// respondEphemeral responds to the provided transaction's interaction
+// with an ephemeral message with the provided content.
+func (bot *Bot) respondEphemeral(t *transaction.Transaction, content string) {
+	if err := bot.session.InteractionRespond(t.Interaction(),
+		&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}); err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error when responding to interaction: %v", err,
+		)
+	}
+}
+
+// flag is a boolean, that is set and read
+// by the handlers from multiple goroutines.
+type flag struct {
+	mutex sync.Mutex
+	value bool
+}
+
+// newFlag constructs a flag that is not set.
+func newFlag() *flag {
+	return &flag{
+		mutex: sync.Mutex{},
+		value: false,
+	}
+}
+
+// set sets the flag's value to the provided value.
+func (f *flag) set(value bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.value = value
+}
+
+// get returns the flag's value.
+func (f *flag) get() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.value
+}
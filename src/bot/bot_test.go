@@ -0,0 +1,1205 @@
+package bot_test
+
+import (
+	"context"
+	"discord-music-bot/bot"
+	"discord-music-bot/bot/audioplayer"
+	"discord-music-bot/bot/fake_session"
+	"discord-music-bot/bot/modal"
+	"discord-music-bot/bot/slash_command"
+	"discord-music-bot/builder"
+	"discord-music-bot/builder/component"
+	"discord-music-bot/builder/history"
+	"discord-music-bot/builder/queue"
+	"discord-music-bot/builder/stats"
+	"discord-music-bot/datastore"
+	"discord-music-bot/i18n"
+	"discord-music-bot/model"
+	"discord-music-bot/settings"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	clientID          = "CLIENT-ID-TEST"
+	guildID           = "GUILD-ID-TEST"
+	userID            = "USER-ID-TEST"
+	textChannelID     = "TEXT-CHANNEL-ID-TEST"
+	voiceChannelID    = "VOICE-CHANNEL-ID-TEST"
+	announceChannelID = "ANNOUNCE-CHANNEL-ID-TEST"
+	otherChannelID    = "OTHER-CHANNEL-ID-TEST"
+)
+
+// fakeStream is a stream that never sends anything,
+// it finishes only once it is stopped.
+type fakeStream struct {
+	source  *fakeAudioSource
+	song    *model.Song
+	start   time.Duration
+	done    chan error
+	once    sync.Once
+	stopped chan struct{}
+}
+
+func (s *fakeStream) Done() <-chan error { return s.done }
+
+func (s *fakeStream) SetPaused(paused bool) {}
+
+func (s *fakeStream) PlaybackPosition() time.Duration {
+	s.source.mutex.Lock()
+	defer s.source.mutex.Unlock()
+	return s.source.played
+}
+
+func (s *fakeStream) Stop() {
+	s.once.Do(func() {
+		close(s.stopped)
+		s.done <- errors.New("stopped")
+	})
+}
+
+func (s *fakeStream) Cleanup() {}
+
+// fakeAudioSource finds a song for every query, named after
+// the query or after the video ID of a url query, and records
+// the songs it streams. The streams report the played duration.
+type fakeAudioSource struct {
+	mutex   sync.Mutex
+	streams []*fakeStream
+	played  time.Duration
+}
+
+func (source *fakeAudioSource) GetSongs(queries []string) []*model.SongInfo {
+	infos := make([]*model.SongInfo, len(queries))
+	for i, q := range queries {
+		if idx := strings.Index(q, "watch?v="); idx >= 0 {
+			q = q[idx+len("watch?v="):]
+		}
+		infos[i] = &model.SongInfo{
+			VideoID:       q,
+			Name:          q,
+			Url:           "https://www.youtube.com/watch?v=" + q,
+			LengthSeconds: 180,
+			ChannelName:   q + " Channel",
+			ThumbnailUrl:  "https://i.ytimg.com/vi/" + q + "/hqdefault.jpg",
+		}
+	}
+	return infos
+}
+
+func (source *fakeAudioSource) VoiceSink(vc *discordgo.VoiceConnection) audioplayer.VoiceSink {
+	return source
+}
+
+func (source *fakeAudioSource) Stream(song *model.Song, start time.Duration) (audioplayer.Stream, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	stream := &fakeStream{
+		source:  source,
+		song:    song,
+		start:   start,
+		done:    make(chan error, 1),
+		stopped: make(chan struct{}),
+	}
+	source.streams = append(source.streams, stream)
+	return stream, nil
+}
+
+func (source *fakeAudioSource) Ready() bool { return true }
+
+func (source *fakeAudioSource) Speaking(speaking bool) {}
+
+// setPlayed sets the duration, that the streams
+// report to have already been played.
+func (source *fakeAudioSource) setPlayed(played time.Duration) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	source.played = played
+}
+
+// lastStart returns the position from which the
+// latest stream has been started.
+func (source *fakeAudioSource) lastStart() time.Duration {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	if len(source.streams) == 0 {
+		return 0
+	}
+	return source.streams[len(source.streams)-1].start
+}
+
+// playing returns the name of the song that is currently
+// streamed, or an empty string if none is.
+func (source *fakeAudioSource) playing() string {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	if len(source.streams) == 0 {
+		return ""
+	}
+	stream := source.streams[len(source.streams)-1]
+	select {
+	case <-stream.stopped:
+		return ""
+	default:
+		return stream.song.Name
+	}
+}
+
+type BotTestSuite struct {
+	suite.Suite
+	cancel  context.CancelFunc
+	done    chan struct{}
+	session *fake_session.Session
+	audio   *fakeAudioSource
+	config  *bot.Configuration
+	locale  discordgo.Locale
+	// permissions are the permissions of the
+	// member that creates the interactions
+	permissions int64
+}
+
+// SetupTest runs before every test and runs the bot with a
+// fake discord session, a fake audio source and an in-memory
+// datastore, with the user already in a voice channel.
+func (s *BotTestSuite) SetupTest() {
+	s.session = fake_session.NewSession(clientID)
+	s.audio = &fakeAudioSource{}
+	s.locale = ""
+	s.permissions = 0
+	showProgress := true
+	recreate := true
+	s.config = &bot.Configuration{
+		LogLevel:     logrus.WarnLevel,
+		MaxAloneTime: time.Minute,
+		Datastore: &datastore.Configuration{
+			LogLevel:        logrus.WarnLevel,
+			InactiveSongTTL: time.Hour,
+			Memory:          true,
+		},
+		Builder: &builder.Configuration{
+			Queue: &queue.Configuration{
+				Title: "Music Queue",
+				Buttons: &queue.ButtonsConfig{
+					Backward: &queue.ButtonConfig{Label: "<"},
+					Forward:  &queue.ButtonConfig{Label: ">"},
+					Pause:    &queue.ButtonConfig{Label: "ll"},
+					Skip:     &queue.ButtonConfig{Label: ">>"},
+					Previous: &queue.ButtonConfig{Label: "<<"},
+					Replay:   &queue.ButtonConfig{Label: "↺"},
+					AddSongs: &queue.ButtonConfig{Label: "Add"},
+					Loop:     &queue.ButtonConfig{Label: "Loop"},
+					Join:     &queue.ButtonConfig{Label: "Join"},
+					Offline:  &queue.ButtonConfig{Label: "Offline"},
+					Page:     &queue.ButtonConfig{Label: "Page"},
+					Search:   &queue.ButtonConfig{Label: "Search"},
+				},
+			},
+			History: &history.Configuration{
+				Title: "History",
+				Empty: "Empty",
+				Buttons: &history.ButtonsConfig{
+					Backward: "<",
+					Forward:  ">",
+					Requeue:  "+",
+				},
+			},
+			Stats: &stats.Configuration{
+				Title: "Stats",
+				Empty: "Empty",
+				Periods: &stats.PeriodsConfig{
+					Week:  "Week",
+					Month: "Month",
+					All:   "All",
+				},
+			},
+		},
+		SlashCommands: &slash_command.SlashCommandsConfig{
+			Music:   &slash_command.ChatCommandConfig{Name: "music", Description: "music"},
+			Stop:    &slash_command.ChatCommandConfig{Name: "stop", Description: "stop"},
+			Help:    &slash_command.ChatCommandConfig{Name: "help", Description: "help"},
+			History: &slash_command.ChatCommandConfig{Name: "history", Description: "history"},
+			Stats:   &slash_command.ChatCommandConfig{Name: "stats", Description: "stats"},
+			Queue:   &slash_command.ChatCommandConfig{Name: "queue", Description: "queue"},
+			Settings: &slash_command.ChatCommandConfig{
+				Name:        "settings",
+				Description: "settings",
+			},
+		},
+		MessageCommands: &slash_command.MessageCommandsConfig{
+			AddToQueue: &slash_command.MessageCommandConfig{Name: "Add to music queue"},
+		},
+		Modals: &modal.ModalsConfig{
+			AddSongs: &modal.ModalConfig{
+				Name:        "Add Songs",
+				Label:       "Songs",
+				Placeholder: "Songs",
+			},
+			QueuePage: &modal.ModalConfig{
+				Name:        "Page",
+				Label:       "Page",
+				Placeholder: "Page",
+			},
+			QueueSearch: &modal.ModalConfig{
+				Name:        "Search",
+				Label:       "Search",
+				Placeholder: "Search",
+			},
+		},
+		Guilds: &settings.Configuration{
+			Guilds: map[string]*settings.Settings{
+				guildID: {
+					Progress: &settings.ProgressSettings{Show: &showProgress},
+					Announcements: &settings.AnnouncementSettings{
+						Show:      &showProgress,
+						ChannelID: announceChannelID,
+					},
+					QueueMessage: &settings.QueueMessageSettings{
+						Recreate: &recreate,
+						Sticky:   3,
+					},
+				},
+			},
+		},
+	}
+	s.session.SetVoiceState(guildID, userID, voiceChannelID)
+	for _, channelID := range []string{textChannelID, announceChannelID, otherChannelID} {
+		s.session.AddChannel(guildID, channelID)
+	}
+	s.runBot()
+}
+
+// TearDownTest runs after every test and
+// waits for the bot to shut down.
+func (s *BotTestSuite) TearDownTest() {
+	s.stopBot()
+}
+
+// TestUnitMusicQueueScenario creates a music queue, adds songs to it,
+// then plays, skips and replays the previous song, and finally
+// stops the music.
+func (s *BotTestSuite) TestUnitMusicQueueScenario() {
+	// NOTE: the /music command sends the queue message
+	music := s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "music"},
+		nil,
+	)
+	queueMessage, err := s.session.InteractionResponse(music)
+	s.Require().NoError(err)
+	s.Equal("Music Queue", queueMessage.Embeds[0].Title)
+
+	// NOTE: the Add button responds with a modal,
+	// submitting it adds the songs and starts playing
+	add := s.clickButton(queueMessage.ID, "Add")
+	resp, ok := s.session.Response(add.ID)
+	s.Require().True(ok)
+	s.Require().Equal(discordgo.InteractionResponseModal, resp.Type)
+	s.interact(
+		discordgo.InteractionModalSubmit,
+		discordgo.ModalSubmitInteractionData{
+			CustomID: resp.Data.CustomID,
+			Components: []discordgo.MessageComponent{
+				&discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						&discordgo.TextInput{Value: "Song1\nSong2\nSong3"},
+					},
+				},
+			},
+		},
+		nil,
+	)
+	vc, ok := s.session.VoiceConnection(guildID)
+	s.Require().True(ok)
+	s.Equal(voiceChannelID, vc.ChannelID)
+	s.expectPlaying(queueMessage.ID, "Song1")
+
+	// NOTE: the playing song's title links to the song,
+	// and it's channel and thumbnail are displayed
+	message, ok := s.session.Message(queueMessage.ID)
+	s.Require().True(ok)
+	s.Require().NotNil(message.Embeds[0].Thumbnail)
+	s.Equal(
+		"https://i.ytimg.com/vi/Song1/hqdefault.jpg",
+		message.Embeds[0].Thumbnail.URL,
+	)
+	now := message.Embeds[0].Fields[0]
+	s.Equal("Now", now.Name)
+	s.Contains(now.Value, "[Song1](https://www.youtube.com/watch?v=Song1)")
+	s.Contains(now.Value, "*Song1 Channel*")
+	s.Contains(now.Value, "`0:00` 🔘▬▬▬▬▬▬▬▬▬▬▬ `3:00`")
+	// NOTE: all of the songs are still to be played
+	next := message.Embeds[0].Fields[1]
+	s.Equal("Next", next.Name)
+	s.Contains(next.Value, "Remaining: ***9:00***")
+
+	s.clickButton(queueMessage.ID, ">>")
+	s.expectPlaying(queueMessage.ID, "Song2")
+
+	s.clickButton(queueMessage.ID, "<<")
+	s.expectPlaying(queueMessage.ID, "Song1")
+
+	// NOTE: the /stop command deletes the queue message,
+	// which stops the playback and leaves the voice channel
+	stop := s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "stop"},
+		nil,
+	)
+	resp, ok = s.session.Response(stop.ID)
+	s.Require().True(ok)
+	s.Equal("Music has been stopped!", resp.Data.Content)
+
+	_, ok = s.session.Message(queueMessage.ID)
+	s.False(ok)
+	_, ok = s.session.VoiceConnection(guildID)
+	s.False(ok)
+	s.Eventually(func() bool {
+		return s.audio.playing() == ""
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// TestUnitMusicWithoutVoice checks that the queue is not created when
+// the user is not in a voice channel, and the user is warned instead.
+func (s *BotTestSuite) TestUnitMusicWithoutVoice() {
+	s.session.SetVoiceState(guildID, userID, "")
+
+	music := s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "music"},
+		nil,
+	)
+	resp, ok := s.session.Response(music.ID)
+	s.Require().True(ok)
+	s.Equal("You need to be in a voice channel!", resp.Data.Content)
+
+	_, ok = s.session.VoiceConnection(guildID)
+	s.False(ok)
+
+	stop := s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "stop"},
+		nil,
+	)
+	resp, ok = s.session.Response(stop.ID)
+	s.Require().True(ok)
+	s.Equal("There is no active music queue!", resp.Data.Content)
+}
+
+// TestUnitPrivateQueueView browses a private view of the queue,
+// and checks that the queue message's page is not changed.
+func (s *BotTestSuite) TestUnitPrivateQueueView() {
+	songs := make([]string, 0)
+	for i := 1; i <= 13; i++ {
+		songs = append(songs, fmt.Sprintf("Song%d", i))
+	}
+	queueMessage := s.startMusic(songs...)
+
+	// NOTE: the /queue command sends a private view of the queue,
+	// where every song shows when it starts playing
+	queue := s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "queue"},
+		nil,
+	)
+	view, err := s.session.InteractionResponse(queue)
+	s.Require().NoError(err)
+	s.Equal(discordgo.MessageFlagsEphemeral, view.Flags)
+	next := view.Embeds[0].Fields[1]
+	s.Contains(next.Value, "***1***\u3000Song2\u3000`in 3:00`")
+	s.Contains(next.Value, "***2***\u3000Song3\u3000`in 6:00`")
+
+	s.clickButton(view.ID, ">")
+	view, ok := s.session.Message(view.ID)
+	s.Require().True(ok)
+	s.Contains(view.Embeds[0].Fields[1].Value, "***11***\u3000Song12")
+
+	// NOTE: the queue message is still on it's first page
+	message, ok := s.session.Message(queueMessage.ID)
+	s.Require().True(ok)
+	s.Contains(message.Embeds[0].Fields[1].Value, "***1***\u3000Song2")
+	s.NotContains(message.Embeds[0].Fields[1].Value, "Song12")
+
+	// NOTE: jump back to the first page
+	page := s.clickButton(view.ID, "Page 2/2")
+	resp, ok := s.session.Response(page.ID)
+	s.Require().True(ok)
+	s.Require().Equal(discordgo.InteractionResponseModal, resp.Type)
+	s.submitModal(resp.Data.CustomID, "1", view)
+	view, ok = s.session.Message(view.ID)
+	s.Require().True(ok)
+	s.Contains(view.Embeds[0].Fields[1].Value, "***1***\u3000Song2")
+
+	// NOTE: search shows the page with the matching song
+	search := s.clickButton(view.ID, "Search")
+	resp, ok = s.session.Response(search.ID)
+	s.Require().True(ok)
+	s.submitModal(resp.Data.CustomID, "song13", view)
+	view, ok = s.session.Message(view.ID)
+	s.Require().True(ok)
+	s.Contains(view.Embeds[0].Fields[1].Value, "***12***\u3000Song13")
+
+	search = s.clickButton(view.ID, "Search")
+	resp, ok = s.session.Response(search.ID)
+	s.Require().True(ok)
+	submit := s.submitModal(resp.Data.CustomID, "Song99", view)
+	resp, ok = s.session.Response(submit.ID)
+	s.Require().True(ok)
+	s.Equal("No song in the queue matches **Song99**.", resp.Data.Content)
+}
+
+// TestUnitNowPlayingAnnouncements checks that the song is announced
+// in the configured channel whenever it starts playing, and that
+// only the latest announcement is kept.
+func (s *BotTestSuite) TestUnitNowPlayingAnnouncements() {
+	queueMessage := s.startMusic("Song1", "Song2")
+	s.expectAnnounced("Now playing: **Song1** (requested by <@USER-ID-TEST>)")
+
+	s.clickButton(queueMessage.ID, ">>")
+	s.expectPlaying(queueMessage.ID, "Song2")
+	s.expectAnnounced("Now playing: **Song2** (requested by <@USER-ID-TEST>)")
+
+	// NOTE: the announcement is deleted once the music is stopped
+	s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "stop"},
+		nil,
+	)
+	s.Eventually(func() bool {
+		return len(s.session.ChannelMessages(announceChannelID)) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// TestUnitRecreateQueueMessage deletes the queue message, also
+// in bulk, and checks that a new one is posted each time, while
+// the music keeps playing.
+func (s *BotTestSuite) TestUnitRecreateQueueMessage() {
+	queueMessage := s.startMusic("Song1", "Song2")
+
+	s.Require().NoError(
+		s.session.ChannelMessageDelete(textChannelID, queueMessage.ID),
+	)
+	recreated := s.lastQueueMessage(textChannelID)
+	s.NotEqual(queueMessage.ID, recreated.ID)
+	s.expectPlaying(recreated.ID, "Song1")
+
+	s.session.Emit(&discordgo.MessageDeleteBulk{
+		Messages:  []string{"UNKNOWN-MESSAGE-ID", recreated.ID},
+		ChannelID: textChannelID,
+		GuildID:   guildID,
+	})
+	s.NotEqual(recreated.ID, s.lastQueueMessage(textChannelID).ID)
+	recreated = s.lastQueueMessage(textChannelID)
+	s.expectPlaying(recreated.ID, "Song1")
+
+	// NOTE: the buttons of the new message control the queue
+	s.clickButton(recreated.ID, ">>")
+	s.expectPlaying(recreated.ID, "Song2")
+
+	// NOTE: only /stop stops the music and removes the queue
+	s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "stop"},
+		nil,
+	)
+	_, ok := s.session.Message(recreated.ID)
+	s.False(ok)
+	stop := s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "stop"},
+		nil,
+	)
+	resp, ok := s.session.Response(stop.ID)
+	s.Require().True(ok)
+	s.Equal("There is no active music queue!", resp.Data.Content)
+	s.Eventually(func() bool {
+		return s.audio.playing() == ""
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// TestUnitMoveQueueMessage moves the queue message to another channel
+// with the /music command, then sends enough messages to that channel,
+// so the sticky queue message is moved below them.
+func (s *BotTestSuite) TestUnitMoveQueueMessage() {
+	queueMessage := s.startMusic("Song1", "Song2")
+
+	// NOTE: without the here option, only the
+	// link to the queue message is sent
+	music := s.interactIn(
+		otherChannelID,
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "music"},
+		nil,
+	)
+	resp, ok := s.session.Response(music.ID)
+	s.Require().True(ok)
+	s.Contains(resp.Data.Content, queueMessage.ID)
+
+	music = s.interactIn(
+		otherChannelID,
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{
+			Name: "music",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{
+					Name:  "here",
+					Type:  discordgo.ApplicationCommandOptionBoolean,
+					Value: true,
+				},
+			},
+		},
+		nil,
+	)
+	resp, ok = s.session.Response(music.ID)
+	s.Require().True(ok)
+	s.Equal("The music queue has been moved to this channel.", resp.Data.Content)
+	_, ok = s.session.Message(queueMessage.ID)
+	s.False(ok)
+	moved := s.lastQueueMessage(otherChannelID)
+	s.expectPlaying(moved.ID, "Song1")
+
+	// NOTE: the messages in other channels are not counted
+	for i := 0; i < 3; i++ {
+		s.sendMessage(textChannelID)
+	}
+	s.Equal(moved.ID, s.lastQueueMessage(otherChannelID).ID)
+
+	s.sendMessage(otherChannelID)
+	s.sendMessage(otherChannelID)
+	s.Equal(moved.ID, s.lastQueueMessage(otherChannelID).ID)
+	s.sendMessage(otherChannelID)
+	sticky := s.lastQueueMessage(otherChannelID)
+	s.NotEqual(moved.ID, sticky.ID)
+	_, ok = s.session.Message(moved.ID)
+	s.False(ok)
+	s.expectPlaying(sticky.ID, "Song1")
+
+	s.clickButton(sticky.ID, ">>")
+	s.expectPlaying(sticky.ID, "Song2")
+}
+
+// TestUnitStickyQueueMessageAnnouncements posts the announcements
+// to the queue message's channel, and checks that only the users'
+// messages are counted for moving the sticky queue message.
+func (s *BotTestSuite) TestUnitStickyQueueMessageAnnouncements() {
+	s.config.Guilds.Guilds[guildID].Announcements.ChannelID = textChannelID
+	announced := func(content string) {
+		s.Eventually(func() bool {
+			for _, m := range s.session.ChannelMessages(textChannelID) {
+				if m.Content == content {
+					return true
+				}
+			}
+			return false
+		}, 5*time.Second, 10*time.Millisecond, "Expected %s to be announced", content)
+	}
+	queueMessage := s.startMusic("Song1", "Song2", "Song3")
+	announced("Now playing: **Song1** (requested by <@USER-ID-TEST>)")
+
+	s.clickButton(queueMessage.ID, ">>")
+	s.expectPlaying(queueMessage.ID, "Song2")
+	announced("Now playing: **Song2** (requested by <@USER-ID-TEST>)")
+
+	s.sendMessage(textChannelID)
+	s.sendMessage(textChannelID)
+	s.Equal(queueMessage.ID, s.lastQueueMessage(textChannelID).ID)
+	s.sendMessage(textChannelID)
+	moved := s.lastQueueMessage(textChannelID)
+	s.NotEqual(queueMessage.ID, moved.ID)
+
+	// NOTE: the reposted queue message and the
+	// announcements start no new count
+	s.clickButton(moved.ID, ">>")
+	s.expectPlaying(moved.ID, "Song3")
+	announced("Now playing: **Song3** (requested by <@USER-ID-TEST>)")
+	s.sendMessage(textChannelID)
+	s.sendMessage(textChannelID)
+	s.Equal(moved.ID, s.lastQueueMessage(textChannelID).ID)
+	s.sendMessage(textChannelID)
+	s.NotEqual(moved.ID, s.lastQueueMessage(textChannelID).ID)
+}
+
+// TestUnitLocalization checks that the slash commands are registered
+// with their translations, and that the responses are translated to
+// the guild's language, or to the user's language when the guild
+// has no language set.
+func (s *BotTestSuite) TestUnitLocalization() {
+	commands, err := s.session.ApplicationCommands(clientID, "")
+	s.Require().NoError(err)
+	var command *discordgo.ApplicationCommand
+	for _, c := range commands {
+		if c.Name == "music" {
+			command = c
+		}
+	}
+	s.Require().NotNil(command)
+	s.Require().NotNil(command.NameLocalizations)
+	s.Equal("musik", (*command.NameLocalizations)[discordgo.German])
+
+	s.locale = discordgo.German
+	stop := s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "stop"},
+		nil,
+	)
+	resp, ok := s.session.Response(stop.ID)
+	s.Require().True(ok)
+	s.Equal("Es gibt keine aktive Musikwarteschlange!", resp.Data.Content)
+
+	s.config.Guilds.Guilds[guildID].Language = "en"
+	stop = s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "stop"},
+		nil,
+	)
+	resp, ok = s.session.Response(stop.ID)
+	s.Require().True(ok)
+	s.Equal("There is no active music queue!", resp.Data.Content)
+
+	// NOTE: the queue message is shared by all the users,
+	// so it is always in the guild's language
+	s.locale = ""
+	s.config.Guilds.Guilds[guildID].Language = "de"
+	music := s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "music"},
+		nil,
+	)
+	queueMessage, err := s.session.InteractionResponse(music)
+	s.Require().NoError(err)
+	s.Equal("Musikwarteschlange", queueMessage.Embeds[0].Title)
+
+	add := s.clickButton(queueMessage.ID, "Hinzufügen")
+	resp, ok = s.session.Response(add.ID)
+	s.Require().True(ok)
+	s.Equal("Songs hinzufügen", resp.Data.Title)
+}
+
+// TestUnitLocalizedStats checks that the /stats and /history
+// embeds are translated to the user's language, including
+// the configured titles.
+func (s *BotTestSuite) TestUnitLocalizedStats() {
+	queueMessage := s.startMusic("Song1", "Song2")
+	s.audio.setPlayed(90 * time.Second)
+	s.clickButton(queueMessage.ID, ">>")
+	s.expectPlaying(queueMessage.ID, "Song2")
+
+	s.locale = discordgo.German
+	stats := s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "stats"},
+		nil,
+	)
+	resp, ok := s.session.Response(stats.ID)
+	s.Require().True(ok)
+	s.Require().Len(resp.Data.Embeds, 1)
+	embed := resp.Data.Embeds[0]
+	s.Equal("Serverstatistik　·　Letzte 7 Tage", embed.Title)
+	names := make([]string, 0)
+	for _, field := range embed.Fields {
+		names = append(names, field.Name)
+	}
+	s.Equal([]string{
+		"Gesamt",
+		"Meistgespielte Songs",
+		"Aktivste Zuhörer",
+		"Aktivste Stunden (UTC)",
+	}, names)
+	s.Equal("**0.0** Stunden　**1** Songs", embed.Fields[0].Value)
+
+	history := s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "history"},
+		nil,
+	)
+	resp, ok = s.session.Response(history.ID)
+	s.Require().True(ok)
+	s.Equal("Hörverlauf", resp.Data.Embeds[0].Title)
+}
+
+// TestUnitQueueLayout checks that the queue message's buttons are
+// arranged by the configured layout, with their emojis and without
+// the hidden buttons, that the compact theme shows the songs in
+// single lines, and that a button still works once it's label changes.
+func (s *BotTestSuite) TestUnitQueueLayout() {
+	buttons := s.config.Builder.Queue.Buttons
+	buttons.Skip = &queue.ButtonConfig{Label: ">>", Emoji: "⏭️"}
+	buttons.Pause = &queue.ButtonConfig{Label: "ll", Hidden: true}
+	buttons.Layout = [][]queue.ComponentAction{
+		{queue.SkipAction, queue.AddSongsAction},
+		{queue.LoopAction},
+		{queue.PauseAction},
+	}
+	s.config.Guilds.Guilds[guildID].QueueMessage.Theme = settings.CompactTheme
+
+	queueMessage := s.startMusic("Song1", "Song2")
+	message, ok := s.session.Message(queueMessage.ID)
+	s.Require().True(ok)
+
+	labels := make([][]string, 0)
+	for _, row := range message.Components {
+		l := make([]string, 0)
+		for _, c := range row.(discordgo.ActionsRow).Components {
+			l = append(l, c.(discordgo.Button).Label)
+		}
+		labels = append(labels, l)
+	}
+	s.Equal([][]string{{">>", "Add"}, {"Loop"}}, labels)
+	skip := message.Components[0].(discordgo.ActionsRow).Components[0].(discordgo.Button)
+	s.Equal("⏭️", skip.Emoji.Name)
+
+	s.Nil(message.Embeds[0].Thumbnail)
+	s.Equal(
+		"**3:00**\u3000[Song1](https://www.youtube.com/watch?v=Song1)",
+		message.Embeds[0].Fields[0].Value,
+	)
+	s.Contains(message.Embeds[0].Fields[1].Value, "***1***\u3000Song2\nSongs in queue: ***1***")
+
+	// NOTE: the buttons are identified by their actions,
+	// so the sent buttons work after their labels change
+	buttons.Skip = &queue.ButtonConfig{Label: "Next"}
+	s.clickButton(queueMessage.ID, ">>")
+	s.expectPlaying(queueMessage.ID, "Song2")
+}
+
+// TestUnitComponentRouting checks that the buttons sent before the
+// customIDs were versioned are still routed by their labels, and that
+// the buttons of another guild's queue are rejected.
+func (s *BotTestSuite) TestUnitComponentRouting() {
+	queueMessage := s.startMusic("Song1", "Song2", "Song3")
+	message, ok := s.session.Message(queueMessage.ID)
+	s.Require().True(ok)
+
+	s.interact(
+		discordgo.InteractionMessageComponent,
+		discordgo.MessageComponentInteractionData{
+			CustomID:      ">><split>" + s.session.NewID(),
+			ComponentType: discordgo.ButtonComponent,
+		},
+		message,
+	)
+	s.expectPlaying(queueMessage.ID, "Song2")
+
+	click := s.interact(
+		discordgo.InteractionMessageComponent,
+		discordgo.MessageComponentInteractionData{
+			CustomID:      component.New(component.QueueSkip, "GUILD-ID-OTHER", "").String(),
+			ComponentType: discordgo.ButtonComponent,
+		},
+		message,
+	)
+	resp, ok := s.session.Response(click.ID)
+	s.Require().True(ok)
+	s.Equal("Sorry, something went wrong ...", resp.Data.Content)
+	s.expectPlaying(queueMessage.ID, "Song2")
+}
+
+// TestUnitAddToQueueMessageCommand checks that the message command
+// is registered and kept when the commands are registered again, and
+// that it adds the songs from the urls in the message's content and
+// embeds to the queue.
+func (s *BotTestSuite) TestUnitAddToQueueMessageCommand() {
+	commands, err := s.session.ApplicationCommands(clientID, "")
+	s.Require().NoError(err)
+	var command *discordgo.ApplicationCommand
+	for _, c := range commands {
+		if c.Name == "Add to music queue" {
+			command = c
+		}
+	}
+	s.Require().NotNil(command)
+	s.Equal(discordgo.MessageApplicationCommand, command.Type)
+	s.Require().NotNil(command.NameLocalizations)
+	s.Equal(
+		"Zur Musikwarteschlange hinzufügen",
+		(*command.NameLocalizations)[discordgo.German],
+	)
+	s.Require().NoError(slash_command.Register(
+		s.session,
+		s.config.SlashCommands,
+		s.config.MessageCommands,
+		i18n.NewTranslator(),
+	))
+	registered, err := s.session.ApplicationCommands(clientID, "")
+	s.Require().NoError(err)
+	s.ElementsMatch(commands, registered)
+
+	queueMessage := s.startMusic("Song1")
+
+	addToQueue := func(message *discordgo.Message) (*discordgo.InteractionResponse, string) {
+		i := s.interact(
+			discordgo.InteractionApplicationCommand,
+			discordgo.ApplicationCommandInteractionData{
+				Name:     "Add to music queue",
+				TargetID: message.ID,
+				Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+					Messages: map[string]*discordgo.Message{message.ID: message},
+				},
+			},
+			nil,
+		)
+		resp, ok := s.session.Response(i.ID)
+		s.Require().True(ok)
+		s.Equal(discordgo.MessageFlagsEphemeral, resp.Data.Flags)
+		m, err := s.session.InteractionResponse(i)
+		s.Require().NoError(err)
+		return resp, m.Content
+	}
+	_, content := addToQueue(&discordgo.Message{
+		ID:      s.session.NewID(),
+		Content: "No songs here, https://example.com/watch?v=Song2",
+	})
+	s.Equal("The message has no supported song urls!", content)
+
+	resp, content := addToQueue(&discordgo.Message{
+		ID:      s.session.NewID(),
+		Content: "Listen to <https://www.youtube.com/watch?v=Song2>!",
+		Embeds: []*discordgo.MessageEmbed{
+			{URL: "https://youtu.be/Song3"},
+		},
+	})
+	// NOTE: the response is deferred while the songs
+	// are searched, then edited once they are added
+	s.Equal(
+		discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		resp.Type,
+	)
+	s.Equal("Added 2 song(s) to the queue.", content)
+
+	s.clickButton(queueMessage.ID, ">>")
+	s.expectPlaying(queueMessage.ID, "Song2")
+	s.clickButton(queueMessage.ID, ">>")
+	s.expectPlaying(queueMessage.ID, "Song3")
+}
+
+// TestUnitResumeAfterRestart plays a song, then restarts the bot
+// and checks that the song is played again from the position
+// at which the bot has been shut down.
+func (s *BotTestSuite) TestUnitResumeAfterRestart() {
+	s.useSqlite()
+	queueMessage := s.startMusic("Song1", "Song2")
+	s.audio.setPlayed(42 * time.Second)
+
+	s.restartBot(func() {})
+
+	s.expectPlaying(queueMessage.ID, "Song1")
+	s.Equal(42*time.Second, s.audio.lastStart())
+	vc, ok := s.session.VoiceConnection(guildID)
+	s.Require().True(ok)
+	s.Equal(voiceChannelID, vc.ChannelID)
+}
+
+// TestUnitResumeAfterRestartWithoutListeners plays a song, then
+// restarts the bot after the user has left the voice channel and
+// checks that the playback is not resumed.
+func (s *BotTestSuite) TestUnitResumeAfterRestartWithoutListeners() {
+	s.useSqlite()
+	s.startMusic("Song1", "Song2")
+
+	s.restartBot(func() {
+		s.session.SetVoiceState(guildID, userID, "")
+	})
+
+	s.Never(func() bool {
+		return s.audio.playing() != ""
+	}, time.Second, 10*time.Millisecond)
+	_, ok := s.session.VoiceConnection(guildID)
+	s.False(ok)
+}
+
+// TestUnitSettings changes the guild's settings with the settings
+// slash command, checks that only the members that may manage the
+// server may change them, and that the changed settings are used
+// for the queue message and kept once the bot is restarted.
+func (s *BotTestSuite) TestUnitSettings() {
+	s.useSqlite()
+	queueMessage := s.startMusic("Song1", "Song2")
+
+	s.Equal(
+		"You need the Manage Server permission to change the settings!",
+		s.changeSetting(settings.ThemeSetting, "compact"),
+	)
+	message, ok := s.session.Message(queueMessage.ID)
+	s.Require().True(ok)
+	s.NotNil(message.Embeds[0].Thumbnail)
+
+	s.permissions = discordgo.PermissionManageServer
+	s.Contains(
+		s.changeSetting(settings.ThemeSetting, "compact"),
+		"**Queue message theme** (`theme`): `compact` (changed)",
+	)
+	message, ok = s.session.Message(queueMessage.ID)
+	s.Require().True(ok)
+	s.Nil(message.Embeds[0].Thumbnail)
+
+	s.Equal(
+		"**fr** is not a valid value for Language!",
+		s.changeSetting(settings.LanguageSetting, "fr"),
+	)
+	s.Contains(
+		s.changeSetting(settings.LanguageSetting, "de"),
+		"**Servereinstellungen**",
+	)
+
+	s.restartBot(func() {})
+
+	// NOTE: the queue message is rendered in the
+	// guild's language with the compact theme
+	s.Eventually(func() bool {
+		message, ok := s.session.Message(queueMessage.ID)
+		return ok && s.audio.playing() == "Song1" &&
+			message.Embeds[0].Title == "Musikwarteschlange" &&
+			message.Embeds[0].Thumbnail == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	content := s.changeSetting(settings.ThemeSetting)
+	s.Contains(content, "**Sprache** (`language`): `de` (geändert)")
+	s.Contains(content, "**Design der Warteschlangennachricht** (`theme`): `detailed`\n")
+	message, ok = s.session.Message(queueMessage.ID)
+	s.Require().True(ok)
+	s.NotNil(message.Embeds[0].Thumbnail)
+}
+
+// changeSetting uses the settings slash command with the provided
+// setting's name and value, omitting the options that are not
+// provided, and returns the content of the command's response.
+func (s *BotTestSuite) changeSetting(nameAndValue ...string) string {
+	options := make([]*discordgo.ApplicationCommandInteractionDataOption, 0)
+	for i, option := range []string{
+		slash_command.SettingsNameOption,
+		slash_command.SettingsValueOption,
+	}[:len(nameAndValue)] {
+		options = append(options, &discordgo.ApplicationCommandInteractionDataOption{
+			Name:  option,
+			Type:  discordgo.ApplicationCommandOptionString,
+			Value: nameAndValue[i],
+		})
+	}
+	i := s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{
+			Name:    "settings",
+			Options: options,
+		},
+		nil,
+	)
+	resp, ok := s.session.Response(i.ID)
+	s.Require().True(ok)
+	return resp.Data.Content
+}
+
+// runBot runs a new bot with the suite's config, fake discord
+// session and fake audio source, and waits until it is ready.
+func (s *BotTestSuite) runBot() {
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+	done := make(chan struct{})
+	s.done = done
+
+	b := bot.NewBot(
+		ctx,
+		s.config,
+		"help",
+		bot.WithSession(s.session),
+		bot.WithAudioSource(s.audio),
+	)
+	s.Require().NoError(b.Init())
+	go func() {
+		defer close(done)
+		b.Run()
+	}()
+	s.Require().Eventually(s.session.Opened, 5*time.Second, 10*time.Millisecond)
+}
+
+// stopBot shuts down the running bot and waits
+// until it's session has been closed.
+func (s *BotTestSuite) stopBot() {
+	s.cancel()
+	<-s.done
+}
+
+// useSqlite runs the bot again with a sqlite datastore,
+// so the queues outlive the bot when it is restarted.
+func (s *BotTestSuite) useSqlite() {
+	s.stopBot()
+	s.config.Datastore = &datastore.Configuration{
+		LogLevel:        logrus.WarnLevel,
+		InactiveSongTTL: time.Hour,
+		Sqlite: &datastore.SqliteConfig{
+			Path: filepath.Join(s.T().TempDir(), "bot.db"),
+		},
+	}
+	s.runBot()
+}
+
+// restartBot shuts down the running bot, disconnecting it from the
+// voice channel, then calls the provided function while the bot is
+// not running, and runs the bot again.
+func (s *BotTestSuite) restartBot(stopped func()) {
+	s.stopBot()
+	s.Require().NoError(s.session.VoiceDisconnect(guildID))
+	stopped()
+	s.runBot()
+}
+
+// sendMessage sends a new message by the user
+// to the channel identified by the provided channelID.
+func (s *BotTestSuite) sendMessage(channelID string) {
+	s.session.Emit(&discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			ID:        s.session.NewID(),
+			ChannelID: channelID,
+			GuildID:   guildID,
+			Author:    &discordgo.User{ID: userID},
+		},
+	})
+}
+
+// lastQueueMessage returns the latest message in the channel
+// identified by the provided channelID, that has been sent
+// with the queue's embed.
+func (s *BotTestSuite) lastQueueMessage(channelID string) *discordgo.Message {
+	var last *discordgo.Message
+	for _, m := range s.session.ChannelMessages(channelID) {
+		if len(m.Embeds) > 0 && m.Embeds[0].Title == "Music Queue" {
+			last = m
+		}
+	}
+	s.Require().NotNil(last)
+	return last
+}
+
+// startMusic sends the queue message with the /music command, then
+// adds the songs with the provided names to the queue, and waits
+// until the first of them is playing. The queue message is returned.
+func (s *BotTestSuite) startMusic(songs ...string) *discordgo.Message {
+	music := s.interact(
+		discordgo.InteractionApplicationCommand,
+		discordgo.ApplicationCommandInteractionData{Name: "music"},
+		nil,
+	)
+	queueMessage, err := s.session.InteractionResponse(music)
+	s.Require().NoError(err)
+
+	add := s.clickButton(queueMessage.ID, "Add")
+	resp, ok := s.session.Response(add.ID)
+	s.Require().True(ok)
+	s.submitModal(resp.Data.CustomID, strings.Join(songs, "\n"), nil)
+	s.expectPlaying(queueMessage.ID, songs[0])
+	return queueMessage
+}
+
+// expectAnnounced checks that the announcements' channel
+// holds a single message, with the provided content.
+func (s *BotTestSuite) expectAnnounced(content string) {
+	s.Eventually(func() bool {
+		messages := s.session.ChannelMessages(announceChannelID)
+		return len(messages) == 1 && messages[0].Content == content
+	}, 5*time.Second, 10*time.Millisecond, "Expected %s to be announced", content)
+}
+
+// submitModal submits the modal identified by the provided customID,
+// with the provided value entered in it's text input. The provided
+// message is the message, from which the modal has been opened.
+func (s *BotTestSuite) submitModal(customID string, value string, message *discordgo.Message) *discordgo.Interaction {
+	return s.interact(
+		discordgo.InteractionModalSubmit,
+		discordgo.ModalSubmitInteractionData{
+			CustomID: customID,
+			Components: []discordgo.MessageComponent{
+				&discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						&discordgo.TextInput{Value: value},
+					},
+				},
+			},
+		},
+		message,
+	)
+}
+
+// interact emits a new interaction of the provided type,
+// created by the user in the text channel, and returns it.
+func (s *BotTestSuite) interact(tp discordgo.InteractionType, data discordgo.InteractionData, message *discordgo.Message) *discordgo.Interaction {
+	return s.interactIn(textChannelID, tp, data, message)
+}
+
+// interactIn emits a new interaction of the provided type, created
+// by the user in the channel identified by the provided channelID.
+func (s *BotTestSuite) interactIn(channelID string, tp discordgo.InteractionType, data discordgo.InteractionData, message *discordgo.Message) *discordgo.Interaction {
+	i := &discordgo.Interaction{
+		ID:        s.session.NewID(),
+		AppID:     clientID,
+		Type:      tp,
+		Data:      data,
+		GuildID:   guildID,
+		ChannelID: channelID,
+		Message:   message,
+		Locale:    s.locale,
+		Member: &discordgo.Member{
+			User:        &discordgo.User{ID: userID},
+			Permissions: s.permissions,
+		},
+	}
+	s.session.Emit(&discordgo.InteractionCreate{Interaction: i})
+	return i
+}
+
+// clickButton clicks the button with the provided label on
+// the message identified by the provided messageID.
+func (s *BotTestSuite) clickButton(messageID string, label string) *discordgo.Interaction {
+	message, ok := s.session.Message(messageID)
+	s.Require().True(ok)
+	for _, row := range message.Components {
+		for _, c := range row.(discordgo.ActionsRow).Components {
+			button := c.(discordgo.Button)
+			if button.Label != label {
+				continue
+			}
+			return s.interact(
+				discordgo.InteractionMessageComponent,
+				discordgo.MessageComponentInteractionData{
+					CustomID:      button.CustomID,
+					ComponentType: discordgo.ButtonComponent,
+				},
+				message,
+			)
+		}
+	}
+	s.FailNow("Button not found", label)
+	return nil
+}
+
+// expectPlaying checks that the song with the provided name is being
+// streamed and that it is shown in the message identified by the
+// provided messageID, as the song that is playing now.
+func (s *BotTestSuite) expectPlaying(messageID string, name string) {
+	s.Eventually(func() bool {
+		if s.audio.playing() != name {
+			return false
+		}
+		message, ok := s.session.Message(messageID)
+		if !ok || len(message.Embeds) == 0 {
+			return false
+		}
+		for _, field := range message.Embeds[0].Fields {
+			if field.Name == "Now" {
+				return strings.Contains(field.Value, name)
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond, "Expected %s to be playing", name)
+}
+
+// TestBotTestSuite runs all tests under
+// the BotTestSuite suite.
+func TestBotTestSuite(t *testing.T) {
+	suite.Run(t, new(BotTestSuite))
+}
@@ -1,6 +1,5 @@
 package bot
 import (
-	"discord-music-bot/bot/modal"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
@@ -12,13 +11,14 @@ type DiscordEventHandler struct {
 
 // setHandlers adds handlers for discord events to the
 // provided session.
-// It Adds handler for ready, message (bulk) delete,
+// It Adds handler for ready, message create, message (bulk) delete,
 // and interaction create events, but it determines
 // the type of interaction and calls the appropriate function.
 func (bot *DiscordEventHandler) setHandlers() {
+	router := bot.newRouter()
+
 	bot.session.AddHandler(
 		func(s *discordgo.Session, r *discordgo.Ready) {
-			bot.session = s
 			bot.onReady(r)
 		},
 	)
@@ -29,15 +29,27 @@ func (bot *DiscordEventHandler) setHandlers() {
 			// when it happens in a guild, the bot is ready
 			// and the bot is author of the deleted message
 
-			if len(m.GuildID) > 0 && bot.ready &&
+			if len(m.GuildID) > 0 && bot.ready.get() &&
 				(m.Author == nil || len(m.Author.ID) == 0 ||
-					m.Author.ID == bot.session.State.User.ID) {
+					m.Author.ID == bot.session.ClientID()) {
 
-				bot.session = s
 				bot.onMessageDelete(m)
 			}
 		},
 	)
+	bot.session.AddHandler(
+		func(s *discordgo.Session, m *discordgo.MessageCreate) {
+
+			// NOTE: handle message create events only in guilds,
+			// for the messages that are not sent by the client
+
+			if len(m.GuildID) > 0 && bot.ready.get() && m.Author != nil &&
+				m.Author.ID != bot.session.ClientID() {
+
+				bot.onMessageCreate(m)
+			}
+		},
+	)
 	bot.session.AddHandler(
 		func(s *discordgo.Session, m *discordgo.MessageDeleteBulk) {
 
@@ -45,8 +57,7 @@ func (bot *DiscordEventHandler) setHandlers() {
 			// this only contains a slice of messageID's so we
 			// cannot check if bot authored them
 
-			if len(m.GuildID) > 0 && bot.ready {
-				bot.session = s
+			if len(m.GuildID) > 0 && bot.ready.get() {
 				bot.onBulkMessageDelete(m)
 			}
 		},
@@ -57,11 +68,10 @@ func (bot *DiscordEventHandler) setHandlers() {
 			// NOTE: handle voice state update events only
 			// in guilds and only updates for the client
 
-			if len(v.GuildID) > 0 && bot.ready &&
-				v.UserID == bot.session.State.User.ID {
+			if len(v.GuildID) > 0 && bot.ready.get() &&
+				v.UserID == bot.session.ClientID() {
 
 				t := bot.transactions.New("VoiceStateUpdate", v.GuildID, nil)
-				bot.session = s
 				bot.onVoiceStateUpdate(t, v)
 			}
 		},
@@ -72,10 +82,9 @@ func (bot *DiscordEventHandler) setHandlers() {
 			// NOTE: handle only interactions authored by the client
 			// created in a guild
 
-			if len(i.GuildID) > 0 && bot.ready &&
-				i.Interaction.AppID == bot.session.State.User.ID {
+			if len(i.GuildID) > 0 && bot.ready.get() &&
+				i.Interaction.AppID == bot.session.ClientID() {
 
-				bot.session = s
 				util := &Util{bot.Bot}
 
 				if len(i.ChannelID) > 0 {
@@ -97,32 +106,18 @@ func (bot *DiscordEventHandler) setHandlers() {
 					bot.onApplicationCommand(t)
 					return
 				case discordgo.InteractionMessageComponent:
-					switch i.Interaction.MessageComponentData().ComponentType {
-					case discordgo.ButtonComponent:
-						label := bot.builder.Queue().GetButtonLabelFromComponentData(
-							i.Interaction.MessageComponentData(),
-						)
-						t := bot.transactions.New(
-							"Interaction/ButtonClick/"+label,
-							i.GuildID,
-							i.Interaction,
-						)
-						bot.onButtonClick(t)
-						return
-					}
+					bot.onRoutedInteraction(
+						router,
+						i.Interaction,
+						i.Interaction.MessageComponentData().CustomID,
+					)
 					return
 				case discordgo.InteractionModalSubmit:
-					name := strings.TrimSpace(
-						modal.GetModalName(
-							i.Interaction.ModalSubmitData(),
-						),
-					)
-					t := bot.transactions.New(
-						"Interaction/ModalSubmit/"+name,
-						i.GuildID,
+					bot.onRoutedInteraction(
+						router,
 						i.Interaction,
+						i.Interaction.ModalSubmitData().CustomID,
 					)
-					bot.onModalSubmit(t)
 					return
 
 				}
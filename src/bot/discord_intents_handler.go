@@ -10,11 +10,11 @@ type DiscordIntentsHandler struct {
 // by the music bot
 func (bot *DiscordIntentsHandler) setIntents() {
 	//NOTE: guilds for interactions in guilds,
-	// guild messages for message delete events,
+	// guild messages for message create and delete events,
 	// voice states for voice state update events
-	bot.session.Identify.Intents =
+	bot.session.SetIntents(
 		discordgo.IntentsGuilds +
 			discordgo.IntentsGuildVoiceStates +
-			discordgo.IntentGuildMessages
-
+			discordgo.IntentGuildMessages,
+	)
 }
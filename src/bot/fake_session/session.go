@@ -0,0 +1,553 @@
+package fake_session
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ErrUnknownMessage is returned when editing or deleting
+// a message that does not exist.
+var ErrUnknownMessage = errors.New("unknown message")
+
+// ErrAlreadyResponded is returned when responding to an
+// interaction that has already been responded to.
+var ErrAlreadyResponded = errors.New("interaction has already been acknowledged")
+
+// Session is an in-process discord session. It records the responses
+// and the messages sent through it, and simulates the guilds' voice
+// states, so the bot may be run without connecting to discord.
+// NOTE: the events are delivered to the handlers synchronously,
+// in the goroutine that emits them.
+type Session struct {
+	mutex            sync.Mutex
+	clientID         string
+	nextID           int
+	nextHandlerID    int
+	open             bool
+	intents          discordgo.Intent
+	status           string
+	handlers         map[int]interface{}
+	responses        map[string]*discordgo.InteractionResponse
+	responseMessages map[string]string
+	messages         map[string]*discordgo.Message
+	voiceStates      map[string]map[string]*discordgo.VoiceState
+	voiceConnections map[string]*discordgo.VoiceConnection
+	commands         map[string]*discordgo.ApplicationCommand
+	channels         map[string]string
+}
+
+// NewSession constructs a fake discord session, in which the
+// bot's user is identified by the provided clientID.
+func NewSession(clientID string) *Session {
+	return &Session{
+		mutex:            sync.Mutex{},
+		clientID:         clientID,
+		nextID:           0,
+		nextHandlerID:    0,
+		handlers:         make(map[int]interface{}),
+		responses:        make(map[string]*discordgo.InteractionResponse),
+		responseMessages: make(map[string]string),
+		messages:         make(map[string]*discordgo.Message),
+		voiceStates:      make(map[string]map[string]*discordgo.VoiceState),
+		voiceConnections: make(map[string]*discordgo.VoiceConnection),
+		commands:         make(map[string]*discordgo.ApplicationCommand),
+		channels:         make(map[string]string),
+	}
+}
+
+// AddChannel adds the text channel identified by the provided
+// channelID to the guild identified by the provided guildID, so
+// the messages sent to it belong to the guild.
+func (s *Session) AddChannel(guildID string, channelID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.channels[channelID] = guildID
+}
+
+// NewID returns a new unique snowflake-like ID.
+func (s *Session) NewID() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.newID()
+}
+
+func (s *Session) newID() string {
+	s.nextID++
+	return strconv.Itoa(s.nextID)
+}
+
+// Emit delivers the provided event to all the
+// handlers that accept the event's type.
+func (s *Session) Emit(event interface{}) {
+	s.mutex.Lock()
+	keys := make([]int, 0, len(s.handlers))
+	for k := range s.handlers {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	handlers := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		handlers = append(handlers, s.handlers[k])
+	}
+	s.mutex.Unlock()
+
+	eventValue := reflect.ValueOf(event)
+	for _, handler := range handlers {
+		h := reflect.ValueOf(handler)
+		if h.Type().NumIn() != 2 || h.Type().In(1) != eventValue.Type() {
+			continue
+		}
+		h.Call([]reflect.Value{reflect.Zero(h.Type().In(0)), eventValue})
+	}
+}
+
+// Opened returns true once the session has been opened
+// and the ready event has been handled.
+func (s *Session) Opened() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.open
+}
+
+// Status returns the listening status set by the bot.
+func (s *Session) Status() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.status
+}
+
+// Response returns the response sent to the interaction identified
+// by the provided ID, and false if it has not been responded to.
+func (s *Session) Response(interactionID string) (*discordgo.InteractionResponse, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	resp, ok := s.responses[interactionID]
+	return resp, ok
+}
+
+// Message returns a copy of the message identified by the
+// provided ID, and false if it does not exist.
+func (s *Session) Message(messageID string) (*discordgo.Message, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	m, ok := s.messages[messageID]
+	if !ok {
+		return nil, false
+	}
+	msg := *m
+	return &msg, true
+}
+
+// ChannelMessages returns copies of the messages in the channel
+// identified by the provided channelID, in the order they were sent.
+func (s *Session) ChannelMessages(channelID string) []*discordgo.Message {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	messages := make([]*discordgo.Message, 0)
+	for _, m := range s.messages {
+		if m.ChannelID == channelID {
+			msg := *m
+			messages = append(messages, &msg)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		a, _ := strconv.Atoi(messages[i].ID)
+		b, _ := strconv.Atoi(messages[j].ID)
+		return a < b
+	})
+	return messages
+}
+
+// SetVoiceState moves the user identified by the provided userID to
+// the voice channel identified by the provided channelID, or removes
+// them from the voice channels when the channelID is empty.
+// The voice state update event is emitted to the handlers.
+func (s *Session) SetVoiceState(guildID string, userID string, channelID string) {
+	s.mutex.Lock()
+	update := s.setVoiceState(guildID, userID, channelID)
+	s.mutex.Unlock()
+
+	s.Emit(update)
+}
+
+func (s *Session) setVoiceState(guildID string, userID string, channelID string) *discordgo.VoiceStateUpdate {
+	states, ok := s.voiceStates[guildID]
+	if !ok {
+		states = make(map[string]*discordgo.VoiceState)
+		s.voiceStates[guildID] = states
+	}
+	before := states[userID]
+	state := &discordgo.VoiceState{
+		GuildID:   guildID,
+		UserID:    userID,
+		ChannelID: channelID,
+	}
+	if len(channelID) == 0 {
+		delete(states, userID)
+	} else {
+		states[userID] = state
+	}
+	return &discordgo.VoiceStateUpdate{
+		VoiceState:   state,
+		BeforeUpdate: before,
+	}
+}
+
+func (s *Session) ClientID() string {
+	return s.clientID
+}
+
+func (s *Session) SetIntents(intents discordgo.Intent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.intents = intents
+}
+
+func (s *Session) AddHandler(handler interface{}) func() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := s.nextHandlerID
+	s.nextHandlerID++
+	s.handlers[key] = handler
+	return func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		delete(s.handlers, key)
+	}
+}
+
+// Open emits the ready event, then marks the session as open.
+func (s *Session) Open() error {
+	s.Emit(&discordgo.Ready{
+		User: &discordgo.User{
+			ID:            s.clientID,
+			Username:      "FakeBot",
+			Discriminator: "0000",
+		},
+	})
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.open = true
+	return nil
+}
+
+// Close marks the session as closed and removes all of its handlers,
+// so a bot run again on the same session receives every event once.
+func (s *Session) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.open = false
+	s.handlers = make(map[int]interface{})
+	return nil
+}
+
+func (s *Session) UpdateListeningStatus(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.status = name
+	return nil
+}
+
+// InteractionRespond records the response to the provided interaction.
+// Messages sent with the response are stored, and the interaction's
+// message is updated when the response updates it. As with discord,
+// an interaction may be responded to only once, and the creation of
+// the sent message is emitted unless it is ephemeral.
+func (s *Session) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse) error {
+	s.mutex.Lock()
+	if _, ok := s.responses[interaction.ID]; ok {
+		s.mutex.Unlock()
+		return ErrAlreadyResponded
+	}
+	var created *discordgo.Message
+	switch resp.Type {
+	case discordgo.InteractionResponseChannelMessageWithSource,
+		discordgo.InteractionResponseDeferredChannelMessageWithSource:
+		m := &discordgo.Message{
+			ID:        s.newID(),
+			ChannelID: interaction.ChannelID,
+			GuildID:   interaction.GuildID,
+			Author:    &discordgo.User{ID: s.clientID},
+		}
+		if resp.Data != nil {
+			m.Content = resp.Data.Content
+			m.Embeds = resp.Data.Embeds
+			m.Components = resp.Data.Components
+			m.Flags = resp.Data.Flags
+		}
+		s.messages[m.ID] = m
+		s.responseMessages[interaction.ID] = m.ID
+		// NOTE: the ephemeral messages are seen
+		// only by the user, not by the bot
+		if m.Flags&discordgo.MessageFlagsEphemeral == 0 {
+			msg := *m
+			created = &msg
+		}
+	case discordgo.InteractionResponseUpdateMessage,
+		discordgo.InteractionResponseDeferredMessageUpdate:
+		// NOTE: only the interactions created from a
+		// message's components may update the message
+		if interaction.Message == nil {
+			s.mutex.Unlock()
+			return ErrUnknownMessage
+		}
+		m, ok := s.messages[interaction.Message.ID]
+		if !ok {
+			s.mutex.Unlock()
+			return ErrUnknownMessage
+		}
+		if resp.Data != nil {
+			m.Embeds = resp.Data.Embeds
+			m.Components = resp.Data.Components
+		}
+	}
+	s.responses[interaction.ID] = resp
+	s.mutex.Unlock()
+
+	if created != nil {
+		s.Emit(&discordgo.MessageCreate{Message: created})
+	}
+	return nil
+}
+
+// InteractionResponseEdit edits the message sent with the response
+// to the provided interaction, e.g. the deferred response's message.
+func (s *Session) InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit) (*discordgo.Message, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	m, ok := s.messages[s.responseMessages[interaction.ID]]
+	if !ok {
+		return nil, ErrUnknownMessage
+	}
+	if newresp.Content != nil {
+		m.Content = *newresp.Content
+	}
+	if newresp.Embeds != nil {
+		m.Embeds = *newresp.Embeds
+	}
+	if newresp.Components != nil {
+		m.Components = *newresp.Components
+	}
+	msg := *m
+	return &msg, nil
+}
+
+func (s *Session) InteractionResponse(interaction *discordgo.Interaction) (*discordgo.Message, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	m, ok := s.messages[s.responseMessages[interaction.ID]]
+	if !ok {
+		return nil, ErrUnknownMessage
+	}
+	msg := *m
+	return &msg, nil
+}
+
+// ChannelMessageSendComplex stores the message sent to the channel,
+// and emits it's creation to the handlers, same as the messages
+// sent by the users.
+func (s *Session) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error) {
+	s.mutex.Lock()
+	m := &discordgo.Message{
+		ID:         s.newID(),
+		ChannelID:  channelID,
+		GuildID:    s.channels[channelID],
+		Author:     &discordgo.User{ID: s.clientID},
+		Content:    data.Content,
+		Embeds:     data.Embeds,
+		Components: data.Components,
+	}
+	s.messages[m.ID] = m
+	created, msg := *m, *m
+	s.mutex.Unlock()
+
+	s.Emit(&discordgo.MessageCreate{Message: &created})
+	return &msg, nil
+}
+
+func (s *Session) ChannelMessageEditComplex(edit *discordgo.MessageEdit) (*discordgo.Message, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	m, ok := s.messages[edit.ID]
+	if !ok || m.ChannelID != edit.Channel {
+		return nil, ErrUnknownMessage
+	}
+	if edit.Embeds != nil {
+		m.Embeds = edit.Embeds
+	}
+	if edit.Components != nil {
+		m.Components = edit.Components
+	}
+	msg := *m
+	return &msg, nil
+}
+
+// ChannelMessageDelete deletes the message identified by the provided
+// messageID and emits the message delete event to the handlers.
+func (s *Session) ChannelMessageDelete(channelID string, messageID string) error {
+	s.mutex.Lock()
+	m, ok := s.messages[messageID]
+	if !ok || m.ChannelID != channelID {
+		s.mutex.Unlock()
+		return ErrUnknownMessage
+	}
+	delete(s.messages, messageID)
+	s.mutex.Unlock()
+
+	s.Emit(&discordgo.MessageDelete{
+		Message: &discordgo.Message{
+			ID:        m.ID,
+			ChannelID: m.ChannelID,
+			GuildID:   m.GuildID,
+		},
+	})
+	return nil
+}
+
+// GuildMembers returns the members that are in the guild's voice channels.
+func (s *Session) GuildMembers(guildID string, after string, limit int) ([]*discordgo.Member, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	members := make([]*discordgo.Member, 0)
+	if len(after) > 0 {
+		return members, nil
+	}
+	for userID := range s.voiceStates[guildID] {
+		members = append(members, &discordgo.Member{
+			GuildID: guildID,
+			User:    &discordgo.User{ID: userID},
+		})
+		if len(members) >= limit {
+			break
+		}
+	}
+	return members, nil
+}
+
+func (s *Session) ApplicationCommands(appID string, guildID string) ([]*discordgo.ApplicationCommand, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	commands := make([]*discordgo.ApplicationCommand, 0, len(s.commands))
+	for _, cmd := range s.commands {
+		if cmd.GuildID == guildID {
+			commands = append(commands, cmd)
+		}
+	}
+	return commands, nil
+}
+
+func (s *Session) ApplicationCommandCreate(appID string, guildID string, cmd *discordgo.ApplicationCommand) (*discordgo.ApplicationCommand, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	created := *cmd
+	created.ID = s.newID()
+	created.ApplicationID = appID
+	created.GuildID = guildID
+	if created.Type == 0 {
+		created.Type = discordgo.ChatApplicationCommand
+	}
+	s.commands[created.ID] = &created
+	return &created, nil
+}
+
+func (s *Session) ApplicationCommandDelete(appID string, guildID string, cmdID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.commands[cmdID]; !ok {
+		return fmt.Errorf("unknown application command %s", cmdID)
+	}
+	delete(s.commands, cmdID)
+	return nil
+}
+
+// Guild returns the guild, if any of its members
+// has ever been in its voice channels.
+func (s *Session) Guild(guildID string) (*discordgo.Guild, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	states, ok := s.voiceStates[guildID]
+	if !ok {
+		return nil, discordgo.ErrStateNotFound
+	}
+	guild := &discordgo.Guild{ID: guildID}
+	for _, state := range states {
+		guild.VoiceStates = append(guild.VoiceStates, state)
+	}
+	return guild, nil
+}
+
+func (s *Session) VoiceState(guildID string, userID string) (*discordgo.VoiceState, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	state, ok := s.voiceStates[guildID][userID]
+	if !ok {
+		return nil, discordgo.ErrStateNotFound
+	}
+	return state, nil
+}
+
+// UserChannelPermissions grants every user all the permissions.
+func (s *Session) UserChannelPermissions(userID string, channelID string) (int64, error) {
+	return discordgo.PermissionAll, nil
+}
+
+// ChannelVoiceJoin moves the client to the voice channel, then
+// emits its voice state update event to the handlers.
+func (s *Session) ChannelVoiceJoin(guildID string, channelID string, mute bool, deaf bool) (*discordgo.VoiceConnection, error) {
+	s.mutex.Lock()
+	vc := &discordgo.VoiceConnection{
+		UserID:    s.clientID,
+		GuildID:   guildID,
+		ChannelID: channelID,
+		Ready:     true,
+	}
+	s.voiceConnections[guildID] = vc
+	update := s.setVoiceState(guildID, s.clientID, channelID)
+	s.mutex.Unlock()
+
+	s.Emit(update)
+	return vc, nil
+}
+
+// VoiceDisconnect removes the client from the voice channel, then
+// emits its voice state update event to the handlers.
+func (s *Session) VoiceDisconnect(guildID string) error {
+	s.mutex.Lock()
+	vc, ok := s.voiceConnections[guildID]
+	if !ok {
+		s.mutex.Unlock()
+		return fmt.Errorf("not connected to voice in guild %s", guildID)
+	}
+	vc.Lock()
+	vc.Ready = false
+	vc.Unlock()
+	delete(s.voiceConnections, guildID)
+	update := s.setVoiceState(guildID, s.clientID, "")
+	s.mutex.Unlock()
+
+	s.Emit(update)
+	return nil
+}
+
+func (s *Session) VoiceConnection(guildID string) (*discordgo.VoiceConnection, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	vc, ok := s.voiceConnections[guildID]
+	return vc, ok
+}
+
+func (s *Session) VoiceConnections() map[string]*discordgo.VoiceConnection {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	connections := make(map[string]*discordgo.VoiceConnection)
+	for guildID, vc := range s.voiceConnections {
+		connections[guildID] = vc
+	}
+	return connections
+}
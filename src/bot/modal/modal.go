@@ -1,10 +1,9 @@
 package modal
 
 import (
-	"strings"
+	"discord-music-bot/builder/component"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/google/uuid"
 )
 
 type ModalConfig struct {
@@ -14,14 +13,17 @@ type ModalConfig struct {
 }
 
 type ModalsConfig struct {
-	AddSongs *ModalConfig `yaml:"AddSongs" validate:"required"`
+	AddSongs    *ModalConfig `yaml:"AddSongs" validate:"required"`
+	QueuePage   *ModalConfig `yaml:"QueuePage" validate:"required"`
+	QueueSearch *ModalConfig `yaml:"QueueSearch" validate:"required"`
 }
 
 // GetModal constructs a modal submit interaction data
-// with the provided components
-func GetModal(name string, components []discordgo.MessageComponent) *discordgo.ModalSubmitInteractionData {
+// with the provided components, identified by the provided ID.
+// The ID's argument is available once the modal is submitted.
+func GetModal(id component.ID, components []discordgo.MessageComponent) *discordgo.ModalSubmitInteractionData {
 	return &discordgo.ModalSubmitInteractionData{
-		CustomID: name + "<split>" + uuid.NewString(),
+		CustomID: id.String(),
 		Components: []discordgo.MessageComponent{
 			discordgo.ActionsRow{
 				Components: components,
@@ -29,9 +31,3 @@ func GetModal(name string, components []discordgo.MessageComponent) *discordgo.M
 		},
 	}
 }
-
-// GetModalName retrieves the name of the modal from
-// it's customID
-func GetModalName(data discordgo.ModalSubmitInteractionData) string {
-	return strings.Split(data.CustomID, "<split>")[0]
-}
@@ -3,7 +3,6 @@ package bot
 import (
 	"discord-music-bot/bot/transaction"
 	"discord-music-bot/model"
-	"fmt"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
@@ -13,6 +12,8 @@ import (
 // submits the add songs modal in a discord servier. This
 // is called when the type of interaction is determined to be
 // add songs modal submit, in the onInteractionCreate function.
+// NOTE: the added songs are then played by onModalSubmit,
+// in the user's voice channel.
 func (bot *DiscordEventHandler) onAddSongsModalSubmit(t *transaction.Transaction) {
 	actionsRow := (t.Interaction().ModalSubmitData().Components[0]).(*discordgo.ActionsRow)
 	textInput := (actionsRow.Components[0]).(*discordgo.TextInput)
@@ -32,19 +33,25 @@ func (bot *DiscordEventHandler) onAddSongsModalSubmit(t *transaction.Transaction
 			&discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
-					Content: fmt.Sprintf(
-						"Cannot query more than %d songs at once",
-						100,
-					),
-					Flags: discordgo.MessageFlagsEphemeral,
+					Content: bot.localizer(t).T("songs.limit", 100),
+					Flags:   discordgo.MessageFlagsEphemeral,
 				},
 			})
 		return
 	}
 
-	songInfos := bot.youtube.Search().GetSongs(queries)
+	bot.addSongs(t, queries)
+}
+
+// addSongs searches the songs for the provided queries and adds
+// the found songs to the queue of the transaction's guild, requested
+// by the user that created the transaction's interaction. The
+// added songs are returned, none if no songs have been found
+// or they could not be added.
+func (bot *Bot) addSongs(t *transaction.Transaction, queries []string) []*model.Song {
+	songInfos := bot.audio.GetSongs(queries)
 	if len(songInfos) == 0 {
-		return
+		return nil
 	}
 
 	// a positive number of songs has been found, save them to the queue
@@ -52,15 +59,19 @@ func (bot *DiscordEventHandler) onAddSongsModalSubmit(t *transaction.Transaction
 	songs := make([]*model.Song, len(songInfos))
 	for i, info := range songInfos {
 		songs[i] = bot.builder.Song().NewSong(info)
+		songs[i].RequesterID = t.Interaction().Member.User.ID
 	}
 
 	if err := bot.datastore.Song().PersistSongs(
-		bot.session.State.User.ID,
+		t.Context(),
+		bot.session.ClientID(),
 		t.GuildID(),
 		songs...,
 	); err != nil {
-		bot.log.Errorf("Error when submitting add songs modal: %v", err)
-		return
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error when adding songs: %v", err,
+		)
+		return nil
 	}
-	bot.play(t, t.Interaction().ChannelID)
+	return songs
 }
@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"discord-music-bot/bot/transaction"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// onAddToQueueMessageCommand is a handler function called when the bot's
+// add to queue message command is used from a message's context menu,
+// this is not emmited through the discord's websocket, but is rather
+// called from INTERACTION_CREATE event when the interaction's command
+// data name matches the add to queue message command's name.
+// All of the supported urls in the message's content and embeds are
+// added to the queue, then the queue starts playing if nothing
+// is currently playing.
+func (bot *DiscordEventHandler) onAddToQueueMessageCommand(t *transaction.Transaction) {
+	l := bot.localizer(t)
+
+	if _, err := bot.datastore.Queue().GetQueue(
+		t.Context(),
+		bot.session.ClientID(),
+		t.GuildID(),
+	); err != nil {
+		bot.respondEphemeral(t, l.T("queue.none"))
+		return
+	}
+
+	data := t.Interaction().ApplicationCommandData()
+	var message *discordgo.Message
+	if data.Resolved != nil {
+		message = data.Resolved.Messages[data.TargetID]
+	}
+	if message == nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Message command's target message %s not resolved",
+			data.TargetID,
+		)
+		bot.respondEphemeral(t, l.T("error.generic"))
+		return
+	}
+
+	texts := []string{message.Content}
+	for _, embed := range message.Embeds {
+		texts = append(texts, embed.URL, embed.Description)
+		if embed.Video != nil {
+			texts = append(texts, embed.Video.URL)
+		}
+		for _, field := range embed.Fields {
+			texts = append(texts, field.Value)
+		}
+	}
+	urls := bot.service.Song().ExtractSongUrls(texts...)
+	if len(urls) == 0 {
+		bot.respondEphemeral(t, l.T("songs.no_urls"))
+		return
+	}
+	// There is a limit for a number of songs that may be queried at once
+	if len(urls) > 100 {
+		bot.respondEphemeral(t, l.T("songs.limit", 100))
+		return
+	}
+
+	// NOTE: searching the songs may take longer than discord waits
+	// for the response, so the response is deferred and edited
+	// once the songs are added. Responding before playing also keeps
+	// the queue from being updated from the command's interaction.
+	if err := bot.session.InteractionRespond(t.Interaction(),
+		&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Flags: discordgo.MessageFlagsEphemeral,
+			},
+		}); err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error when deferring the add to queue response: %v", err,
+		)
+		return
+	}
+	songs := bot.addSongs(t, urls)
+	content := l.T("songs.added", len(songs))
+	if len(songs) == 0 {
+		content = l.T("songs.not_found")
+	}
+	if _, err := bot.session.InteractionResponseEdit(
+		t.Interaction(),
+		&discordgo.WebhookEdit{Content: &content},
+	); err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error when editing the add to queue response: %v", err,
+		)
+	}
+	if len(songs) == 0 {
+		return
+	}
+
+	channelID := ""
+	if userState, _ := bot.session.VoiceState(
+		t.GuildID(),
+		t.Interaction().Member.User.ID,
+	); userState != nil {
+		channelID = userState.ChannelID
+	}
+	bot.play(t, channelID)
+}
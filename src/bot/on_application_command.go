@@ -13,9 +13,25 @@ func (bot *DiscordEventHandler) onApplicationCommand(t *transaction.Transaction)
 	// NOTE: an application command has been used,
 	// determine which one.
 
-	name := strings.TrimSpace(
-		t.Interaction().ApplicationCommandData().Name,
-	)
+	data := t.Interaction().ApplicationCommandData()
+	name := strings.TrimSpace(data.Name)
+
+	if len(data.TargetID) > 0 {
+		// NOTE: a message command has been used from a message's
+		// context menu, as only the context menu commands have
+		// a target and the bot registers no user commands
+		switch name {
+		case strings.TrimSpace(bot.config.MessageCommands.AddToQueue.Name):
+			// add to queue message command has been used
+			if !util.checkVoice(t) {
+				// should check voice connection when
+				// adding songs to the queue
+				return
+			}
+			bot.onAddToQueueMessageCommand(t)
+		}
+		return
+	}
 	switch name {
 	case strings.TrimSpace(bot.config.SlashCommands.Music.Name):
 		// music slash command has been used
@@ -33,5 +49,21 @@ func (bot *DiscordEventHandler) onApplicationCommand(t *transaction.Transaction)
 		// help slash command has been used
 		bot.onHelpSlashCommand(t)
 		return
+	case strings.TrimSpace(bot.config.SlashCommands.History.Name):
+		// history slash command has been used
+		bot.onHistorySlashCommand(t)
+		return
+	case strings.TrimSpace(bot.config.SlashCommands.Stats.Name):
+		// stats slash command has been used
+		bot.onStatsSlashCommand(t)
+		return
+	case strings.TrimSpace(bot.config.SlashCommands.Queue.Name):
+		// queue slash command has been used
+		bot.onQueueSlashCommand(t)
+		return
+	case strings.TrimSpace(bot.config.SlashCommands.Settings.Name):
+		// settings slash command has been used
+		bot.onSettingsSlashCommand(t)
+		return
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"discord-music-bot/bot/audioplayer"
 	"discord-music-bot/bot/modal"
 	"discord-music-bot/bot/transaction"
+	"discord-music-bot/builder/component"
 	"discord-music-bot/model"
 	"time"
 
@@ -18,9 +19,9 @@ type ButtonClickHandler struct {
 // onButtonClick is a handler function called when a user
 // clicks a button on a message owned by the bot.
 // This is not emitted through the discord websocket, but is rather
-// called from the INTERACTIONCREATE event when the interaction type
-// is button click and the message author is bot
-func (bot *DiscordEventHandler) onButtonClick(t *transaction.Transaction) {
+// routed from the INTERACTIONCREATE event when the button's customID
+// has one of the queue message's actions.
+func (bot *DiscordEventHandler) onButtonClick(t *transaction.Transaction, id component.ID) {
 	util := &Util{bot.Bot}
 	if !util.checkVoice(t) {
 		// NOTE: all message components require the user to
@@ -28,45 +29,42 @@ func (bot *DiscordEventHandler) onButtonClick(t *transaction.Transaction) {
 		// in any channel or be in the same channel as the user.
 		return
 	}
-	label := bot.builder.Queue().GetButtonLabelFromComponentData(
-		t.Interaction().MessageComponentData(),
-	)
 	button := &ButtonClickHandler{bot.Bot}
 
 	channelID := ""
-	if userState, _ := bot.session.State.VoiceState(
+	if userState, _ := bot.session.VoiceState(
 		t.GuildID(),
 		t.Interaction().Member.User.ID,
 	); userState != nil {
 		channelID = userState.ChannelID
 	}
 
-	switch label {
-	case bot.builder.Queue().ButtonsConfig().AddSongs:
+	switch id.Action {
+	case component.QueueAddSongs:
 		button.addSongsButtonClick(t, channelID)
 		return
-	case bot.builder.Queue().ButtonsConfig().Backward:
+	case component.QueueBackward:
 		button.backwardButtonClick(t)
 		return
-	case bot.builder.Queue().ButtonsConfig().Forward:
+	case component.QueueForward:
 		button.forwardButtonClick(t)
 		return
-	case bot.builder.Queue().ButtonsConfig().Loop:
+	case component.QueueLoop:
 		button.loopButtonClick(t)
 		return
-	case bot.builder.Queue().ButtonsConfig().Pause:
+	case component.QueuePause:
 		button.pauseButtonClick(t)
 		return
-	case bot.builder.Queue().ButtonsConfig().Skip:
+	case component.QueueSkip:
 		button.skipButtonClick(t, channelID)
 		return
-	case bot.builder.Queue().ButtonsConfig().Previous:
+	case component.QueuePrevious:
 		button.previousButtonClick(t, channelID)
 		return
-	case bot.builder.Queue().ButtonsConfig().Replay:
+	case component.QueueReplay:
 		button.replayButtonClick(t, channelID)
 		return
-	case bot.builder.Queue().ButtonsConfig().Join:
+	case component.QueueJoin:
 		button.joinButtonClick(t, channelID)
 		return
 	default:
@@ -74,7 +72,7 @@ func (bot *DiscordEventHandler) onButtonClick(t *transaction.Transaction) {
 			&discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
-					Content: "Sorry, something went wrong ...",
+					Content: bot.localizer(t).T("error.generic"),
 					Flags:   discordgo.MessageFlagsEphemeral,
 				},
 			})
@@ -85,13 +83,14 @@ func (bot *DiscordEventHandler) onButtonClick(t *transaction.Transaction) {
 // and then updates the queue message
 func (bot *ButtonClickHandler) forwardButtonClick(t *transaction.Transaction) {
 	queue, _ := bot.datastore.Queue().GetQueue(
-		bot.session.State.User.ID,
+		t.Context(),
+		bot.session.ClientID(),
 		t.GuildID(),
 	)
-	queue, _ = bot.datastore.Song().UpdateQueueWithSongs(queue)
+	queue, _ = bot.datastore.Song().UpdateQueueWithSongs(t.Context(), queue)
 
 	bot.service.Queue().IncrementQueueOffset(queue)
-	if err := bot.datastore.Queue().UpdateQueue(queue); err != nil {
+	if err := bot.datastore.Queue().UpdateQueue(t.Context(), queue); err != nil {
 		bot.log.Errorf("log.Error on forward button click: %v", err)
 		return
 	}
@@ -102,13 +101,14 @@ func (bot *ButtonClickHandler) forwardButtonClick(t *transaction.Transaction) {
 // and then updates the queue message
 func (bot *ButtonClickHandler) backwardButtonClick(t *transaction.Transaction) {
 	queue, _ := bot.datastore.Queue().GetQueue(
-		bot.session.State.User.ID,
+		t.Context(),
+		bot.session.ClientID(),
 		t.GuildID(),
 	)
-	queue, _ = bot.datastore.Song().UpdateQueueWithSongs(queue)
+	queue, _ = bot.datastore.Song().UpdateQueueWithSongs(t.Context(), queue)
 
 	bot.service.Queue().DecrementQueueOffset(queue)
-	if err := bot.datastore.Queue().UpdateQueue(queue); err != nil {
+	if err := bot.datastore.Queue().UpdateQueue(t.Context(), queue); err != nil {
 		bot.log.Errorf("log.Error on backward button click: %v", err)
 		return
 	}
@@ -118,137 +118,129 @@ func (bot *ButtonClickHandler) backwardButtonClick(t *transaction.Transaction) {
 // pauseButtonClick adds or removes the queue's Paused option, updates it
 // and then updates the queue message
 func (bot *ButtonClickHandler) pauseButtonClick(t *transaction.Transaction) {
-	bot.blockAndGetAudioplayer("PAUSE", t.GuildID(), func(ap *audioplayer.AudioPlayer) {
-		time.Sleep(300 * time.Millisecond)
+	ap, playing := bot.playingAudioplayer(t.GuildID())
 
-		if bot.datastore.Queue().QueueHasOption(
-			bot.session.State.User.ID,
+	if bot.datastore.Queue().QueueHasOption(
+		t.Context(),
+		bot.session.ClientID(),
+		t.GuildID(),
+		model.Paused,
+	) {
+		bot.datastore.Queue().RemoveQueueOptions(
+			t.Context(),
+			bot.session.ClientID(),
 			t.GuildID(),
 			model.Paused,
-		) {
-			bot.datastore.Queue().RemoveQueueOptions(
-				bot.session.State.User.ID,
-				t.GuildID(),
-				model.Paused,
-			)
-			if ap, ok := bot.audioplayers.Get(t.GuildID()); ok {
-				ap.Subscriptions().Emit("unpause")
-			}
-		} else {
-			bot.datastore.Queue().PersistQueueOptions(
-				bot.session.State.User.ID,
-				t.GuildID(),
-				model.PausedOption(),
-			)
-			if ap, ok := bot.audioplayers.Get(t.GuildID()); ok {
-				ap.Subscriptions().Emit("pause")
-			}
-
+		)
+		if playing {
+			bot.handleAudioplayerError(t, ap.Unpause(t.Context()))
+		}
+	} else {
+		bot.datastore.Queue().PersistQueueOptions(
+			t.Context(),
+			bot.session.ClientID(),
+			t.GuildID(),
+			model.PausedOption(),
+		)
+		if playing {
+			bot.handleAudioplayerError(t, ap.Pause(t.Context()))
 		}
-		t.UpdateQueue(100 * time.Millisecond)
-	})
+	}
+	t.UpdateQueue(100 * time.Millisecond)
 }
 
 // loopButtonClick adds or removes the queue's Loop option, updates it
 // and then updates the queue message
 func (bot *ButtonClickHandler) loopButtonClick(t *transaction.Transaction) {
-	bot.blockAndGetAudioplayer("LOOP", t.GuildID(), func(ap *audioplayer.AudioPlayer) {
-		time.Sleep(300 * time.Millisecond)
-
-		if bot.datastore.Queue().QueueHasOption(
-			bot.session.State.User.ID,
+	if bot.datastore.Queue().QueueHasOption(
+		t.Context(),
+		bot.session.ClientID(),
+		t.GuildID(),
+		model.Loop,
+	) {
+		bot.datastore.Queue().RemoveQueueOptions(
+			t.Context(),
+			bot.session.ClientID(),
 			t.GuildID(),
 			model.Loop,
-		) {
-			bot.datastore.Queue().RemoveQueueOptions(
-				bot.session.State.User.ID,
-				t.GuildID(),
-				model.Loop,
-			)
-		} else {
-			bot.datastore.Queue().PersistQueueOptions(
-				bot.session.State.User.ID,
-				t.GuildID(),
-				model.LoopOption(),
-			)
-		}
-		t.UpdateQueue(100 * time.Millisecond)
-	})
+		)
+	} else {
+		bot.datastore.Queue().PersistQueueOptions(
+			t.Context(),
+			bot.session.ClientID(),
+			t.GuildID(),
+			model.LoopOption(),
+		)
+	}
+	t.UpdateQueue(100 * time.Millisecond)
 }
 
 // skipButtonClick skips the currently playing song if any
 func (bot *ButtonClickHandler) skipButtonClick(t *transaction.Transaction, channelID string) {
-	bot.blockAndGetAudioplayer("SKIP", t.GuildID(), func(ap *audioplayer.AudioPlayer) {
-		if ap == nil {
-			bot.play(t, channelID)
-
-		} else if ap.IsPaused() {
-			return
-		}
-		time.Sleep(500 * time.Millisecond)
-		if ap != nil {
-			ap.Subscriptions().Emit("skip")
-		}
-	})
+	ap, playing := bot.playingAudioplayer(t.GuildID())
+	if !playing {
+		bot.play(t, channelID)
+		return
+	} else if ap.State().Paused {
+		return
+	}
+	bot.handleAudioplayerError(t, ap.Skip(t.Context()))
 }
 
-// replayButtonClick adds a different defer func to the audioplayer, that does not remove,
-// the queue's current headSong, and restarts the audioplayer.
+// replayButtonClick restarts the currently playing
+// song from the start, without removing it.
 func (bot *ButtonClickHandler) replayButtonClick(t *transaction.Transaction, channelID string) {
-	bot.blockAndGetAudioplayer("REPLAY", t.GuildID(), func(ap *audioplayer.AudioPlayer) {
-		if ap == nil {
-			bot.play(t, channelID)
-			return
-		} else if ap.IsPaused() {
-			return
-		}
-		time.Sleep(500 * time.Millisecond)
-		if ap != nil {
-			ap.Subscriptions().Emit("replay")
-		}
-	})
+	ap, playing := bot.playingAudioplayer(t.GuildID())
+	if !playing {
+		bot.play(t, channelID)
+		return
+	} else if ap.State().Paused {
+		return
+	}
+	bot.handleAudioplayerError(t, ap.Seek(t.Context(), 0))
 }
 
-// previousButtonClick adds a different defer func to the audioplayer, that adds
-// the queue's previous  song as its head song, and restarts the player.
+// previousButtonClick moves the queue's previous song to the front
+// of the queue, and starts playing it.
 func (bot *ButtonClickHandler) previousButtonClick(t *transaction.Transaction, channelID string) {
-	bot.blockAndGetAudioplayer("PREVIOUS", t.GuildID(), func(ap *audioplayer.AudioPlayer) {
-		if ap != nil && ap.IsPaused() {
-			return
-		}
+	ap, playing := bot.playingAudioplayer(t.GuildID())
+	if playing && ap.State().Paused {
+		return
+	}
 
-		queue, err := bot.datastore.Queue().GetQueue(
-			bot.session.State.User.ID,
+	queue, err := bot.datastore.Queue().GetQueue(
+		t.Context(),
+		bot.session.ClientID(),
+		t.GuildID(),
+	)
+	if err != nil {
+		bot.log.Errorf("log.Error on previous button click: %v", err)
+		return
+	}
+	queue, err = bot.datastore.Song().UpdateQueueWithSongs(t.Context(), queue)
+	if err != nil {
+		bot.log.Errorf("log.Error on previous button click: %v", err)
+		return
+	}
+	if queue.InactiveSize == 0 && !(queue.Size > 1 &&
+		bot.datastore.Queue().QueueHasOption(
+			t.Context(),
+			bot.session.ClientID(),
 			t.GuildID(),
-		)
-		if err != nil {
-			bot.log.Errorf("log.Error on previous button click: %v", err)
-			return
-		}
-		queue, err = bot.datastore.Song().UpdateQueueWithSongs(queue)
-		if err != nil {
-			bot.log.Errorf("log.Error on previous button click: %v", err)
-			return
-		}
-		if queue.InactiveSize == 0 && !(queue.Size > 1 &&
-			bot.datastore.Queue().QueueHasOption(
-				bot.session.State.User.ID,
-				t.GuildID(),
-				model.Loop,
-			)) {
-
-			return
-		}
+			model.Loop,
+		)) {
 
-		if ap == nil {
-			h := &AudioplayerEventHandler{bot.Bot}
-			h.handleReverseHeadSongRemoval(t)
-			bot.play(t, channelID)
-			return
-		}
-		time.Sleep(500 * time.Millisecond)
-		ap.Subscriptions().Emit("skipToPrevious")
-	})
+		return
+	}
+	if playing {
+		bot.handleAudioplayerError(t, ap.Previous(t.Context()))
+		return
+	}
+	// NOTE: the audioplayer without a voice connection only moves
+	// the previous song to the front, it is played once the
+	// voice channel is joined
+	bot.handleAudioplayerError(t, bot.audioplayer(t.GuildID()).Previous(t.Context()))
+	bot.play(t, channelID)
 }
 
 // joinButtonClick removes the inactive option from the queue, updates
@@ -269,10 +261,11 @@ func (bot *ButtonClickHandler) addSongsButtonClick(t *transaction.Transaction, c
 
 	util.joinVoice(t, channelID)
 
+	l := bot.localizer(t)
 	textInput := discordgo.TextInput{
 		CustomID:    uuid.NewString(),
-		Label:       bot.config.Modals.AddSongs.Label,
-		Placeholder: bot.config.Modals.AddSongs.Placeholder,
+		Label:       l.Or("modals.add_songs.label", bot.config.Modals.AddSongs.Label),
+		Placeholder: l.Or("modals.add_songs.placeholder", bot.config.Modals.AddSongs.Placeholder),
 		Style:       discordgo.TextInputParagraph,
 		MinLength:   1,
 		MaxLength:   4000,
@@ -280,7 +273,7 @@ func (bot *ButtonClickHandler) addSongsButtonClick(t *transaction.Transaction, c
 	}
 
 	m := modal.GetModal(
-		bot.config.Modals.AddSongs.Name,
+		component.New(component.AddSongsModal, t.GuildID(), ""),
 		[]discordgo.MessageComponent{textInput},
 	)
 	if err := bot.session.InteractionRespond(
@@ -290,7 +283,7 @@ func (bot *ButtonClickHandler) addSongsButtonClick(t *transaction.Transaction, c
 			Data: &discordgo.InteractionResponseData{
 				Components: m.Components,
 				CustomID:   m.CustomID,
-				Title:      bot.config.Modals.AddSongs.Name,
+				Title:      l.Or("modals.add_songs.title", bot.config.Modals.AddSongs.Name),
 			},
 		},
 	); err != nil {
@@ -303,16 +296,25 @@ func (bot *ButtonClickHandler) addSongsButtonClick(t *transaction.Transaction, c
 	return nil
 }
 
-func (bot *ButtonClickHandler) blockAndGetAudioplayer(blockKey string, guildID string, f func(*audioplayer.AudioPlayer)) {
-	if bot.blockedCommands.IsBlocked(guildID, blockKey) {
-		return
+// playingAudioplayer returns the audioplayer of the guild identified by
+// the provided guildID, and true if the audioplayer is playing a song.
+func (bot *ButtonClickHandler) playingAudioplayer(guildID string) (*audioplayer.AudioPlayer, bool) {
+	ap, ok := bot.audioplayers.Get(guildID)
+	if !ok || ap.Stopped() || !ap.State().Playing {
+		return nil, false
 	}
-	bot.blockedCommands.Block(guildID, blockKey)
-	defer bot.blockedCommands.Unblock(guildID, blockKey)
+	return ap, true
+}
 
-	ap, ok := bot.audioplayers.Get(guildID)
-	if ok && ap.IsPaused() {
+// handleAudioplayerError logs the provided error returned by a
+// command sent to the audioplayer, if any, and updates the queue
+// message, as the command has not changed the playback.
+func (bot *ButtonClickHandler) handleAudioplayerError(t *transaction.Transaction, err error) {
+	if err == nil {
 		return
 	}
-	f(ap)
+	bot.log.WithField("GuildID", t.GuildID()).Debugf(
+		"Audioplayer command failed: %v", err,
+	)
+	t.UpdateQueue(100 * time.Millisecond)
 }
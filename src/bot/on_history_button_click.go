@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"discord-music-bot/bot/transaction"
+	"discord-music-bot/builder/component"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type HistoryButtonClickHandler struct {
+	*Bot
+}
+
+// onHistoryButtonClick is a handler function called when a user
+// clicks a button on a history message sent by the bot.
+// This is not emitted through the discord websocket, but is rather
+// routed from the INTERACTIONCREATE event when the button's customID
+// has one of the history message's actions. The customID's argument
+// is the history's offset, or the history entry's ID when the
+// song is added back to the queue.
+func (bot *DiscordEventHandler) onHistoryButtonClick(t *transaction.Transaction, id component.ID) {
+	button := &HistoryButtonClickHandler{bot.Bot}
+
+	switch id.Action {
+	case component.HistoryBackward, component.HistoryForward:
+		offset, err := strconv.Atoi(id.Argument)
+		if err != nil {
+			bot.log.WithField("GuildID", t.GuildID()).Errorf(
+				"Error on history button click: %v", err,
+			)
+			return
+		}
+		button.pageButtonClick(t, id.Action, offset)
+		return
+	case component.HistoryRequeue:
+		entryID, err := strconv.ParseUint(id.Argument, 10, 64)
+		if err != nil {
+			bot.log.WithField("GuildID", t.GuildID()).Errorf(
+				"Error on history button click: %v", err,
+			)
+			return
+		}
+		button.requeueButtonClick(t, uint(entryID))
+		return
+	}
+}
+
+// pageButtonClick increments or decrements the history's offset
+// and updates the history message with the new page.
+func (bot *HistoryButtonClickHandler) pageButtonClick(t *transaction.Transaction, action component.Action, offset int) {
+	defer t.Defer()
+
+	h := bot.builder.History().NewHistory(
+		bot.session.ClientID(),
+		t.GuildID(),
+	)
+	h.Offset = offset
+	h.Size = bot.datastore.History().GetHistorySize(t.Context(), h.ClientID, h.GuildID)
+
+	if action == component.HistoryForward {
+		bot.service.History().IncrementHistoryOffset(h)
+	} else {
+		bot.service.History().DecrementHistoryOffset(h)
+	}
+	h, err := bot.datastore.History().UpdateHistoryWithEntries(t.Context(), h)
+	if err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error on history page button click: %v", err,
+		)
+		return
+	}
+	if err := bot.session.InteractionRespond(
+		t.Interaction(),
+		&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{
+					bot.builder.History().MapHistoryToEmbed(h, t.Interaction().Locale),
+				},
+				Components: bot.builder.History().GetHistoryComponents(h, t.Interaction().Locale),
+			},
+		}); err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error when updating history message: %v", err,
+		)
+	}
+}
+
+// requeueButtonClick adds the song from the history entry identified
+// by the provided id back to the queue, then starts playing if
+// nothing is currently playing.
+func (bot *HistoryButtonClickHandler) requeueButtonClick(t *transaction.Transaction, id uint) {
+	util := &Util{bot.Bot}
+	if !util.checkVoice(t) {
+		return
+	}
+	if _, err := bot.datastore.Queue().GetQueue(
+		t.Context(),
+		bot.session.ClientID(),
+		t.GuildID(),
+	); err != nil {
+		bot.respondEphemeral(t, bot.localizer(t).T("queue.none"))
+		return
+	}
+	entry, err := bot.datastore.History().GetHistoryEntry(
+		t.Context(),
+		bot.session.ClientID(),
+		t.GuildID(),
+		id,
+	)
+	if err != nil {
+		bot.respondEphemeral(t, bot.localizer(t).T("history.none"))
+		return
+	}
+	song := bot.builder.Song().NewSongFromHistoryEntry(entry)
+	song.RequesterID = t.Interaction().Member.User.ID
+
+	if err := bot.datastore.Song().PersistSongs(
+		t.Context(),
+		bot.session.ClientID(),
+		t.GuildID(),
+		song,
+	); err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error when adding a song from history: %v", err,
+		)
+		return
+	}
+	// NOTE: respond before playing, so the history message
+	// is not replaced by the queue when the queue is updated
+	bot.respondEphemeral(t, bot.localizer(t).T("history.added", song.Name))
+
+	channelID := ""
+	if userState, _ := bot.session.VoiceState(
+		t.GuildID(),
+		t.Interaction().Member.User.ID,
+	); userState != nil {
+		channelID = userState.ChannelID
+	}
+	bot.play(t, channelID)
+}
@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"discord-music-bot/bot/transaction"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// onHistorySlashCommand is a handler function called when the bot's history
+// slash command is called in the discord channel, this is not emmited through
+// the discord's websocket, but is rather called from INTERACTION_CREATE event
+// when the interaction's command data name matches the history slash
+// command's name.
+func (bot *DiscordEventHandler) onHistorySlashCommand(t *transaction.Transaction) {
+	defer t.Defer()
+
+	history := bot.builder.History().NewHistory(
+		bot.session.ClientID(),
+		t.GuildID(),
+	)
+	history, err := bot.datastore.History().UpdateHistoryWithEntries(t.Context(), history)
+	if err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error when fetching history: %v",
+			err,
+		)
+		return
+	}
+	if err := bot.session.InteractionRespond(
+		t.Interaction(),
+		&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{
+					bot.builder.History().MapHistoryToEmbed(history, t.Interaction().Locale),
+				},
+				Components: bot.builder.History().GetHistoryComponents(history, t.Interaction().Locale),
+				Flags:      discordgo.MessageFlagsEphemeral,
+			},
+		}); err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error when responding to history command: %v",
+			err,
+		)
+	}
+}
@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// messageCounter counts the messages sent below every
+// guild's queue message, since it has been posted.
+type messageCounter struct {
+	mutex  sync.Mutex
+	counts map[string]*messageCount
+}
+
+type messageCount struct {
+	messageID string
+	count     int
+}
+
+// newMessageCounter constructs a counter
+// without any counted messages.
+func newMessageCounter() *messageCounter {
+	return &messageCounter{
+		mutex:  sync.Mutex{},
+		counts: make(map[string]*messageCount),
+	}
+}
+
+// increment counts a new message below the queue message identified
+// by the provided messageID, in the guild identified by the provided
+// guildID, and returns the number of messages below it. The count
+// starts over once the guild's queue message changes.
+func (c *messageCounter) increment(guildID string, messageID string) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	count, ok := c.counts[guildID]
+	if !ok || count.messageID != messageID {
+		count = &messageCount{messageID: messageID}
+		c.counts[guildID] = count
+	}
+	count.count++
+	return count.count
+}
+
+// onMessageCreate is a handler function called when discord emits
+// MESSAGE_CREATE event. When the guild has the queue message sticky,
+// the users' messages sent to the queue message's channel are counted, and
+// once there are enough of them, the queue message is moved below them.
+func (bot *DiscordEventHandler) onMessageCreate(m *discordgo.MessageCreate) {
+	sticky := bot.guilds.Get(m.GuildID).QueueMessage.Sticky
+	if sticky == 0 {
+		return
+	}
+	// NOTE: the bot's own messages, e.g. the announcements and
+	// the reposted queue message, are not counted
+	if m.Author != nil && m.Author.ID == bot.session.ClientID() {
+		return
+	}
+	queue, err := bot.datastore.Queue().GetQueue(
+		bot.ctx,
+		bot.session.ClientID(),
+		m.GuildID,
+	)
+	if err != nil || queue.ChannelID != m.ChannelID ||
+		queue.MessageID == m.ID {
+		return
+	}
+	if bot.stickyCounts.increment(m.GuildID, queue.MessageID)%sticky != 0 {
+		return
+	}
+	bot.log.WithField("GuildID", m.GuildID).Trace(
+		"Moving the sticky queue message below the new messages",
+	)
+	util := &Util{bot.Bot}
+	if err := util.moveQueueMessage(queue, queue.ChannelID); err != nil {
+		bot.log.WithField("GuildID", m.GuildID).Errorf(
+			"Error when moving the sticky queue message: %v", err,
+		)
+	}
+}
@@ -1,36 +1,38 @@
 package bot
 
 import (
-	"discord-music-bot/bot/modal"
 	"discord-music-bot/bot/transaction"
-	"strings"
+	"discord-music-bot/builder/component"
 )
 
 // onModalSubmit is a handler function called when discord emits
 // INTERACTION_CREATE event and the interaction's type is modalSubmit.
-func (bot *DiscordEventHandler) onModalSubmit(t *transaction.Transaction) {
+// It is routed by the action in the submitted modal's customID.
+func (bot *DiscordEventHandler) onModalSubmit(t *transaction.Transaction, id component.ID) {
 	// NOTE: a user has submited a modal in the discord server
 	// determine which modal has been submitted
 	// NOTE: no need to check voice connection, as
 	// it has already been checked in order to reach the modal
 	channelID := ""
-	if userState, _ := bot.session.State.VoiceState(
+	if userState, _ := bot.session.VoiceState(
 		t.GuildID(),
 		t.Interaction().Member.User.ID,
 	); userState != nil {
 		channelID = userState.ChannelID
 	}
 
-	name := strings.TrimSpace(
-		modal.GetModalName(
-			t.Interaction().ModalSubmitData(),
-		),
-	)
-	switch name {
+	switch id.Action {
 	// add songs modal has been submited
-	case strings.TrimSpace(bot.config.Modals.AddSongs.Name):
+	case component.AddSongsModal:
 		bot.onAddSongsModalSubmit(t)
 		bot.play(t, channelID)
 		return
+	// a private queue view's modals have been submited
+	case component.QueuePageModal:
+		bot.onQueuePageModalSubmit(t, id)
+		return
+	case component.QueueSearchModal:
+		bot.onQueueSearchModalSubmit(t, id)
+		return
 	}
 }
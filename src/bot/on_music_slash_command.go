@@ -1,7 +1,9 @@
 package bot
 
 import (
+	"discord-music-bot/bot/slash_command"
 	"discord-music-bot/bot/transaction"
+	"discord-music-bot/model"
 	"fmt"
 
 	"github.com/bwmarrin/discordgo"
@@ -11,24 +13,39 @@ import (
 // command is called in the discord channel, this is not emmited through the
 // discord's websocket, but is rather called from INTERACTION_CREATE event when
 // the interaction's command data name matches the music slash command's name.
+// When the queue is already active and the command's here option is set,
+// the queue message is moved to the command's channel.
 func (bot *DiscordEventHandler) onMusicSlashCommand(t *transaction.Transaction) {
 	defer t.Defer()
 
 	// NOTE: only a single queue may be active in a guild at once
 	if queue, err := bot.datastore.Queue().GetQueue(
-		bot.session.State.User.ID,
+		t.Context(),
+		bot.session.ClientID(),
 		t.GuildID(),
 	); err == nil {
+		here := false
+		for _, o := range t.Interaction().ApplicationCommandData().Options {
+			if o.Name == slash_command.MusicHereOption {
+				here = o.BoolValue()
+			}
+		}
+		if here {
+			bot.moveQueue(t, queue)
+			return
+		}
 		bot.session.InteractionRespond(t.Interaction(),
 			&discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
-					Content: fmt.Sprintf(
-						"A music queue is already active in this server: "+
+					Content: bot.localizer(t).T(
+						"queue.active",
+						fmt.Sprintf(
 							"https://discord.com/channels/%s/%s/%s",
-						queue.GuildID,
-						queue.ChannelID,
-						queue.MessageID,
+							queue.GuildID,
+							queue.ChannelID,
+							queue.MessageID,
+						),
 					),
 					Flags: discordgo.MessageFlagsEphemeral,
 				},
@@ -42,7 +59,7 @@ func (bot *DiscordEventHandler) onMusicSlashCommand(t *transaction.Transaction)
 	// Construct a new queue, send it to the channel
 	// and persist it in the datastore
 	queue := bot.builder.Queue().NewQueue(
-		bot.session.State.User.ID,
+		bot.session.ClientID(),
 		t.GuildID(),
 		"", "",
 	)
@@ -75,8 +92,36 @@ func (bot *DiscordEventHandler) onMusicSlashCommand(t *transaction.Transaction)
 	}
 	queue.MessageID = msg.ID
 	queue.ChannelID = msg.ChannelID
-	if err := bot.datastore.Queue().PersistQueue(queue); err != nil {
+	if err := bot.datastore.Queue().PersistQueue(t.Context(), queue); err != nil {
 		bot.log.Errorf("Error when persisting a new queue: %v", err)
 		return
 	}
 }
+
+// moveQueue moves the provided queue's message to the channel
+// in which the music slash command has been used, and responds
+// to the command once the queue message has been moved.
+func (bot *DiscordEventHandler) moveQueue(t *transaction.Transaction, queue *model.Queue) {
+	util := &Util{bot.Bot}
+	content := bot.localizer(t).T("queue.moved")
+	if err := util.moveQueueMessage(queue, t.Interaction().ChannelID); err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error when moving the queue message: %v",
+			err,
+		)
+		content = bot.localizer(t).T("queue.not_moved")
+	}
+	if err := bot.session.InteractionRespond(t.Interaction(),
+		&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}); err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error when responding to music command: %v",
+			err,
+		)
+	}
+}
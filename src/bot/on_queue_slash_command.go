@@ -0,0 +1,26 @@
+package bot
+
+import (
+	"discord-music-bot/bot/transaction"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// onQueueSlashCommand is a handler function called when the bot's queue
+// slash command is called in the discord channel, this is not emmited through
+// the discord's websocket, but is rather called from INTERACTION_CREATE event
+// when the interaction's command data name matches the queue slash
+// command's name.
+// It responds with a private view of the music queue, that has it's own
+// page, so browsing it does not change the page of the queue message.
+func (bot *DiscordEventHandler) onQueueSlashCommand(t *transaction.Transaction) {
+	defer t.Defer()
+
+	view := &QueueViewHandler{bot.Bot}
+	queue, err := view.getQueue(t, 0, nil)
+	if err != nil {
+		view.respondEphemeral(t, bot.localizer(t).T("queue.none"))
+		return
+	}
+	view.respond(t, queue, discordgo.InteractionResponseChannelMessageWithSource)
+}
@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"discord-music-bot/bot/modal"
+	"discord-music-bot/bot/transaction"
+	"discord-music-bot/builder/component"
+	"discord-music-bot/model"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+)
+
+type QueueViewHandler struct {
+	*Bot
+}
+
+// onQueueViewButtonClick is a handler function called when a user
+// clicks a button on a private view of the queue sent by the bot.
+// This is not emitted through the discord websocket, but is rather
+// routed from the INTERACTIONCREATE event when the button's customID
+// has one of the private queue view's actions. The customID's
+// argument is the view's offset.
+func (bot *DiscordEventHandler) onQueueViewButtonClick(t *transaction.Transaction, id component.ID) {
+	offset, err := strconv.Atoi(id.Argument)
+	if err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error on queue view button click: %v", err,
+		)
+		return
+	}
+	view := &QueueViewHandler{bot.Bot}
+
+	switch id.Action {
+	case component.ViewBackward, component.ViewForward:
+		view.pageButtonClick(t, id.Action, offset)
+		return
+	case component.ViewPage:
+		view.sendModal(t, component.QueuePageModal, "queue_page", bot.config.Modals.QueuePage, offset)
+		return
+	case component.ViewSearch:
+		view.sendModal(t, component.QueueSearchModal, "queue_search", bot.config.Modals.QueueSearch, offset)
+		return
+	}
+}
+
+// pageButtonClick increments or decrements the view's offset
+// and updates the view with the new page.
+func (bot *QueueViewHandler) pageButtonClick(t *transaction.Transaction, action component.Action, offset int) {
+	defer t.Defer()
+
+	q, err := bot.getQueue(t, offset, func(q *model.Queue) error {
+		if action == component.ViewForward {
+			bot.service.Queue().IncrementQueueOffset(q)
+		} else {
+			bot.service.Queue().DecrementQueueOffset(q)
+		}
+		return nil
+	})
+	if err != nil {
+		bot.respondEphemeral(t, bot.localizer(t).T("queue.none"))
+		return
+	}
+	bot.respond(t, q, discordgo.InteractionResponseUpdateMessage)
+}
+
+// sendModal responds to the transaction's interaction with the
+// modal with the provided action and config, that holds the view's
+// offset. The modal's texts are translated with the modals.<key> keys.
+func (bot *QueueViewHandler) sendModal(t *transaction.Transaction, action component.Action, key string, config *modal.ModalConfig, offset int) {
+	defer t.Defer()
+
+	l := bot.localizer(t)
+	textInput := discordgo.TextInput{
+		CustomID:    uuid.NewString(),
+		Label:       l.Or("modals."+key+".label", config.Label),
+		Placeholder: l.Or("modals."+key+".placeholder", config.Placeholder),
+		Style:       discordgo.TextInputShort,
+		MinLength:   1,
+		MaxLength:   100,
+		Required:    true,
+	}
+	m := modal.GetModal(
+		component.New(action, t.GuildID(), strconv.Itoa(offset)),
+		[]discordgo.MessageComponent{textInput},
+	)
+	if err := bot.session.InteractionRespond(
+		t.Interaction(),
+		&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseModal,
+			Data: &discordgo.InteractionResponseData{
+				Components: m.Components,
+				CustomID:   m.CustomID,
+				Title:      l.Or("modals."+key+".title", config.Name),
+			},
+		},
+	); err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error when responding with queue view modal: %v", err,
+		)
+	}
+}
+
+// getQueue fetches the queue of the transaction's guild, with the
+// provided offset instead of the queue's own offset, that is never
+// updated from the private view. The provided page function, if not
+// nil, may then change the offset before the queue's songs are
+// fetched, the error it returns is returned without fetching them.
+func (bot *QueueViewHandler) getQueue(t *transaction.Transaction, offset int, page func(*model.Queue) error) (*model.Queue, error) {
+	q, err := bot.datastore.Queue().GetQueue(
+		t.Context(),
+		bot.session.ClientID(),
+		t.GuildID(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	q.Offset = offset
+	q.Size = bot.datastore.Song().GetSongCountForQueue(
+		t.Context(),
+		q.ClientID,
+		q.GuildID,
+	)
+	// NOTE: the offset may be out of range,
+	// if songs have been removed since
+	if q.Offset+1 >= q.Size {
+		q.Offset = 0
+	}
+	if page != nil {
+		if err := page(q); err != nil {
+			return nil, err
+		}
+	}
+	q, err = bot.datastore.Song().UpdateQueueWithSongs(t.Context(), q)
+	if err != nil {
+		return nil, err
+	}
+	if q.HeadSong != nil {
+		q.Elapsed = int(bot.playbackPosition(t.GuildID()).Seconds())
+	}
+	return q, nil
+}
+
+// respond responds to the transaction's interaction with the
+// private view of the provided queue, the provided type determines
+// whether a new view is sent or the existing one is updated.
+func (bot *QueueViewHandler) respond(t *transaction.Transaction, q *model.Queue, tp discordgo.InteractionResponseType) {
+	if err := bot.session.InteractionRespond(
+		t.Interaction(),
+		&discordgo.InteractionResponse{
+			Type: tp,
+			Data: &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{
+					bot.builder.Queue().MapQueueToEmbedWithETA(q, t.Interaction().Locale),
+				},
+				Components: bot.builder.Queue().GetPrivateQueueComponents(q, t.Interaction().Locale),
+				Flags:      discordgo.MessageFlagsEphemeral,
+			},
+		}); err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error when responding with queue view: %v", err,
+		)
+	}
+}
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"discord-music-bot/bot/transaction"
+	"discord-music-bot/builder/component"
+	"discord-music-bot/model"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// errNoMatchingSong is returned when no song in
+// the queue matches the searched query.
+var errNoMatchingSong = errors.New("No matching song in the queue")
+
+// onQueuePageModalSubmit is a handler function called when a user
+// submits the page modal of a private queue view. The view is
+// updated with the page, whose number has been entered.
+func (bot *DiscordEventHandler) onQueuePageModalSubmit(t *transaction.Transaction, id component.ID) {
+	defer t.Defer()
+
+	view := &QueueViewHandler{bot.Bot}
+	offset, value := view.parseModal(t, id)
+	page, err := strconv.Atoi(value)
+	if err != nil {
+		view.respondEphemeral(t, bot.localizer(t).T("queue.page_invalid", value))
+		return
+	}
+	q, err := view.getQueue(t, offset, func(q *model.Queue) error {
+		bot.service.Queue().SetQueuePage(q, page)
+		return nil
+	})
+	if err != nil {
+		view.respondEphemeral(t, bot.localizer(t).T("queue.none"))
+		return
+	}
+	view.respond(t, q, discordgo.InteractionResponseUpdateMessage)
+}
+
+// onQueueSearchModalSubmit is a handler function called when a user
+// submits the search modal of a private queue view. The view is
+// updated with the page of the next song, whose name contains the
+// entered query.
+func (bot *DiscordEventHandler) onQueueSearchModalSubmit(t *transaction.Transaction, id component.ID) {
+	defer t.Defer()
+
+	view := &QueueViewHandler{bot.Bot}
+	offset, query := view.parseModal(t, id)
+	q, err := view.getQueue(t, offset, func(q *model.Queue) error {
+		songs, err := bot.datastore.Song().GetAllSongsForQueue(
+			t.Context(),
+			q.ClientID,
+			q.GuildID,
+		)
+		if err != nil {
+			return err
+		}
+		if !bot.service.Queue().SearchQueue(q, songs, query) {
+			return errNoMatchingSong
+		}
+		return nil
+	})
+	if errors.Is(err, errNoMatchingSong) {
+		view.respondEphemeral(t, bot.localizer(t).T("queue.search_none", query))
+		return
+	} else if err != nil {
+		view.respondEphemeral(t, bot.localizer(t).T("queue.none"))
+		return
+	}
+	view.respond(t, q, discordgo.InteractionResponseUpdateMessage)
+}
+
+// parseModal retrieves the private view's offset from the
+// modal's ID and the entered value from the submitted modal.
+func (bot *QueueViewHandler) parseModal(t *transaction.Transaction, id component.ID) (int, string) {
+	data := t.Interaction().ModalSubmitData()
+	offset, _ := strconv.Atoi(id.Argument)
+	value := ""
+	if len(data.Components) > 0 {
+		actionsRow := (data.Components[0]).(*discordgo.ActionsRow)
+		if len(actionsRow.Components) > 0 {
+			textInput := (actionsRow.Components[0]).(*discordgo.TextInput)
+			value = strings.TrimSpace(textInput.Value)
+		}
+	}
+	return offset, value
+}
@@ -13,10 +13,14 @@ func (bot *DiscordEventHandler) onReady(r *discordgo.Ready) {
 	bot.session.UpdateListeningStatus(
 		"/" + bot.config.SlashCommands.Help.Name,
 	)
-	bot._ready = true
+	bot._ready.set(true)
 
-	// check if any queues should be removed from datastore
+	// NOTE: load the guilds' own settings before the
+	// queue messages are updated with them
 	util := &Util{bot.Bot}
+	util.loadGuildSettings()
+
+	// check if any queues should be removed from datastore
 	util.cleanDiscordMusicQueues()
 
 	time.Sleep(500 * time.Millisecond)
@@ -27,6 +31,9 @@ func (bot *DiscordEventHandler) onReady(r *discordgo.Ready) {
 		"Username": r.User.Username + " #" + r.User.Discriminator,
 	}).Info("Bot ready")
 
-	bot.ready = true
+	bot.ready.set(true)
 
+	// NOTE: resume playback in the voice channels the
+	// bot has been in when it has been shut down
+	util.resumeQueues()
 }
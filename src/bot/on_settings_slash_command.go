@@ -0,0 +1,152 @@
+package bot
+
+import (
+	"discord-music-bot/bot/slash_command"
+	"discord-music-bot/bot/transaction"
+	"discord-music-bot/model"
+	"discord-music-bot/settings"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
+)
+
+// onSettingsSlashCommand is a handler function called when the bot's settings
+// slash command is called in the discord channel, this is not emmited through
+// the discord's websocket, but is rather called from INTERACTION_CREATE event
+// when the interaction's command data name matches the settings slash
+// command's name.
+// It shows the guild's settings when no setting is provided, changes the
+// provided setting to the provided value, or resets it to the configured
+// one when no value is provided. Only the members that may manage the
+// server may use it.
+func (bot *DiscordEventHandler) onSettingsSlashCommand(t *transaction.Transaction) {
+	defer t.Defer()
+
+	l := bot.localizer(t)
+	// NOTE: discord hides the command from the members that
+	// may not manage the server, but the server may allow
+	// it to them, so the permissions are checked again
+	member := t.Interaction().Member
+	if member == nil || member.Permissions&(discordgo.PermissionManageServer|
+		discordgo.PermissionAdministrator) == 0 {
+		bot.respondEphemeral(t, l.T("settings.forbidden"))
+		return
+	}
+
+	name, value, hasValue := "", "", false
+	for _, o := range t.Interaction().ApplicationCommandData().Options {
+		switch o.Name {
+		case slash_command.SettingsNameOption:
+			name = o.StringValue()
+		case slash_command.SettingsValueOption:
+			value, hasValue = o.StringValue(), true
+		}
+	}
+	if len(name) == 0 {
+		bot.respondEphemeral(t, bot.settingsContent(t))
+		return
+	}
+
+	fields := log.Fields{"GuildID": t.GuildID(), "Name": name}
+	if !hasValue {
+		if err := bot.datastore.GuildSettings().RemoveGuildSetting(
+			t.Context(),
+			bot.session.ClientID(),
+			t.GuildID(),
+			name,
+		); err != nil {
+			bot.log.WithFields(fields).Errorf(
+				"Error when resetting guild setting: %v", err,
+			)
+			bot.respondEphemeral(t, l.T("error.generic"))
+			return
+		}
+		bot.guilds.Remove(t.GuildID(), name)
+		bot.log.WithFields(fields).Info("Guild setting reset")
+	} else {
+		normalized, err := settings.Normalize(name, value)
+		if err == nil && name == settings.LanguageSetting &&
+			!bot.hasLanguage(normalized) {
+			err = settings.ErrInvalidValue
+		}
+		if err != nil {
+			bot.respondEphemeral(t, l.T(
+				"settings.invalid",
+				value,
+				l.T("command.settings.setting."+name),
+			))
+			return
+		}
+		if err := bot.datastore.GuildSettings().PersistGuildSetting(
+			t.Context(),
+			&model.GuildSetting{
+				ClientID: bot.session.ClientID(),
+				GuildID:  t.GuildID(),
+				Name:     name,
+				Value:    normalized,
+			},
+		); err != nil {
+			bot.log.WithFields(fields).Errorf(
+				"Error when changing guild setting: %v", err,
+			)
+			bot.respondEphemeral(t, l.T("error.generic"))
+			return
+		}
+		bot.guilds.Set(t.GuildID(), name, normalized)
+		bot.log.WithFields(fields).WithField("Value", normalized).Info(
+			"Guild setting changed",
+		)
+	}
+	// NOTE: respond in the guild's new language,
+	// when the language has been changed
+	bot.respondEphemeral(t, bot.settingsContent(t))
+
+	// NOTE: the queue message is rendered with the
+	// new settings, if there is an active queue
+	t.UpdateQueue(0)
+}
+
+// settingsContent returns the list of the current settings
+// of the provided transaction's guild, where the settings
+// changed for the guild are marked.
+func (bot *Bot) settingsContent(t *transaction.Transaction) string {
+	l := bot.localizer(t)
+	guild := bot.guilds.Get(t.GuildID())
+
+	lines := []string{l.T("settings.title")}
+	for _, name := range settings.Names {
+		value := guild.Value(name)
+		switch {
+		case len(value) == 0:
+			value = l.T("settings.unset")
+		case name == settings.AnnouncementsChannelSetting:
+			value = "<#" + value + ">"
+		default:
+			value = "`" + value + "`"
+		}
+		line := fmt.Sprintf(
+			"**%s** (`%s`): %s",
+			l.T("command.settings.setting."+name),
+			name,
+			value,
+		)
+		if bot.guilds.IsSet(t.GuildID(), name) {
+			line += " " + l.T("settings.changed")
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// hasLanguage checks whether the bot's messages
+// are translated to the provided language.
+func (bot *Bot) hasLanguage(language string) bool {
+	for _, l := range bot.translator.Languages() {
+		if l == language {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"discord-music-bot/bot/slash_command"
+	"discord-music-bot/bot/transaction"
+	"discord-music-bot/model"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// onStatsSlashCommand is a handler function called when the bot's stats
+// slash command is called in the discord channel, this is not emmited through
+// the discord's websocket, but is rather called from INTERACTION_CREATE event
+// when the interaction's command data name matches the stats slash
+// command's name.
+func (bot *DiscordEventHandler) onStatsSlashCommand(t *transaction.Transaction) {
+	defer t.Defer()
+
+	period := model.WeekPeriod
+	for _, o := range t.Interaction().ApplicationCommandData().Options {
+		if o.Name == slash_command.StatsPeriodOption {
+			period = model.StatsPeriod(o.StringValue())
+		}
+	}
+	stats := bot.builder.Stats().NewStats(
+		bot.session.ClientID(),
+		t.GuildID(),
+		period,
+	)
+	stats, err := bot.datastore.History().UpdateStats(t.Context(), stats)
+	if err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error when computing stats: %v",
+			err,
+		)
+		return
+	}
+	if err := bot.session.InteractionRespond(
+		t.Interaction(),
+		&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{
+					bot.builder.Stats().MapStatsToEmbed(stats, t.Interaction().Locale),
+				},
+				Flags: discordgo.MessageFlagsEphemeral,
+			},
+		}); err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Errorf(
+			"Error when responding to stats command: %v",
+			err,
+		)
+	}
+}
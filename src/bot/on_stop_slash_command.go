@@ -14,7 +14,8 @@ func (bot *DiscordEventHandler) onStopSlashCommand(t *transaction.Transaction) {
 	defer t.Defer()
 
 	queue, err := bot.datastore.Queue().GetQueue(
-		bot.session.State.User.ID,
+		t.Context(),
+		bot.session.ClientID(),
 		t.GuildID(),
 	)
 	if err != nil {
@@ -22,7 +23,7 @@ func (bot *DiscordEventHandler) onStopSlashCommand(t *transaction.Transaction) {
 			&discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
-					Content: "There is no active music queue!",
+					Content: bot.localizer(t).T("queue.none"),
 					Flags:   discordgo.MessageFlagsEphemeral,
 				},
 			}); err != nil {
@@ -33,29 +34,22 @@ func (bot *DiscordEventHandler) onStopSlashCommand(t *transaction.Transaction) {
 		}
 		return
 	}
+	// NOTE: the queue is removed before it's message is deleted,
+	// so the message is not recreated once it is deleted
+	util := &Util{bot.Bot}
+	util.removeQueue(queue)
 	if err := bot.session.ChannelMessageDelete(queue.ChannelID, queue.MessageID); err != nil {
-		if err := bot.session.InteractionRespond(t.Interaction(),
-			&discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "Something went wrong!",
-					Flags:   discordgo.MessageFlagsEphemeral,
-				},
-			}); err != nil {
-			bot.log.WithField("GuildID", t.GuildID()).Errorf(
-				"Error when responding to stop command: %v",
-				err,
-			)
-		}
-		util := &Util{bot.Bot}
-		util.deleteQueue(t.GuildID(), []string{queue.MessageID})
+		bot.log.WithField("GuildID", t.GuildID()).Debugf(
+			"Could not delete the stopped queue's message: %v",
+			err,
+		)
 	}
 
 	if err := bot.session.InteractionRespond(t.Interaction(),
 		&discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "Music has been stopped!",
+				Content: bot.localizer(t).T("queue.stopped"),
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		}); err != nil {
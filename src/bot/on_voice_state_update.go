@@ -28,19 +28,20 @@ func (bot *DiscordEventHandler) onVoiceStateUpdate(t *transaction.Transaction, i
 		}).Trace("Client has left the channel")
 
 		if ap, ok := bot.audioplayers.Get(i.GuildID); ok && ap != nil {
-			ap.Subscriptions().Emit("terminate")
+			ap.Stop(t.Context())
 		}
 
-		time.Sleep(1 * time.Second)
 		_, e := bot.datastore.Queue().GetQueue(
-			bot.session.State.User.ID,
+			t.Context(),
+			bot.session.ClientID(),
 			t.GuildID(),
 		)
 		if e == nil {
 			// NOTE: remove paused option, so that on reconnect the
 			// bot is ready to play
 			bot.datastore.Queue().RemoveQueueOptions(
-				bot.session.State.User.ID,
+				t.Context(),
+				bot.session.ClientID(),
 				i.GuildID,
 				model.Paused,
 			)
@@ -59,18 +60,18 @@ func (bot *DiscordEventHandler) onVoiceStateUpdate(t *transaction.Transaction, i
 			"TO":      i.ChannelID,
 		}).Trace("Client has switched channels")
 
-		voice, ok := bot.session.VoiceConnections[i.GuildID]
+		_, ok := bot.session.VoiceConnection(i.GuildID)
 		time.Sleep(1 * time.Second)
-		if v, ok := bot.session.VoiceConnections[i.GuildID]; ok && v.Ready {
+		if v, ok := bot.session.VoiceConnection(i.GuildID); ok && v.Ready {
 			return
 		}
 		if ok {
-			voice.Disconnect()
+			bot.session.VoiceDisconnect(i.GuildID)
 		}
-		bot._ready = false
+		bot._ready.set(false)
 		t.UpdateQueue(500 * time.Millisecond)
 		time.Sleep(4 * time.Second)
-		bot._ready = true
+		bot._ready.set(true)
 		t.Refresh()
 		t.UpdateQueue(500 * time.Millisecond)
 	}
@@ -1,10 +1,15 @@
 package bot
 
 import (
+	"context"
 	"discord-music-bot/bot/audioplayer"
 	"discord-music-bot/bot/transaction"
+	"discord-music-bot/datastore"
 	"discord-music-bot/model"
+	"fmt"
 	"time"
+
+	log "github.com/sirupsen/logrus"
 )
 
 type AudioplayerEventHandler struct {
@@ -14,25 +19,16 @@ type AudioplayerEventHandler struct {
 // play searches for a queue that belongs to the provided guildID
 // and starts playing it's headSong if no song is currently playing.
 func (bot *Bot) play(t *transaction.Transaction, channelID string) {
-	// NOTE: make sure there is no audioplayer already active
-	if _, ok := bot.audioplayers.Get(t.GuildID()); ok {
-		// NOTE: audio has already been started from
-		// another source, do not continue
-		time.Sleep(300 * time.Millisecond)
-		if _, ok := bot.audioplayers.Get(t.GuildID()); ok {
-			// NOTE: should still update even when
-			// returning as there must be a reason
-			// for the play request
-			t.UpdateQueue(100 * time.Millisecond)
-			return
-		}
-	}
+	bot.playFrom(t, channelID, 0)
+}
 
-	bot.log.WithField("GuildID", t.Interaction().GuildID).Trace(
-		"Play requested, creating an audioplayer ...",
+// playFrom searches for a queue that belongs to the provided guildID
+// and starts playing it's headSong from the provided position,
+// if no song is currently playing.
+func (bot *Bot) playFrom(t *transaction.Transaction, channelID string, position time.Duration) {
+	bot.log.WithField("GuildID", t.GuildID()).Trace(
+		"Play requested ...",
 	)
-
-	events := &AudioplayerEventHandler{bot}
 	util := &Util{bot}
 
 	// NOTE: try to join the voice channel, if client
@@ -42,240 +38,239 @@ func (bot *Bot) play(t *transaction.Transaction, channelID string) {
 		t.UpdateQueue(100 * time.Millisecond)
 		return
 	}
-
-	ap := audioplayer.NewAudioPlayer(bot.youtube)
-
-	// NOTE: handle all external logic for audioplayer
-	// with subscriptions. That way we can easily trigger
-	// custom audioplayer events when clicking on buttons etc.
-	events.handleSubscriptions(t, ap)
-
-	bot.audioplayers.Add(t.GuildID(), ap)
-
-	// NOTE: try to start playing, and then
-	// update the queue, as it should be updated if
+	vc, ok := bot.session.VoiceConnection(t.GuildID())
+	if !ok {
+		t.UpdateQueue(100 * time.Millisecond)
+		return
+	}
+	// NOTE: the audioplayer starts playing only if no song is
+	// playing yet, the play requests from other sources are
+	// processed by the audioplayer one at a time
+	if err := bot.audioplayer(t.GuildID()).Play(
+		t.Context(),
+		bot.audio.VoiceSink(vc),
+		position,
+	); err != nil {
+		bot.log.WithField("GuildID", t.GuildID()).Debugf(
+			"Failed to start playing: %v", err,
+		)
+	}
+	// NOTE: update the queue, as it should be updated if
 	// audioplayer started succesfully or if it failed.
-	events.startPlayingSong(t, ap)
-
 	t.UpdateQueue(100 * time.Millisecond)
+}
 
+// audioplayer returns the audioplayer of the guild identified by
+// the provided guildID. A new audioplayer is created if the guild
+// has none, or if its audioplayer has already been stopped.
+func (bot *Bot) audioplayer(guildID string) *audioplayer.AudioPlayer {
+	return bot.audioplayers.GetOrAdd(guildID, func() *audioplayer.AudioPlayer {
+		bot.log.WithField("GuildID", guildID).Trace(
+			"Creating an audioplayer ...",
+		)
+		events := &AudioplayerEventHandler{bot}
+		ap := audioplayer.NewAudioPlayer(
+			bot.ctx,
+			guildID,
+			&audioplayerQueue{events, guildID},
+			bot.log,
+		)
+		events.handleSubscriptions(guildID, ap)
+		go bot.refreshProgress(guildID, ap)
+		return ap
+	})
 }
 
-func (bot *AudioplayerEventHandler) handleSubscriptions(t *transaction.Transaction, ap *audioplayer.AudioPlayer) {
-	bot.log.WithField("GuildID", t.Interaction().GuildID).Trace(
+// handleSubscriptions subscribes the handlers of the events emitted
+// by the provided audioplayer. The events are handled one at a time
+// in the order in which they are emitted.
+func (bot *AudioplayerEventHandler) handleSubscriptions(guildID string, ap *audioplayer.AudioPlayer) {
+	bot.log.WithField("GuildID", guildID).Trace(
 		"Handling audioplayer subscriptions",
 	)
-	ap.Subscriptions().Subscribe("stop", func() {
-		ap.Stop()
-	})
-	ap.Subscriptions().Subscribe("pause", func() {
-		ap.Unpause()
-		t.Refresh()
-		t.UpdateQueue(100 * time.Millisecond)
-	})
-	ap.Subscriptions().Subscribe("unpause", func() {
-		ap.Pause()
-		t.Refresh()
-		t.UpdateQueue(100 * time.Millisecond)
-	})
-	ap.Subscriptions().Subscribe("finished", func() {
-		bot.handleHeadSongRemoval(t)
-		bot.startPlayingSong(t, ap)
-		t.Refresh()
-		t.UpdateQueue(100 * time.Millisecond)
-	})
-	ap.Subscriptions().Subscribe("replay", func() {
-		ap.Subscriptions().Emit("stop")
-		bot.startPlayingSong(t, ap)
-	})
-	ap.Subscriptions().Subscribe("skip", func() {
-		ap.Subscriptions().Emit("stop")
-		ap.Subscriptions().Emit("finished")
-	})
-	ap.Subscriptions().Subscribe("skipToPrevious", func() {
-		ap.Subscriptions().Emit("stop")
-		bot.handleReverseHeadSongRemoval(t)
-		bot.startPlayingSong(t, ap)
-		t.Refresh()
-		t.UpdateQueue(100 * time.Millisecond)
-	})
-	ap.Subscriptions().Subscribe("error", func() {
-		bot.handleAudioplayerError(t.GuildID())
-		bot.startPlayingSong(t, ap)
-		t.Refresh()
-		t.UpdateQueue(100 * time.Millisecond)
+	events := ap.Events()
+
+	events.OnError(func(event audioplayer.Event, err error) {
+		bot.log.WithFields(log.Fields{
+			"GuildID": guildID,
+			"Event":   event,
+		}).Errorf("Error when handling audioplayer event: %v", err)
 	})
-	ap.Subscriptions().Subscribe("delete", func() {
-		bot.audioplayers.Remove(t.GuildID())
+	for _, event := range audioplayer.Events {
+		tp := "Audioplayer" + event.String()
+		// NOTE: every change of the playback is
+		// shown in the queue message
+		events.Subscribe(event, func() error {
+			t := bot.transactions.New(tp, guildID, nil)
+			t.UpdateQueue(100 * time.Millisecond)
+			return nil
+		})
+	}
+	// NOTE: the song is announced whenever the head song changes,
+	// a play event is emitted also when a song is already playing
+	for _, event := range []audioplayer.Event{
+		audioplayer.EventSkip,
+		audioplayer.EventSkipToPrevious,
+		audioplayer.EventFinished,
+		audioplayer.EventError,
+	} {
+		events.Subscribe(event, func() error {
+			return bot.announce(guildID, ap, false)
+		})
+	}
+	events.Subscribe(audioplayer.EventPlay, func() error {
+		return bot.announce(guildID, ap, true)
 	})
-	ap.Subscriptions().Subscribe("terminate", func() {
-		ap.Subscriptions().Emit("stop")
-		ap.Subscriptions().Emit("delete")
+	events.Subscribe(audioplayer.EventStop, func() error {
+		bot.clearAnnouncement(guildID)
+		return nil
 	})
 }
 
-func (bot *AudioplayerEventHandler) startPlayingSong(t *transaction.Transaction, ap *audioplayer.AudioPlayer) {
-	song, err := bot.datastore.Song().GetHeadSongForQueue(
-		bot.session.State.User.ID,
-		t.GuildID(),
+// audioplayerQueue is the guild's queue in the datastore,
+// from which the guild's audioplayer plays the songs.
+type audioplayerQueue struct {
+	bot     *AudioplayerEventHandler
+	guildID string
+}
+
+func (q *audioplayerQueue) HeadSong(ctx context.Context) (*model.Song, error) {
+	return q.bot.datastore.Song().GetHeadSongForQueue(
+		ctx,
+		q.bot.session.ClientID(),
+		q.guildID,
 	)
-	if err != nil {
-		ap.Subscriptions().Emit("delete")
-		bot.log.WithField("GuildID", t.GuildID()).Trace(
-			"No head song found, cannot start playing",
-		)
-		return
-	}
-	voice, ok := bot.session.VoiceConnections[t.GuildID()]
-	if ok == false || !voice.Ready {
-		time.Sleep(300 * time.Second)
-		voice, ok = bot.session.VoiceConnections[t.GuildID()]
-		if ok == false || !voice.Ready {
-			ap.Subscriptions().Emit("delete")
-			bot.log.WithField("GuildID", t.GuildID()).Debug(
-				"Failed to connect to voice",
-			)
-			return
-		}
-	}
-	go func() {
-		bot.log.WithField("GuildID", t.GuildID()).Tracef(
-			"Playing song: %v", song.Name,
-		)
-		code, err := ap.Play(bot.ctx, song, voice)
-		switch code {
-		case 0:
-			bot.log.WithField("GuildID", t.GuildID()).Tracef(
-				"Audioplayer finished on itself",
-			)
-			ap.Subscriptions().Emit("finished")
-			return
-		case 1:
-			bot.log.WithField("GuildID", t.GuildID()).Tracef(
-				"Audioplayer finished with error: %v",
-				err,
-			)
-			ap.Subscriptions().Emit("error")
-			return
-		default:
-			bot.log.WithField("GuildID", t.Interaction().GuildID).Tracef(
-				"Audioplayer exited with code: %d", code,
-			)
-		}
-	}()
-	return
 }
 
-func (bot *AudioplayerEventHandler) handleAudioplayerError(guildID string) {
-	bot.log.WithField("GuildID", guildID).Trace(
+func (q *audioplayerQueue) Next(ctx context.Context) error {
+	return q.bot.handleHeadSongRemoval(ctx, q.guildID)
+}
+
+func (q *audioplayerQueue) Previous(ctx context.Context) error {
+	return q.bot.handleReverseHeadSongRemoval(ctx, q.guildID)
+}
+
+func (q *audioplayerQueue) Drop(ctx context.Context) error {
+	q.bot.log.WithField("GuildID", q.guildID).Trace(
 		"Removing queue's head song",
 	)
-	bot.removeHeadSong(guildID)
+	return q.bot.removeHeadSong(ctx, q.guildID)
 }
 
-func (bot *AudioplayerEventHandler) handleHeadSongRemoval(t *transaction.Transaction) {
-	if bot.datastore.Queue().QueueHasOption(
-		bot.session.State.User.ID,
-		t.GuildID(),
-		model.Loop,
-	) {
-		bot.log.WithField("GuildID", t.GuildID()).Trace(
-			"Bot has loop enabled, pushing head song to back",
-		)
-		if err := bot.datastore.Song().PushHeadSongToBack(
-			bot.session.State.User.ID,
-			t.GuildID(),
-		); err != nil {
-			bot.log.WithField("GuildID", t.GuildID()).Errorf(
-				"Error when pushing head song to back during play: %v",
-				err,
-			)
-		}
+func (q *audioplayerQueue) Played(song *model.Song, startedAt time.Time, played time.Duration) {
+	q.bot.persistHistoryEntry(q.guildID, song, startedAt, played)
+}
+
+// persistHistoryEntry saves the provided song to the played songs' history,
+// if any part of it has actually been played.
+func (bot *AudioplayerEventHandler) persistHistoryEntry(guildID string, song *model.Song, startedAt time.Time, played time.Duration) {
+	if played < time.Second {
 		return
 	}
-	bot.log.WithField("GuildID", t.GuildID()).Trace(
-		"Bot does not have loop enabled, removing head song",
-	)
-	headsong, err := bot.datastore.Song().GetHeadSongForQueue(
-		bot.session.State.User.ID,
-		t.GuildID(),
+	// NOTE: the entry is persisted also once the playback has been
+	// stopped by the done bot's context, so it is not used here
+	ctx := context.Background()
+
+	entry := bot.builder.History().NewHistoryEntry(
+		bot.session.ClientID(),
+		guildID,
+		song,
+		startedAt,
+		played,
 	)
-	if err != nil {
-		bot.log.WithField("GuildID", t.GuildID()).Errorf(
-			"Error when fetching head song during play: %v",
-			err,
+	if err := bot.datastore.History().PersistHistoryEntry(ctx, entry); err != nil {
+		bot.log.WithField("GuildID", guildID).Errorf(
+			"Error when persisting history entry: %v", err,
 		)
-	} else {
+	}
+}
+
+// handleHeadSongRemoval removes the queue's head song and persists it
+// as an inactive song, or pushes it to the back of the queue when the
+// queue has loop enabled. All the changes are made in a single unit
+// of work, so the head song is never lost or duplicated.
+func (bot *AudioplayerEventHandler) handleHeadSongRemoval(ctx context.Context, guildID string) error {
+	clientID := bot.session.ClientID()
+
+	err := bot.datastore.RunUnitOfWork(ctx, func(uow *datastore.UnitOfWork) error {
+		if err := uow.Queue().LockQueue(ctx, clientID, guildID); err != nil {
+			return err
+		}
+		if uow.Queue().QueueHasOption(ctx, clientID, guildID, model.Loop) {
+			bot.log.WithField("GuildID", guildID).Trace(
+				"Bot has loop enabled, pushing head song to back",
+			)
+			return uow.Song().PushHeadSongToBack(ctx, clientID, guildID)
+		}
+		bot.log.WithField("GuildID", guildID).Trace(
+			"Bot does not have loop enabled, removing head song",
+		)
+		headsong, err := uow.Song().GetHeadSongForQueue(ctx, clientID, guildID)
+		if err != nil {
+			return err
+		}
 		// NOTE: persist queue's headSong to inactive song table
-		if err = bot.datastore.Song().PersistInactiveSongs(
-			bot.session.State.User.ID,
-			t.GuildID(),
+		if err := uow.Song().PersistInactiveSongs(
+			ctx,
+			clientID,
+			guildID,
 			headsong,
 		); err != nil {
-			bot.log.WithField("GuildID", t.GuildID()).Errorf(
-				"Error when persisting inactive song during play: %v",
-				err,
-			)
-
+			return err
 		}
-		bot.removeHeadSong(t.GuildID())
+		return uow.Song().RemoveHeadSong(ctx, clientID, guildID)
+	})
+	if err != nil {
+		return fmt.Errorf("removing head song during play: %v", err)
 	}
+	return nil
 }
 
-func (bot *AudioplayerEventHandler) handleReverseHeadSongRemoval(t *transaction.Transaction) {
-	if bot.datastore.Queue().QueueHasOption(
-		bot.session.State.User.ID,
-		t.GuildID(),
-		model.Loop,
-	) {
-		bot.log.WithField("GuildID", t.GuildID()).Trace(
-			"Bot has loop enabled, pushing last song to front",
-		)
-		if err := bot.datastore.Song().PushLastSongToFront(
-			bot.session.State.User.ID,
-			t.GuildID(),
-		); err != nil {
-			bot.log.WithField("GuildID", t.GuildID()).Errorf(
-				"Error when pushing last song to fron during play: %v",
-				err,
+// handleReverseHeadSongRemoval moves the latest inactive song to
+// the front of the queue, or pushes the queue's last song to the
+// front when the queue has loop enabled. All the changes are made
+// in a single unit of work.
+func (bot *AudioplayerEventHandler) handleReverseHeadSongRemoval(ctx context.Context, guildID string) error {
+	clientID := bot.session.ClientID()
+
+	err := bot.datastore.RunUnitOfWork(ctx, func(uow *datastore.UnitOfWork) error {
+		if err := uow.Queue().LockQueue(ctx, clientID, guildID); err != nil {
+			return err
+		}
+		if uow.Queue().QueueHasOption(ctx, clientID, guildID, model.Loop) {
+			bot.log.WithField("GuildID", guildID).Trace(
+				"Bot has loop enabled, pushing last song to front",
 			)
+			return uow.Song().PushLastSongToFront(ctx, clientID, guildID)
 		}
-		return
-	}
-
-	bot.log.WithField("GuildID", t.GuildID()).Trace(
-		"Bot does not have loop enabled, popping latest inactive song",
-	)
-	song, err := bot.datastore.Song().PopLatestInactiveSong(
-		bot.session.State.User.ID,
-		t.GuildID(),
-	)
-	if err != nil {
-		bot.log.WithField("GuildID", t.GuildID()).Errorf(
-			"Error when popping latest inactive song during play: %v",
-			err,
-		)
-		return
-	}
-	if err := bot.datastore.Song().PersistSongToFront(
-		bot.session.State.User.ID,
-		t.GuildID(),
-		song,
-	); err != nil {
-		bot.log.WithField("GuildID", t.GuildID()).Errorf(
-			"Error when persisting song to fron during play: %v",
-			err,
+		bot.log.WithField("GuildID", guildID).Trace(
+			"Bot does not have loop enabled, popping latest inactive song",
 		)
+		song, err := uow.Song().PopLatestInactiveSong(ctx, clientID, guildID)
+		if err != nil {
+			return err
+		}
+		return uow.Song().PersistSongToFront(ctx, clientID, guildID, song)
+	})
+	if err != nil {
+		return fmt.Errorf("moving previous song to front during play: %v", err)
 	}
+	return nil
 }
 
-func (bot *AudioplayerEventHandler) removeHeadSong(guildID string) {
-	if err := bot.datastore.Song().RemoveHeadSong(
-		bot.session.State.User.ID,
-		guildID,
-	); err != nil {
-		bot.log.Errorf(
-			"Error when removing song during play: %v", err,
-		)
+// removeHeadSong removes the queue's head song, without
+// persisting it as an inactive song.
+func (bot *AudioplayerEventHandler) removeHeadSong(ctx context.Context, guildID string) error {
+	clientID := bot.session.ClientID()
+
+	err := bot.datastore.RunUnitOfWork(ctx, func(uow *datastore.UnitOfWork) error {
+		if err := uow.Queue().LockQueue(ctx, clientID, guildID); err != nil {
+			return err
+		}
+		return uow.Song().RemoveHeadSong(ctx, clientID, guildID)
+	})
+	if err != nil {
+		return fmt.Errorf("removing song during play: %v", err)
 	}
+	return nil
 }
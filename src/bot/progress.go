@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"discord-music-bot/bot/audioplayer"
+	"time"
+)
+
+// playbackPosition returns the position in the song that is
+// currently played in the guild identified by the provided
+// guildID, or 0 if nothing is playing there.
+func (bot *Bot) playbackPosition(guildID string) time.Duration {
+	ap, ok := bot.audioplayers.Get(guildID)
+	if !ok || ap == nil {
+		return 0
+	}
+	state := ap.State()
+	if !state.Playing {
+		return 0
+	}
+	return state.Position
+}
+
+// refreshProgress is a long lived worker that periodically updates
+// the queue message of the guild identified by the provided guildID,
+// so the progress of the playing song is shown, while the provided
+// audioplayer is running. The message is not updated while the song is
+// paused or when the guild does not show the progress. The interval
+// is read from the guild's settings before every refresh.
+// NOTE: the updates go through the guild's renderer, that backs off
+// when discord responds with too many requests, so a refresh waits
+// until the previous one is complete.
+func (bot *Bot) refreshProgress(guildID string, ap *audioplayer.AudioPlayer) {
+	done := bot.ctx.Done()
+	for {
+		progress := bot.guilds.Get(guildID).Progress
+		select {
+		case <-done:
+			return
+		case <-ap.Done():
+			return
+		case <-time.After(progress.Interval):
+		}
+		if !progress.Enabled() || !bot._ready.get() {
+			continue
+		}
+		if state := ap.State(); !state.Playing || state.Paused {
+			continue
+		}
+		t := bot.transactions.New("RefreshProgress", guildID, nil).Quiet()
+		t.UpdateQueue(0)
+	}
+}
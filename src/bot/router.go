@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"discord-music-bot/bot/transaction"
+	"discord-music-bot/builder/component"
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// route handles an interaction with a component or a modal,
+// identified by the provided ID.
+type route func(t *transaction.Transaction, id component.ID)
+
+// router dispatches the interactions with the message components
+// and the modals to their routes, by the actions in their customIDs.
+type router struct {
+	routes map[component.Action]route
+}
+
+// newRouter constructs an object that routes the interactions
+// with all of the components and modals sent by the bot.
+func (bot *DiscordEventHandler) newRouter() *router {
+	r := &router{routes: make(map[component.Action]route)}
+	r.handle(
+		bot.onButtonClick,
+		component.QueueBackward,
+		component.QueueForward,
+		component.QueuePrevious,
+		component.QueueSkip,
+		component.QueuePause,
+		component.QueueReplay,
+		component.QueueAddSongs,
+		component.QueueLoop,
+		component.QueueJoin,
+	)
+	r.handle(
+		bot.onQueueViewButtonClick,
+		component.ViewBackward,
+		component.ViewForward,
+		component.ViewPage,
+		component.ViewSearch,
+	)
+	r.handle(
+		bot.onHistoryButtonClick,
+		component.HistoryBackward,
+		component.HistoryForward,
+		component.HistoryRequeue,
+	)
+	r.handle(
+		bot.onModalSubmit,
+		component.AddSongsModal,
+		component.QueuePageModal,
+		component.QueueSearchModal,
+	)
+	return r
+}
+
+// handle adds the provided route for all of the provided actions.
+func (r *router) handle(route route, actions ...component.Action) {
+	for _, action := range actions {
+		r.routes[action] = route
+	}
+}
+
+// onRoutedInteraction is a handler function called when a user
+// interacts with a message component or submits a modal. The
+// interaction is dispatched to the route of the action in the
+// provided customID, if the customID belongs to the queue of
+// the interaction's guild.
+func (bot *DiscordEventHandler) onRoutedInteraction(r *router, i *discordgo.Interaction, customID string) {
+	id, err := bot.parseCustomID(customID, i.GuildID)
+	t := bot.transactions.New(
+		"Interaction/Component/"+string(id.Action),
+		i.GuildID,
+		i,
+	)
+	route, ok := r.routes[id.Action]
+	if err == nil && !ok {
+		err = fmt.Errorf("No route for action %s", id.Action)
+	}
+	if err == nil && id.Queue != i.GuildID {
+		err = fmt.Errorf("Component belongs to the queue of guild %s", id.Queue)
+	}
+	if err != nil {
+		bot.log.WithField("GuildID", i.GuildID).Debugf(
+			"Could not route the interaction: %v", err,
+		)
+		bot.session.InteractionRespond(i,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: bot.localizer(t).T("error.generic"),
+					Flags:   discordgo.MessageFlagsEphemeral,
+				},
+			})
+		return
+	}
+	route(t, id)
+}
+
+// parseCustomID parses the provided customID of a component or a
+// modal. The unversioned customIDs of the components sent before
+// the customIDs were versioned are assigned to the queue of the
+// guild identified by the provided guildID.
+func (bot *DiscordEventHandler) parseCustomID(customID string, guildID string) (component.ID, error) {
+	id, err := component.Parse(customID)
+	if !errors.Is(err, component.ErrUnversioned) {
+		return id, err
+	}
+	if id, ok := bot.builder.History().ParseLegacyComponentData(customID, guildID); ok {
+		return id, nil
+	}
+	if id, ok := bot.builder.Queue().ParseLegacyComponentData(customID, guildID); ok {
+		return id, nil
+	}
+	return component.ID{}, fmt.Errorf("Unknown custom ID: %s", customID)
+}
@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"discord-music-bot/bot/audioplayer"
+	"discord-music-bot/model"
+	"discord-music-bot/youtube"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Session is the connection to discord, through which the bot
+// receives the events and responds to them.
+type Session interface {
+	// ClientID returns the ID of the bot's discord user.
+	ClientID() string
+	// SetIntents sets the intents identified when the session is opened.
+	SetIntents(intents discordgo.Intent)
+	// AddHandler adds a handler of the discord events, the handler's
+	// signature is the one accepted by discordgo.Session.AddHandler.
+	AddHandler(handler interface{}) func()
+	Open() error
+	Close() error
+	UpdateListeningStatus(name string) error
+
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse) error
+	InteractionResponse(interaction *discordgo.Interaction) (*discordgo.Message, error)
+	InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error)
+	ChannelMessageEditComplex(m *discordgo.MessageEdit) (*discordgo.Message, error)
+	ChannelMessageDelete(channelID string, messageID string) error
+	GuildMembers(guildID string, after string, limit int) ([]*discordgo.Member, error)
+
+	ApplicationCommands(appID string, guildID string) ([]*discordgo.ApplicationCommand, error)
+	ApplicationCommandCreate(appID string, guildID string, cmd *discordgo.ApplicationCommand) (*discordgo.ApplicationCommand, error)
+	ApplicationCommandDelete(appID string, guildID string, cmdID string) error
+
+	// Guild returns the guild from the session's state.
+	Guild(guildID string) (*discordgo.Guild, error)
+	// VoiceState returns the voice state of the user identified by
+	// the provided userID from the session's state.
+	VoiceState(guildID string, userID string) (*discordgo.VoiceState, error)
+	// UserChannelPermissions returns the permissions of the user
+	// identified by the provided userID in the channel identified
+	// by the provided channelID, from the session's state.
+	UserChannelPermissions(userID string, channelID string) (int64, error)
+
+	// ChannelVoiceJoin connects the client to the voice channel identified
+	// by the provided guildID and channelID.
+	ChannelVoiceJoin(guildID string, channelID string, mute bool, deaf bool) (*discordgo.VoiceConnection, error)
+	// VoiceDisconnect disconnects the client from the voice
+	// channel in the guild identified by the provided guildID.
+	VoiceDisconnect(guildID string) error
+	// VoiceConnection returns the client's voice connection in
+	// the guild identified by the provided guildID.
+	VoiceConnection(guildID string) (*discordgo.VoiceConnection, bool)
+	// VoiceConnections returns the client's voice connections
+	// mapped by their guilds' IDs.
+	VoiceConnections() map[string]*discordgo.VoiceConnection
+}
+
+// AudioSource finds the songs requested by the users and
+// streams them to the voice channels.
+type AudioSource interface {
+	// GetSongs returns the info of the songs found
+	// for the provided queries.
+	GetSongs(queries []string) []*model.SongInfo
+	// VoiceSink returns the voice sink through which the
+	// songs are streamed to the provided voice connection.
+	VoiceSink(vc *discordgo.VoiceConnection) audioplayer.VoiceSink
+}
+
+type discordSession struct {
+	*discordgo.Session
+}
+
+type youtubeAudioSource struct {
+	youtube *youtube.Youtube
+}
+
+// NewDiscordSession constructs a session that uses the provided
+// discordgo session to connect to discord.
+func NewDiscordSession(s *discordgo.Session) Session {
+	return &discordSession{s}
+}
+
+// NewYoutubeAudioSource constructs an audio source
+// that finds and streams the songs from youtube.
+func NewYoutubeAudioSource(yt *youtube.Youtube) AudioSource {
+	return &youtubeAudioSource{yt}
+}
+
+func (s *discordSession) ClientID() string {
+	return s.State.User.ID
+}
+
+func (s *discordSession) SetIntents(intents discordgo.Intent) {
+	s.Identify.Intents = intents
+}
+
+func (s *discordSession) Guild(guildID string) (*discordgo.Guild, error) {
+	return s.State.Guild(guildID)
+}
+
+func (s *discordSession) VoiceState(guildID string, userID string) (*discordgo.VoiceState, error) {
+	return s.State.VoiceState(guildID, userID)
+}
+
+func (s *discordSession) UserChannelPermissions(userID string, channelID string) (int64, error) {
+	return s.State.UserChannelPermissions(userID, channelID)
+}
+
+func (s *discordSession) VoiceDisconnect(guildID string) error {
+	vc, ok := s.VoiceConnection(guildID)
+	if !ok {
+		return fmt.Errorf("not connected to voice in guild %s", guildID)
+	}
+	return vc.Disconnect()
+}
+
+func (s *discordSession) VoiceConnection(guildID string) (*discordgo.VoiceConnection, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	vc, ok := s.Session.VoiceConnections[guildID]
+	return vc, ok
+}
+
+func (s *discordSession) VoiceConnections() map[string]*discordgo.VoiceConnection {
+	s.RLock()
+	defer s.RUnlock()
+	connections := make(map[string]*discordgo.VoiceConnection)
+	for guildID, vc := range s.Session.VoiceConnections {
+		connections[guildID] = vc
+	}
+	return connections
+}
+
+func (source *youtubeAudioSource) GetSongs(queries []string) []*model.SongInfo {
+	return source.youtube.Search().GetSongs(queries)
+}
+
+func (source *youtubeAudioSource) VoiceSink(vc *discordgo.VoiceConnection) audioplayer.VoiceSink {
+	return audioplayer.NewDiscordVoiceSink(source.youtube, vc)
+}
@@ -1,9 +1,12 @@
 package slash_command
 
 import (
+	"discord-music-bot/i18n"
+	"discord-music-bot/settings"
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -14,15 +17,136 @@ type ChatCommandConfig struct {
 }
 
 type SlashCommandsConfig struct {
-	Music *ChatCommandConfig `yaml:"Music" validate:"required"`
-	Stop  *ChatCommandConfig `yaml:"Stop" validate:"required"`
-	Help  *ChatCommandConfig `yaml:"Help" validate:"required"`
+	Music    *ChatCommandConfig `yaml:"Music" validate:"required"`
+	Stop     *ChatCommandConfig `yaml:"Stop" validate:"required"`
+	Help     *ChatCommandConfig `yaml:"Help" validate:"required"`
+	History  *ChatCommandConfig `yaml:"History" validate:"required"`
+	Stats    *ChatCommandConfig `yaml:"Stats" validate:"required"`
+	Queue    *ChatCommandConfig `yaml:"Queue" validate:"required"`
+	Settings *ChatCommandConfig `yaml:"Settings" validate:"required"`
+}
+
+type MessageCommandConfig struct {
+	Name string `yaml:"Name" validate:"required"`
+}
+
+type MessageCommandsConfig struct {
+	AddToQueue *MessageCommandConfig `yaml:"AddToQueue" validate:"required"`
+}
+
+// StatsPeriodOption is the name of the stats slash command's option,
+// that determines the period for which the statistics are shown.
+const StatsPeriodOption = "period"
+
+// MusicHereOption is the name of the music slash command's option,
+// that moves the active queue message to the command's channel.
+const MusicHereOption = "here"
+
+// SettingsNameOption is the name of the settings slash command's
+// option, that determines which of the guild's settings is changed.
+const SettingsNameOption = "setting"
+
+// SettingsValueOption is the name of the settings slash command's
+// option, that holds the setting's new value. The setting is reset
+// to the configured one when the value is omitted.
+const SettingsValueOption = "value"
+
+// commandOptions returns the options for the slash command
+// with the provided name of it's field in the SlashCommandsConfig.
+// Their descriptions and choices are translated with the provided
+// translator, to the default and all other supported languages.
+func commandOptions(field string, t *i18n.Translator) []*discordgo.ApplicationCommandOption {
+	l := t.Localizer(i18n.DefaultLanguage)
+	choice := func(key string, value string) *discordgo.ApplicationCommandOptionChoice {
+		return &discordgo.ApplicationCommandOptionChoice{
+			Name:              l.T(key),
+			NameLocalizations: t.Localizations(key),
+			Value:             value,
+		}
+	}
+	switch field {
+	case "Music":
+		return []*discordgo.ApplicationCommandOption{
+			{
+				Type:                     discordgo.ApplicationCommandOptionBoolean,
+				Name:                     MusicHereOption,
+				Description:              l.T("command.music.here"),
+				DescriptionLocalizations: t.Localizations("command.music.here"),
+				Required:                 false,
+			},
+		}
+	case "Stats":
+		return []*discordgo.ApplicationCommandOption{
+			{
+				Type:                     discordgo.ApplicationCommandOptionString,
+				Name:                     StatsPeriodOption,
+				Description:              l.T("command.stats.period"),
+				DescriptionLocalizations: t.Localizations("command.stats.period"),
+				Required:                 false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					choice("command.stats.period.7d", "7d"),
+					choice("command.stats.period.30d", "30d"),
+					choice("command.stats.period.all", "all"),
+				},
+			},
+		}
+	case "Settings":
+		choices := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+		for _, name := range settings.Names {
+			choices = append(choices, choice("command.settings.setting."+name, name))
+		}
+		return []*discordgo.ApplicationCommandOption{
+			{
+				Type:                     discordgo.ApplicationCommandOptionString,
+				Name:                     SettingsNameOption,
+				Description:              l.T("command.settings.setting"),
+				DescriptionLocalizations: t.Localizations("command.settings.setting"),
+				Required:                 false,
+				Choices:                  choices,
+			},
+			{
+				Type:                     discordgo.ApplicationCommandOptionString,
+				Name:                     SettingsValueOption,
+				Description:              l.T("command.settings.value"),
+				DescriptionLocalizations: t.Localizations("command.settings.value"),
+				Required:                 false,
+			},
+		}
+	}
+	return nil
+}
+
+// commandPermissions returns the permissions a member needs, for the
+// slash command with the provided name of it's field in the
+// SlashCommandsConfig to be shown to them, or nil if the command
+// is shown to every member.
+func commandPermissions(field string) *int64 {
+	switch field {
+	case "Settings":
+		var permissions int64 = discordgo.PermissionManageServer
+		return &permissions
+	}
+	return nil
+}
+
+// Session is the discord session through
+// which the slash commands are registered.
+type Session interface {
+	ClientID() string
+	ApplicationCommands(appID string, guildID string) ([]*discordgo.ApplicationCommand, error)
+	ApplicationCommandCreate(appID string, guildID string, cmd *discordgo.ApplicationCommand) (*discordgo.ApplicationCommand, error)
+	ApplicationCommandDelete(appID string, guildID string, cmdID string) error
 }
 
 // Register deletes all of the bot's previously
-// registered global slash commands, then registers the new
-// music and help global slash commands.
-func Register(session *discordgo.Session, config *SlashCommandsConfig) error {
+// registered global slash commands and message context menu
+// commands, that differ from the ones in the provided configs,
+// then registers the missing global commands. The commands' names
+// and descriptions are localized to the languages supported by the
+// provided translator, with the keys command.<field>.name and
+// command.<field>.description, where field is the lowercase name
+// of the command's field in the config.
+func Register(session Session, config *SlashCommandsConfig, messageConfig *MessageCommandsConfig, t *i18n.Translator) error {
 	// NOTE: guildID  is an empty string, so the commands are
 	// global
 	guildID := ""
@@ -34,16 +158,47 @@ func Register(session *discordgo.Session, config *SlashCommandsConfig) error {
 	for i := 0; i < r.NumField(); i++ {
 		name := r.Field(i).Elem().FieldByName("Name").Interface().(string)
 		desc := r.Field(i).Elem().FieldByName("Description").Interface().(string)
-		commands = append(commands, &discordgo.ApplicationCommand{
-			Name:        name,
-			Description: desc,
-		})
+		field := r.Type().Field(i).Name
+		cmd := &discordgo.ApplicationCommand{
+			Type:                     discordgo.ChatApplicationCommand,
+			Name:                     name,
+			Description:              desc,
+			Options:                  commandOptions(field, t),
+			DefaultMemberPermissions: commandPermissions(field),
+		}
+		key := "command." + strings.ToLower(field)
+		if l := t.Localizations(key + ".name"); l != nil {
+			cmd.NameLocalizations = &l
+		}
+		if l := t.Localizations(key + ".description"); l != nil {
+			cmd.DescriptionLocalizations = &l
+		}
+		commands = append(commands, cmd)
+	}
+
+	// NOTE: the message commands are shown in the context menu
+	// of a message, so they have a name but no description
+	m := reflect.ValueOf(*messageConfig)
+
+	for i := 0; i < m.NumField(); i++ {
+		name := m.Field(i).Elem().FieldByName("Name").Interface().(string)
+		field := m.Type().Field(i).Name
+		cmd := &discordgo.ApplicationCommand{
+			Type: discordgo.MessageApplicationCommand,
+			Name: name,
+		}
+		if l := t.Localizations(
+			"command." + strings.ToLower(field) + ".name",
+		); l != nil {
+			cmd.NameLocalizations = &l
+		}
+		commands = append(commands, cmd)
 	}
 
 	// fetch all global application commands defined by
 	// the bot user
 	registeredCommands, err := session.ApplicationCommands(
-		session.State.User.ID,
+		session.ClientID(),
 		guildID,
 	)
 	if err != nil {
@@ -59,13 +214,9 @@ func Register(session *discordgo.Session, config *SlashCommandsConfig) error {
 	toAdd := make([]*discordgo.ApplicationCommand, 0)
 
 	for _, v := range registeredCommands {
-		if v.Type != discordgo.ChatApplicationCommand {
-			toDelete = append(toDelete, v)
-			continue
-		}
 		del := true
 		for _, v2 := range commands {
-			if v.Name == v2.Name && v.Description == v2.Description {
+			if equalCommands(v, v2) {
 				del = false
 				break
 			}
@@ -77,7 +228,7 @@ func Register(session *discordgo.Session, config *SlashCommandsConfig) error {
 	for _, v := range commands {
 		add := true
 		for _, v2 := range registeredCommands {
-			if v.Name == v2.Name && v.Description == v2.Description {
+			if equalCommands(v, v2) {
 				add = false
 				break
 			}
@@ -89,7 +240,7 @@ func Register(session *discordgo.Session, config *SlashCommandsConfig) error {
 	// delete the fetched global application commands
 	for _, v := range toDelete {
 		if err := session.ApplicationCommandDelete(
-			session.State.User.ID,
+			session.ClientID(),
 			guildID,
 			v.ID,
 		); err != nil {
@@ -106,7 +257,7 @@ func Register(session *discordgo.Session, config *SlashCommandsConfig) error {
 	// register the global application commands
 	for _, cmd := range toAdd {
 		if _, err := session.ApplicationCommandCreate(
-			session.State.User.ID,
+			session.ClientID(),
 			guildID,
 			cmd,
 		); err != nil {
@@ -122,3 +273,77 @@ func Register(session *discordgo.Session, config *SlashCommandsConfig) error {
 	}
 	return nil
 }
+
+// equalCommands checks whether the provided commands have equal
+// types, names, descriptions, options, permissions and their
+// localizations, so the registered command does not have to
+// be created again.
+func equalCommands(a *discordgo.ApplicationCommand, b *discordgo.ApplicationCommand) bool {
+	if commandType(a) != commandType(b) ||
+		a.Name != b.Name || a.Description != b.Description ||
+		len(a.Options) != len(b.Options) ||
+		!equalPermissions(a.DefaultMemberPermissions, b.DefaultMemberPermissions) ||
+		!equalLocalizations(deref(a.NameLocalizations), deref(b.NameLocalizations)) ||
+		!equalLocalizations(deref(a.DescriptionLocalizations), deref(b.DescriptionLocalizations)) {
+		return false
+	}
+	for i, o := range a.Options {
+		o2 := b.Options[i]
+		if o.Name != o2.Name || o.Description != o2.Description ||
+			o.Type != o2.Type || o.Required != o2.Required ||
+			len(o.Choices) != len(o2.Choices) ||
+			!equalLocalizations(o.DescriptionLocalizations, o2.DescriptionLocalizations) {
+			return false
+		}
+		for j, c := range o.Choices {
+			if c.Name != o2.Choices[j].Name ||
+				fmt.Sprint(c.Value) != fmt.Sprint(o2.Choices[j].Value) ||
+				!equalLocalizations(c.NameLocalizations, o2.Choices[j].NameLocalizations) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// commandType returns the type of the provided command, the
+// commands with no type set are chat commands.
+func commandType(cmd *discordgo.ApplicationCommand) discordgo.ApplicationCommandType {
+	if cmd.Type == 0 {
+		return discordgo.ChatApplicationCommand
+	}
+	return cmd.Type
+}
+
+// equalLocalizations checks whether the provided localizations
+// are equal, a nil localizations equal to the empty ones.
+func equalLocalizations(a map[discordgo.Locale]string, b map[discordgo.Locale]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if v2, ok := b[k]; !ok || v != v2 {
+			return false
+		}
+	}
+	return true
+}
+
+// equalPermissions checks whether the provided
+// permissions are equal, a nil permissions equal
+// only to the nil permissions.
+func equalPermissions(a *int64, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// deref returns the localizations the provided pointer
+// points to, or nil if the pointer is nil.
+func deref(l *map[discordgo.Locale]string) map[discordgo.Locale]string {
+	if l == nil {
+		return nil
+	}
+	return *l
+}
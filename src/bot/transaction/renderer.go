@@ -0,0 +1,232 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// renderWindow is the duration for which the queue updates are
+	// combined, before the queue message is rendered.
+	renderWindow = 200 * time.Millisecond
+	// renderMaxWait limits how long the queue message's rendering
+	// may be postponed, when the updates keep being requested.
+	renderMaxWait = time.Second
+	// renderRetries is the number of times the rendering is
+	// retried when discord responds with too many requests.
+	renderRetries = 3
+	// rendererIdleTime is the duration after which the worker of
+	// a guild without queue updates exits.
+	rendererIdleTime = time.Minute
+)
+
+type renderRequest struct {
+	ctx    context.Context
+	at     time.Time
+	result chan error
+}
+
+type guildRenderer struct {
+	pending      []*renderRequest
+	signal       chan struct{}
+	backoffUntil time.Time
+}
+
+type renderer struct {
+	transactions *Transactions
+	guilds       map[string]*guildRenderer
+	mutex        sync.Mutex
+}
+
+// newRenderer constructs an object that renders the queue messages.
+// Every guild's queue message is rendered by a single worker, one
+// update at a time, so the updates are never sent out of order.
+func newRenderer(t *Transactions) *renderer {
+	return &renderer{
+		transactions: t,
+		guilds:       make(map[string]*guildRenderer),
+		mutex:        sync.Mutex{},
+	}
+}
+
+// Render requests the queue message of the guild identified by the
+// provided guildID to be rendered after the provided delay, and waits
+// until it is rendered. The requests received within a short window
+// are combined and the message is rendered only once, from the latest
+// state of the queue, when all of them are due.
+func (r *renderer) Render(ctx context.Context, guildID string, delay time.Duration) error {
+	request := &renderRequest{
+		ctx:    ctx,
+		at:     time.Now().Add(delay),
+		result: make(chan error, 1),
+	}
+	r.mutex.Lock()
+	g, ok := r.guilds[guildID]
+	if !ok {
+		g = &guildRenderer{
+			pending: make([]*renderRequest, 0),
+			signal:  make(chan struct{}, 1),
+		}
+		r.guilds[guildID] = g
+		go r.run(guildID, g)
+	}
+	g.pending = append(g.pending, request)
+	r.mutex.Unlock()
+
+	select {
+	case g.signal <- struct{}{}:
+	default:
+	}
+	select {
+	case err := <-request.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run renders the guild's queue message whenever the pending
+// requests are due, until no requests are received for a while.
+func (r *renderer) run(guildID string, g *guildRenderer) {
+	idle := time.NewTimer(rendererIdleTime)
+	defer idle.Stop()
+
+	for {
+		select {
+		case <-g.signal:
+		case <-idle.C:
+			r.mutex.Lock()
+			if len(g.pending) == 0 {
+				delete(r.guilds, guildID)
+				r.mutex.Unlock()
+				return
+			}
+			r.mutex.Unlock()
+		}
+		r.renderPending(guildID, g)
+
+		if !idle.Stop() {
+			select {
+			case <-idle.C:
+			default:
+			}
+		}
+		idle.Reset(rendererIdleTime)
+	}
+}
+
+// renderPending waits until the guild's pending requests are
+// due, then renders the queue message once for all of them.
+func (r *renderer) renderPending(guildID string, g *guildRenderer) {
+	attempts := 0
+	for {
+		r.mutex.Lock()
+		if len(g.pending) == 0 {
+			r.mutex.Unlock()
+			return
+		}
+		at := renderAt(g.pending)
+		if g.backoffUntil.After(at) {
+			at = g.backoffUntil
+		}
+		r.mutex.Unlock()
+
+		if wait := time.Until(at); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-g.signal:
+				// NOTE: a new request has been received,
+				// it may postpone the rendering
+				continue
+			}
+		}
+		r.mutex.Lock()
+		batch := g.pending
+		g.pending = make([]*renderRequest, 0)
+		r.mutex.Unlock()
+
+		// NOTE: the latest request's context, that is still valid,
+		// is used, as the others may have been canceled, e.g. when
+		// the bot's context is done while the queue is cleaned up
+		ctx := liveContext(batch)
+		if ctx == nil {
+			for _, request := range batch {
+				request.result <- request.ctx.Err()
+			}
+			continue
+		}
+		err := r.transactions.renderQueue(ctx, guildID)
+
+		if retryAfter, ok := tooManyRequests(err); ok && attempts < renderRetries {
+			attempts++
+			r.transactions.log.WithFields(log.Fields{
+				"GuildID":    guildID,
+				"RetryAfter": retryAfter,
+			}).Debug("Too many queue updates, backing off")
+
+			r.mutex.Lock()
+			g.backoffUntil = time.Now().Add(retryAfter)
+			g.pending = append(batch, g.pending...)
+			r.mutex.Unlock()
+			continue
+		}
+		for _, request := range batch {
+			request.result <- err
+		}
+	}
+}
+
+// renderAt returns the time at which the provided pending requests
+// should be rendered. That is when all of them are due, but not
+// before the render window since the first one has passed, and
+// not after the max wait since the first one.
+func renderAt(pending []*renderRequest) time.Time {
+	first := pending[0].at
+	at := first.Add(renderWindow)
+	for _, request := range pending {
+		if request.at.After(at) {
+			at = request.at
+		}
+	}
+	if max := first.Add(renderMaxWait); at.After(max) {
+		at = max
+	}
+	return at
+}
+
+// liveContext returns the context of the latest of the provided
+// requests that is not done, or nil if all of them are done.
+func liveContext(batch []*renderRequest) context.Context {
+	for i := len(batch) - 1; i >= 0; i-- {
+		if batch[i].ctx.Err() == nil {
+			return batch[i].ctx
+		}
+	}
+	return nil
+}
+
+// tooManyRequests returns the duration after which the request should
+// be retried, and true if the provided error is discord's 429 response.
+func tooManyRequests(err error) (time.Duration, bool) {
+	if err == nil {
+		return 0, false
+	}
+	var rateLimitErr *discordgo.RateLimitError
+	if errors.As(err, &rateLimitErr) &&
+		rateLimitErr.RateLimit != nil &&
+		rateLimitErr.TooManyRequests != nil {
+		return rateLimitErr.TooManyRequests.RetryAfter, true
+	}
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil &&
+		restErr.Response.StatusCode == http.StatusTooManyRequests {
+		return renderMaxWait, true
+	}
+	return 0, false
+}
@@ -1,6 +1,7 @@
 package transaction
 
 import (
+	"context"
 	"discord-music-bot/builder"
 	"discord-music-bot/datastore"
 	"sync"
@@ -10,19 +11,33 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// Session is the discord session through
+// which the queue messages are updated.
+type Session interface {
+	ClientID() string
+	VoiceConnection(guildID string) (*discordgo.VoiceConnection, bool)
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse) error
+	ChannelMessageEditComplex(m *discordgo.MessageEdit) (*discordgo.Message, error)
+}
+
 type Transactions struct {
 	id               uint
+	idSync           sync.Mutex
+	ctx              context.Context
 	log              *log.Logger
-	session          func() *discordgo.Session
+	session          func() Session
 	interactions     map[string]chan *discordgo.Interaction
 	interactionsSync sync.RWMutex
 	datastore        *datastore.Datastore
 	builder          *builder.Builder
 	ready            func() bool
+	position         func(guildID string) time.Duration
+	renderer         *renderer
 }
 
 type Transaction struct {
 	id              uint
+	ctx             context.Context
 	t               string
 	interaction     *discordgo.Interaction
 	guildID         string
@@ -32,17 +47,24 @@ type Transaction struct {
 }
 
 // NewTransactions constructs a new object that handles the
-// creation and holds data for Transaction objects
-func NewTransactions(s func() *discordgo.Session, log *log.Logger, ds *datastore.Datastore, b *builder.Builder, ready func() bool) *Transactions {
-	return &Transactions{
+// creation and holds data for Transaction objects.
+// The transactions' datastore calls are cancelled once
+// the provided ctx is done. The provided position returns
+// the playback position in the head song of a guild's queue.
+func NewTransactions(ctx context.Context, s func() Session, log *log.Logger, ds *datastore.Datastore, b *builder.Builder, ready func() bool, position func(guildID string) time.Duration) *Transactions {
+	t := &Transactions{
 		id:           0,
+		ctx:          ctx,
 		log:          log,
 		interactions: make(map[string]chan *discordgo.Interaction),
 		session:      s,
 		datastore:    ds,
 		builder:      b,
 		ready:        ready,
+		position:     position,
 	}
+	t.renderer = newRenderer(t)
+	return t
 }
 
 // New constructs a new Transaction objects.
@@ -58,8 +80,10 @@ func NewTransactions(s func() *discordgo.Session, log *log.Logger, ds *datastore
 // but it should be noted that it is not known from which interaction the
 // queue will be updated when calling transaction.UpdateQueue.
 func (t *Transactions) New(tp string, guildID string, interaction *discordgo.Interaction) *Transaction {
+	t.idSync.Lock()
 	id := t.id
 	t.id = (t.id + 1) % 100000
+	t.idSync.Unlock()
 	t.log.WithFields(log.Fields{
 		"ID":      id,
 		"Type":    tp,
@@ -71,6 +95,7 @@ func (t *Transactions) New(tp string, guildID string, interaction *discordgo.Int
 	return &Transaction{
 		t:               tp,
 		id:              id,
+		ctx:             t.ctx,
 		allTransactions: t,
 		guildID:         guildID,
 		interaction:     interaction,
@@ -83,11 +108,32 @@ func (t *Transaction) Interaction() *discordgo.Interaction {
 	return t.interaction
 }
 
+// Context returns the context that should be passed
+// to the datastore calls made in the transaction.
+func (t *Transaction) Context() context.Context {
+	return t.ctx
+}
+
+// WithContext replaces the transaction's context with
+// the provided ctx and returns the transaction.
+func (t *Transaction) WithContext(ctx context.Context) *Transaction {
+	t.ctx = ctx
+	return t
+}
+
 // Interaction returns the guildID
 func (t *Transaction) GuildID() string {
 	return t.guildID
 }
 
+// Quiet stops the transaction from logging its queue
+// updates and returns the transaction. This is used for
+// the transactions that are created periodically.
+func (t *Transaction) Quiet() *Transaction {
+	t.quiet = true
+	return t
+}
+
 // Refresh  marks the transaction as not yet completed.
 // A transaction is automatically marked as completed when it
 // is defered or when it updates a queue.
@@ -122,22 +168,21 @@ func (t *Transaction) Defer() {
 }
 
 // UpdateQueue updates the queue after the provided timeout.
-// This first tries to update the queue from the interactions stored
-// in the Transactions object when new transactions are added.
-// If unsuccessful, it updates it based on it's messageID and channelID.
+// The updates requested for the same guild within a short window
+// are combined by the guild's renderer, which renders the latest
+// state of the queue once all of them are due, and returns the
+// same result to each of them.
 func (t *Transaction) UpdateQueue(timeout time.Duration) error {
 	if t.done {
 		return nil
 	}
-
-	time.Sleep(timeout)
-
+	fields := log.Fields{
+		"ID":      t.id,
+		"Type":    t.t,
+		"GuildID": t.GuildID(),
+	}
 	if !t.quiet {
-		t.allTransactions.log.WithFields(log.Fields{
-			"ID":      t.id,
-			"Type":    t.t,
-			"GuildID": t.GuildID(),
-		}).Debug(
+		t.allTransactions.log.WithFields(fields).Debug(
 			"Transaction updating queue ...",
 		)
 	}
@@ -147,44 +192,52 @@ func (t *Transaction) UpdateQueue(timeout time.Duration) error {
 		t.done = true
 	}()
 
-	clientID := t.allTransactions.session().State.User.ID
-	guildID := t.GuildID()
+	err := t.allTransactions.renderer.Render(t.ctx, t.GuildID(), timeout)
+	if err != nil {
+		if !t.quiet {
+			t.allTransactions.log.WithFields(fields).Debugf(
+				"Transaction queue updating failed: %v",
+				err,
+			)
+		}
+		return err
+	}
+	if !t.quiet {
+		t.allTransactions.log.WithFields(fields).Debug(
+			"Transaction done",
+		)
+	}
+	return nil
+}
+
+// renderQueue updates the queue message of the guild identified by
+// the provided guildID. This first tries to update the queue from the
+// interactions stored in the Transactions object when new transactions
+// are added. If unsuccessful, it updates it based on it's messageID
+// and channelID.
+func (t *Transactions) renderQueue(ctx context.Context, guildID string) error {
+	clientID := t.session().ClientID()
 
 	// NOTE: first fetch the queue as the queue message
 	// is built from the queue object
-	queue, err := t.allTransactions.datastore.Queue().GetQueue(
+	queue, err := t.datastore.Queue().GetQueue(
+		ctx,
 		clientID,
 		guildID,
 	)
 	if err != nil {
-		if !t.quiet {
-			t.allTransactions.log.WithFields(log.Fields{
-				"ID":      t.id,
-				"Type":    t.t,
-				"GuildID": t.GuildID(),
-			}).Debugf(
-				"Transaction queue updating failed: %v",
-				err,
-			)
-		}
 		return err
 	}
-	queue, err = t.allTransactions.datastore.Song().UpdateQueueWithSongs(
+	queue, err = t.datastore.Song().UpdateQueueWithSongs(
+		ctx,
 		queue,
 	)
 	if err != nil {
-		if !t.quiet {
-			t.allTransactions.log.WithFields(log.Fields{
-				"ID":      t.id,
-				"Type":    t.t,
-				"GuildID": t.GuildID(),
-			}).Debugf(
-				"Transaction queue updating failed: %v",
-				err,
-			)
-		}
 		return err
 	}
+	if queue.HeadSong != nil {
+		queue.Elapsed = int(t.position(guildID).Seconds())
+	}
 	// NOTE: get queue message's components based on the state of
 	// the bot. If bot._ready = false, offline components will be added which
 	// consist of a single disabled button.
@@ -192,49 +245,41 @@ func (t *Transaction) UpdateQueue(timeout time.Duration) error {
 	// components will be added which consist of Join button.
 	// Otherwise all other buttons will be added.
 	var c []discordgo.MessageComponent
-	if !t.allTransactions.ready() {
-		c = t.allTransactions.builder.Queue().GetOfflineQueueComponents(
+	if !t.ready() {
+		c = t.builder.Queue().GetOfflineQueueComponents(
 			queue,
 		)
 	} else {
-		vc, ok := t.allTransactions.session().VoiceConnections[guildID]
+		vc, ok := t.session().VoiceConnection(guildID)
 		if ok && vc.Ready {
-			c = t.allTransactions.builder.Queue().GetMusicQueueComponents(
+			c = t.builder.Queue().GetMusicQueueComponents(
 				queue,
 			)
 		} else {
-			c = t.allTransactions.builder.Queue().GetInactiveQueueComponents(
+			c = t.builder.Queue().GetInactiveQueueComponents(
 				queue,
 			)
 
 		}
 	}
-	embed := t.allTransactions.builder.Queue().MapQueueToEmbed(queue)
-
-	err = nil
+	embed := t.builder.Queue().MapQueueToEmbed(queue)
 
 	// NOTE: get the interactions buffer, and try to update the
 	// queue from one of the stored interactions as it is much
 	// faster and discord gives no limit on it.
 	// If all these interaction responses fail, update
 	// based on the queue's messageID and guildID
-	t.allTransactions.interactionsSync.RLock()
-	buffer, ok := t.allTransactions.interactions[guildID]
-	t.allTransactions.interactionsSync.RUnlock()
+	t.interactionsSync.RLock()
+	buffer, ok := t.interactions[guildID]
+	t.interactionsSync.RUnlock()
 
 	if !ok {
 		buffer = make(chan *discordgo.Interaction)
 	}
-	fields := log.Fields{
-		"ID":      t.id,
-		"Type":    t.t,
-		"GuildID": t.GuildID(),
-	}
-updateLoop:
 	for {
 		select {
 		case i := <-buffer:
-			err = t.allTransactions.session().InteractionRespond(i,
+			err = t.session().InteractionRespond(i,
 				&discordgo.InteractionResponse{
 					Type: discordgo.InteractionResponseUpdateMessage,
 					Data: &discordgo.InteractionResponseData{
@@ -243,12 +288,14 @@ updateLoop:
 					},
 				})
 			if err != nil {
-				continue updateLoop
+				continue
 			}
-			fields["Info"] = "Queue Updated from interaction"
-			break updateLoop
+			t.log.WithFields(log.Fields{
+				"GuildID": guildID,
+			}).Trace("Queue updated from interaction")
+			return nil
 		default:
-			_, err = t.allTransactions.session().ChannelMessageEditComplex(
+			_, err = t.session().ChannelMessageEditComplex(
 				&discordgo.MessageEdit{
 					ID:         queue.MessageID,
 					Channel:    queue.ChannelID,
@@ -256,26 +303,14 @@ updateLoop:
 					Components: c,
 				})
 			if err != nil {
-				t.allTransactions.log.WithFields(log.Fields{
-					"ID":      t.id,
-					"Type":    t.t,
-					"GuildID": t.GuildID(),
-				}).Debugf(
-					"Transaction queue updating failed: %v",
-					err,
-				)
 				return err
 			}
-			fields["Info"] = "Queue Updated from message ID"
-			break updateLoop
+			t.log.WithFields(log.Fields{
+				"GuildID": guildID,
+			}).Trace("Queue updated from message ID")
+			return nil
 		}
 	}
-	if !t.quiet {
-		t.allTransactions.log.WithFields(fields).Debug(
-			"Transaction done",
-		)
-	}
-	return nil
 }
 
 func (t *Transactions) addInteraction(i *discordgo.Interaction) {
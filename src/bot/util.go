@@ -1,7 +1,10 @@
 package bot
 
 import (
+	"context"
 	"discord-music-bot/bot/transaction"
+	"discord-music-bot/model"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	log "github.com/sirupsen/logrus"
@@ -16,11 +19,11 @@ type Util struct {
 // the bot responds to the interaction and warns the user, else the bot does not
 // respond and true is returned.
 func (bot *Util) checkVoice(t *transaction.Transaction) bool {
-	botState, _ := bot.session.State.VoiceState(
+	botState, _ := bot.session.VoiceState(
 		t.GuildID(),
-		bot.session.State.User.ID,
+		bot.session.ClientID(),
 	)
-	userState, _ := bot.session.State.VoiceState(
+	userState, _ := bot.session.VoiceState(
 		t.GuildID(),
 		t.Interaction().Member.User.ID,
 	)
@@ -30,7 +33,7 @@ func (bot *Util) checkVoice(t *transaction.Transaction) bool {
 			&discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
-					Content: "You need to be in a voice channel!",
+					Content: bot.localizer(t).T("voice.required"),
 					Flags:   discordgo.MessageFlagsEphemeral,
 				},
 			})
@@ -40,7 +43,7 @@ func (bot *Util) checkVoice(t *transaction.Transaction) bool {
 			&discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
-					Content: "You need to undeafen!",
+					Content: bot.localizer(t).T("voice.undeafen"),
 					Flags:   discordgo.MessageFlagsEphemeral,
 				},
 			})
@@ -52,7 +55,7 @@ func (bot *Util) checkVoice(t *transaction.Transaction) bool {
 			&discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
-					Content: "We need to be in the same voice channel!",
+					Content: bot.localizer(t).T("voice.same_channel"),
 					Flags:   discordgo.MessageFlagsEphemeral,
 				},
 			})
@@ -62,11 +65,13 @@ func (bot *Util) checkVoice(t *transaction.Transaction) bool {
 }
 
 // deleteQueue checks if any of the provided messageIDs belongs
-// to a queue message. If so, it deletes it.
+// to a queue message. If so, it deletes it, or posts a new queue
+// message when the guild has the queue messages recreated.
 func (bot *Util) deleteQueue(guildID string, messageIDs []string) {
-	clientID := bot.session.State.User.ID
+	clientID := bot.session.ClientID()
 
 	queue, err := bot.datastore.Queue().GetQueue(
+		bot.ctx,
 		clientID,
 		guildID,
 	)
@@ -83,25 +88,95 @@ func (bot *Util) deleteQueue(guildID string, messageIDs []string) {
 	if !ok {
 		return
 	}
+	if bot.guilds.Get(guildID).QueueMessage.Recreated() {
+		bot.log.Trace("The queue message was deleted, recreating it")
+		err := bot.postQueueMessage(queue, queue.ChannelID)
+		if err == nil {
+			return
+		}
+		bot.log.WithField("GuildID", guildID).Errorf(
+			"Error when recreating the queue message: %v", err,
+		)
+	}
 	bot.log.Trace("The queue message was deleted, removing the queue")
+	bot.removeQueue(queue)
+}
+
+// removeQueue stops the playback in the provided queue's guild,
+// disconnects from it's voice channel and removes the queue.
+func (bot *Util) removeQueue(queue *model.Queue) {
+	clientID := bot.session.ClientID()
+	guildID := queue.GuildID
+
 	if ap, ok := bot.audioplayers.Get(guildID); ok {
-		ap.Subscriptions().Emit("terminate")
+		ap.Stop(bot.ctx)
 	}
-	if vc, ok := bot.session.VoiceConnections[guildID]; ok {
-		vc.Disconnect()
+	if _, ok := bot.session.VoiceConnection(guildID); ok {
+		bot.session.VoiceDisconnect(guildID)
 	}
 
 	if err := bot.datastore.Queue().RemoveQueue(
+		bot.ctx,
 		clientID,
 		queue.GuildID,
 	); err != nil {
 		bot.log.Errorf(
-			"Error when removing queue after message delete: %v",
+			"Error when removing queue: %v",
 			err,
 		)
 	}
 }
 
+// moveQueueMessage posts a new message with the provided queue to the
+// channel identified by the provided channelID, below the latest
+// messages, then deletes the queue's previous message.
+func (bot *Util) moveQueueMessage(queue *model.Queue, channelID string) error {
+	prevChannelID, prevMessageID := queue.ChannelID, queue.MessageID
+	if err := bot.postQueueMessage(queue, channelID); err != nil {
+		return err
+	}
+	// NOTE: the queue already has the new message, so
+	// deleting the previous one does not remove the queue
+	if err := bot.session.ChannelMessageDelete(prevChannelID, prevMessageID); err != nil {
+		bot.log.WithField("GuildID", queue.GuildID).Debugf(
+			"Could not delete the moved queue's message: %v", err,
+		)
+	}
+	return nil
+}
+
+// postQueueMessage posts a new message with the provided queue to the
+// channel identified by the provided channelID, and replaces the
+// queue's messageID and channelID with the new message's.
+// The message is then rendered as any other queue update.
+func (bot *Util) postQueueMessage(queue *model.Queue, channelID string) error {
+	queue, err := bot.datastore.Song().UpdateQueueWithSongs(bot.ctx, queue)
+	if err != nil {
+		return err
+	}
+	msg, err := bot.session.ChannelMessageSendComplex(
+		channelID,
+		&discordgo.MessageSend{
+			Embeds: []*discordgo.MessageEmbed{
+				bot.builder.Queue().MapQueueToEmbed(queue),
+			},
+			Components: bot.builder.Queue().GetInactiveQueueComponents(queue),
+		},
+	)
+	if err != nil {
+		return err
+	}
+	queue.MessageID = msg.ID
+	queue.ChannelID = msg.ChannelID
+	if err := bot.datastore.Queue().UpdateQueue(bot.ctx, queue); err != nil {
+		bot.session.ChannelMessageDelete(msg.ChannelID, msg.ID)
+		return err
+	}
+	t := bot.transactions.New("PostQueueMessage", queue.GuildID, nil)
+	t.UpdateQueue(0)
+	return nil
+}
+
 // cleanDiscordMusicQueues removes all queue messages from datastore,
 // for which the messages not longer exist in the discord channels.
 // For those that exist, it marks them as paused.
@@ -112,7 +187,11 @@ func (bot *Util) deleteQueue(guildID string, messageIDs []string) {
 func (bot *Util) cleanDiscordMusicQueues() {
 	bot.log.Debug("Cleaning up discord music queues ...")
 
-	queues, err := bot.datastore.Queue().FindAllQueues()
+	// NOTE: queues are cleaned up after the bot's context
+	// is done, so a new context is used for the cleanup
+	ctx := context.Background()
+
+	queues, err := bot.datastore.Queue().FindAllQueues(ctx)
 	if err != nil {
 		bot.log.Errorf(
 			"Error when checking if all queues exist: %v", err,
@@ -120,12 +199,15 @@ func (bot *Util) cleanDiscordMusicQueues() {
 		return
 	}
 	for _, queue := range queues {
-		t := bot.transactions.New("CleanQueues", queue.GuildID, nil)
+		t := bot.transactions.New(
+			"CleanQueues", queue.GuildID, nil,
+		).WithContext(ctx)
 		if err := t.UpdateQueue(0); err != nil {
 			// NOTE: this will be called if updating the queue
 			// failed... the queue was then deleted while the
 			// bot had been offline
 			err = bot.datastore.Queue().RemoveQueue(
+				ctx,
 				queue.ClientID,
 				queue.GuildID,
 			)
@@ -138,6 +220,161 @@ func (bot *Util) cleanDiscordMusicQueues() {
 	}
 }
 
+// loadGuildSettings loads the settings the guilds' admins have
+// changed for their guilds, so they override the configured ones.
+func (bot *Util) loadGuildSettings() {
+	bot.log.Debug("Loading guilds' settings ...")
+
+	stored, err := bot.datastore.GuildSettings().FindAllGuildSettings(
+		bot.ctx,
+		bot.session.ClientID(),
+	)
+	if err != nil {
+		bot.log.Errorf("Error when loading guilds' settings: %v", err)
+		return
+	}
+	for _, setting := range stored {
+		if err := bot.guilds.Set(
+			setting.GuildID,
+			setting.Name,
+			setting.Value,
+		); err != nil {
+			bot.log.WithFields(log.Fields{
+				"GuildID": setting.GuildID,
+				"Name":    setting.Name,
+			}).Warnf("Ignoring stored guild setting: %v", err)
+		}
+	}
+}
+
+// saveResumeStates saves the voice channel and the position in the
+// currently playing song for every guild in which the client is
+// connected to a voice channel, so the playback may be resumed
+// once the bot is restarted.
+func (bot *Util) saveResumeStates() {
+	bot.log.Debug("Saving playback states of the queues ...")
+
+	// NOTE: states are saved after the bot's context is
+	// done, so a new context is used for saving them
+	ctx := context.Background()
+
+	for guildID, vc := range bot.session.VoiceConnections() {
+		ap, ok := bot.audioplayers.Get(guildID)
+		if !ok || ap == nil {
+			continue
+		}
+		// NOTE: the audioplayer is stopped by the done context,
+		// wait for it, so the position at which it has been
+		// stopped is used
+		select {
+		case <-ap.Done():
+		case <-time.After(5 * time.Second):
+		}
+		position := ap.State().Position
+		queue, err := bot.datastore.Queue().GetQueue(
+			ctx,
+			bot.session.ClientID(),
+			guildID,
+		)
+		if err != nil {
+			continue
+		}
+		queue.VoiceChannelID = vc.ChannelID
+		queue.PlaybackPosition = int(position.Seconds())
+		if err := bot.datastore.Queue().UpdateQueue(ctx, queue); err != nil {
+			bot.log.WithField("GuildID", guildID).Errorf(
+				"Error when saving playback state: %v", err,
+			)
+			continue
+		}
+		bot.log.WithFields(log.Fields{
+			"GuildID":   guildID,
+			"ChannelID": vc.ChannelID,
+			"Position":  position,
+		}).Trace("Playback state saved")
+	}
+}
+
+// resumeQueues rejoins the voice channels saved when the bot has been
+// shut down and resumes playing the queues' head songs from the saved
+// positions, if there are still any listeners in those channels.
+// The saved states are cleared, whether the playback is resumed or not.
+func (bot *Util) resumeQueues() {
+	queues, err := bot.datastore.Queue().FindAllQueues(bot.ctx)
+	if err != nil {
+		bot.log.Errorf(
+			"Error when fetching queues to resume: %v", err,
+		)
+		return
+	}
+	for _, queue := range queues {
+		if len(queue.VoiceChannelID) == 0 {
+			continue
+		}
+		channelID := queue.VoiceChannelID
+		position := time.Duration(queue.PlaybackPosition) * time.Second
+
+		queue.VoiceChannelID = ""
+		queue.PlaybackPosition = 0
+		if err := bot.datastore.Queue().UpdateQueue(bot.ctx, queue); err != nil {
+			bot.log.WithField("GuildID", queue.GuildID).Errorf(
+				"Error when clearing playback state: %v", err,
+			)
+			continue
+		}
+		go bot.resumeQueue(queue.GuildID, channelID, position)
+	}
+}
+
+// resumeQueue waits for the guild identified by the provided guildID
+// to become available, then rejoins the voice channel identified by
+// the provided channelID and starts playing the queue's head song
+// from the provided position.
+func (bot *Util) resumeQueue(guildID string, channelID string, position time.Duration) {
+	done := bot.ctx.Done()
+	// NOTE: guilds' voice states are received after
+	// the READY event, so wait until the guild is available
+	for i := 0; ; i++ {
+		if _, err := bot.session.Guild(guildID); err == nil {
+			break
+		}
+		if i >= 60 {
+			bot.log.WithField("GuildID", guildID).Debug(
+				"Guild not available, cannot resume playback",
+			)
+			return
+		}
+		select {
+		case <-done:
+			return
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	if !bot.hasListenersInChannel(guildID, channelID) {
+		bot.log.WithField("GuildID", guildID).Trace(
+			"No listeners in the voice channel, not resuming playback",
+		)
+		return
+	}
+	bot.log.WithFields(log.Fields{
+		"GuildID":   guildID,
+		"ChannelID": channelID,
+		"Position":  position,
+	}).Trace("Resuming playback")
+
+	// NOTE: remove paused option, as the
+	// playback is started again
+	bot.datastore.Queue().RemoveQueueOptions(
+		bot.ctx,
+		bot.session.ClientID(),
+		guildID,
+		model.Paused,
+	)
+	t := bot.transactions.New("ResumePlayback", guildID, nil)
+	defer t.Defer()
+	bot.playFrom(t, channelID, position)
+}
+
 // joinVoice connects to the voice channel identified by the provided guilID and
 // channelID, returns error on failure. If the client is already connected to the
 // voice channel, it does not connect again.
@@ -147,9 +384,9 @@ func (bot *Util) joinVoice(t *transaction.Transaction, channelID string) error {
 		"ChannelID": channelID,
 	}).Trace("Joining voice")
 
-	vc, ok := bot.session.VoiceConnections[t.GuildID()]
+	vc, ok := bot.session.VoiceConnection(t.GuildID())
 	if ok && vc.ChannelID == channelID {
-		bot.log.WithField("GuildID", t.Interaction().GuildID).Trace(
+		bot.log.WithField("GuildID", t.GuildID()).Trace(
 			"Client already in the requested voice",
 		)
 		return nil
@@ -163,7 +400,7 @@ func (bot *Util) joinVoice(t *transaction.Transaction, channelID string) error {
 		bot.log.Debugf("Could not join voice: %v", err)
 		return err
 	}
-	bot.log.WithField("GuildID", t.Interaction().GuildID).Trace(
+	bot.log.WithField("GuildID", t.GuildID()).Trace(
 		"Successfully joined voice",
 	)
 	return nil
@@ -175,8 +412,8 @@ func (bot *Util) joinVoice(t *transaction.Transaction, channelID string) error {
 func (bot *Util) ensureClientTextChannelPermissions(channelID string) bool {
 	// NOTE: check permissions for the client in the channel
 	// ... it should always have the send messages permission
-	per, err := bot.session.State.UserChannelPermissions(
-		bot.session.State.User.ID,
+	per, err := bot.session.UserChannelPermissions(
+		bot.session.ClientID(),
 		channelID,
 	)
 	if err != nil {
@@ -196,13 +433,20 @@ func (bot *Util) ensureClientTextChannelPermissions(channelID string) bool {
 // the provided guildID.
 // Listeners are undeafened members in the same channel as the client.
 func (bot *Util) hasListeners(guildID string) bool {
-	clientState, err := bot.session.State.VoiceState(
+	clientState, err := bot.session.VoiceState(
 		guildID,
-		bot.session.State.User.ID,
+		bot.session.ClientID(),
 	)
 	if err != nil {
 		return false
 	}
+	return bot.hasListenersInChannel(guildID, clientState.ChannelID)
+}
+
+// hasListenersInChannel checks whether there are any listeners in the
+// voice channel identified by the provided guildID and channelID.
+// Listeners are undeafened members other than the client.
+func (bot *Util) hasListenersInChannel(guildID string, channelID string) bool {
 	maxMembersFetch := 1000
 	done := bot.ctx.Done()
 	after := ""
@@ -218,17 +462,17 @@ outerMemberLoop:
 			case <-done:
 				return false
 			default:
-				if m.User.ID == bot.session.State.User.ID {
+				if m.User.ID == bot.session.ClientID() {
 					continue innerMemberLoop
 				}
-				memberState, err := bot.session.State.VoiceState(
+				memberState, err := bot.session.VoiceState(
 					guildID,
 					m.User.ID,
 				)
 				if err != nil {
 					continue innerMemberLoop
 				}
-				if memberState.ChannelID == clientState.ChannelID &&
+				if memberState.ChannelID == channelID &&
 					!memberState.Deaf && !memberState.SelfDeaf {
 					return true
 				}
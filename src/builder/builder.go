@@ -1,26 +1,38 @@
 package builder
 
 import (
+	"discord-music-bot/builder/history"
 	"discord-music-bot/builder/queue"
 	"discord-music-bot/builder/song"
+	"discord-music-bot/builder/stats"
+	"discord-music-bot/i18n"
+	"discord-music-bot/settings"
 )
 
 type Configuration struct {
-	Queue *queue.Configuration `yaml:"Queue" validate:"required"`
+	Queue   *queue.Configuration   `yaml:"Queue" validate:"required"`
+	History *history.Configuration `yaml:"History" validate:"required"`
+	Stats   *stats.Configuration   `yaml:"Stats" validate:"required"`
 }
 
 type Builder struct {
-	queue *queue.QueueBuilder
-	song  *song.SongBuilder
+	queue   *queue.QueueBuilder
+	song    *song.SongBuilder
+	history *history.HistoryBuilder
+	stats   *stats.StatsBuilder
 }
 
 // NewBuilder constructs an object that handles building
 // the queue's embed, components, ... based on it's current state
-func NewBuilder(config *Configuration) *Builder {
+// and the settings of the guild to which the queue belongs, with
+// the texts translated by the provided translator.
+func NewBuilder(config *Configuration, guilds *settings.GuildSettings, translator *i18n.Translator) *Builder {
 	b := &Builder{
-		song: song.NewSongBuilder(),
+		song:  song.NewSongBuilder(),
+		stats: stats.NewStatsBuilder(config.Stats, guilds, translator),
 	}
-	b.queue = queue.NewQueueBuidler(config.Queue, b.song)
+	b.queue = queue.NewQueueBuidler(config.Queue, b.song, guilds, translator)
+	b.history = history.NewHistoryBuilder(config.History, b.song, guilds, translator)
 	return b
 }
 
@@ -35,3 +47,15 @@ func (builder *Builder) Queue() *queue.QueueBuilder {
 func (builder *Builder) Song() *song.SongBuilder {
 	return builder.song
 }
+
+// History returns an object that handles building
+// the played songs' history and mapping it to embeds.
+func (builder *Builder) History() *history.HistoryBuilder {
+	return builder.history
+}
+
+// Stats returns an object that handles building
+// the server's statistics and mapping them to embeds.
+func (builder *Builder) Stats() *stats.StatsBuilder {
+	return builder.stats
+}
@@ -0,0 +1,95 @@
+package component
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Version is the first part of every customID, so the customIDs'
+// scheme may change without breaking the components of the
+// messages that have already been sent.
+const Version = "v1"
+
+// separator separates the parts of the customID.
+const separator = ":"
+
+// ErrUnversioned is returned when parsing a customID that has
+// not been created with the versioned scheme, e.g. the customID
+// of a component sent before the scheme was introduced.
+var ErrUnversioned = errors.New("Unversioned custom ID")
+
+// Action identifies what happens when the component is used or
+// the modal is submitted, the interactions are routed by it.
+type Action string
+
+const (
+	QueueBackward Action = "queue.backward"  // Show the previous page of the queue message
+	QueueForward  Action = "queue.forward"   // Show the next page of the queue message
+	QueuePrevious Action = "queue.previous"  // Play the previous song
+	QueueSkip     Action = "queue.skip"      // Skip the playing song
+	QueuePause    Action = "queue.pause"     // Pause or unpause the playing song
+	QueueReplay   Action = "queue.replay"    // Replay the playing song from the start
+	QueueAddSongs Action = "queue.add_songs" // Open the modal for adding songs
+	QueueLoop     Action = "queue.loop"      // Enable or disable the queue's loop
+	QueueJoin     Action = "queue.join"      // Join the voice channel of the inactive queue
+	QueueOffline  Action = "queue.offline"   // Shown while the bot is offline
+
+	ViewBackward Action = "view.backward" // Show the previous page of the private queue view
+	ViewForward  Action = "view.forward"  // Show the next page of the private queue view
+	ViewPage     Action = "view.page"     // Open the modal for jumping to a page of the private queue view
+	ViewSearch   Action = "view.search"   // Open the modal for searching the private queue view
+
+	HistoryBackward Action = "history.backward" // Show the previous page of the history
+	HistoryForward  Action = "history.forward"  // Show the next page of the history
+	HistoryRequeue  Action = "history.requeue"  // Add the history entry's song back to the queue
+
+	AddSongsModal    Action = "modal.add_songs"    // Add the entered songs to the queue
+	QueuePageModal   Action = "modal.queue_page"   // Show the entered page of the private queue view
+	QueueSearchModal Action = "modal.queue_search" // Show the page of the private queue view with the searched song
+)
+
+// ID is the parsed customID of a component or a modal.
+type ID struct {
+	Action   Action // What happens when the component is used
+	Queue    string // ID of the guild, whose queue the component belongs to
+	Argument string // Optional argument of the action, e.g. a page's offset
+}
+
+// New constructs the ID of a component or a modal, with the
+// provided action, belonging to the queue of the guild
+// identified by the provided guildID.
+func New(action Action, guildID string, argument string) ID {
+	return ID{
+		Action:   action,
+		Queue:    guildID,
+		Argument: argument,
+	}
+}
+
+// String formats the ID as a customID, that may be
+// added to a component or a modal.
+func (id ID) String() string {
+	return strings.Join(
+		[]string{Version, string(id.Action), id.Queue, id.Argument},
+		separator,
+	)
+}
+
+// Parse parses the provided customID of a component or a modal.
+// ErrUnversioned is returned if the customID has not been
+// created with the versioned scheme.
+func Parse(customID string) (ID, error) {
+	parts := strings.SplitN(customID, separator, 4)
+	if parts[0] != Version {
+		return ID{}, ErrUnversioned
+	}
+	if len(parts) != 4 || len(parts[1]) == 0 {
+		return ID{}, fmt.Errorf("Invalid custom ID: %s", customID)
+	}
+	return ID{
+		Action:   Action(parts[1]),
+		Queue:    parts[2],
+		Argument: parts[3],
+	}, nil
+}
@@ -0,0 +1,58 @@
+package component_test
+
+import (
+	"discord-music-bot/builder/component"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ComponentTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitParse checks that the customIDs are parsed
+// back to the IDs from which they have been formatted.
+func (s *ComponentTestSuite) TestUnitParse() {
+	for _, id := range []component.ID{
+		component.New(component.QueueSkip, "GUILD-ID-TEST", ""),
+		component.New(component.ViewPage, "GUILD-ID-TEST", "20"),
+		component.New(component.QueueSearchModal, "GUILD-ID-TEST", "a:b"),
+	} {
+		parsed, err := component.Parse(id.String())
+		s.NoError(err)
+		s.Equal(id, parsed)
+	}
+	s.Equal(
+		"v1:history.requeue:GUILD-ID-TEST:15",
+		component.New(component.HistoryRequeue, "GUILD-ID-TEST", "15").String(),
+	)
+}
+
+// TestUnitParseInvalid checks that the customIDs created without
+// the versioned scheme and the malformed customIDs are rejected.
+func (s *ComponentTestSuite) TestUnitParseInvalid() {
+	for _, customID := range []string{
+		"Loop<split>2c7f1b0e",
+		"queue-view<split>page<split>10",
+		"",
+	} {
+		_, err := component.Parse(customID)
+		s.True(errors.Is(err, component.ErrUnversioned), customID)
+	}
+	for _, customID := range []string{
+		"v1:queue.skip",
+		"v1::GUILD-ID-TEST:",
+	} {
+		_, err := component.Parse(customID)
+		s.Error(err)
+		s.False(errors.Is(err, component.ErrUnversioned), customID)
+	}
+}
+
+// TestComponentTestSuite runs all tests under
+// the ComponentTestSuite suite.
+func TestComponentTestSuite(t *testing.T) {
+	suite.Run(t, new(ComponentTestSuite))
+}
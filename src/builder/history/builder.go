@@ -0,0 +1,206 @@
+package history
+
+import (
+	"discord-music-bot/builder/component"
+	"discord-music-bot/builder/song"
+	"discord-music-bot/i18n"
+	"discord-music-bot/model"
+	"discord-music-bot/settings"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type Configuration struct {
+	Title       string         `yaml:"Title" validate:"required"`
+	Description string         `yaml:"Description"`
+	Footer      string         `yaml:"Footer"`
+	Empty       string         `yaml:"Empty" validate:"required"`
+	Buttons     *ButtonsConfig `yaml:"Buttons" validate:"required"`
+}
+
+type ButtonsConfig struct {
+	Backward string `yaml:"Backward" validate:"required"`
+	Forward  string `yaml:"Forward" validate:"required"`
+	Requeue  string `yaml:"Requeue" validate:"required"`
+}
+
+// legacyPrefix is the first part of the unversioned
+// customIDs of the history message's components.
+const legacyPrefix = "history"
+
+type HistoryBuilder struct {
+	config      *Configuration
+	songBuilder *song.SongBuilder
+	guilds      *settings.GuildSettings
+	translator  *i18n.Translator
+}
+
+// NewHistoryBuilder constructs an object that handles
+// building the played songs' history and mapping it to embeds,
+// translated to the language of the server.
+func NewHistoryBuilder(config *Configuration, songBuilder *song.SongBuilder, guilds *settings.GuildSettings, translator *i18n.Translator) *HistoryBuilder {
+	return &HistoryBuilder{
+		config:      config,
+		songBuilder: songBuilder,
+		guilds:      guilds,
+		translator:  translator,
+	}
+}
+
+// NewHistory constructs an object that represents a single page
+// of the played songs' history in the discord server
+// identified by the clientID and guildID.
+func (builder *HistoryBuilder) NewHistory(clientID string, guildID string) *model.History {
+	history := new(model.History)
+	history.ClientID = clientID
+	history.GuildID = guildID
+	history.Offset = 0
+	history.Limit = 5
+	history.Size = 0
+	history.Entries = make([]*model.HistoryEntry, 0)
+	return history
+}
+
+// NewHistoryEntry constructs a history entry for the provided song,
+// that has been played in the discord server identified by the
+// clientID and guildID.
+func (builder *HistoryBuilder) NewHistoryEntry(clientID string, guildID string, song *model.Song, startedAt time.Time, played time.Duration) *model.HistoryEntry {
+	entry := new(model.HistoryEntry)
+	entry.ClientID = clientID
+	entry.GuildID = guildID
+	entry.RequesterID = song.RequesterID
+	entry.Name = song.Name
+	entry.ShortName = song.ShortName
+	entry.Url = song.Url
+	entry.DurationSeconds = song.DurationSeconds
+	entry.DurationString = song.DurationString
+	// NOTE: history is stored in UTC, so the statistics
+	// by the hour of the day do not depend on the bot's timezone
+	entry.StartedAt = startedAt.UTC()
+	entry.PlayedSeconds = int(played.Seconds())
+	return entry
+}
+
+// MapHistoryToEmbed maps the provided history to a message embed.
+// The embed lists the history's entries, limited by it's
+// offset and limit, with the time they started playing,
+// how long they have been played and who requested them.
+// The embed is translated to the provided locale, when the
+// history's guild has no language set.
+func (builder *HistoryBuilder) MapHistoryToEmbed(history *model.History, locale discordgo.Locale) *discordgo.MessageEmbed {
+	l := builder.localizer(history, locale)
+	embed := &discordgo.MessageEmbed{
+		Title:       l.Or("history.title", builder.config.Title),
+		Fields:      make([]*discordgo.MessageEmbedField, 0),
+		Description: l.Or("history.description", builder.config.Description),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: l.Or("history.footer", builder.config.Footer),
+		},
+	}
+	if len(history.Entries) == 0 {
+		embed.Description = l.Or("history.empty", builder.config.Empty)
+		return embed
+	}
+	spacer := "> "
+	entries := make([]string, 0)
+	for i, e := range history.Entries {
+		entry := fmt.Sprintf(
+			"***%d***　%s\n%s<t:%d:R>　**%s** / %s",
+			i+history.Offset+1,
+			e.ShortName,
+			spacer,
+			e.StartedAt.Unix(),
+			builder.songBuilder.DurationToString(e.PlayedSeconds),
+			e.DurationString,
+		)
+		if len(e.RequesterID) > 0 {
+			entry += fmt.Sprintf("　<@%s>", e.RequesterID)
+		}
+		entries = append(entries, entry)
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name: fmt.Sprintf(
+			"%d - %d / %d",
+			history.Offset+1,
+			history.Offset+len(history.Entries),
+			history.Size,
+		),
+		Value: strings.Join(entries, "\n"),
+	})
+	return embed
+}
+
+// GetHistoryComponents constructs a slice of message components
+// that belong to the provided history. There is a button for adding
+// each of the displayed entries back to the queue and buttons
+// for navigating through the history. The buttons' labels are
+// translated same as the history's embed.
+func (builder *HistoryBuilder) GetHistoryComponents(history *model.History, locale discordgo.Locale) []discordgo.MessageComponent {
+	if len(history.Entries) == 0 {
+		return []discordgo.MessageComponent{}
+	}
+	l := builder.localizer(history, locale)
+	requeue := make([]discordgo.MessageComponent, 0)
+	for i, e := range history.Entries {
+		requeue = append(requeue, builder.newButton(
+			fmt.Sprintf("%s %d", l.Or("history.buttons.requeue", builder.config.Buttons.Requeue), i+history.Offset+1),
+			component.New(component.HistoryRequeue, history.GuildID, strconv.FormatUint(uint64(e.ID), 10)),
+			false,
+		))
+	}
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: requeue,
+		},
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				builder.newButton(l.Or("history.buttons.backward", builder.config.Buttons.Backward), component.New(component.HistoryBackward, history.GuildID, strconv.Itoa(history.Offset)), history.Size <= history.Limit),
+				builder.newButton(l.Or("history.buttons.forward", builder.config.Buttons.Forward), component.New(component.HistoryForward, history.GuildID, strconv.Itoa(history.Offset)), history.Size <= history.Limit),
+			},
+		},
+	}
+}
+
+// ParseLegacyComponentData parses the unversioned customID of a
+// history message's button, sent before the customIDs were versioned.
+// The customID is assigned to the queue of the guild identified by
+// the provided guildID. Returns false if the customID does not
+// belong to any of the history's buttons.
+func (builder *HistoryBuilder) ParseLegacyComponentData(customID string, guildID string) (component.ID, bool) {
+	parts := strings.Split(customID, "<split>")
+	if len(parts) != 4 || parts[0] != legacyPrefix {
+		return component.ID{}, false
+	}
+	switch parts[1] {
+	case "backward":
+		return component.New(component.HistoryBackward, guildID, parts[2]), true
+	case "forward":
+		return component.New(component.HistoryForward, guildID, parts[2]), true
+	case "requeue":
+		return component.New(component.HistoryRequeue, guildID, parts[3]), true
+	}
+	return component.ID{}, false
+}
+
+// localizer returns an object that translates the history's texts
+// to the language of the history's guild, or to the provided locale
+// when the guild has no language set.
+func (builder *HistoryBuilder) localizer(history *model.History, locale discordgo.Locale) *i18n.Localizer {
+	return builder.translator.Localizer(
+		builder.guilds.Get(history.GuildID).Language,
+		string(locale),
+	)
+}
+
+func (builder *HistoryBuilder) newButton(label string, id component.ID, disabled bool) discordgo.Button {
+	return discordgo.Button{
+		CustomID: id.String(),
+		Label:    label,
+		Style:    discordgo.SecondaryButton,
+		Disabled: disabled,
+	}
+}
@@ -1,13 +1,17 @@
 package queue
 
 import (
+	"discord-music-bot/builder/component"
 	"discord-music-bot/builder/song"
+	"discord-music-bot/i18n"
 	"discord-music-bot/model"
+	"discord-music-bot/settings"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
 )
 
 type Configuration struct {
@@ -17,29 +21,131 @@ type Configuration struct {
 	Buttons     *ButtonsConfig `yaml:"Buttons" validate:"required"`
 }
 type ButtonsConfig struct {
-	Backward string `yaml:"Backward" validate:"required"`
-	Forward  string `yaml:"Forward" validate:"required"`
-	Pause    string `yaml:"Pause" validate:"required"`
-	Skip     string `yaml:"Skip" validate:"required"`
-	Previous string `yaml:"Previous" validate:"required"`
-	Replay   string `yaml:"Replay" validate:"required"`
-	AddSongs string `yaml:"AddSongs" validate:"required"`
-	Loop     string `yaml:"Loop" validate:"required"`
-	Join     string `yaml:"Join" validate:"required"`
-	Offline  string `yaml:"Offline" validate:"required"`
+	Backward *ButtonConfig       `yaml:"Backward" validate:"required"`
+	Forward  *ButtonConfig       `yaml:"Forward" validate:"required"`
+	Pause    *ButtonConfig       `yaml:"Pause" validate:"required"`
+	Skip     *ButtonConfig       `yaml:"Skip" validate:"required"`
+	Previous *ButtonConfig       `yaml:"Previous" validate:"required"`
+	Replay   *ButtonConfig       `yaml:"Replay" validate:"required"`
+	AddSongs *ButtonConfig       `yaml:"AddSongs" validate:"required"`
+	Loop     *ButtonConfig       `yaml:"Loop" validate:"required"`
+	Join     *ButtonConfig       `yaml:"Join" validate:"required"`
+	Offline  *ButtonConfig       `yaml:"Offline" validate:"required"`
+	Page     *ButtonConfig       `yaml:"Page" validate:"required"`
+	Search   *ButtonConfig       `yaml:"Search" validate:"required"`
+	Layout   [][]ComponentAction `yaml:"Layout"` // Rows of the queue message's buttons, identified by their actions, DefaultLayout when omitted
 }
 
+// ButtonConfig configures the appearance of a single button.
+// A button configured with a plain string has only a label.
+type ButtonConfig struct {
+	Label  string `yaml:"Label"`
+	Emoji  string `yaml:"Emoji"`  // Unicode emoji (e.g. ⏭️) or a custom emoji (e.g. <:skip:123456789012345678>), shown before the label
+	Hidden bool   `yaml:"Hidden"` // Omit the button from the queue message's layout
+}
+
+type ComponentAction string
+
+const (
+	BackwardAction ComponentAction = "backward"  // Show the previous page of the queue
+	ForwardAction  ComponentAction = "forward"   // Show the next page of the queue
+	PreviousAction ComponentAction = "previous"  // Play the previous song
+	SkipAction     ComponentAction = "skip"      // Skip the playing song
+	PauseAction    ComponentAction = "pause"     // Pause or unpause the playing song
+	ReplayAction   ComponentAction = "replay"    // Replay the playing song from the start
+	AddSongsAction ComponentAction = "add_songs" // Open the modal for adding songs
+	LoopAction     ComponentAction = "loop"      // Enable or disable the queue's loop
+	JoinAction     ComponentAction = "join"      // Join the voice channel of the inactive queue
+	OfflineAction  ComponentAction = "offline"   // Shown while the bot is offline
+	PageAction     ComponentAction = "page"      // Jump to a page of the private queue view
+	SearchAction   ComponentAction = "search"    // Search for a song in the private queue view
+)
+
+// DefaultLayout is the layout of the queue message's
+// buttons, used when no other layout is configured.
+var DefaultLayout = [][]ComponentAction{
+	{BackwardAction, ForwardAction, PreviousAction, SkipAction},
+	{AddSongsAction, LoopAction, PauseAction, ReplayAction},
+}
+
+// MaxLayoutRows and MaxLayoutButtons are discord's limits of the
+// message's component rows and the buttons in a single row.
+const (
+	MaxLayoutRows    = 5
+	MaxLayoutButtons = 5
+)
+
+// ValidateLayout checks that the provided layout of the queue
+// message's buttons fits in discord's limits and that no action
+// is repeated, as the buttons' customIDs must be unique.
+func ValidateLayout(layout [][]ComponentAction) error {
+	if len(layout) > MaxLayoutRows {
+		return fmt.Errorf(
+			"Layout has %d rows, at most %d are allowed",
+			len(layout), MaxLayoutRows,
+		)
+	}
+	found := make(map[ComponentAction]struct{})
+	for i, actions := range layout {
+		if len(actions) > MaxLayoutButtons {
+			return fmt.Errorf(
+				"Layout's row %d has %d buttons, at most %d are allowed",
+				i+1, len(actions), MaxLayoutButtons,
+			)
+		}
+		for _, action := range actions {
+			if _, ok := found[action]; ok {
+				return fmt.Errorf("Layout repeats the %s button", action)
+			}
+			found[action] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// sharedActions maps the actions of the queue message's
+// buttons to the actions in their customIDs.
+var sharedActions = map[ComponentAction]component.Action{
+	BackwardAction: component.QueueBackward,
+	ForwardAction:  component.QueueForward,
+	PreviousAction: component.QueuePrevious,
+	SkipAction:     component.QueueSkip,
+	PauseAction:    component.QueuePause,
+	ReplayAction:   component.QueueReplay,
+	AddSongsAction: component.QueueAddSongs,
+	LoopAction:     component.QueueLoop,
+	JoinAction:     component.QueueJoin,
+	OfflineAction:  component.QueueOffline,
+}
+
+// privateActions maps the actions of the private queue
+// view's buttons to the actions in their customIDs.
+var privateActions = map[ComponentAction]component.Action{
+	BackwardAction: component.ViewBackward,
+	ForwardAction:  component.ViewForward,
+	PageAction:     component.ViewPage,
+	SearchAction:   component.ViewSearch,
+}
+
+// legacyViewPrefix is the first part of the unversioned
+// customIDs of the private queue view's components.
+const legacyViewPrefix = "queue-view"
+
 type QueueBuilder struct {
 	config      *Configuration
 	songBuilder *song.SongBuilder
+	guilds      *settings.GuildSettings
+	translator  *i18n.Translator
 }
 
 // NewQueueBuidler constructs an object that handles
 // building queues and mapping them to embeds.
-func NewQueueBuidler(config *Configuration, songBuilder *song.SongBuilder) *QueueBuilder {
+func NewQueueBuidler(config *Configuration, songBuilder *song.SongBuilder, guilds *settings.GuildSettings, translator *i18n.Translator) *QueueBuilder {
 	return &QueueBuilder{
 		config:      config,
 		songBuilder: songBuilder,
+		guilds:      guilds,
+		translator:  translator,
 	}
 }
 
@@ -48,6 +154,65 @@ func (builder *QueueBuilder) ButtonsConfig() *ButtonsConfig {
 	return builder.config.Buttons
 }
 
+// Button returns the config of the button with the provided
+// action, or nil if there is no such button.
+func (config *ButtonsConfig) Button(action ComponentAction) *ButtonConfig {
+	switch action {
+	case BackwardAction:
+		return config.Backward
+	case ForwardAction:
+		return config.Forward
+	case PreviousAction:
+		return config.Previous
+	case SkipAction:
+		return config.Skip
+	case PauseAction:
+		return config.Pause
+	case ReplayAction:
+		return config.Replay
+	case AddSongsAction:
+		return config.AddSongs
+	case LoopAction:
+		return config.Loop
+	case JoinAction:
+		return config.Join
+	case OfflineAction:
+		return config.Offline
+	case PageAction:
+		return config.Page
+	case SearchAction:
+		return config.Search
+	}
+	return nil
+}
+
+// UnmarshalYAML allows the button to be configured
+// with only it's label, e.g. Skip: ">>".
+func (config *ButtonConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var label string
+	if err := unmarshal(&label); err == nil {
+		config.Label = label
+		return nil
+	}
+	type plain ButtonConfig
+	return unmarshal((*plain)(config))
+}
+
+// UnmarshalYAML validates the configured layout of the buttons,
+// an invalid layout is logged and replaced by the DefaultLayout,
+// as discord would reject every queue message sent with it.
+func (config *ButtonsConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain ButtonsConfig
+	if err := unmarshal((*plain)(config)); err != nil {
+		return err
+	}
+	if err := ValidateLayout(config.Layout); err != nil {
+		log.Errorf("Invalid queue buttons' layout, using the default one: %v", err)
+		config.Layout = nil
+	}
+	return nil
+}
+
 // NewQueue constructs an object that represents a music queue
 // in a discord server. It is identified by the clientID and guildID.
 func (builder *QueueBuilder) NewQueue(clientID string, guildID string, messageID string, channelID string) *model.Queue {
@@ -71,9 +236,24 @@ func (builder *QueueBuilder) NewQueue(clientID string, guildID string, messageID
 // limited by queue's limit and offset, in the second field.
 // It has buttons for all of the available commands and
 // a text input, through which the songs may be added.
+// The remaining duration of the queue is shown below the songs.
+// The embed is translated to the language of the queue's guild.
 func (builder *QueueBuilder) MapQueueToEmbed(queue *model.Queue) *discordgo.MessageEmbed {
+	return builder.mapQueueToEmbed(queue, false, builder.localizer(queue, ""))
+}
+
+// MapQueueToEmbedWithETA maps the provided queue to a message
+// embed, same as MapQueueToEmbed, but every song in the second
+// field also shows the time left until it starts playing.
+// The embed is translated to the provided locale, when the
+// queue's guild has no language set.
+func (builder *QueueBuilder) MapQueueToEmbedWithETA(queue *model.Queue, locale discordgo.Locale) *discordgo.MessageEmbed {
+	return builder.mapQueueToEmbed(queue, true, builder.localizer(queue, locale))
+}
+
+func (builder *QueueBuilder) mapQueueToEmbed(queue *model.Queue, eta bool, l *i18n.Localizer) *discordgo.MessageEmbed {
 	embed := &discordgo.MessageEmbed{
-		Title:       builder.config.Title,
+		Title:       l.Or("queue.title", builder.config.Title),
 		Fields:      make([]*discordgo.MessageEmbedField, 0),
 		Description: builder.config.Description,
 		Footer: &discordgo.MessageEmbedFooter{
@@ -82,9 +262,48 @@ func (builder *QueueBuilder) MapQueueToEmbed(queue *model.Queue) *discordgo.Mess
 	}
 	spacer := "> "
 	spacer2 := spacer + "ㅤ"
-	if queue.HeadSong != nil {
+	compact := builder.guilds.Get(queue.GuildID).QueueMessage.Compact()
+	if queue.HeadSong != nil && compact {
+		// NOTE: the compact theme shows the head song in a single
+		// line, without it's thumbnail, channel and progress
 		embed.Color = queue.HeadSong.Color
+		headSong := builder.escapeMarkdown(queue.HeadSong.ShortName)
+		if len(queue.HeadSong.Url) > 0 {
+			headSong = builder.linkLines(headSong, queue.HeadSong.Url)
+		}
+		embed.Fields = append(embed.Fields,
+			&discordgo.MessageEmbedField{
+				Name: l.T("queue.now"),
+				Value: fmt.Sprintf(
+					"**%s**\u3000%s",
+					queue.HeadSong.DurationString, headSong,
+				),
+			},
+		)
+	} else if queue.HeadSong != nil {
+		embed.Color = queue.HeadSong.Color
+		if len(queue.HeadSong.ThumbnailUrl) > 0 {
+			embed.Thumbnail = &discordgo.MessageEmbedThumbnail{
+				URL: queue.HeadSong.ThumbnailUrl,
+			}
+		}
 		headSong := builder.songBuilder.WrapName(queue.HeadSong.Name)
+		if len(queue.HeadSong.Url) > 0 {
+			headSong = builder.linkLines(headSong, queue.HeadSong.Url)
+		}
+		if len(queue.HeadSong.ChannelName) > 0 {
+			headSong += fmt.Sprintf(
+				"\n%s*%s*",
+				spacer2, builder.escapeMarkdown(queue.HeadSong.ChannelName),
+			)
+		}
+		if builder.guilds.Get(queue.GuildID).Progress.Enabled() &&
+			queue.HeadSong.DurationSeconds > 0 {
+			headSong += fmt.Sprintf(
+				"\n%s%s",
+				spacer2, builder.progressLine(queue),
+			)
+		}
 		headSong = fmt.Sprintf(
 			"**%s**\u3000%s\n%s",
 			queue.HeadSong.DurationString, headSong, spacer2,
@@ -92,33 +311,51 @@ func (builder *QueueBuilder) MapQueueToEmbed(queue *model.Queue) *discordgo.Mess
 		headSong = fmt.Sprintf("%s\n%s", spacer, headSong)
 		embed.Fields = append(embed.Fields,
 			&discordgo.MessageEmbedField{
-				Name:  "Now",
+				Name:  l.T("queue.now"),
 				Value: "\u2000" + headSong,
 			},
 		)
 	}
 	if len(queue.Songs) > 0 {
 		songs := make([]string, 0)
+		// NOTE: the songs in front of the displayed ones
+		// start playing once the head song is finished
+		start := builder.remaining(queue.DurationBefore, queue.Elapsed)
 		for i, s := range queue.Songs {
-			songs = append(songs, fmt.Sprintf(
+			song := fmt.Sprintf(
 				"***%d***\u3000%s",
 				i+queue.Offset+1,
 				s.ShortName,
-			),
 			)
+			if eta {
+				song += fmt.Sprintf(
+					"\u3000`%s`",
+					l.T("queue.eta", builder.songBuilder.DurationToString(start)),
+				)
+				start += s.DurationSeconds
+			}
+			songs = append(songs, song)
 		}
 		sngs := strings.Join(songs, "\n")
-		if len(songs) < queue.Limit && queue.Size > queue.Limit+1 {
+		if !compact && len(songs) < queue.Limit && queue.Size > queue.Limit+1 {
 			sngs += strings.Repeat("\n"+spacer, queue.Limit-len(songs))
 		}
+		padding := fmt.Sprintf("\n%s\n%s%s", spacer, spacer, strings.Repeat("\u3000", 3))
+		if compact {
+			padding = "\n"
+		}
 		sngs += fmt.Sprintf(
-			"\n%s\n%s%sSongs in queue: ***%d***",
-			spacer, spacer,
-			strings.Repeat("\u3000", 3),
+			"%s%s: ***%d***\u3000%s: ***%s***",
+			padding,
+			l.T("queue.songs"),
 			queue.Size-1,
+			l.T("queue.remaining"),
+			builder.songBuilder.DurationToString(
+				builder.remaining(queue.Duration, queue.Elapsed),
+			),
 		)
 		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-			Name:  "Next",
+			Name:  l.T("queue.next"),
 			Value: sngs,
 		})
 	}
@@ -128,10 +365,11 @@ func (builder *QueueBuilder) MapQueueToEmbed(queue *model.Queue) *discordgo.Mess
 // GetInactiveQueueComponents constructs a slice of  message components
 // that belong to the provided queue when the bot is about to go offline.
 func (builder *QueueBuilder) GetOfflineQueueComponents(queue *model.Queue) []discordgo.MessageComponent {
+	l := builder.localizer(queue, "")
 	return []discordgo.MessageComponent{
 		discordgo.ActionsRow{
 			Components: []discordgo.MessageComponent{
-				builder.newButton(builder.config.Buttons.Offline, discordgo.SecondaryButton, true),
+				builder.newButton(queue, l, OfflineAction, discordgo.SecondaryButton, true),
 			},
 		},
 	}
@@ -140,10 +378,11 @@ func (builder *QueueBuilder) GetOfflineQueueComponents(queue *model.Queue) []dis
 // GetInactiveQueueComponents constructs a slice of  message components
 // that belong to the provided queue when it is considered inactive.
 func (builder *QueueBuilder) GetInactiveQueueComponents(queue *model.Queue) []discordgo.MessageComponent {
+	l := builder.localizer(queue, "")
 	return []discordgo.MessageComponent{
 		discordgo.ActionsRow{
 			Components: []discordgo.MessageComponent{
-				builder.newButton(builder.config.Buttons.Join, discordgo.SecondaryButton, false),
+				builder.newButton(queue, l, JoinAction, discordgo.SecondaryButton, false),
 			},
 		},
 	}
@@ -151,7 +390,8 @@ func (builder *QueueBuilder) GetInactiveQueueComponents(queue *model.Queue) []di
 
 // GetMusicQueueComponents constructs a slice of message components
 // that belong to the provided queue, they may vary based on
-// the queue's options
+// the queue's options. The buttons are arranged in rows by the
+// configured layout, and the hidden buttons are omitted.
 func (builder *QueueBuilder) GetMusicQueueComponents(queue *model.Queue) []discordgo.MessageComponent {
 	loopStyle := discordgo.SecondaryButton
 	pauseStyle := discordgo.SecondaryButton
@@ -161,38 +401,244 @@ func (builder *QueueBuilder) GetMusicQueueComponents(queue *model.Queue) []disco
 	if builder.queueHasOption(queue, model.Paused) {
 		pauseStyle = discordgo.SuccessButton
 	}
+	styles := map[ComponentAction]discordgo.ButtonStyle{
+		LoopAction:  loopStyle,
+		PauseAction: pauseStyle,
+	}
+	disabled := map[ComponentAction]bool{
+		BackwardAction: queue.Size <= queue.Limit,
+		ForwardAction:  queue.Size <= queue.Limit,
+		PreviousAction: queue.InactiveSize == 0 && !(queue.Size > 1 && builder.queueHasOption(queue, model.Loop)) || builder.queueHasOption(queue, model.Paused),
+		SkipAction:     queue.HeadSong == nil || builder.queueHasOption(queue, model.Paused),
+		AddSongsAction: false,
+		LoopAction:     queue.Size == 0 && !builder.queueHasOption(queue, model.Loop),
+		PauseAction:    queue.HeadSong == nil,
+		ReplayAction:   queue.HeadSong == nil || builder.queueHasOption(queue, model.Paused),
+	}
+	layout := builder.config.Buttons.Layout
+	if len(layout) == 0 || ValidateLayout(layout) != nil {
+		layout = DefaultLayout
+	}
+	l := builder.localizer(queue, "")
+	rows := make([]discordgo.MessageComponent, 0, len(layout))
+	for _, actions := range layout {
+		buttons := make([]discordgo.MessageComponent, 0, len(actions))
+		for _, action := range actions {
+			// NOTE: only the queue's commands may be
+			// added to the layout, the unknown are skipped
+			d, ok := disabled[action]
+			if !ok || builder.config.Buttons.Button(action).Hidden {
+				continue
+			}
+			style, ok := styles[action]
+			if !ok {
+				style = discordgo.SecondaryButton
+			}
+			buttons = append(buttons, builder.newButton(queue, l, action, style, d))
+		}
+		if len(buttons) > 0 {
+			rows = append(rows, discordgo.ActionsRow{Components: buttons})
+		}
+	}
+	return rows
+}
+
+// GetPrivateQueueComponents constructs a slice of message components
+// that belong to a private view of the provided queue. The view has
+// buttons for navigating through the queue, jumping to a page and
+// searching for a song, all of them hold the view's own offset.
+// The buttons' labels are translated same as the embed built
+// by MapQueueToEmbedWithETA.
+func (builder *QueueBuilder) GetPrivateQueueComponents(queue *model.Queue, locale discordgo.Locale) []discordgo.MessageComponent {
+	l := builder.localizer(queue, locale)
+	pages := 1
+	if queue.Size > 1 && queue.Limit > 0 {
+		pages = (queue.Size - 2 + queue.Limit) / queue.Limit
+	}
+	page := 1
+	if queue.Limit > 0 {
+		page = queue.Offset/queue.Limit + 1
+	}
 	return []discordgo.MessageComponent{
 		discordgo.ActionsRow{
 			Components: []discordgo.MessageComponent{
-				builder.newButton(builder.config.Buttons.Backward, discordgo.SecondaryButton, queue.Size <= queue.Limit),
-				builder.newButton(builder.config.Buttons.Forward, discordgo.SecondaryButton, queue.Size <= queue.Limit),
-				builder.newButton(builder.config.Buttons.Previous, discordgo.SecondaryButton, queue.InactiveSize == 0 && !(queue.Size > 1 && builder.queueHasOption(queue, model.Loop)) || builder.queueHasOption(queue, model.Paused)),
-				builder.newButton(builder.config.Buttons.Skip, discordgo.SecondaryButton, queue.HeadSong == nil || builder.queueHasOption(queue, model.Paused)),
-			},
-		},
-		discordgo.ActionsRow{
-			Components: []discordgo.MessageComponent{
-				builder.newButton(builder.config.Buttons.AddSongs, discordgo.SecondaryButton, false),
-				builder.newButton(builder.config.Buttons.Loop, loopStyle, queue.Size == 0 && !builder.queueHasOption(queue, model.Loop)),
-				builder.newButton(builder.config.Buttons.Pause, pauseStyle, queue.HeadSong == nil),
-				builder.newButton(builder.config.Buttons.Replay, discordgo.SecondaryButton, queue.HeadSong == nil || builder.queueHasOption(queue, model.Paused)),
+				builder.newPrivateButton(queue, l, BackwardAction, "", pages <= 1),
+				builder.newPrivateButton(queue, l, ForwardAction, "", pages <= 1),
+				builder.newPrivateButton(queue, l, PageAction, fmt.Sprintf("%d/%d", page, pages), pages <= 1),
+				builder.newPrivateButton(queue, l, SearchAction, "", queue.Size <= 1),
 			},
 		},
 	}
 }
 
-//GetButtonLabelFromComponentData returns the button's label from
-//it's customID
-func (builder *QueueBuilder) GetButtonLabelFromComponentData(data discordgo.MessageComponentInteractionData) string {
-	return strings.Split(data.CustomID, "<split>")[0]
+// localizer returns an object that translates the queue's texts
+// to the language of the queue's guild, or to the provided locale
+// when the guild has no language set.
+func (builder *QueueBuilder) localizer(queue *model.Queue, locale discordgo.Locale) *i18n.Localizer {
+	return builder.translator.Localizer(
+		builder.guilds.Get(queue.GuildID).Language,
+		string(locale),
+	)
+}
+
+// progressLine returns a line that shows the elapsed time of the
+// queue's head song, it's duration and a bar between them, with
+// a knob placed at the song's current position.
+func (builder *QueueBuilder) progressLine(queue *model.Queue) string {
+	length := 12
+	elapsed := queue.Elapsed
+	duration := queue.HeadSong.DurationSeconds
+	if elapsed > duration {
+		elapsed = duration
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	knob := elapsed * length / duration
+	if knob >= length {
+		knob = length - 1
+	}
+	return fmt.Sprintf(
+		"`%s` %s🔘%s `%s`",
+		builder.songBuilder.DurationToString(elapsed),
+		strings.Repeat("▬", knob),
+		strings.Repeat("▬", length-knob-1),
+		queue.HeadSong.DurationString,
+	)
+}
+
+// remaining returns the provided duration reduced by the provided
+// elapsed seconds of the head song, but never less than 0.
+func (builder *QueueBuilder) remaining(duration int, elapsed int) int {
+	if elapsed > duration {
+		return 0
+	}
+	return duration - elapsed
+}
+
+// linkLines turns every line of the provided wrapped name into
+// a link to the provided url, as a markdown link may not span
+// multiple lines. The lines' leading padding is not linked.
+func (builder *QueueBuilder) linkLines(name string, url string) string {
+	escape := strings.NewReplacer("[", `\[`, "]", `\]`)
+	lines := strings.Split(name, "\n")
+	for i, line := range lines {
+		text := strings.TrimLeft(line, ">ㅤ\u2000 ")
+		if len(text) == 0 {
+			continue
+		}
+		padding := line[:len(line)-len(text)]
+		lines[i] = fmt.Sprintf("%s[%s](%s)", padding, escape.Replace(text), url)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escapeMarkdown escapes the characters that would make
+// the provided text bold, italic or crossed.
+func (builder *QueueBuilder) escapeMarkdown(text string) string {
+	return strings.NewReplacer(
+		"*", `\*`, "_", `\_`, "~", `\~`, "`", `'`,
+	).Replace(text)
+}
+
+// ParseLegacyComponentData parses the unversioned customID of a queue
+// message's button or a private queue view's button, sent before the
+// customIDs were versioned. The customID is assigned to the queue of
+// the guild identified by the provided guildID. Returns false if the
+// customID does not belong to any of the queue's buttons.
+func (builder *QueueBuilder) ParseLegacyComponentData(customID string, guildID string) (component.ID, bool) {
+	parts := strings.Split(customID, "<split>")
+	if len(parts) == 3 && parts[0] == legacyViewPrefix {
+		action, ok := privateActions[ComponentAction(parts[1])]
+		return component.New(action, guildID, parts[2]), ok
+	}
+	// NOTE: the queue message's buttons were first identified
+	// by their labels, and then by their actions
+	for _, action := range builder.actions() {
+		if parts[0] == string(action) {
+			return component.New(sharedActions[action], guildID, ""), true
+		}
+	}
+	for _, action := range builder.actions() {
+		if parts[0] == builder.config.Buttons.Button(action).Label {
+			return component.New(sharedActions[action], guildID, ""), true
+		}
+	}
+	return component.ID{}, false
+}
+
+// newButton constructs a button of the provided queue's message, with
+// the configured label and emoji of the provided action. The action
+// in it's customID identifies the button, while the displayed label
+// is translated with the queue.buttons.<action> key, when the
+// localizer has it.
+func (builder *QueueBuilder) newButton(queue *model.Queue, l *i18n.Localizer, action ComponentAction, style discordgo.ButtonStyle, disabled bool) discordgo.Button {
+	button := builder.styledButton(l, action, "")
+	button.CustomID = component.New(sharedActions[action], queue.GuildID, "").String()
+	button.Style = style
+	button.Disabled = disabled
+	return button
+}
+
+// newPrivateButton constructs a button of a private view of the
+// provided queue, that holds the view's own offset in it's customID.
+func (builder *QueueBuilder) newPrivateButton(queue *model.Queue, l *i18n.Localizer, action ComponentAction, suffix string, disabled bool) discordgo.Button {
+	button := builder.styledButton(l, action, suffix)
+	button.CustomID = component.New(
+		privateActions[action], queue.GuildID, strconv.Itoa(queue.Offset),
+	).String()
+	button.Style = discordgo.SecondaryButton
+	button.Disabled = disabled
+	return button
+}
+
+// styledButton constructs a button with the translated label and
+// the emoji of the provided action's config, the provided suffix
+// is appended to the label.
+func (builder *QueueBuilder) styledButton(l *i18n.Localizer, action ComponentAction, suffix string) discordgo.Button {
+	config := builder.config.Buttons.Button(action)
+	label := config.Label
+	if len(label) > 0 {
+		label = l.Or("queue.buttons."+string(action), label)
+	}
+	button := discordgo.Button{
+		Label: strings.TrimSpace(label + " " + suffix),
+	}
+	if emoji := builder.parseEmoji(config.Emoji); emoji != nil {
+		button.Emoji = *emoji
+	}
+	return button
+}
+
+// parseEmoji parses the configured emoji, either a unicode emoji or
+// a custom emoji formatted as <:name:id>, <a:name:id> or name:id.
+// Returns nil if no emoji is configured.
+func (builder *QueueBuilder) parseEmoji(emoji string) *discordgo.ComponentEmoji {
+	emoji = strings.TrimSpace(emoji)
+	if len(emoji) == 0 {
+		return nil
+	}
+	animated := strings.HasPrefix(emoji, "<a:")
+	custom := strings.Trim(strings.TrimPrefix(emoji, "<a"), "<>")
+	parts := strings.Split(strings.TrimPrefix(custom, ":"), ":")
+	if len(parts) == 2 && len(parts[1]) > 0 {
+		if _, err := strconv.ParseUint(parts[1], 10, 64); err == nil {
+			return &discordgo.ComponentEmoji{
+				Name:     parts[0],
+				ID:       parts[1],
+				Animated: animated,
+			}
+		}
+	}
+	return &discordgo.ComponentEmoji{Name: emoji}
 }
 
-func (builder *QueueBuilder) newButton(label string, style discordgo.ButtonStyle, disabled bool) discordgo.Button {
-	return discordgo.Button{
-		CustomID: label + "<split>" + uuid.NewString(),
-		Label:    label,
-		Style:    style,
-		Disabled: disabled,
+// actions returns the actions of all the queue message's buttons.
+func (builder *QueueBuilder) actions() []ComponentAction {
+	return []ComponentAction{
+		BackwardAction, ForwardAction, PreviousAction, SkipAction,
+		PauseAction, ReplayAction, AddSongsAction, LoopAction,
+		JoinAction, OfflineAction,
 	}
 }
 
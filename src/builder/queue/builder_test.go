@@ -0,0 +1,49 @@
+package queue_test
+
+import (
+	"discord-music-bot/builder/queue"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v2"
+)
+
+type QueueBuilderTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitLayout checks that a valid layout of the buttons is
+// loaded, and that a layout with a repeated button or with more
+// rows or buttons than discord allows is replaced by the default.
+func (s *QueueBuilderTestSuite) TestUnitLayout() {
+	load := func(layout string) [][]queue.ComponentAction {
+		config := new(queue.ButtonsConfig)
+		s.Require().NoError(yaml.Unmarshal(
+			[]byte("Skip: \">>\"\nLayout: "+layout),
+			config,
+		))
+		s.Equal(">>", config.Skip.Label)
+		return config.Layout
+	}
+	s.Equal(
+		[][]queue.ComponentAction{
+			{queue.SkipAction, queue.PauseAction},
+			{queue.LoopAction},
+		},
+		load("[[skip, pause], [loop]]"),
+	)
+	s.Nil(load("[[skip, pause], [skip]]"))
+	s.Nil(load("[[skip, pause, loop, replay, previous, add_songs]]"))
+	s.Nil(load("[[skip], [pause], [loop], [replay], [previous], [add_songs]]"))
+
+	s.NoError(queue.ValidateLayout(queue.DefaultLayout))
+	s.Error(queue.ValidateLayout([][]queue.ComponentAction{
+		{queue.SkipAction}, {queue.SkipAction},
+	}))
+}
+
+// TestQueueBuilderTestSuite runs all tests under
+// the QueueBuilderTestSuite suite.
+func TestQueueBuilderTestSuite(t *testing.T) {
+	suite.Run(t, new(QueueBuilderTestSuite))
+}
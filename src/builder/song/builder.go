@@ -21,7 +21,7 @@ func NewSongBuilder() *SongBuilder {
 
 // NewSong constructs a song object from the provided song info.
 // It trims and shortens the song's name, converts duration seconds to
-// duration string and adds a random color.
+// duration string, keeps the thumbnail and channel and adds a random color.
 func (builder *SongBuilder) NewSong(info *model.SongInfo) *model.Song {
 	song := new(model.Song)
 	song.DurationSeconds = info.LengthSeconds
@@ -29,10 +29,31 @@ func (builder *SongBuilder) NewSong(info *model.SongInfo) *model.Song {
 	song.Name = builder.trimYoutubeSongName(info.Name)
 	song.ShortName = builder.shortenYoutubeSongName(song.Name)
 	song.Url = info.Url
+	song.ThumbnailUrl = info.ThumbnailUrl
+	song.ChannelName = info.ChannelName
 	song.Color = rand.Intn(16777216)
 	return song
 }
 
+// NewSongFromHistoryEntry constructs a song object from the provided
+// history entry, so the already played song may be added to the queue again.
+func (builder *SongBuilder) NewSongFromHistoryEntry(entry *model.HistoryEntry) *model.Song {
+	song := new(model.Song)
+	song.DurationSeconds = entry.DurationSeconds
+	song.DurationString = entry.DurationString
+	song.Name = entry.Name
+	song.ShortName = entry.ShortName
+	song.Url = entry.Url
+	song.Color = rand.Intn(16777216)
+	return song
+}
+
+// DurationToString converts the seconds to a string
+// formated as hh:mm:ss, hours and minutes are not added if zero
+func (builder *SongBuilder) DurationToString(seconds int) string {
+	return builder.secondsToTimeString(seconds)
+}
+
 // WrapName wraps the provided name to multiple shorter lines,
 // so the full name may be displayed without widening the queue embed
 func (builder *SongBuilder) WrapName(name string) string {
@@ -0,0 +1,179 @@
+package stats
+
+import (
+	"discord-music-bot/i18n"
+	"discord-music-bot/model"
+	"discord-music-bot/settings"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type Configuration struct {
+	Title       string         `yaml:"Title" validate:"required"`
+	Description string         `yaml:"Description"`
+	Footer      string         `yaml:"Footer"`
+	Empty       string         `yaml:"Empty" validate:"required"`
+	Periods     *PeriodsConfig `yaml:"Periods" validate:"required"`
+}
+
+type PeriodsConfig struct {
+	Week  string `yaml:"Week" validate:"required"`
+	Month string `yaml:"Month" validate:"required"`
+	All   string `yaml:"All" validate:"required"`
+}
+
+type StatsBuilder struct {
+	config     *Configuration
+	guilds     *settings.GuildSettings
+	translator *i18n.Translator
+}
+
+// NewStatsBuilder constructs an object that handles
+// building the server's statistics and mapping them to embeds,
+// translated to the language of the server.
+func NewStatsBuilder(config *Configuration, guilds *settings.GuildSettings, translator *i18n.Translator) *StatsBuilder {
+	return &StatsBuilder{
+		config:     config,
+		guilds:     guilds,
+		translator: translator,
+	}
+}
+
+// PeriodsConfig returns the builder's periods config.
+func (builder *StatsBuilder) PeriodsConfig() *PeriodsConfig {
+	return builder.config.Periods
+}
+
+// NewStats constructs an object that represents the statistics
+// of the songs played in the discord server identified by the
+// clientID and guildID, for the provided period.
+// Unknown periods default to the last 7 days.
+func (builder *StatsBuilder) NewStats(clientID string, guildID string, period model.StatsPeriod) *model.Stats {
+	stats := new(model.Stats)
+	stats.ClientID = clientID
+	stats.GuildID = guildID
+	stats.Limit = 5
+	stats.TopSongs = make([]*model.SongStats, 0)
+	stats.TopRequesters = make([]*model.RequesterStats, 0)
+	stats.BusiestHours = make([]*model.HourStats, 0)
+
+	now := time.Now().UTC()
+	switch period {
+	case model.AllPeriod:
+		stats.Period = model.AllPeriod
+		stats.Since = time.Time{}
+	case model.MonthPeriod:
+		stats.Period = model.MonthPeriod
+		stats.Since = now.AddDate(0, 0, -30)
+	default:
+		stats.Period = model.WeekPeriod
+		stats.Since = now.AddDate(0, 0, -7)
+	}
+	return stats
+}
+
+// MapStatsToEmbed maps the provided statistics to a message embed.
+// The embed has the total time played in the first field, then
+// the most played songs, the most active requesters and
+// the busiest hours of the day, each in it's own field.
+// The embed is translated to the provided locale, when the
+// stats' guild has no language set.
+func (builder *StatsBuilder) MapStatsToEmbed(stats *model.Stats, locale discordgo.Locale) *discordgo.MessageEmbed {
+	l := builder.translator.Localizer(
+		builder.guilds.Get(stats.GuildID).Language,
+		string(locale),
+	)
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf(
+			"%s　·　%s",
+			l.Or("stats.title", builder.config.Title),
+			builder.periodName(stats.Period, l),
+		),
+		Fields:      make([]*discordgo.MessageEmbedField, 0),
+		Description: l.Or("stats.description", builder.config.Description),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: l.Or("stats.footer", builder.config.Footer),
+		},
+	}
+	if stats.PlayCount == 0 {
+		embed.Description = l.Or("stats.empty", builder.config.Empty)
+		return embed
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name: l.T("stats.total"),
+		Value: l.T(
+			"stats.total_value",
+			float64(stats.TotalPlayedSeconds)/3600,
+			stats.PlayCount,
+		),
+	})
+	if len(stats.TopSongs) > 0 {
+		songs := make([]string, 0)
+		for i, s := range stats.TopSongs {
+			songs = append(songs, fmt.Sprintf(
+				"***%d***　%s　**%d**×",
+				i+1, s.ShortName, s.PlayCount,
+			))
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  l.T("stats.top_songs"),
+			Value: strings.Join(songs, "\n"),
+		})
+	}
+	if len(stats.TopRequesters) > 0 {
+		requesters := make([]string, 0)
+		for i, r := range stats.TopRequesters {
+			requesters = append(requesters, fmt.Sprintf(
+				"***%d***　<@%s>　%s",
+				i+1, r.RequesterID,
+				l.T(
+					"stats.requester_value",
+					r.PlayCount,
+					float64(r.PlayedSeconds)/3600,
+				),
+			))
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  l.T("stats.top_requesters"),
+			Value: strings.Join(requesters, "\n"),
+		})
+	}
+	if len(stats.BusiestHours) > 0 {
+		max := stats.BusiestHours[0].PlayCount
+		hours := make([]string, 0)
+		for _, h := range stats.BusiestHours {
+			bar := 1
+			if max > 0 {
+				bar = h.PlayCount * 10 / max
+			}
+			if bar < 1 {
+				bar = 1
+			}
+			hours = append(hours, fmt.Sprintf(
+				"`%.2d:00` %s **%d**",
+				h.Hour, strings.Repeat("▇", bar), h.PlayCount,
+			))
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  l.T("stats.busiest_hours"),
+			Value: strings.Join(hours, "\n"),
+		})
+	}
+	return embed
+}
+
+// periodName returns the displayed name of the provided
+// period, translated by the provided localizer.
+func (builder *StatsBuilder) periodName(period model.StatsPeriod, l *i18n.Localizer) string {
+	switch period {
+	case model.AllPeriod:
+		return l.Or("stats.periods.all", builder.config.Periods.All)
+	case model.MonthPeriod:
+		return l.Or("stats.periods.month", builder.config.Periods.Month)
+	default:
+		return l.Or("stats.periods.week", builder.config.Periods.Week)
+	}
+}
@@ -0,0 +1,521 @@
+package conformance
+
+import (
+	"context"
+	"discord-music-bot/datastore/guild"
+	"discord-music-bot/datastore/history"
+	"discord-music-bot/datastore/queue"
+	"discord-music-bot/datastore/song"
+	"discord-music-bot/model"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// ctx is the context passed to the repositories' calls.
+var ctx = context.Background()
+
+// RepositorySuite is a test suite shared between all the datastore
+// backends, that checks whether the backend's repositories behave
+// the same. The repositories should use the same database, so that
+// removing a queue also removes it's songs. The Transaction runs the
+// provided function with a queue repository bound to a transaction,
+// that is rolled back if the function returns an error.
+type RepositorySuite struct {
+	Queue         queue.QueueRepository
+	Song          song.SongRepository
+	History       history.HistoryRepository
+	GuildSettings guild.GuildSettingsRepository
+	Transaction   func(fn func(queue queue.QueueRepository) error) error
+	Reset         func() error
+	Close         func() error
+	suite.Suite
+}
+
+// SetupTest runs before every test and empties the
+// database shared by the repositories.
+func (s *RepositorySuite) SetupTest() {
+	s.NoError(s.Reset())
+}
+
+// TearDownSuite runs after all tests have been run, empties
+// the database and closes the database connection.
+func (s *RepositorySuite) TearDownSuite() {
+	s.NoError(s.Reset())
+
+	if s.Close != nil {
+		s.NoError(s.Close())
+	}
+}
+
+// TestQueueCRUD persists a queue, fetches it and checks it's
+// fields, then updates and removes it.
+func (s *RepositorySuite) TestQueueCRUD() {
+	queue := s.persistQueue("CLIENT-ID-TEST", "GUILD-ID-TEST")
+
+	queue2, err := s.Queue.GetQueue(ctx, queue.ClientID, queue.GuildID)
+	s.NoError(err)
+	s.Equal(queue.ChannelID, queue2.ChannelID)
+	s.Equal(queue.MessageID, queue2.MessageID)
+	s.Equal(10, queue2.Limit)
+	s.Equal(0, queue2.Offset)
+	s.Equal("", queue2.VoiceChannelID)
+	s.Len(queue2.Options, 0)
+
+	// Should not persist the same queue twice
+	s.Error(s.Queue.PersistQueue(ctx, queue))
+
+	queue.MessageID = "MESSAGE-ID-TEST2"
+	queue.Limit = 5
+	queue.VoiceChannelID = "VOICE-CHANNEL-ID-TEST"
+	queue.PlaybackPosition = 42
+	s.NoError(s.Queue.UpdateQueue(ctx, queue))
+
+	// Modifying the updated queue should not modify the stored one
+	queue.MessageID = "MESSAGE-ID-TEST3"
+
+	queue2, err = s.Queue.GetQueue(ctx, queue.ClientID, queue.GuildID)
+	s.NoError(err)
+	s.Equal("MESSAGE-ID-TEST2", queue2.MessageID)
+	s.Equal(5, queue2.Limit)
+	s.Equal("VOICE-CHANNEL-ID-TEST", queue2.VoiceChannelID)
+	s.Equal(42, queue2.PlaybackPosition)
+
+	s.NoError(s.Queue.RemoveQueue(ctx, queue.ClientID, queue.GuildID))
+
+	_, err = s.Queue.GetQueue(ctx, queue.ClientID, queue.GuildID)
+	s.Error(err)
+	s.Equal("sql: no rows in result set", err.Error())
+}
+
+// TestFindAllQueues persists queues then fetches all of them.
+func (s *RepositorySuite) TestFindAllQueues() {
+	queues, err := s.Queue.FindAllQueues(ctx)
+	s.NoError(err)
+	s.Len(queues, 0)
+
+	for i := 1; i < 10; i++ {
+		s.persistQueue(
+			fmt.Sprintf("CLIENT-ID-TEST%d", i),
+			fmt.Sprintf("GUILD-ID-TEST%d", i),
+		)
+	}
+	queues, err = s.Queue.FindAllQueues(ctx)
+	s.NoError(err)
+	s.Len(queues, 9)
+	found := make(map[string]bool)
+	for _, queue := range queues {
+		s.Equal(fmt.Sprintf("MESSAGE-ID-%s", queue.GuildID), queue.MessageID)
+		found[queue.GuildID] = true
+	}
+	s.Len(found, 9)
+}
+
+// TestQueueOptions adds and removes options from a queue.
+func (s *RepositorySuite) TestQueueOptions() {
+	queue := s.persistQueue("CLIENT-ID-TEST", "GUILD-ID-TEST")
+
+	s.NoError(s.Queue.PersistQueueOptions(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+		model.LoopOption(),
+		model.PausedOption(),
+	))
+	// Duplicated options should be ignored
+	s.NoError(s.Queue.PersistQueueOptions(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+		model.LoopOption(),
+	))
+	s.True(s.Queue.QueueHasOption(ctx, queue.ClientID, queue.GuildID, model.Loop))
+	s.True(s.Queue.QueueHasOption(ctx, queue.ClientID, queue.GuildID, model.Paused))
+
+	queue, err := s.Queue.GetQueue(ctx, queue.ClientID, queue.GuildID)
+	s.NoError(err)
+	s.Len(queue.Options, 2)
+
+	s.NoError(s.Queue.RemoveQueueOptions(ctx, queue.ClientID, queue.GuildID, model.Paused))
+	s.False(s.Queue.QueueHasOption(ctx, queue.ClientID, queue.GuildID, model.Paused))
+
+	options, err := s.Queue.GetOptionsForQueue(ctx, queue.ClientID, queue.GuildID)
+	s.NoError(err)
+	s.Len(options, 1)
+	s.Equal(model.Loop, options[0].Name)
+}
+
+// TestLockQueue checks that locking a queue outside of
+// a unit of work has no effect.
+func (s *RepositorySuite) TestLockQueue() {
+	queue := s.persistQueue("CLIENT-ID-TEST", "GUILD-ID-TEST")
+
+	s.NoError(s.Queue.LockQueue(ctx, queue.ClientID, queue.GuildID))
+	// Locking a queue that does not exist should not fail
+	s.NoError(s.Queue.LockQueue(ctx, "CLIENT-ID-TEST2", "GUILD-ID-TEST2"))
+
+	_, err := s.Queue.GetQueue(ctx, queue.ClientID, queue.GuildID)
+	s.NoError(err)
+}
+
+// TestSongsCRUD persists songs, then moves them around
+// the queue and removes them.
+func (s *RepositorySuite) TestSongsCRUD() {
+	queue := s.persistQueue("CLIENT-ID-TEST", "GUILD-ID-TEST")
+
+	s.NoError(s.Song.PersistSongs(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+		newSong(1), newSong(2), newSong(3),
+		// Songs with a duplicated name are persisted only once
+		newSong(3),
+	))
+	// Song added to the front should have the smallest position
+	s.NoError(s.Song.PersistSongToFront(ctx, queue.ClientID, queue.GuildID, newSong(4)))
+	s.Equal(4, s.Song.GetSongCountForQueue(ctx, queue.ClientID, queue.GuildID))
+	s.Equal(40, s.Song.GetDurationForQueue(ctx, queue.ClientID, queue.GuildID, 0, 4))
+	s.Equal(20, s.Song.GetDurationForQueue(ctx, queue.ClientID, queue.GuildID, 2, 5))
+
+	songs, err := s.Song.GetAllSongsForQueue(ctx, queue.ClientID, queue.GuildID)
+	s.NoError(err)
+	s.Len(songs, 4)
+	s.Equal(uint(4), songs[0].ID)
+	s.Equal(0, songs[0].Position)
+	for i := 1; i < 4; i++ {
+		s.Equal(uint(i), songs[i].ID)
+		s.Equal(i, songs[i].Position)
+		s.Equal(fmt.Sprintf("Song%d", i), songs[i].Name)
+		s.Equal(fmt.Sprintf("ThumbnailUrl%d", i), songs[i].ThumbnailUrl)
+		s.Equal(fmt.Sprintf("Channel%d", i), songs[i].ChannelName)
+	}
+
+	songs, err = s.Song.GetSongsForQueue(ctx, queue.ClientID, queue.GuildID, 2, 2)
+	s.NoError(err)
+	s.Len(songs, 2)
+	s.Equal(uint(2), songs[0].ID)
+	s.Equal(uint(3), songs[1].ID)
+
+	head, err := s.Song.GetHeadSongForQueue(ctx, queue.ClientID, queue.GuildID)
+	s.NoError(err)
+	s.Equal(uint(4), head.ID)
+
+	// The head song should be pushed behind the last one
+	s.NoError(s.Song.PushHeadSongToBack(ctx, queue.ClientID, queue.GuildID))
+	songs, err = s.Song.GetAllSongsForQueue(ctx, queue.ClientID, queue.GuildID)
+	s.NoError(err)
+	s.Equal(uint(1), songs[0].ID)
+	s.Equal(uint(4), songs[3].ID)
+	s.Equal(4, songs[3].Position)
+
+	// And then back in front
+	s.NoError(s.Song.PushLastSongToFront(ctx, queue.ClientID, queue.GuildID))
+	songs, err = s.Song.GetAllSongsForQueue(ctx, queue.ClientID, queue.GuildID)
+	s.NoError(err)
+	s.Equal(uint(4), songs[0].ID)
+	s.Equal(0, songs[0].Position)
+
+	s.NoError(s.Song.RemoveHeadSong(ctx, queue.ClientID, queue.GuildID))
+	s.NoError(s.Song.RemoveSongs(ctx, queue.ClientID, queue.GuildID, 2, 3))
+	songs, err = s.Song.GetAllSongsForQueue(ctx, queue.ClientID, queue.GuildID)
+	s.NoError(err)
+	s.Len(songs, 1)
+	s.Equal(uint(1), songs[0].ID)
+
+	s.NoError(s.Song.RemoveHeadSong(ctx, queue.ClientID, queue.GuildID))
+	_, err = s.Song.GetHeadSongForQueue(ctx, queue.ClientID, queue.GuildID)
+	s.Error(err)
+}
+
+// TestSongsForQueue persists songs to multiple queues, then
+// updates a queue with it's songs.
+func (s *RepositorySuite) TestSongsForQueue() {
+	queue := s.persistQueue("CLIENT-ID-TEST", "GUILD-ID-TEST")
+	queue2 := s.persistQueue("CLIENT-ID-TEST2", "GUILD-ID-TEST2")
+
+	s.NoError(s.Song.PersistSongs(
+		ctx,
+		queue.ClientID, queue.GuildID,
+		newSong(1), newSong(2), newSong(3),
+	))
+	s.NoError(s.Song.PersistSongs(
+		ctx,
+		queue2.ClientID, queue2.GuildID,
+		newSong(3),
+	))
+	s.NoError(s.Song.PersistInactiveSongs(
+		ctx,
+		queue.ClientID, queue.GuildID,
+		newSong(4), newSong(5),
+	))
+
+	songs, err := s.Song.GetSongsForQueue(ctx, queue2.ClientID, queue2.GuildID, 0, 3)
+	s.NoError(err)
+	s.Len(songs, 1)
+	s.Equal(uint(4), songs[0].ID)
+
+	queue, err = s.Song.UpdateQueueWithSongs(ctx, queue)
+	s.NoError(err)
+	s.Equal(3, queue.Size)
+	s.Equal(2, queue.InactiveSize)
+	s.Equal(30, queue.Duration)
+	// Only the head song is in front of the displayed songs
+	s.Equal(10, queue.DurationBefore)
+	s.Equal(uint(1), queue.HeadSong.ID)
+	s.Len(queue.Songs, 2)
+	s.Equal(uint(2), queue.Songs[0].ID)
+	s.Equal(uint(3), queue.Songs[1].ID)
+}
+
+// TestInactiveSongs persists inactive songs, then pops them.
+func (s *RepositorySuite) TestInactiveSongs() {
+	queue := s.persistQueue("CLIENT-ID-TEST", "GUILD-ID-TEST")
+
+	s.NoError(s.Song.PersistInactiveSongs(
+		ctx,
+		queue.ClientID, queue.GuildID,
+		newSong(1), newSong(2),
+	))
+	song3 := newSong(3)
+	song3.RequesterID = "USER-ID-TEST"
+	s.NoError(s.Song.PersistInactiveSongs(ctx, queue.ClientID, queue.GuildID, song3))
+	s.Equal(3, s.Song.GetInactiveSongCountForQueue(ctx, queue.ClientID, queue.GuildID))
+
+	// The latest added song should be popped first
+	song, err := s.Song.PopLatestInactiveSong(ctx, queue.ClientID, queue.GuildID)
+	s.NoError(err)
+	s.Equal(uint(3), song.ID)
+	s.Equal("Song3", song.Name)
+	s.Equal("SongUrl3", song.Url)
+	s.Equal("USER-ID-TEST", song.RequesterID)
+	s.Equal("ThumbnailUrl3", song.ThumbnailUrl)
+	s.Equal("Channel3", song.ChannelName)
+	s.Equal(2, s.Song.GetInactiveSongCountForQueue(ctx, queue.ClientID, queue.GuildID))
+
+	song, err = s.Song.PopLatestInactiveSong(ctx, queue.ClientID, queue.GuildID)
+	s.NoError(err)
+	s.Equal(uint(2), song.ID)
+	s.Equal(1, s.Song.GetInactiveSongCountForQueue(ctx, queue.ClientID, queue.GuildID))
+}
+
+// TestRemoveQueueCascade checks that removing a queue removes
+// it's options, songs and inactive songs, but not it's history.
+func (s *RepositorySuite) TestRemoveQueueCascade() {
+	queue := s.persistQueue("CLIENT-ID-TEST", "GUILD-ID-TEST")
+	queue2 := s.persistQueue("CLIENT-ID-TEST2", "GUILD-ID-TEST2")
+	for _, q := range []*model.Queue{queue, queue2} {
+		s.NoError(s.Queue.PersistQueueOptions(ctx, q.ClientID, q.GuildID, model.LoopOption()))
+		s.NoError(s.Song.PersistSongs(ctx, q.ClientID, q.GuildID, newSong(1), newSong(2)))
+		s.NoError(s.Song.PersistInactiveSongs(ctx, q.ClientID, q.GuildID, newSong(3)))
+		s.NoError(s.History.PersistHistoryEntry(ctx, newHistoryEntry(q, 1, time.Now())))
+	}
+
+	s.NoError(s.Queue.RemoveQueue(ctx, queue.ClientID, queue.GuildID))
+
+	s.False(s.Queue.QueueHasOption(ctx, queue.ClientID, queue.GuildID, model.Loop))
+	s.Equal(0, s.Song.GetSongCountForQueue(ctx, queue.ClientID, queue.GuildID))
+	s.Equal(0, s.Song.GetInactiveSongCountForQueue(ctx, queue.ClientID, queue.GuildID))
+	s.Equal(1, s.History.GetHistorySize(ctx, queue.ClientID, queue.GuildID))
+
+	// Other queues should not be affected
+	s.True(s.Queue.QueueHasOption(ctx, queue2.ClientID, queue2.GuildID, model.Loop))
+	s.Equal(2, s.Song.GetSongCountForQueue(ctx, queue2.ClientID, queue2.GuildID))
+	s.Equal(1, s.Song.GetInactiveSongCountForQueue(ctx, queue2.ClientID, queue2.GuildID))
+}
+
+// TestHistory persists history entries, fetches them and
+// computes the statistics from them.
+func (s *RepositorySuite) TestHistory() {
+	queue := &model.Queue{ClientID: "CLIENT-ID-TEST", GuildID: "GUILD-ID-TEST"}
+	now := time.Now().UTC().Truncate(time.Hour)
+	for i := 1; i <= 7; i++ {
+		entry := newHistoryEntry(queue, i%3, now.Add(-time.Duration(i)*time.Hour))
+		entry.PlayedSeconds = i
+		s.NoError(s.History.PersistHistoryEntry(ctx, entry))
+	}
+	s.NoError(s.History.PersistHistoryEntry(ctx, newHistoryEntry(
+		&model.Queue{ClientID: "CLIENT-ID-TEST2", GuildID: "GUILD-ID-TEST2"},
+		1, now,
+	)))
+	s.Equal(7, s.History.GetHistorySize(ctx, queue.ClientID, queue.GuildID))
+
+	history, err := s.History.UpdateHistoryWithEntries(ctx, &model.History{
+		ClientID: queue.ClientID,
+		GuildID:  queue.GuildID,
+		Offset:   5,
+		Limit:    5,
+	})
+	s.NoError(err)
+	s.Equal(7, history.Size)
+	s.Len(history.Entries, 2)
+	// The most recently started entries are first
+	s.Equal(6, history.Entries[0].PlayedSeconds)
+	s.Equal(7, history.Entries[1].PlayedSeconds)
+
+	entry, err := s.History.GetHistoryEntry(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+		history.Entries[0].ID,
+	)
+	s.NoError(err)
+	s.True(entry.StartedAt.Equal(now.Add(-6 * time.Hour)))
+	_, err = s.History.GetHistoryEntry(ctx, "CLIENT-ID-TEST2", "GUILD-ID-TEST2", entry.ID)
+	s.Error(err)
+
+	stats, err := s.History.UpdateStats(ctx, &model.Stats{
+		ClientID: queue.ClientID,
+		GuildID:  queue.GuildID,
+		Since:    now.Add(-4 * time.Hour),
+		Limit:    5,
+	})
+	s.NoError(err)
+	s.Equal(4, stats.PlayCount)
+	s.Equal(10, stats.TotalPlayedSeconds)
+	s.Len(stats.TopSongs, 3)
+	s.Equal("SongUrl1", stats.TopSongs[0].Url)
+	s.Equal(2, stats.TopSongs[0].PlayCount)
+	s.Equal(5, stats.TopSongs[0].PlayedSeconds)
+	s.Equal("USER-ID-SongUrl1", stats.TopRequesters[0].RequesterID)
+	s.Len(stats.BusiestHours, 4)
+	s.Equal(1, stats.BusiestHours[0].PlayCount)
+}
+
+// TestGuildSettings persists the guilds' settings, replaces
+// and removes them, and checks that the guilds' settings
+// do not affect each other and are kept with the queue removed.
+func (s *RepositorySuite) TestGuildSettings() {
+	queue := s.persistQueue("CLIENT-ID-TEST", "GUILD-ID-TEST")
+	for _, setting := range []*model.GuildSetting{
+		{ClientID: queue.ClientID, GuildID: queue.GuildID, Name: "theme", Value: "compact"},
+		{ClientID: queue.ClientID, GuildID: queue.GuildID, Name: "language", Value: "en"},
+		{ClientID: queue.ClientID, GuildID: "GUILD-ID-TEST2", Name: "theme", Value: "detailed"},
+		{ClientID: "CLIENT-ID-TEST2", GuildID: queue.GuildID, Name: "sticky", Value: "3"},
+	} {
+		s.NoError(s.GuildSettings.PersistGuildSetting(ctx, setting))
+	}
+	// Persisting an existing setting should replace it's value
+	s.NoError(s.GuildSettings.PersistGuildSetting(ctx, &model.GuildSetting{
+		ClientID: queue.ClientID,
+		GuildID:  queue.GuildID,
+		Name:     "language",
+		Value:    "de",
+	}))
+
+	settings, err := s.GuildSettings.GetGuildSettings(ctx, queue.ClientID, queue.GuildID)
+	s.NoError(err)
+	s.Len(settings, 2)
+	// The settings are ordered by their names
+	s.Equal("language", settings[0].Name)
+	s.Equal("de", settings[0].Value)
+	s.Equal("theme", settings[1].Name)
+	s.Equal("compact", settings[1].Value)
+
+	settings, err = s.GuildSettings.FindAllGuildSettings(ctx, queue.ClientID)
+	s.NoError(err)
+	s.Len(settings, 3)
+	s.Equal("GUILD-ID-TEST2", settings[2].GuildID)
+
+	s.NoError(s.GuildSettings.RemoveGuildSetting(ctx, queue.ClientID, queue.GuildID, "theme"))
+	// Removing a missing setting should not fail
+	s.NoError(s.GuildSettings.RemoveGuildSetting(ctx, queue.ClientID, queue.GuildID, "theme"))
+	s.NoError(s.Queue.RemoveQueue(ctx, queue.ClientID, queue.GuildID))
+
+	settings, err = s.GuildSettings.GetGuildSettings(ctx, queue.ClientID, queue.GuildID)
+	s.NoError(err)
+	s.Len(settings, 1)
+	s.Equal("language", settings[0].Name)
+
+	settings, err = s.GuildSettings.GetGuildSettings(ctx, "CLIENT-ID-TEST2", queue.GuildID)
+	s.NoError(err)
+	s.Len(settings, 1)
+	s.Equal("3", settings[0].Value)
+}
+
+// TestTransactionKeepsOutsideWrites persists a queue in a failing
+// transaction while a history entry is concurrently persisted
+// outside of it, and checks that only the transaction's queue
+// is discarded.
+func (s *RepositorySuite) TestTransactionKeepsOutsideWrites() {
+	q := &model.Queue{ClientID: "CLIENT-ID-TEST", GuildID: "GUILD-ID-TEST"}
+	written := make(chan error, 1)
+	failed := errors.New("failed")
+
+	err := s.Transaction(func(tx queue.QueueRepository) error {
+		if err := tx.PersistQueue(ctx, &model.Queue{
+			ClientID:  q.ClientID,
+			GuildID:   q.GuildID,
+			MessageID: "MESSAGE-ID-TEST",
+			ChannelID: "CHANNEL-ID-TEST",
+			Limit:     10,
+		}); err != nil {
+			return err
+		}
+		go func() {
+			written <- s.History.PersistHistoryEntry(
+				ctx,
+				newHistoryEntry(q, 1, time.Now()),
+			)
+		}()
+		// NOTE: the outside write may have to wait
+		// for the transaction to finish
+		select {
+		case err := <-written:
+			written <- err
+		case <-time.After(100 * time.Millisecond):
+		}
+		return failed
+	})
+	s.Require().ErrorIs(err, failed)
+	s.NoError(<-written)
+
+	_, err = s.Queue.GetQueue(ctx, q.ClientID, q.GuildID)
+	s.Error(err)
+	s.Equal(1, s.History.GetHistorySize(ctx, q.ClientID, q.GuildID))
+}
+
+// persistQueue persists an empty queue identified by
+// the provided clientID and guildID.
+func (s *RepositorySuite) persistQueue(clientID string, guildID string) *model.Queue {
+	queue := &model.Queue{
+		ClientID:  clientID,
+		GuildID:   guildID,
+		MessageID: "MESSAGE-ID-" + guildID,
+		ChannelID: "CHANNEL-ID-" + guildID,
+		Offset:    0,
+		Limit:     10,
+	}
+	s.NoError(s.Queue.PersistQueue(ctx, queue))
+	return queue
+}
+
+func newSong(i int) *model.Song {
+	return &model.Song{
+		Name:            fmt.Sprintf("Song%d", i),
+		ShortName:       fmt.Sprintf("Song%d", i),
+		Url:             fmt.Sprintf("SongUrl%d", i),
+		DurationSeconds: 10,
+		DurationString:  "00:10",
+		Color:           0,
+		ThumbnailUrl:    fmt.Sprintf("ThumbnailUrl%d", i),
+		ChannelName:     fmt.Sprintf("Channel%d", i),
+	}
+}
+
+func newHistoryEntry(queue *model.Queue, i int, startedAt time.Time) *model.HistoryEntry {
+	song := newSong(i)
+	return &model.HistoryEntry{
+		ClientID:        queue.ClientID,
+		GuildID:         queue.GuildID,
+		RequesterID:     "USER-ID-" + song.Url,
+		Name:            song.Name,
+		ShortName:       song.ShortName,
+		Url:             song.Url,
+		DurationSeconds: song.DurationSeconds,
+		DurationString:  song.DurationString,
+		StartedAt:       startedAt,
+		PlayedSeconds:   10,
+	}
+}
@@ -0,0 +1,106 @@
+package conformance_test
+
+import (
+	"database/sql"
+	"discord-music-bot/datastore/conformance"
+	"discord-music-bot/datastore/guild"
+	"discord-music-bot/datastore/history"
+	"discord-music-bot/datastore/memory"
+	"discord-music-bot/datastore/migration"
+	"discord-music-bot/datastore/queue"
+	"discord-music-bot/datastore/song"
+	"discord-music-bot/datastore/sqldb"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// TestMemoryRepositorySuite runs the repository suite
+// against the in-memory datastore.
+func TestMemoryRepositorySuite(t *testing.T) {
+	db := memory.NewDB()
+	suite.Run(t, &conformance.RepositorySuite{
+		Queue:         queue.NewMemoryQueueStore(db, logrus.StandardLogger()),
+		Song:          song.NewMemorySongStore(db, logrus.StandardLogger(), 2*time.Second),
+		History:       history.NewMemoryHistoryStore(db, logrus.StandardLogger()),
+		GuildSettings: guild.NewMemoryGuildSettingsStore(db, logrus.StandardLogger()),
+		Transaction: func(fn func(queue queue.QueueRepository) error) error {
+			tx := queue.NewMemoryQueueStore(db.Transactional(), logrus.StandardLogger())
+			return db.Transaction(func() error { return fn(tx) })
+		},
+		Reset: func() error {
+			db.Reset()
+			return nil
+		},
+	})
+}
+
+// TestPostgresRepositorySuite runs the repository suite
+// against the postgres datastore.
+func TestPostgresRepositorySuite(t *testing.T) {
+	db, err := sql.Open(
+		"postgres",
+		"host=postgres port=5432 user=postgres password=postgres "+
+			"dbname=discord_bot_test sslmode=disable",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite.Run(t, &conformance.RepositorySuite{
+		Queue:         queue.NewQueueStore(db, logrus.StandardLogger(), sqldb.Options{}),
+		Song:          song.NewSongStore(db, logrus.StandardLogger(), 2*time.Second, sqldb.Options{}),
+		History:       history.NewHistoryStore(db, logrus.StandardLogger(), sqldb.Options{}),
+		GuildSettings: guild.NewGuildSettingsStore(db, logrus.StandardLogger(), sqldb.Options{}),
+		Transaction: transaction(db, func(tx sqldb.Querier) queue.QueueRepository {
+			return queue.NewQueueStore(tx, logrus.StandardLogger(), sqldb.Options{})
+		}),
+		Reset: migration.NewMigrator(db, logrus.StandardLogger(), migration.Postgres).Reset,
+		Close: db.Close,
+	})
+}
+
+// TestSqliteRepositorySuite runs the repository suite
+// against the sqlite datastore.
+func TestSqliteRepositorySuite(t *testing.T) {
+	db, err := sql.Open(
+		"sqlite3",
+		"file:"+filepath.Join(t.TempDir(), "discord_bot_test.db")+
+			"?_foreign_keys=on&_busy_timeout=5000",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite.Run(t, &conformance.RepositorySuite{
+		Queue:         queue.NewSqliteQueueStore(db, logrus.StandardLogger(), sqldb.Options{}),
+		Song:          song.NewSqliteSongStore(db, logrus.StandardLogger(), 2*time.Second, sqldb.Options{}),
+		History:       history.NewSqliteHistoryStore(db, logrus.StandardLogger(), sqldb.Options{}),
+		GuildSettings: guild.NewSqliteGuildSettingsStore(db, logrus.StandardLogger(), sqldb.Options{}),
+		Transaction: transaction(db, func(tx sqldb.Querier) queue.QueueRepository {
+			return queue.NewSqliteQueueStore(tx, logrus.StandardLogger(), sqldb.Options{})
+		}),
+		Reset: migration.NewMigrator(db, logrus.StandardLogger(), migration.Sqlite).Reset,
+		Close: db.Close,
+	})
+}
+
+// transaction returns a function, that runs the provided function
+// with a queue repository created by newQueue for a new transaction
+// of the provided database, rolled back if the function fails.
+func transaction(db *sql.DB, newQueue func(tx sqldb.Querier) queue.QueueRepository) func(fn func(queue queue.QueueRepository) error) error {
+	return func(fn func(queue queue.QueueRepository) error) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if err := fn(newQueue(tx)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
+}
@@ -3,20 +3,32 @@ package datastore
 import (
 	"context"
 	"database/sql"
+	"discord-music-bot/datastore/guild"
+	"discord-music-bot/datastore/history"
+	"discord-music-bot/datastore/memory"
+	"discord-music-bot/datastore/migration"
 	"discord-music-bot/datastore/queue"
 	"discord-music-bot/datastore/song"
+	"discord-music-bot/datastore/sqldb"
 	"fmt"
 	"time"
 
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 	log "github.com/sirupsen/logrus"
 )
 
 type Datastore struct {
 	*log.Logger
-	config *Configuration
-	queue  *queue.QueueStore
-	song   *song.SongStore
+	config   *Configuration
+	migrator *migration.Migrator
+	queue    queue.QueueRepository
+	song     song.SongRepository
+	history  history.HistoryRepository
+	guild    guild.GuildSettingsRepository
+	// runUnitOfWork runs the provided function with the
+	// repositories bound to a single transaction
+	runUnitOfWork func(ctx context.Context, fn func(uow *UnitOfWork) error) error
 }
 
 type PostgresConfig struct {
@@ -27,14 +39,22 @@ type PostgresConfig struct {
 	Port     int    `yaml:"Port" validate:"required"`
 }
 
+type SqliteConfig struct {
+	Path string `yaml:"Path" validate:"required"`
+}
+
 type Configuration struct {
-	LogLevel        log.Level       `yaml:"LogLevel" validate:"required"`
-	InactiveSongTTL time.Duration   `yaml:"InactiveSongTTL" validate:"required"`
-	Postgres        *PostgresConfig `yaml:"Postgres" validate:"required"`
+	LogLevel           log.Level       `yaml:"LogLevel" validate:"required"`
+	InactiveSongTTL    time.Duration   `yaml:"InactiveSongTTL" validate:"required"`
+	QueryTimeout       time.Duration   `yaml:"QueryTimeout"`
+	SlowQueryThreshold time.Duration   `yaml:"SlowQueryThreshold"`
+	Postgres           *PostgresConfig `yaml:"Postgres" validate:"required_without_all=Sqlite Memory"`
+	Sqlite             *SqliteConfig   `yaml:"Sqlite" validate:"required_without_all=Postgres Memory"`
+	Memory             bool            `yaml:"Memory"`
 }
 
 // NewDatastore constructs an object that handles persisting
-// the models to the postgres database and recieving them from it.
+// the models to the postgres or sqlite database and recieving them from it.
 // It does not implement any of the bot's logics.
 func NewDatastore(config *Configuration) *Datastore {
 	l := log.New()
@@ -43,11 +63,25 @@ func NewDatastore(config *Configuration) *Datastore {
 	return &Datastore{Logger: l, config: config}
 }
 
-// Connect opens a new postges connection based on the
-// provided database configuration
+// Connect opens a new database connection based on the
+// provided database configuration. The in-memory datastore is
+// used when it is enabled, then the sqlite database when it is
+// configured, postgres otherwise.
 func (datastore *Datastore) Connect() error {
 	datastore.Info("Oppening datastore connection ...")
 
+	if datastore.config.Memory {
+		return datastore.ConnectMemory()
+	}
+	if datastore.config.Sqlite != nil {
+		return datastore.connectSqlite()
+	}
+	return datastore.connectPostgres()
+}
+
+// connectPostgres opens a new postgres connection based on the
+// provided postgres configuration
+func (datastore *Datastore) connectPostgres() error {
 	datastore.WithField("Url",
 		fmt.Sprintf(
 			"postgres://%s:****@%s:%d/%s",
@@ -75,29 +109,130 @@ func (datastore *Datastore) Connect() error {
 	if err := db.Ping(); err != nil {
 		return err
 	}
-	datastore.queue = queue.NewQueueStore(db, datastore.Logger)
-	datastore.song = song.NewSongStore(
+	datastore.migrator = migration.NewMigrator(
 		db,
 		datastore.Logger,
-		datastore.config.InactiveSongTTL,
+		migration.Postgres,
 	)
+	datastore.useRepositories(db, func(db sqldb.Querier) *UnitOfWork {
+		return &UnitOfWork{
+			queue: queue.NewQueueStore(db, datastore.Logger, datastore.options()),
+			song: song.NewSongStore(
+				db,
+				datastore.Logger,
+				datastore.config.InactiveSongTTL,
+				datastore.options(),
+			),
+			history: history.NewHistoryStore(db, datastore.Logger, datastore.options()),
+			guild:   guild.NewGuildSettingsStore(db, datastore.Logger, datastore.options()),
+		}
+	})
 
 	datastore.Info("Datastore connection established")
 	return nil
 }
 
-// Init creates all the tables required by the datastore
-// and runs the goroutine required for deleting the
-// outdated inactive songs.
-func (datastore *Datastore) Init(ctx context.Context) error {
-	datastore.Debug("Initializing datastore ...")
-
-	if err := datastore.queue.Init(); err != nil {
+// connectSqlite opens the sqlite database file at the
+// configured path, creating it if it does not exist.
+func (datastore *Datastore) connectSqlite() error {
+	datastore.WithField(
+		"Path", datastore.config.Sqlite.Path,
+	).Debug("Connecting to sqlite database")
+	db, err := sql.Open(
+		"sqlite3",
+		fmt.Sprintf(
+			"file:%s?_foreign_keys=on&_busy_timeout=5000",
+			datastore.config.Sqlite.Path,
+		),
+	)
+	if err != nil {
 		return err
 	}
-	if err := datastore.song.Init(); err != nil {
+	// NOTE: sqlite allows a single writer at a time, so use a
+	// single connection instead of waiting for the database locks.
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
 		return err
 	}
+	datastore.migrator = migration.NewMigrator(
+		db,
+		datastore.Logger,
+		migration.Sqlite,
+	)
+	datastore.useRepositories(db, func(db sqldb.Querier) *UnitOfWork {
+		return &UnitOfWork{
+			queue: queue.NewSqliteQueueStore(db, datastore.Logger, datastore.options()),
+			song: song.NewSqliteSongStore(
+				db,
+				datastore.Logger,
+				datastore.config.InactiveSongTTL,
+				datastore.options(),
+			),
+			history: history.NewSqliteHistoryStore(db, datastore.Logger, datastore.options()),
+			guild:   guild.NewSqliteGuildSettingsStore(db, datastore.Logger, datastore.options()),
+		}
+	})
+
+	datastore.Info("Datastore connection established")
+	return nil
+}
+
+// ConnectMemory opens a new in-memory datastore. Nothing
+// persisted to it outlives the bot, so it is mostly
+// useful when testing the bot without a database.
+func (datastore *Datastore) ConnectMemory() error {
+	datastore.Info("Oppening in-memory datastore ...")
+
+	db := memory.NewDB()
+	datastore.queue = queue.NewMemoryQueueStore(db, datastore.Logger)
+	datastore.song = song.NewMemorySongStore(
+		db,
+		datastore.Logger,
+		datastore.config.InactiveSongTTL,
+	)
+	datastore.history = history.NewMemoryHistoryStore(db, datastore.Logger)
+	datastore.guild = guild.NewMemoryGuildSettingsStore(db, datastore.Logger)
+
+	// NOTE: the unit of work's stores use the transactional handle,
+	// so they do not wait for the transaction they run in
+	tx := db.Transactional()
+	uow := &UnitOfWork{
+		queue: queue.NewMemoryQueueStore(tx, datastore.Logger),
+		song: song.NewMemorySongStore(
+			tx,
+			datastore.Logger,
+			datastore.config.InactiveSongTTL,
+		),
+		history: history.NewMemoryHistoryStore(tx, datastore.Logger),
+		guild:   guild.NewMemoryGuildSettingsStore(tx, datastore.Logger),
+	}
+	datastore.runUnitOfWork = func(ctx context.Context, fn func(uow *UnitOfWork) error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return db.Transaction(func() error {
+			return fn(uow)
+		})
+	}
+
+	datastore.Info("In-memory datastore opened")
+	return nil
+}
+
+// Init applies the pending migrations, that create and update
+// all the tables required by the datastore, and runs the goroutine
+// required for deleting the outdated inactive songs.
+func (datastore *Datastore) Init(ctx context.Context) error {
+	datastore.Debug("Initializing datastore ...")
+
+	// NOTE: the in-memory datastore has no schema to migrate
+	if datastore.migrator != nil {
+		count, err := datastore.migrator.Up()
+		if err != nil {
+			return err
+		}
+		datastore.WithField("Count", count).Debug("Applied pending migrations")
+	}
 
 	go datastore.song.RunInactiveSongsCleanup(ctx)
 
@@ -105,14 +240,42 @@ func (datastore *Datastore) Init(ctx context.Context) error {
 	return nil
 }
 
+// options returns the options of the sql stores' calls,
+// based on the provided database configuration.
+func (datastore *Datastore) options() sqldb.Options {
+	return sqldb.Options{
+		QueryTimeout:       datastore.config.QueryTimeout,
+		SlowQueryThreshold: datastore.config.SlowQueryThreshold,
+	}
+}
+
+// Migrator returns the object that applies and reverts the
+// datastore's schema migrations, or nil if the datastore
+// has no schema.
+func (datastore *Datastore) Migrator() *migration.Migrator {
+	return datastore.migrator
+}
+
 // Queue returns the object that handles persisting and
 // removing Queues in the datastore.
-func (datastore *Datastore) Queue() *queue.QueueStore {
+func (datastore *Datastore) Queue() queue.QueueRepository {
 	return datastore.queue
 }
 
 // Song returns the object that handles persisting and
 // removing Songs in the datastore.
-func (datastore *Datastore) Song() *song.SongStore {
+func (datastore *Datastore) Song() song.SongRepository {
 	return datastore.song
 }
+
+// History returns the object that handles persisting and
+// fetching the played songs' history in the datastore.
+func (datastore *Datastore) History() history.HistoryRepository {
+	return datastore.history
+}
+
+// GuildSettings returns the object that handles persisting
+// and removing the guilds' settings in the datastore.
+func (datastore *Datastore) GuildSettings() guild.GuildSettingsRepository {
+	return datastore.guild
+}
@@ -0,0 +1,96 @@
+package guild
+
+import (
+	"context"
+	"discord-music-bot/datastore/memory"
+	"discord-music-bot/model"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type MemoryGuildSettingsStore struct {
+	log *log.Logger
+	db  *memory.DB
+}
+
+// NewMemoryGuildSettingsStore creates an object that handles
+// persisting and removing the guilds' settings
+// in an in-memory database.
+func NewMemoryGuildSettingsStore(db *memory.DB, log *log.Logger) *MemoryGuildSettingsStore {
+	return &MemoryGuildSettingsStore{
+		db:  db,
+		log: log,
+	}
+}
+
+// PersistGuildSetting saves the provided setting to the store,
+// replacing the guild's setting with the same name, if there is one.
+func (store *MemoryGuildSettingsStore) PersistGuildSetting(ctx context.Context, setting *model.GuildSetting) error {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: setting.ClientID, GuildID: setting.GuildID}
+	if _, ok := store.db.GuildSettings[key]; !ok {
+		store.db.GuildSettings[key] = make(map[string]string)
+	}
+	store.db.GuildSettings[key][setting.Name] = setting.Value
+	return nil
+}
+
+// RemoveGuildSetting removes the setting with the provided name,
+// from the guild identified by the provided clientID and guildID.
+func (store *MemoryGuildSettingsStore) RemoveGuildSetting(ctx context.Context, clientID string, guildID string, name string) error {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	delete(store.db.GuildSettings[key], name)
+	if len(store.db.GuildSettings[key]) == 0 {
+		delete(store.db.GuildSettings, key)
+	}
+	return nil
+}
+
+// GetGuildSettings fetches all the settings of the guild
+// identified by the provided clientID and guildID.
+func (store *MemoryGuildSettingsStore) GetGuildSettings(ctx context.Context, clientID string, guildID string) ([]*model.GuildSetting, error) {
+	settings, _ := store.FindAllGuildSettings(ctx, clientID)
+	guildSettings := make([]*model.GuildSetting, 0)
+	for _, setting := range settings {
+		if setting.GuildID == guildID {
+			guildSettings = append(guildSettings, setting)
+		}
+	}
+	return guildSettings, nil
+}
+
+// FindAllGuildSettings fetches the settings of all the
+// guilds, that belong to the provided clientID.
+func (store *MemoryGuildSettingsStore) FindAllGuildSettings(ctx context.Context, clientID string) ([]*model.GuildSetting, error) {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	settings := make([]*model.GuildSetting, 0)
+	for key, values := range store.db.GuildSettings {
+		if key.ClientID != clientID {
+			continue
+		}
+		for name, value := range values {
+			settings = append(settings, &model.GuildSetting{
+				ClientID: key.ClientID,
+				GuildID:  key.GuildID,
+				Name:     name,
+				Value:    value,
+			})
+		}
+	}
+	// NOTE: same order as in the sql stores
+	sort.Slice(settings, func(i, j int) bool {
+		if settings[i].GuildID != settings[j].GuildID {
+			return settings[i].GuildID < settings[j].GuildID
+		}
+		return settings[i].Name < settings[j].Name
+	})
+	return settings, nil
+}
@@ -0,0 +1,29 @@
+package guild
+
+import (
+	"context"
+	"discord-music-bot/model"
+)
+
+// GuildSettingsRepository handles persisting and removing
+// the settings changed by the guilds in a datastore.
+type GuildSettingsRepository interface {
+	// PersistGuildSetting saves the provided setting, replacing
+	// the guild's setting with the same name, if there is one.
+	PersistGuildSetting(ctx context.Context, setting *model.GuildSetting) error
+	// RemoveGuildSetting removes the setting with the provided name,
+	// from the guild identified by the provided clientID and guildID.
+	RemoveGuildSetting(ctx context.Context, clientID string, guildID string, name string) error
+	// GetGuildSettings fetches all the settings of the guild
+	// identified by the provided clientID and guildID.
+	GetGuildSettings(ctx context.Context, clientID string, guildID string) ([]*model.GuildSetting, error)
+	// FindAllGuildSettings fetches the settings of all the
+	// guilds, that belong to the provided clientID.
+	FindAllGuildSettings(ctx context.Context, clientID string) ([]*model.GuildSetting, error)
+}
+
+var (
+	_ GuildSettingsRepository = (*GuildSettingsStore)(nil)
+	_ GuildSettingsRepository = (*MemoryGuildSettingsStore)(nil)
+	_ GuildSettingsRepository = (*SqliteGuildSettingsStore)(nil)
+)
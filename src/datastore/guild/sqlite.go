@@ -0,0 +1,177 @@
+package guild
+
+import (
+	"context"
+	"discord-music-bot/datastore/sqldb"
+	"discord-music-bot/model"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type SqliteGuildSettingsStore struct {
+	log   *log.Logger
+	db    sqldb.Querier
+	trace *sqldb.Tracer
+}
+
+// NewSqliteGuildSettingsStore creates an object that handles
+// persisting and removing the guilds' settings
+// in sqlite database.
+func NewSqliteGuildSettingsStore(db sqldb.Querier, log *log.Logger, options sqldb.Options) *SqliteGuildSettingsStore {
+	return &SqliteGuildSettingsStore{
+		db:    db,
+		log:   log,
+		trace: sqldb.NewTracer(log, "G", options),
+	}
+}
+
+// PersistGuildSetting saves the provided setting to the database,
+// replacing the guild's setting with the same name, if there is one.
+func (store *SqliteGuildSettingsStore) PersistGuildSetting(ctx context.Context, setting *model.GuildSetting) error {
+	i, t, ctx, done := store.trace.Start(ctx, "PersistGuildSetting")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": setting.ClientID,
+		"GuildID":  setting.GuildID,
+		"Name":     setting.Name,
+	}).Tracef("[G%d]Start: Persist guild setting", i)
+
+	if _, err := store.db.ExecContext(
+		ctx,
+		`
+        INSERT INTO "guild_setting" (client_id, guild_id, name, value)
+        VALUES (?, ?, ?, ?)
+        ON CONFLICT (client_id, guild_id, name)
+            DO UPDATE SET value = EXCLUDED.value;
+        `,
+		setting.ClientID,
+		setting.GuildID,
+		setting.Name,
+		setting.Value,
+	); err != nil {
+		store.log.Tracef("[G%d]Error: %v", i, err)
+		return err
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[G%d]Done : Guild setting persisted", i)
+	return nil
+}
+
+// RemoveGuildSetting removes the setting with the provided name,
+// from the guild identified by the provided clientID and guildID.
+func (store *SqliteGuildSettingsStore) RemoveGuildSetting(ctx context.Context, clientID string, guildID string, name string) error {
+	i, t, ctx, done := store.trace.Start(ctx, "RemoveGuildSetting")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+		"Name":     name,
+	}).Tracef("[G%d]Start: Remove guild setting", i)
+
+	if _, err := store.db.ExecContext(
+		ctx,
+		`
+        DELETE FROM "guild_setting"
+        WHERE "guild_setting".client_id = ? AND
+            "guild_setting".guild_id = ? AND
+            "guild_setting".name = ?;
+        `,
+		clientID,
+		guildID,
+		name,
+	); err != nil {
+		store.log.Tracef("[G%d]Error: %v", i, err)
+		return err
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[G%d]Done : Guild setting removed", i)
+	return nil
+}
+
+// GetGuildSettings fetches all the settings of the guild
+// identified by the provided clientID and guildID.
+func (store *SqliteGuildSettingsStore) GetGuildSettings(ctx context.Context, clientID string, guildID string) ([]*model.GuildSetting, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetGuildSettings")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[G%d]Start: Fetch guild settings", i)
+
+	settings, err := store.query(
+		ctx,
+		`
+        SELECT client_id, guild_id, name, value FROM "guild_setting"
+        WHERE "guild_setting".client_id = ? AND
+            "guild_setting".guild_id = ?
+        ORDER BY name;
+        `,
+		clientID,
+		guildID,
+	)
+	if err != nil {
+		store.log.Tracef("[G%d]Error: %v", i, err)
+		return nil, err
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[G%d]Done : %d guild settings fetched", i, len(settings))
+	return settings, nil
+}
+
+// FindAllGuildSettings fetches the settings of all the
+// guilds, that belong to the provided clientID.
+func (store *SqliteGuildSettingsStore) FindAllGuildSettings(ctx context.Context, clientID string) ([]*model.GuildSetting, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "FindAllGuildSettings")
+	defer done()
+
+	store.log.WithField(
+		"ClientID", clientID,
+	).Tracef("[G%d]Start: Fetch all guild settings", i)
+
+	settings, err := store.query(
+		ctx,
+		`
+        SELECT client_id, guild_id, name, value FROM "guild_setting"
+        WHERE "guild_setting".client_id = ?
+        ORDER BY guild_id, name;
+        `,
+		clientID,
+	)
+	if err != nil {
+		store.log.Tracef("[G%d]Error: %v", i, err)
+		return nil, err
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[G%d]Done : %d guild settings fetched", i, len(settings))
+	return settings, nil
+}
+
+// query fetches the guild settings selected by the provided query.
+func (store *SqliteGuildSettingsStore) query(ctx context.Context, query string, args ...interface{}) ([]*model.GuildSetting, error) {
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make([]*model.GuildSetting, 0)
+	for rows.Next() {
+		setting := &model.GuildSetting{}
+		if err := rows.Scan(
+			&setting.ClientID, &setting.GuildID,
+			&setting.Name, &setting.Value,
+		); err != nil {
+			return nil, err
+		}
+		settings = append(settings, setting)
+	}
+	return settings, rows.Err()
+}
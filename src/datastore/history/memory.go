@@ -0,0 +1,275 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"discord-music-bot/datastore/memory"
+	"discord-music-bot/model"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type MemoryHistoryStore struct {
+	log *log.Logger
+	db  *memory.DB
+}
+
+// NewMemoryHistoryStore creates an object that handles
+// persisting and fetching the played songs' history
+// in an in-memory database.
+func NewMemoryHistoryStore(db *memory.DB, log *log.Logger) *MemoryHistoryStore {
+	return &MemoryHistoryStore{
+		db:  db,
+		log: log,
+	}
+}
+
+// UpdateHistoryWithEntries fetches the history's entries,
+// limited by the history's offset and limit, and the total
+// size of the history.
+func (store *MemoryHistoryStore) UpdateHistoryWithEntries(ctx context.Context, history *model.History) (*model.History, error) {
+	entries, err := store.GetHistoryEntries(
+		ctx,
+		history.ClientID,
+		history.GuildID,
+		history.Offset,
+		history.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	history.Entries = entries
+	history.Size = store.GetHistorySize(
+		ctx,
+		history.ClientID,
+		history.GuildID,
+	)
+	return history, nil
+}
+
+// PersistHistoryEntry saves the provided entry to the store.
+// History entries are not removed together with the queue.
+func (store *MemoryHistoryStore) PersistHistoryEntry(ctx context.Context, entry *model.HistoryEntry) error {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	e := *entry
+	e.ID = store.db.NextID("song_history")
+	store.db.HistoryEntries = append(store.db.HistoryEntries, &e)
+	return nil
+}
+
+// GetHistoryEntry fetches the history entry with the provided id,
+// that belongs to the guild identified by the provided clientID
+// and guildID. Returns error if no such entry exists.
+func (store *MemoryHistoryStore) GetHistoryEntry(ctx context.Context, clientID string, guildID string, id uint) (*model.HistoryEntry, error) {
+	for _, e := range store.entries(clientID, guildID, time.Time{}) {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// GetHistoryEntries fetches the history entries that belong to the
+// guild identified by the provided clientID and guildID, limited
+// by the provided offset and limit. The most recently started
+// entries are returned first.
+func (store *MemoryHistoryStore) GetHistoryEntries(ctx context.Context, clientID string, guildID string, offset int, limit int) ([]*model.HistoryEntry, error) {
+	entries := store.entries(clientID, guildID, time.Time{})
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].StartedAt.Equal(entries[j].StartedAt) {
+			return entries[i].StartedAt.After(entries[j].StartedAt)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+	if offset >= len(entries) {
+		return make([]*model.HistoryEntry, 0), nil
+	}
+	entries = entries[offset:]
+	if limit < len(entries) {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// GetHistorySize returns the number of history entries that belong
+// to the guild identified by the provided clientID and guildID.
+func (store *MemoryHistoryStore) GetHistorySize(ctx context.Context, clientID string, guildID string) int {
+	return len(store.entries(clientID, guildID, time.Time{}))
+}
+
+// UpdateStats computes the statistics of the played songs' history
+// for the guild identified by the stats' clientID and guildID.
+// Only songs that started playing after the stats' Since are included.
+func (store *MemoryHistoryStore) UpdateStats(ctx context.Context, stats *model.Stats) (*model.Stats, error) {
+	count, played, err := store.GetTotalPlayed(
+		ctx,
+		stats.ClientID,
+		stats.GuildID,
+		stats.Since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	stats.PlayCount = count
+	stats.TotalPlayedSeconds = played
+
+	if stats.TopSongs, err = store.GetTopSongs(
+		ctx,
+		stats.ClientID,
+		stats.GuildID,
+		stats.Since,
+		stats.Limit,
+	); err != nil {
+		return nil, err
+	}
+	if stats.TopRequesters, err = store.GetTopRequesters(
+		ctx,
+		stats.ClientID,
+		stats.GuildID,
+		stats.Since,
+		stats.Limit,
+	); err != nil {
+		return nil, err
+	}
+	if stats.BusiestHours, err = store.GetBusiestHours(
+		ctx,
+		stats.ClientID,
+		stats.GuildID,
+		stats.Since,
+		stats.Limit,
+	); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetTotalPlayed returns the number of songs played in the guild
+// identified by the provided clientID and guildID since the provided
+// time, and the total number of seconds they have been played.
+func (store *MemoryHistoryStore) GetTotalPlayed(ctx context.Context, clientID string, guildID string, since time.Time) (int, int, error) {
+	entries := store.entries(clientID, guildID, since)
+	played := 0
+	for _, e := range entries {
+		played += e.PlayedSeconds
+	}
+	return len(entries), played, nil
+}
+
+// GetTopSongs returns the most played songs in the guild identified
+// by the provided clientID and guildID since the provided time.
+// Songs are grouped by their url.
+func (store *MemoryHistoryStore) GetTopSongs(ctx context.Context, clientID string, guildID string, since time.Time, limit int) ([]*model.SongStats, error) {
+	grouped := make(map[string]*model.SongStats)
+	songs := make([]*model.SongStats, 0)
+	for _, e := range store.entries(clientID, guildID, since) {
+		song, ok := grouped[e.Url]
+		if !ok {
+			song = &model.SongStats{Url: e.Url}
+			grouped[e.Url] = song
+			songs = append(songs, song)
+		}
+		if e.ShortName > song.ShortName {
+			song.ShortName = e.ShortName
+		}
+		song.PlayCount++
+		song.PlayedSeconds += e.PlayedSeconds
+	}
+	sort.SliceStable(songs, func(i, j int) bool {
+		if songs[i].PlayCount != songs[j].PlayCount {
+			return songs[i].PlayCount > songs[j].PlayCount
+		}
+		if songs[i].PlayedSeconds != songs[j].PlayedSeconds {
+			return songs[i].PlayedSeconds > songs[j].PlayedSeconds
+		}
+		return songs[i].Url < songs[j].Url
+	})
+	if limit < len(songs) {
+		songs = songs[:limit]
+	}
+	return songs, nil
+}
+
+// GetTopRequesters returns the users, whose requested songs have been
+// played the most in the guild identified by the provided clientID
+// and guildID since the provided time.
+func (store *MemoryHistoryStore) GetTopRequesters(ctx context.Context, clientID string, guildID string, since time.Time, limit int) ([]*model.RequesterStats, error) {
+	grouped := make(map[string]*model.RequesterStats)
+	requesters := make([]*model.RequesterStats, 0)
+	for _, e := range store.entries(clientID, guildID, since) {
+		if len(e.RequesterID) == 0 {
+			continue
+		}
+		requester, ok := grouped[e.RequesterID]
+		if !ok {
+			requester = &model.RequesterStats{RequesterID: e.RequesterID}
+			grouped[e.RequesterID] = requester
+			requesters = append(requesters, requester)
+		}
+		requester.PlayCount++
+		requester.PlayedSeconds += e.PlayedSeconds
+	}
+	sort.SliceStable(requesters, func(i, j int) bool {
+		if requesters[i].PlayCount != requesters[j].PlayCount {
+			return requesters[i].PlayCount > requesters[j].PlayCount
+		}
+		if requesters[i].PlayedSeconds != requesters[j].PlayedSeconds {
+			return requesters[i].PlayedSeconds > requesters[j].PlayedSeconds
+		}
+		return requesters[i].RequesterID < requesters[j].RequesterID
+	})
+	if limit < len(requesters) {
+		requesters = requesters[:limit]
+	}
+	return requesters, nil
+}
+
+// GetBusiestHours returns the hours of the day (UTC) in which the most
+// songs started playing in the guild identified by the provided
+// clientID and guildID since the provided time.
+func (store *MemoryHistoryStore) GetBusiestHours(ctx context.Context, clientID string, guildID string, since time.Time, limit int) ([]*model.HourStats, error) {
+	grouped := make(map[int]*model.HourStats)
+	hours := make([]*model.HourStats, 0)
+	for _, e := range store.entries(clientID, guildID, since) {
+		h := e.StartedAt.UTC().Hour()
+		hour, ok := grouped[h]
+		if !ok {
+			hour = &model.HourStats{Hour: h}
+			grouped[h] = hour
+			hours = append(hours, hour)
+		}
+		hour.PlayCount++
+	}
+	sort.SliceStable(hours, func(i, j int) bool {
+		if hours[i].PlayCount != hours[j].PlayCount {
+			return hours[i].PlayCount > hours[j].PlayCount
+		}
+		return hours[i].Hour < hours[j].Hour
+	})
+	if limit < len(hours) {
+		hours = hours[:limit]
+	}
+	return hours, nil
+}
+
+// entries returns copies of the history entries that belong to the
+// guild identified by the provided clientID and guildID and
+// started playing after the provided time.
+func (store *MemoryHistoryStore) entries(clientID string, guildID string, since time.Time) []*model.HistoryEntry {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	entries := make([]*model.HistoryEntry, 0)
+	for _, e := range store.db.HistoryEntries {
+		if e.ClientID != clientID || e.GuildID != guildID ||
+			e.StartedAt.Before(since) {
+			continue
+		}
+		entry := *e
+		entries = append(entries, &entry)
+	}
+	return entries
+}
@@ -0,0 +1,48 @@
+package history
+
+import (
+	"context"
+	"discord-music-bot/model"
+	"time"
+)
+
+// HistoryRepository handles persisting and fetching
+// the played songs' history in a datastore.
+type HistoryRepository interface {
+	// UpdateHistoryWithEntries fetches the history's entries, limited
+	// by the history's offset and limit, and the total size of the history.
+	UpdateHistoryWithEntries(ctx context.Context, history *model.History) (*model.History, error)
+	// PersistHistoryEntry saves the provided entry.
+	PersistHistoryEntry(ctx context.Context, entry *model.HistoryEntry) error
+	// GetHistoryEntry fetches the history entry with the provided id,
+	// that belongs to the guild identified by the provided clientID
+	// and guildID. Returns error if no such entry exists.
+	GetHistoryEntry(ctx context.Context, clientID string, guildID string, id uint) (*model.HistoryEntry, error)
+	// GetHistoryEntries fetches the most recently started history
+	// entries of the guild identified by the provided clientID and
+	// guildID, limited by the provided offset and limit.
+	GetHistoryEntries(ctx context.Context, clientID string, guildID string, offset int, limit int) ([]*model.HistoryEntry, error)
+	// GetHistorySize returns the number of history entries that belong
+	// to the guild identified by the provided clientID and guildID.
+	GetHistorySize(ctx context.Context, clientID string, guildID string) int
+	// UpdateStats computes the statistics of the played songs' history
+	// for the guild identified by the stats' clientID and guildID.
+	UpdateStats(ctx context.Context, stats *model.Stats) (*model.Stats, error)
+	// GetTotalPlayed returns the number of songs played since the
+	// provided time and the total number of seconds they have been played.
+	GetTotalPlayed(ctx context.Context, clientID string, guildID string, since time.Time) (int, int, error)
+	// GetTopSongs returns the most played songs since the provided time.
+	GetTopSongs(ctx context.Context, clientID string, guildID string, since time.Time, limit int) ([]*model.SongStats, error)
+	// GetTopRequesters returns the users, whose requested songs have
+	// been played the most since the provided time.
+	GetTopRequesters(ctx context.Context, clientID string, guildID string, since time.Time, limit int) ([]*model.RequesterStats, error)
+	// GetBusiestHours returns the hours of the day (UTC) in which the
+	// most songs started playing since the provided time.
+	GetBusiestHours(ctx context.Context, clientID string, guildID string, since time.Time, limit int) ([]*model.HourStats, error)
+}
+
+var (
+	_ HistoryRepository = (*HistoryStore)(nil)
+	_ HistoryRepository = (*MemoryHistoryStore)(nil)
+	_ HistoryRepository = (*SqliteHistoryStore)(nil)
+)
@@ -0,0 +1,458 @@
+package history
+
+import (
+	"context"
+	"discord-music-bot/datastore/sqldb"
+	"discord-music-bot/model"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type SqliteHistoryStore struct {
+	log   *log.Logger
+	db    sqldb.Querier
+	trace *sqldb.Tracer
+}
+
+// NewSqliteHistoryStore creates an object that handles
+// persisting and fetching the played songs' history
+// in sqlite database.
+func NewSqliteHistoryStore(db sqldb.Querier, log *log.Logger, options sqldb.Options) *SqliteHistoryStore {
+	return &SqliteHistoryStore{
+		db:    db,
+		log:   log,
+		trace: sqldb.NewTracer(log, "H", options),
+	}
+}
+
+// UpdateHistoryWithEntries fetches the history's entries,
+// limited by the history's offset and limit, and the total
+// size of the history.
+func (store *SqliteHistoryStore) UpdateHistoryWithEntries(ctx context.Context, history *model.History) (*model.History, error) {
+	entries, err := store.GetHistoryEntries(
+		ctx,
+		history.ClientID,
+		history.GuildID,
+		history.Offset,
+		history.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	history.Entries = entries
+	history.Size = store.GetHistorySize(
+		ctx,
+		history.ClientID,
+		history.GuildID,
+	)
+	return history, nil
+}
+
+// PersistHistoryEntry saves the provided entry to the database.
+// History entries are not removed together with the queue.
+func (store *SqliteHistoryStore) PersistHistoryEntry(ctx context.Context, entry *model.HistoryEntry) error {
+	i, t, ctx, done := store.trace.Start(ctx, "PersistHistoryEntry")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": entry.ClientID,
+		"GuildID":  entry.GuildID,
+	}).Tracef("[H%d]Start: Persist history entry", i)
+
+	if _, err := store.db.ExecContext(
+		ctx,
+		`
+        INSERT INTO "song_history" (
+            client_id, guild_id, requester_id, name, short_name, url,
+            duration_seconds, duration_string, started_at, played_seconds
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+        `,
+		entry.ClientID,
+		entry.GuildID,
+		entry.RequesterID,
+		entry.Name,
+		entry.ShortName,
+		entry.Url,
+		entry.DurationSeconds,
+		entry.DurationString,
+		entry.StartedAt.UTC(),
+		entry.PlayedSeconds,
+	); err != nil {
+		store.log.Tracef("[H%d]Error: %v", i, err)
+		return err
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[H%d]Done : History entry persisted", i)
+	return nil
+}
+
+// GetHistoryEntry fetches the history entry with the provided id,
+// that belongs to the guild identified by the provided clientID
+// and guildID. Returns error if no such entry exists.
+func (store *SqliteHistoryStore) GetHistoryEntry(ctx context.Context, clientID string, guildID string, id uint) (*model.HistoryEntry, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetHistoryEntry")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+		"ID":       id,
+	}).Tracef("[H%d]Start: Fetch history entry", i)
+
+	entry := &model.HistoryEntry{}
+	if err := store.db.QueryRowContext(
+		ctx,
+		`
+        SELECT * FROM "song_history"
+        WHERE "song_history".client_id = ? AND
+            "song_history".guild_id = ? AND
+            "song_history".id = ?;
+        `,
+		clientID,
+		guildID,
+		id,
+	).Scan(
+		&entry.ID, &entry.ClientID, &entry.GuildID,
+		&entry.RequesterID, &entry.Name, &entry.ShortName,
+		&entry.Url, &entry.DurationSeconds, &entry.DurationString,
+		&entry.StartedAt, &entry.PlayedSeconds,
+	); err != nil {
+		store.log.Tracef("[H%d]Error: %v", i, err)
+		return nil, err
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[H%d]Done : History entry fetched", i)
+	return entry, nil
+}
+
+// GetHistoryEntries fetches the history entries that belong to the
+// guild identified by the provided clientID and guildID, limited
+// by the provided offset and limit. The most recently started
+// entries are returned first.
+func (store *SqliteHistoryStore) GetHistoryEntries(ctx context.Context, clientID string, guildID string, offset int, limit int) ([]*model.HistoryEntry, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetHistoryEntries")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+		"Offset":   offset,
+	}).Tracef("[H%d]Start: Fetch %d history entries", i, limit)
+
+	rows, err := store.db.QueryContext(
+		ctx,
+		`
+        SELECT * FROM "song_history"
+        WHERE "song_history".client_id = ? AND
+            "song_history".guild_id = ?
+        ORDER BY started_at DESC, id DESC
+        LIMIT ?
+        OFFSET ?;
+        `,
+		clientID,
+		guildID,
+		limit,
+		offset,
+	)
+	if err != nil {
+		store.log.Tracef("[H%d]Error: %v", i, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*model.HistoryEntry, 0)
+	for rows.Next() {
+		entry := &model.HistoryEntry{}
+		if err := rows.Scan(
+			&entry.ID, &entry.ClientID, &entry.GuildID,
+			&entry.RequesterID, &entry.Name, &entry.ShortName,
+			&entry.Url, &entry.DurationSeconds, &entry.DurationString,
+			&entry.StartedAt, &entry.PlayedSeconds,
+		); err != nil {
+			store.log.Tracef("[H%d]Error: %v", i, err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[H%d]Done : %d history entries fetched", i, len(entries))
+	return entries, nil
+}
+
+// GetHistorySize returns the number of history entries that belong
+// to the guild identified by the provided clientID and guildID.
+func (store *SqliteHistoryStore) GetHistorySize(ctx context.Context, clientID string, guildID string) int {
+	i, t, ctx, done := store.trace.Start(ctx, "GetHistorySize")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[H%d]Start: Fetch history size", i)
+
+	var count int
+	if err := store.db.QueryRowContext(
+		ctx,
+		`
+        SELECT COUNT(*) FROM "song_history"
+        WHERE "song_history".client_id = ? AND
+            "song_history".guild_id = ?
+        `,
+		clientID,
+		guildID,
+	).Scan(&count); err != nil {
+		store.log.Tracef("[H%d]Error: %v", i, err)
+		count = 0
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[H%d]Done : History size fetched (%d)", i, count)
+	return count
+}
+
+// UpdateStats computes the statistics of the played songs' history
+// for the guild identified by the stats' clientID and guildID.
+// Only songs that started playing after the stats' Since are included.
+func (store *SqliteHistoryStore) UpdateStats(ctx context.Context, stats *model.Stats) (*model.Stats, error) {
+	count, played, err := store.GetTotalPlayed(
+		ctx,
+		stats.ClientID,
+		stats.GuildID,
+		stats.Since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	stats.PlayCount = count
+	stats.TotalPlayedSeconds = played
+
+	if stats.TopSongs, err = store.GetTopSongs(
+		ctx,
+		stats.ClientID,
+		stats.GuildID,
+		stats.Since,
+		stats.Limit,
+	); err != nil {
+		return nil, err
+	}
+	if stats.TopRequesters, err = store.GetTopRequesters(
+		ctx,
+		stats.ClientID,
+		stats.GuildID,
+		stats.Since,
+		stats.Limit,
+	); err != nil {
+		return nil, err
+	}
+	if stats.BusiestHours, err = store.GetBusiestHours(
+		ctx,
+		stats.ClientID,
+		stats.GuildID,
+		stats.Since,
+		stats.Limit,
+	); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetTotalPlayed returns the number of songs played in the guild
+// identified by the provided clientID and guildID since the provided
+// time, and the total number of seconds they have been played.
+func (store *SqliteHistoryStore) GetTotalPlayed(ctx context.Context, clientID string, guildID string, since time.Time) (int, int, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetTotalPlayed")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+		"Since":    since,
+	}).Tracef("[H%d]Start: Fetch total played", i)
+
+	var count, played int
+	if err := store.db.QueryRowContext(
+		ctx,
+		`
+        SELECT COUNT(*), COALESCE(SUM(played_seconds), 0)
+        FROM "song_history"
+        WHERE "song_history".client_id = ? AND
+            "song_history".guild_id = ? AND
+            "song_history".started_at >= ?;
+        `,
+		clientID,
+		guildID,
+		since.UTC(),
+	).Scan(&count, &played); err != nil {
+		store.log.Tracef("[H%d]Error: %v", i, err)
+		return 0, 0, err
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[H%d]Done : Total played fetched (%d, %ds)", i, count, played)
+	return count, played, nil
+}
+
+// GetTopSongs returns the most played songs in the guild identified
+// by the provided clientID and guildID since the provided time.
+// Songs are grouped by their url.
+func (store *SqliteHistoryStore) GetTopSongs(ctx context.Context, clientID string, guildID string, since time.Time, limit int) ([]*model.SongStats, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetTopSongs")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+		"Since":    since,
+	}).Tracef("[H%d]Start: Fetch %d top songs", i, limit)
+
+	rows, err := store.db.QueryContext(
+		ctx,
+		`
+        SELECT MAX(short_name), url, COUNT(*), SUM(played_seconds)
+        FROM "song_history"
+        WHERE "song_history".client_id = ? AND
+            "song_history".guild_id = ? AND
+            "song_history".started_at >= ?
+        GROUP BY url
+        ORDER BY COUNT(*) DESC, SUM(played_seconds) DESC, url
+        LIMIT ?;
+        `,
+		clientID,
+		guildID,
+		since.UTC(),
+		limit,
+	)
+	if err != nil {
+		store.log.Tracef("[H%d]Error: %v", i, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	songs := make([]*model.SongStats, 0)
+	for rows.Next() {
+		song := &model.SongStats{}
+		if err := rows.Scan(
+			&song.ShortName, &song.Url,
+			&song.PlayCount, &song.PlayedSeconds,
+		); err != nil {
+			store.log.Tracef("[H%d]Error: %v", i, err)
+			return nil, err
+		}
+		songs = append(songs, song)
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[H%d]Done : %d top songs fetched", i, len(songs))
+	return songs, nil
+}
+
+// GetTopRequesters returns the users, whose requested songs have been
+// played the most in the guild identified by the provided clientID
+// and guildID since the provided time.
+func (store *SqliteHistoryStore) GetTopRequesters(ctx context.Context, clientID string, guildID string, since time.Time, limit int) ([]*model.RequesterStats, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetTopRequesters")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+		"Since":    since,
+	}).Tracef("[H%d]Start: Fetch %d top requesters", i, limit)
+
+	rows, err := store.db.QueryContext(
+		ctx,
+		`
+        SELECT requester_id, COUNT(*), SUM(played_seconds)
+        FROM "song_history"
+        WHERE "song_history".client_id = ? AND
+            "song_history".guild_id = ? AND
+            "song_history".started_at >= ? AND
+            "song_history".requester_id <> ''
+        GROUP BY requester_id
+        ORDER BY COUNT(*) DESC, SUM(played_seconds) DESC, requester_id
+        LIMIT ?;
+        `,
+		clientID,
+		guildID,
+		since.UTC(),
+		limit,
+	)
+	if err != nil {
+		store.log.Tracef("[H%d]Error: %v", i, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	requesters := make([]*model.RequesterStats, 0)
+	for rows.Next() {
+		requester := &model.RequesterStats{}
+		if err := rows.Scan(
+			&requester.RequesterID,
+			&requester.PlayCount, &requester.PlayedSeconds,
+		); err != nil {
+			store.log.Tracef("[H%d]Error: %v", i, err)
+			return nil, err
+		}
+		requesters = append(requesters, requester)
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[H%d]Done : %d top requesters fetched", i, len(requesters))
+	return requesters, nil
+}
+
+// GetBusiestHours returns the hours of the day (UTC) in which the most
+// songs started playing in the guild identified by the provided
+// clientID and guildID since the provided time.
+func (store *SqliteHistoryStore) GetBusiestHours(ctx context.Context, clientID string, guildID string, since time.Time, limit int) ([]*model.HourStats, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetBusiestHours")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+		"Since":    since,
+	}).Tracef("[H%d]Start: Fetch %d busiest hours", i, limit)
+
+	rows, err := store.db.QueryContext(
+		ctx,
+		`
+        SELECT CAST(strftime('%H', started_at) AS INTEGER) AS hour,
+            COUNT(*)
+        FROM "song_history"
+        WHERE "song_history".client_id = ? AND
+            "song_history".guild_id = ? AND
+            "song_history".started_at >= ?
+        GROUP BY hour
+        ORDER BY COUNT(*) DESC, hour
+        LIMIT ?;
+        `,
+		clientID,
+		guildID,
+		since.UTC(),
+		limit,
+	)
+	if err != nil {
+		store.log.Tracef("[H%d]Error: %v", i, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	hours := make([]*model.HourStats, 0)
+	for rows.Next() {
+		hour := &model.HourStats{}
+		if err := rows.Scan(&hour.Hour, &hour.PlayCount); err != nil {
+			store.log.Tracef("[H%d]Error: %v", i, err)
+			return nil, err
+		}
+		hours = append(hours, hour)
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[H%d]Done : %d busiest hours fetched", i, len(hours))
+	return hours, nil
+}
@@ -0,0 +1,260 @@
+package history_test
+
+import (
+	"context"
+	"database/sql"
+	"discord-music-bot/datastore/history"
+	"discord-music-bot/datastore/migration"
+	"discord-music-bot/datastore/sqldb"
+	"discord-music-bot/model"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// ctx is the context passed to the repositories' calls.
+var ctx = context.Background()
+
+type HistoryStoreTestSuite struct {
+	driver   string
+	db       *sql.DB
+	migrator *migration.Migrator
+	store    history.HistoryRepository
+	suite.Suite
+}
+
+// SetupSuite runs when the suite is initialized and
+// connects to the suite's database and initialized the history store.
+func (s *HistoryStoreTestSuite) SetupSuite() {
+	db, err := openDatabase(s.driver, s.T().TempDir())
+	s.NoError(err)
+
+	s.db = db
+	if s.driver == "sqlite3" {
+		s.migrator = migration.NewMigrator(db, logrus.StandardLogger(), migration.Sqlite)
+		s.store = history.NewSqliteHistoryStore(db, logrus.StandardLogger(), sqldb.Options{})
+	} else {
+		s.migrator = migration.NewMigrator(db, logrus.StandardLogger(), migration.Postgres)
+		s.store = history.NewHistoryStore(db, logrus.StandardLogger(), sqldb.Options{})
+	}
+}
+
+// SetupTest runs before every test and recreates the
+// store's tables by reverting and reapplying the migrations.
+func (s *HistoryStoreTestSuite) SetupTest() {
+	err := s.migrator.Reset()
+	s.NoError(err)
+}
+
+// TearDownSuite runs after all tests have been run, reverts
+// all the migrations and closes database connection.
+func (s *HistoryStoreTestSuite) TearDownSuite() {
+	_, err := s.migrator.Down(len(s.migrator.Migrations()))
+	s.NoError(err)
+
+	err = s.db.Close()
+	s.NoError(err)
+}
+
+// TestIntegrationHistoryEntries persists history entries, then
+// fetches them and checks their order and fields.
+func (s *HistoryStoreTestSuite) TestIntegrationHistoryEntries() {
+	started := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 1; i <= 7; i++ {
+		err := s.store.PersistHistoryEntry(ctx, &model.HistoryEntry{
+			ClientID:        "CLIENT-ID-TEST",
+			GuildID:         "GUILD-ID-TEST",
+			RequesterID:     fmt.Sprintf("USER-ID-TEST%d", i),
+			Name:            fmt.Sprintf("Song%d", i),
+			ShortName:       fmt.Sprintf("Song%d", i),
+			Url:             fmt.Sprintf("SongUrl%d", i),
+			DurationSeconds: 10,
+			DurationString:  "00:10",
+			StartedAt:       started.Add(time.Duration(i) * time.Minute),
+			PlayedSeconds:   i,
+		})
+		s.NoError(err)
+	}
+	// Entries of another guild should not be fetched
+	err := s.store.PersistHistoryEntry(ctx, &model.HistoryEntry{
+		ClientID:        "CLIENT-ID-TEST2",
+		GuildID:         "GUILD-ID-TEST2",
+		Name:            "Song8",
+		ShortName:       "Song8",
+		Url:             "SongUrl8",
+		DurationSeconds: 10,
+		DurationString:  "00:10",
+		StartedAt:       started,
+	})
+	s.NoError(err)
+
+	s.Equal(7, s.store.GetHistorySize(ctx, "CLIENT-ID-TEST", "GUILD-ID-TEST"))
+	s.Equal(1, s.store.GetHistorySize(ctx, "CLIENT-ID-TEST2", "GUILD-ID-TEST2"))
+
+	// The most recently started entries should be fetched first
+	entries, err := s.store.GetHistoryEntries(
+		ctx,
+		"CLIENT-ID-TEST",
+		"GUILD-ID-TEST",
+		0, 5,
+	)
+	s.NoError(err)
+	s.Len(entries, 5)
+	s.Equal("Song7", entries[0].Name)
+	s.Equal("Song3", entries[4].Name)
+	s.Equal("USER-ID-TEST7", entries[0].RequesterID)
+	s.Equal(7, entries[0].PlayedSeconds)
+
+	history, err := s.store.UpdateHistoryWithEntries(ctx, &model.History{
+		ClientID: "CLIENT-ID-TEST",
+		GuildID:  "GUILD-ID-TEST",
+		Offset:   5,
+		Limit:    5,
+	})
+	s.NoError(err)
+	s.Equal(7, history.Size)
+	s.Len(history.Entries, 2)
+	s.Equal("Song2", history.Entries[0].Name)
+	s.Equal("Song1", history.Entries[1].Name)
+
+	entry, err := s.store.GetHistoryEntry(
+		ctx,
+		"CLIENT-ID-TEST",
+		"GUILD-ID-TEST",
+		history.Entries[0].ID,
+	)
+	s.NoError(err)
+	s.Equal("Song2", entry.Name)
+	s.Equal("SongUrl2", entry.Url)
+	s.True(entry.StartedAt.Equal(started.Add(2 * time.Minute)))
+
+	// Should not fetch entries of other guilds by ID
+	_, err = s.store.GetHistoryEntry(
+		ctx,
+		"CLIENT-ID-TEST2",
+		"GUILD-ID-TEST2",
+		history.Entries[0].ID,
+	)
+	s.Error(err)
+}
+
+// TestIntegrationStats persists history entries, then computes
+// statistics for different periods and checks the aggregates.
+func (s *HistoryStoreTestSuite) TestIntegrationStats() {
+	now := time.Now().UTC().Truncate(time.Hour)
+	entries := []*model.HistoryEntry{
+		{RequesterID: "USER-ID-TEST1", Url: "SongUrl1", StartedAt: now.Add(-1 * time.Hour), PlayedSeconds: 100},
+		{RequesterID: "USER-ID-TEST1", Url: "SongUrl1", StartedAt: now.Add(-2 * time.Hour), PlayedSeconds: 100},
+		{RequesterID: "USER-ID-TEST2", Url: "SongUrl1", StartedAt: now.Add(-25 * time.Hour), PlayedSeconds: 50},
+		{RequesterID: "USER-ID-TEST2", Url: "SongUrl2", StartedAt: now.Add(-25 * time.Hour), PlayedSeconds: 200},
+		{RequesterID: "", Url: "SongUrl3", StartedAt: now.Add(-24 * 20 * time.Hour), PlayedSeconds: 300},
+		{RequesterID: "USER-ID-TEST3", Url: "SongUrl3", StartedAt: now.Add(-24 * 60 * time.Hour), PlayedSeconds: 400},
+	}
+	for _, e := range entries {
+		e.ClientID = "CLIENT-ID-TEST"
+		e.GuildID = "GUILD-ID-TEST"
+		e.Name = "Song" + e.Url
+		e.ShortName = "Song" + e.Url
+		e.DurationString = "10:00"
+		e.DurationSeconds = 600
+		s.NoError(s.store.PersistHistoryEntry(ctx, e))
+	}
+
+	// Last 7 days
+	stats, err := s.store.UpdateStats(ctx, &model.Stats{
+		ClientID: "CLIENT-ID-TEST",
+		GuildID:  "GUILD-ID-TEST",
+		Since:    now.AddDate(0, 0, -7),
+		Limit:    5,
+	})
+	s.NoError(err)
+	s.Equal(4, stats.PlayCount)
+	s.Equal(450, stats.TotalPlayedSeconds)
+	s.Len(stats.TopSongs, 2)
+	s.Equal("SongUrl1", stats.TopSongs[0].Url)
+	s.Equal(3, stats.TopSongs[0].PlayCount)
+	s.Equal(250, stats.TopSongs[0].PlayedSeconds)
+	s.Len(stats.TopRequesters, 2)
+	// Both requesters have 2 songs, the one with more played seconds first
+	s.Equal("USER-ID-TEST2", stats.TopRequesters[0].RequesterID)
+	s.Equal(250, stats.TopRequesters[0].PlayedSeconds)
+	s.Equal("USER-ID-TEST1", stats.TopRequesters[1].RequesterID)
+	// -1h and -25h are in the same hour of the day
+	s.Equal(now.Add(-1*time.Hour).Hour(), stats.BusiestHours[0].Hour)
+	s.Equal(3, stats.BusiestHours[0].PlayCount)
+	s.Equal(1, stats.BusiestHours[1].PlayCount)
+
+	// Last 30 days, songs without requester are not in the top requesters
+	stats, err = s.store.UpdateStats(ctx, &model.Stats{
+		ClientID: "CLIENT-ID-TEST",
+		GuildID:  "GUILD-ID-TEST",
+		Since:    now.AddDate(0, 0, -30),
+		Limit:    5,
+	})
+	s.NoError(err)
+	s.Equal(5, stats.PlayCount)
+	s.Equal(750, stats.TotalPlayedSeconds)
+	s.Len(stats.TopSongs, 3)
+	s.Len(stats.TopRequesters, 2)
+
+	// All time, limited to a single result
+	stats, err = s.store.UpdateStats(ctx, &model.Stats{
+		ClientID: "CLIENT-ID-TEST",
+		GuildID:  "GUILD-ID-TEST",
+		Since:    time.Time{},
+		Limit:    1,
+	})
+	s.NoError(err)
+	s.Equal(6, stats.PlayCount)
+	s.Equal(1150, stats.TotalPlayedSeconds)
+	s.Len(stats.TopSongs, 1)
+	s.Equal("SongUrl1", stats.TopSongs[0].Url)
+	s.Len(stats.TopRequesters, 1)
+	s.Len(stats.BusiestHours, 1)
+
+	// Other guilds have no statistics
+	stats, err = s.store.UpdateStats(ctx, &model.Stats{
+		ClientID: "CLIENT-ID-TEST2",
+		GuildID:  "GUILD-ID-TEST2",
+		Since:    time.Time{},
+		Limit:    5,
+	})
+	s.NoError(err)
+	s.Equal(0, stats.PlayCount)
+	s.Len(stats.TopSongs, 0)
+}
+
+// TestHistoryStorageTestSuite runs all tests under
+// the HistoryStoreTestSuite suite against the postgres database.
+func TestHistoryStorageTestSuite(t *testing.T) {
+	suite.Run(t, &HistoryStoreTestSuite{driver: "postgres"})
+}
+
+// TestSqliteHistoryStorageTestSuite runs all tests under
+// the HistoryStoreTestSuite suite against the sqlite database.
+func TestSqliteHistoryStorageTestSuite(t *testing.T) {
+	suite.Run(t, &HistoryStoreTestSuite{driver: "sqlite3"})
+}
+
+// openDatabase opens the test database for the provided driver.
+// The sqlite database is created in the provided directory.
+func openDatabase(driver string, dir string) (*sql.DB, error) {
+	if driver == "sqlite3" {
+		return sql.Open(
+			"sqlite3",
+			"file:"+filepath.Join(dir, "discord_bot_test.db")+
+				"?_foreign_keys=on",
+		)
+	}
+	return sql.Open(
+		"postgres",
+		"host=postgres port=5432 user=postgres password=postgres "+
+			"dbname=discord_bot_test sslmode=disable",
+	)
+}
@@ -0,0 +1,199 @@
+package memory
+
+import (
+	"discord-music-bot/model"
+	"sync"
+	"time"
+)
+
+// QueueKey identifies a queue and all the rows that belong to it.
+type QueueKey struct {
+	ClientID string
+	GuildID  string
+}
+
+// InactiveSong is a song removed from the queue, together
+// with the time it has been removed at.
+type InactiveSong struct {
+	Song  *model.Song
+	Added time.Time
+}
+
+// DB holds all the in-memory tables shared between the
+// in-memory stores. The stores must lock the DB before
+// accessing any of it's tables.
+type DB struct {
+	*tables
+	tx            *sync.Mutex
+	transactional bool
+}
+
+// tables are the in-memory tables, shared between the
+// DB and it's transactional handle.
+type tables struct {
+	sync.Mutex
+	Queues         map[QueueKey]*model.Queue
+	QueueOptions   map[QueueKey][]*model.QueueOption
+	Songs          map[QueueKey][]*model.Song
+	InactiveSongs  map[QueueKey][]*InactiveSong
+	HistoryEntries []*model.HistoryEntry
+	// NOTE: the guilds' settings are not removed with the
+	// queue, they are only identified by the same key
+	GuildSettings map[QueueKey]map[string]string
+	sequences     map[string]uint
+}
+
+// NewDB constructs an empty in-memory database, that
+// is used by the in-memory stores in place of the sql.DB.
+func NewDB() *DB {
+	return &DB{
+		tables: &tables{
+			Queues:         make(map[QueueKey]*model.Queue),
+			QueueOptions:   make(map[QueueKey][]*model.QueueOption),
+			Songs:          make(map[QueueKey][]*model.Song),
+			InactiveSongs:  make(map[QueueKey][]*InactiveSong),
+			HistoryEntries: make([]*model.HistoryEntry, 0),
+			GuildSettings:  make(map[QueueKey]map[string]string),
+			sequences:      make(map[string]uint),
+		},
+		tx: &sync.Mutex{},
+	}
+}
+
+// Transactional returns a handle of the database, that shares all of
+// it's tables, for the stores used in the transactions' functions.
+// Locking the handle does not wait for the running transaction.
+func (db *DB) Transactional() *DB {
+	return &DB{
+		tables:        db.tables,
+		tx:            db.tx,
+		transactional: true,
+	}
+}
+
+// Lock locks the database's tables. Unless the DB is a
+// transactional handle, this waits for the running transaction
+// to finish, so the changes made outside of the transaction
+// are never discarded when it fails.
+func (db *DB) Lock() {
+	if !db.transactional {
+		db.tx.Lock()
+	}
+	db.tables.Lock()
+}
+
+// Unlock unlocks the database's tables.
+func (db *DB) Unlock() {
+	db.tables.Unlock()
+	if !db.transactional {
+		db.tx.Unlock()
+	}
+}
+
+// NextID returns the next ID in the sequence of the table
+// with the provided name. The first returned ID is 1.
+// NOTE: the DB should be locked when calling this.
+func (db *DB) NextID(table string) uint {
+	db.sequences[table]++
+	return db.sequences[table]
+}
+
+// Reset removes all the rows from all the tables and resets
+// the sequences, leaving an empty database.
+func (db *DB) Reset() {
+	db.Lock()
+	defer db.Unlock()
+
+	db.Queues = make(map[QueueKey]*model.Queue)
+	db.QueueOptions = make(map[QueueKey][]*model.QueueOption)
+	db.Songs = make(map[QueueKey][]*model.Song)
+	db.InactiveSongs = make(map[QueueKey][]*InactiveSong)
+	db.HistoryEntries = make([]*model.HistoryEntry, 0)
+	db.GuildSettings = make(map[QueueKey]map[string]string)
+	db.sequences = make(map[string]uint)
+}
+
+// Transaction runs the provided function, discarding all the changes
+// made to the database if it returns an error. The transactions
+// run one after another, and the changes outside of them wait
+// for the running transaction to finish.
+// NOTE: the function should access the database only through the
+// stores created with the DB's Transactional handle, the DB should
+// not be locked when calling this.
+func (db *DB) Transaction(fn func() error) error {
+	db.tx.Lock()
+	defer db.tx.Unlock()
+
+	db.tables.Lock()
+	snapshot := db.copy()
+	db.tables.Unlock()
+
+	if err := fn(); err != nil {
+		db.tables.Lock()
+		db.Queues = snapshot.Queues
+		db.QueueOptions = snapshot.QueueOptions
+		db.Songs = snapshot.Songs
+		db.InactiveSongs = snapshot.InactiveSongs
+		db.HistoryEntries = snapshot.HistoryEntries
+		db.GuildSettings = snapshot.GuildSettings
+		db.sequences = snapshot.sequences
+		db.tables.Unlock()
+		return err
+	}
+	return nil
+}
+
+// copy returns a deep copy of all the tables in the database.
+// NOTE: the DB should be locked when calling this.
+func (db *DB) copy() *DB {
+	c := NewDB()
+	for key, queue := range db.Queues {
+		q := *queue
+		c.Queues[key] = &q
+	}
+	for key, options := range db.QueueOptions {
+		for _, option := range options {
+			o := *option
+			c.QueueOptions[key] = append(c.QueueOptions[key], &o)
+		}
+	}
+	for key, songs := range db.Songs {
+		for _, song := range songs {
+			s := *song
+			c.Songs[key] = append(c.Songs[key], &s)
+		}
+	}
+	for key, songs := range db.InactiveSongs {
+		for _, inactive := range songs {
+			s := *inactive.Song
+			c.InactiveSongs[key] = append(
+				c.InactiveSongs[key],
+				&InactiveSong{Song: &s, Added: inactive.Added},
+			)
+		}
+	}
+	for _, entry := range db.HistoryEntries {
+		e := *entry
+		c.HistoryEntries = append(c.HistoryEntries, &e)
+	}
+	for key, settings := range db.GuildSettings {
+		c.GuildSettings[key] = make(map[string]string)
+		for name, value := range settings {
+			c.GuildSettings[key][name] = value
+		}
+	}
+	for table, id := range db.sequences {
+		c.sequences[table] = id
+	}
+	return c
+}
+
+// RemoveQueue removes the queue identified by the provided key
+// and cascades the removal to it's options, songs and inactive songs.
+// NOTE: the DB should be locked when calling this.
+func (db *DB) RemoveQueue(key QueueKey) {
+	delete(db.Queues, key)
+	delete(db.QueueOptions, key)
+	delete(db.Songs, key)
+	delete(db.InactiveSongs, key)
+}
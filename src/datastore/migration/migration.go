@@ -0,0 +1,299 @@
+package migration
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Migration is a single versioned change of the datastore's
+// schema. Up applies the change and Down reverts it.
+type Migration struct {
+	Version     uint
+	Description string
+	Up          string
+	Down        string
+}
+
+// Status describes whether a migration has been applied
+// to the datastore and when.
+type Status struct {
+	Version     uint
+	Description string
+	Applied     bool
+	AppliedAt   time.Time
+}
+
+type Migrator struct {
+	log        *log.Logger
+	db         *sql.DB
+	migrations []*Migration
+	idx        int
+}
+
+// NewMigrator creates an object that applies and reverts the
+// provided migrations, and keeps track of the applied ones in
+// the "schema_version" table.
+func NewMigrator(db *sql.DB, log *log.Logger, migrations []*Migration) *Migrator {
+	sorted := make([]*Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	return &Migrator{
+		log:        log,
+		db:         db,
+		migrations: sorted,
+		idx:        0,
+	}
+}
+
+// Migrations returns the migrations known to the migrator,
+// ordered by their version.
+func (migrator *Migrator) Migrations() []*Migration {
+	return migrator.migrations
+}
+
+// Version returns the version of the last applied migration,
+// or 0 if no migrations have been applied yet.
+func (migrator *Migrator) Version() (uint, error) {
+	if err := migrator.createSchemaVersionTable(); err != nil {
+		return 0, err
+	}
+	var version uint
+	err := migrator.db.QueryRow(
+		`SELECT COALESCE(MAX(version), 0) FROM "schema_version"`,
+	).Scan(&version)
+	return version, err
+}
+
+// Up applies all the pending migrations in a single transaction,
+// so either all of them are applied or none. Returns the number
+// of applied migrations.
+func (migrator *Migrator) Up() (int, error) {
+	i, t := migrator.idx, time.Now()
+	migrator.idx++
+
+	migrator.log.Tracef("[M%d]Start: Apply pending migrations", i)
+
+	if err := migrator.createSchemaVersionTable(); err != nil {
+		migrator.log.Tracef("[M%d]Error: %v", i, err)
+		return 0, err
+	}
+	tx, err := migrator.db.Begin()
+	if err != nil {
+		migrator.log.Tracef("[M%d]Error: %v", i, err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	applied, err := migrator.appliedVersions(tx)
+	if err != nil {
+		migrator.log.Tracef("[M%d]Error: %v", i, err)
+		return 0, err
+	}
+	count := 0
+	for _, m := range migrator.migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		migrator.log.WithField("Version", m.Version).Debugf(
+			"Applying migration: %s", m.Description,
+		)
+		if _, err := tx.Exec(m.Up); err != nil {
+			err = fmt.Errorf("migration %d: %v", m.Version, err)
+			migrator.log.Tracef("[M%d]Error: %v", i, err)
+			return 0, err
+		}
+		if _, err := tx.Exec(
+			`
+            INSERT INTO "schema_version" (version, description)
+            VALUES ($1, $2);
+            `,
+			m.Version,
+			m.Description,
+		); err != nil {
+			migrator.log.Tracef("[M%d]Error: %v", i, err)
+			return 0, err
+		}
+		count++
+	}
+	if err := tx.Commit(); err != nil {
+		migrator.log.Tracef("[M%d]Error: %v", i, err)
+		return 0, err
+	}
+	migrator.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[M%d]Done : %d migrations applied", i, count)
+	return count, nil
+}
+
+// Down reverts the provided number of the most recently applied
+// migrations in a single transaction. Returns the number of
+// reverted migrations.
+func (migrator *Migrator) Down(steps int) (int, error) {
+	i, t := migrator.idx, time.Now()
+	migrator.idx++
+
+	migrator.log.Tracef("[M%d]Start: Revert %d migrations", i, steps)
+
+	if err := migrator.createSchemaVersionTable(); err != nil {
+		migrator.log.Tracef("[M%d]Error: %v", i, err)
+		return 0, err
+	}
+	tx, err := migrator.db.Begin()
+	if err != nil {
+		migrator.log.Tracef("[M%d]Error: %v", i, err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	applied, err := migrator.appliedVersions(tx)
+	if err != nil {
+		migrator.log.Tracef("[M%d]Error: %v", i, err)
+		return 0, err
+	}
+	versions := make([]uint, 0)
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] > versions[j]
+	})
+	count := 0
+	for _, v := range versions {
+		if count >= steps {
+			break
+		}
+		m := migrator.find(v)
+		if m == nil {
+			err := fmt.Errorf("migration %d: unknown migration", v)
+			migrator.log.Tracef("[M%d]Error: %v", i, err)
+			return 0, err
+		}
+		migrator.log.WithField("Version", m.Version).Debugf(
+			"Reverting migration: %s", m.Description,
+		)
+		if _, err := tx.Exec(m.Down); err != nil {
+			err = fmt.Errorf("migration %d: %v", m.Version, err)
+			migrator.log.Tracef("[M%d]Error: %v", i, err)
+			return 0, err
+		}
+		if _, err := tx.Exec(
+			`DELETE FROM "schema_version" WHERE version = $1`,
+			m.Version,
+		); err != nil {
+			migrator.log.Tracef("[M%d]Error: %v", i, err)
+			return 0, err
+		}
+		count++
+	}
+	if err := tx.Commit(); err != nil {
+		migrator.log.Tracef("[M%d]Error: %v", i, err)
+		return 0, err
+	}
+	migrator.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[M%d]Done : %d migrations reverted", i, count)
+	return count, nil
+}
+
+// Reset reverts all the applied migrations and then applies
+// all of them again, leaving an empty datastore.
+func (migrator *Migrator) Reset() error {
+	if _, err := migrator.Down(len(migrator.migrations)); err != nil {
+		return err
+	}
+	_, err := migrator.Up()
+	return err
+}
+
+// Status returns the status of all the known migrations,
+// ordered by their version.
+func (migrator *Migrator) Status() ([]*Status, error) {
+	if err := migrator.createSchemaVersionTable(); err != nil {
+		return nil, err
+	}
+	rows, err := migrator.db.Query(
+		`SELECT version, applied_at FROM "schema_version"`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[uint]time.Time)
+	for rows.Next() {
+		var version uint
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*Status, 0)
+	for _, m := range migrator.migrations {
+		at, ok := appliedAt[m.Version]
+		statuses = append(statuses, &Status{
+			Version:     m.Version,
+			Description: m.Description,
+			Applied:     ok,
+			AppliedAt:   at,
+		})
+	}
+	return statuses, nil
+}
+
+// appliedVersions returns the versions of all the migrations
+// that have already been applied.
+func (migrator *Migrator) appliedVersions(tx *sql.Tx) (map[uint]struct{}, error) {
+	rows, err := tx.Query(`SELECT version FROM "schema_version"`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[uint]struct{})
+	for rows.Next() {
+		var version uint
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = struct{}{}
+	}
+	return applied, rows.Err()
+}
+
+// find returns the known migration with the provided
+// version, or nil if there is no such migration.
+func (migrator *Migrator) find(version uint) *Migration {
+	for _, m := range migrator.migrations {
+		if m.Version == version {
+			return m
+		}
+	}
+	return nil
+}
+
+// createSchemaVersionTable creates the "schema_version" table,
+// that holds the applied migrations, if it does not already exist.
+func (migrator *Migrator) createSchemaVersionTable() error {
+	_, err := migrator.db.Exec(
+		`
+        CREATE TABLE IF NOT EXISTS "schema_version" (
+            version INTEGER NOT NULL,
+            description VARCHAR NOT NULL,
+            applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (version)
+        );
+        `,
+	)
+	return err
+}
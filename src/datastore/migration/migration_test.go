@@ -0,0 +1,136 @@
+package migration_test
+
+import (
+	"database/sql"
+	"discord-music-bot/datastore/migration"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+type MigratorTestSuite struct {
+	db *sql.DB
+	suite.Suite
+}
+
+// SetupTest runs before every test and opens
+// a new empty sqlite database.
+func (s *MigratorTestSuite) SetupTest() {
+	db, err := sql.Open(
+		"sqlite3",
+		"file:"+filepath.Join(s.T().TempDir(), "discord_bot_test.db")+
+			"?_foreign_keys=on",
+	)
+	s.NoError(err)
+	s.db = db
+}
+
+// TearDownTest runs after every test and closes
+// the database connection.
+func (s *MigratorTestSuite) TearDownTest() {
+	s.NoError(s.db.Close())
+}
+
+// TestUpDown applies all the migrations, then reverts
+// them one by one and checks the migrations' status.
+func (s *MigratorTestSuite) TestUpDown() {
+	migrator := migration.NewMigrator(
+		s.db,
+		logrus.StandardLogger(),
+		migration.Sqlite,
+	)
+	version, err := migrator.Version()
+	s.NoError(err)
+	s.Equal(uint(0), version)
+
+	count, err := migrator.Up()
+	s.NoError(err)
+	s.Equal(len(migration.Sqlite), count)
+
+	// Applying the migrations again should have no effect
+	count, err = migrator.Up()
+	s.NoError(err)
+	s.Equal(0, count)
+
+	version, err = migrator.Version()
+	s.NoError(err)
+	s.Equal(uint(len(migration.Sqlite)), version)
+
+	statuses, err := migrator.Status()
+	s.NoError(err)
+	s.Len(statuses, len(migration.Sqlite))
+	for _, status := range statuses {
+		s.True(status.Applied)
+		s.False(status.AppliedAt.IsZero())
+	}
+
+	count, err = migrator.Down(1)
+	s.NoError(err)
+	s.Equal(1, count)
+
+	statuses, err = migrator.Status()
+	s.NoError(err)
+	s.False(statuses[len(statuses)-1].Applied)
+	s.True(statuses[0].Applied)
+
+	// Reverting more migrations than applied should
+	// revert only the applied ones
+	count, err = migrator.Down(len(migration.Sqlite) + 1)
+	s.NoError(err)
+	s.Equal(len(migration.Sqlite)-1, count)
+
+	version, err = migrator.Version()
+	s.NoError(err)
+	s.Equal(uint(0), version)
+
+	// The tables should have been dropped
+	var name string
+	err = s.db.QueryRow(
+		`SELECT name FROM sqlite_master WHERE name = 'queue'`,
+	).Scan(&name)
+	s.Equal(sql.ErrNoRows, err)
+}
+
+// TestUpInTransaction checks that a failing migration
+// rolls back all the migrations applied together with it.
+func (s *MigratorTestSuite) TestUpInTransaction() {
+	migrator := migration.NewMigrator(
+		s.db,
+		logrus.StandardLogger(),
+		[]*migration.Migration{
+			{
+				Version:     2,
+				Description: "Invalid migration",
+				Up:          `INSERT INTO "missing_table" VALUES (1);`,
+				Down:        ``,
+			},
+			{
+				Version:     1,
+				Description: "Create a table",
+				Up:          `CREATE TABLE "test_table" (id INTEGER);`,
+				Down:        `DROP TABLE "test_table";`,
+			},
+		},
+	)
+	_, err := migrator.Up()
+	s.Error(err)
+
+	version, err := migrator.Version()
+	s.NoError(err)
+	s.Equal(uint(0), version)
+
+	var name string
+	err = s.db.QueryRow(
+		`SELECT name FROM sqlite_master WHERE name = 'test_table'`,
+	).Scan(&name)
+	s.Equal(sql.ErrNoRows, err)
+}
+
+// TestMigratorTestSuite runs all tests under
+// the MigratorTestSuite suite.
+func TestMigratorTestSuite(t *testing.T) {
+	suite.Run(t, new(MigratorTestSuite))
+}
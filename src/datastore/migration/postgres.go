@@ -0,0 +1,175 @@
+package migration
+
+// Postgres holds the migrations of the postgres datastore.
+// NOTE: the first migrations create the tables only if they do
+// not exist, so that databases created before the migrations
+// were introduced are adopted without losing any data.
+// New migrations should be appended with increasing versions
+// and never modified once released.
+var Postgres = []*Migration{
+	{
+		Version:     1,
+		Description: "Create the queue and queue_option tables",
+		Up: `
+        CREATE TABLE IF NOT EXISTS "queue" (
+            client_id VARCHAR,
+            guild_id VARCHAR,
+            message_id VARCHAR NOT NULL,
+            channel_id VARCHAR NOT NULL,
+            "offset" INTEGER NOT NULL DEFAULT '0',
+            "limit" INTEGER NOT NULL DEFAULT '10',
+            UNIQUE (client_id, guild_id),
+            PRIMARY KEY (client_id, guild_id)
+        );
+
+        CREATE TABLE IF NOT EXISTS "queue_option" (
+            name VARCHAR,
+            queue_client_id VARCHAR,
+            queue_guild_id VARCHAR,
+            PRIMARY KEY (name, queue_client_id, queue_guild_id),
+            FOREIGN KEY (queue_client_id, queue_guild_id)
+                REFERENCES "queue" (client_id, guild_id)
+                    ON DELETE CASCADE
+        );
+        `,
+		Down: `
+        DROP TABLE IF EXISTS "queue_option";
+        DROP TABLE IF EXISTS "queue";
+        `,
+	},
+	{
+		Version:     2,
+		Description: "Create the song and inactive_song tables",
+		Up: `
+        CREATE TABLE IF NOT EXISTS "song" (
+            id SERIAL,
+            position INTEGER NOT NULL DEFAULT '0',
+            name VARCHAR NOT NULL,
+            short_name VARCHAR NOT NULL,
+            url VARCHAR NOT NULL,
+            duration_seconds INTEGER NOT NULL,
+            duration_string VARCHAR NOT NULL,
+            color INTEGER NOT NULL,
+            queue_client_id VARCHAR,
+            queue_guild_id VARCHAR,
+            PRIMARY KEY (id),
+            CONSTRAINT "song_queue_fkey"
+                FOREIGN KEY (queue_client_id, queue_guild_id)
+                    REFERENCES "queue" (client_id, guild_id)
+                        ON DELETE CASCADE
+        );
+
+        CREATE TABLE IF NOT EXISTS "inactive_song" (
+            id SERIAL,
+            name VARCHAR NOT NULL,
+            short_name VARCHAR NOT NULL,
+            url VARCHAR NOT NULL,
+            duration_seconds INTEGER NOT NULL,
+            duration_string VARCHAR NOT NULL,
+            color INTEGER NOT NULL,
+            queue_client_id VARCHAR,
+            queue_guild_id VARCHAR,
+            added timestamp DEFAULT ((CURRENT_TIMESTAMP)),
+            PRIMARY KEY (id),
+            CONSTRAINT "inactive_song_queue_fkey"
+                FOREIGN KEY (queue_client_id, queue_guild_id)
+                    REFERENCES "queue" (client_id, guild_id)
+                        ON DELETE CASCADE
+        );
+        `,
+		Down: `
+        DROP TABLE IF EXISTS "inactive_song";
+        DROP TABLE IF EXISTS "song";
+        `,
+	},
+	{
+		Version:     3,
+		Description: "Add the requester_id column to the song tables",
+		Up: `
+        ALTER TABLE "song" ADD COLUMN
+            IF NOT EXISTS requester_id VARCHAR NOT NULL DEFAULT '';
+        ALTER TABLE "inactive_song" ADD COLUMN
+            IF NOT EXISTS requester_id VARCHAR NOT NULL DEFAULT '';
+        `,
+		Down: `
+        ALTER TABLE "inactive_song" DROP COLUMN IF EXISTS requester_id;
+        ALTER TABLE "song" DROP COLUMN IF EXISTS requester_id;
+        `,
+	},
+	{
+		Version:     4,
+		Description: "Create the song_history table",
+		Up: `
+        CREATE TABLE IF NOT EXISTS "song_history" (
+            id SERIAL,
+            client_id VARCHAR NOT NULL,
+            guild_id VARCHAR NOT NULL,
+            requester_id VARCHAR NOT NULL DEFAULT '',
+            name VARCHAR NOT NULL,
+            short_name VARCHAR NOT NULL,
+            url VARCHAR NOT NULL,
+            duration_seconds INTEGER NOT NULL,
+            duration_string VARCHAR NOT NULL,
+            started_at timestamp NOT NULL DEFAULT ((CURRENT_TIMESTAMP)),
+            played_seconds INTEGER NOT NULL DEFAULT '0',
+            PRIMARY KEY (id)
+        );
+
+        CREATE INDEX IF NOT EXISTS "song_history_guild_idx"
+            ON "song_history" (client_id, guild_id, started_at);
+        `,
+		Down: `
+        DROP TABLE IF EXISTS "song_history";
+        `,
+	},
+	{
+		Version:     5,
+		Description: "Add the playback resume columns to the queue table",
+		Up: `
+        ALTER TABLE "queue" ADD COLUMN IF NOT EXISTS
+            voice_channel_id VARCHAR NOT NULL DEFAULT '';
+        ALTER TABLE "queue" ADD COLUMN IF NOT EXISTS
+            playback_position INTEGER NOT NULL DEFAULT '0';
+        `,
+		Down: `
+        ALTER TABLE "queue" DROP COLUMN IF EXISTS playback_position;
+        ALTER TABLE "queue" DROP COLUMN IF EXISTS voice_channel_id;
+        `,
+	},
+	{
+		Version:     6,
+		Description: "Add the thumbnail and channel columns to the song tables",
+		Up: `
+        ALTER TABLE "song" ADD COLUMN
+            IF NOT EXISTS thumbnail_url VARCHAR NOT NULL DEFAULT '';
+        ALTER TABLE "song" ADD COLUMN
+            IF NOT EXISTS channel_name VARCHAR NOT NULL DEFAULT '';
+        ALTER TABLE "inactive_song" ADD COLUMN
+            IF NOT EXISTS thumbnail_url VARCHAR NOT NULL DEFAULT '';
+        ALTER TABLE "inactive_song" ADD COLUMN
+            IF NOT EXISTS channel_name VARCHAR NOT NULL DEFAULT '';
+        `,
+		Down: `
+        ALTER TABLE "inactive_song" DROP COLUMN IF EXISTS channel_name;
+        ALTER TABLE "inactive_song" DROP COLUMN IF EXISTS thumbnail_url;
+        ALTER TABLE "song" DROP COLUMN IF EXISTS channel_name;
+        ALTER TABLE "song" DROP COLUMN IF EXISTS thumbnail_url;
+        `,
+	},
+	{
+		Version:     7,
+		Description: "Create the guild_setting table",
+		Up: `
+        CREATE TABLE IF NOT EXISTS "guild_setting" (
+            client_id VARCHAR NOT NULL,
+            guild_id VARCHAR NOT NULL,
+            name VARCHAR NOT NULL,
+            value VARCHAR NOT NULL,
+            PRIMARY KEY (client_id, guild_id, name)
+        );
+        `,
+		Down: `
+        DROP TABLE IF EXISTS "guild_setting";
+        `,
+	},
+}
@@ -0,0 +1,145 @@
+package migration
+
+// Sqlite holds the migrations of the sqlite datastore.
+// NOTE: same as with postgres, the first migrations create the
+// tables only if they do not exist, so the databases created
+// before the migrations were introduced are adopted.
+// New migrations should be appended with increasing versions
+// and never modified once released.
+var Sqlite = []*Migration{
+	{
+		Version:     1,
+		Description: "Create the queue and queue_option tables",
+		Up: `
+        CREATE TABLE IF NOT EXISTS "queue" (
+            client_id VARCHAR,
+            guild_id VARCHAR,
+            message_id VARCHAR NOT NULL,
+            channel_id VARCHAR NOT NULL,
+            "offset" INTEGER NOT NULL DEFAULT '0',
+            "limit" INTEGER NOT NULL DEFAULT '10',
+            voice_channel_id VARCHAR NOT NULL DEFAULT '',
+            playback_position INTEGER NOT NULL DEFAULT '0',
+            PRIMARY KEY (client_id, guild_id)
+        );
+
+        CREATE TABLE IF NOT EXISTS "queue_option" (
+            name VARCHAR,
+            queue_client_id VARCHAR,
+            queue_guild_id VARCHAR,
+            PRIMARY KEY (name, queue_client_id, queue_guild_id),
+            FOREIGN KEY (queue_client_id, queue_guild_id)
+                REFERENCES "queue" (client_id, guild_id)
+                    ON DELETE CASCADE
+        );
+        `,
+		Down: `
+        DROP TABLE IF EXISTS "queue_option";
+        DROP TABLE IF EXISTS "queue";
+        `,
+	},
+	{
+		Version:     2,
+		Description: "Create the song and inactive_song tables",
+		Up: `
+        CREATE TABLE IF NOT EXISTS "song" (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            position INTEGER NOT NULL DEFAULT '0',
+            name VARCHAR NOT NULL,
+            short_name VARCHAR NOT NULL,
+            url VARCHAR NOT NULL,
+            duration_seconds INTEGER NOT NULL,
+            duration_string VARCHAR NOT NULL,
+            color INTEGER NOT NULL,
+            queue_client_id VARCHAR,
+            queue_guild_id VARCHAR,
+            requester_id VARCHAR NOT NULL DEFAULT '',
+            FOREIGN KEY (queue_client_id, queue_guild_id)
+                REFERENCES "queue" (client_id, guild_id)
+                    ON DELETE CASCADE
+        );
+
+        CREATE TABLE IF NOT EXISTS "inactive_song" (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            name VARCHAR NOT NULL,
+            short_name VARCHAR NOT NULL,
+            url VARCHAR NOT NULL,
+            duration_seconds INTEGER NOT NULL,
+            duration_string VARCHAR NOT NULL,
+            color INTEGER NOT NULL,
+            queue_client_id VARCHAR,
+            queue_guild_id VARCHAR,
+            added TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            requester_id VARCHAR NOT NULL DEFAULT '',
+            FOREIGN KEY (queue_client_id, queue_guild_id)
+                REFERENCES "queue" (client_id, guild_id)
+                    ON DELETE CASCADE
+        );
+        `,
+		Down: `
+        DROP TABLE IF EXISTS "inactive_song";
+        DROP TABLE IF EXISTS "song";
+        `,
+	},
+	{
+		Version:     3,
+		Description: "Create the song_history table",
+		Up: `
+        CREATE TABLE IF NOT EXISTS "song_history" (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            client_id VARCHAR NOT NULL,
+            guild_id VARCHAR NOT NULL,
+            requester_id VARCHAR NOT NULL DEFAULT '',
+            name VARCHAR NOT NULL,
+            short_name VARCHAR NOT NULL,
+            url VARCHAR NOT NULL,
+            duration_seconds INTEGER NOT NULL,
+            duration_string VARCHAR NOT NULL,
+            started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            played_seconds INTEGER NOT NULL DEFAULT '0'
+        );
+
+        CREATE INDEX IF NOT EXISTS "song_history_guild_idx"
+            ON "song_history" (client_id, guild_id, started_at);
+        `,
+		Down: `
+        DROP TABLE IF EXISTS "song_history";
+        `,
+	},
+	{
+		Version:     4,
+		Description: "Add the thumbnail and channel columns to the song tables",
+		Up: `
+        ALTER TABLE "song" ADD COLUMN
+            thumbnail_url VARCHAR NOT NULL DEFAULT '';
+        ALTER TABLE "song" ADD COLUMN
+            channel_name VARCHAR NOT NULL DEFAULT '';
+        ALTER TABLE "inactive_song" ADD COLUMN
+            thumbnail_url VARCHAR NOT NULL DEFAULT '';
+        ALTER TABLE "inactive_song" ADD COLUMN
+            channel_name VARCHAR NOT NULL DEFAULT '';
+        `,
+		Down: `
+        ALTER TABLE "inactive_song" DROP COLUMN channel_name;
+        ALTER TABLE "inactive_song" DROP COLUMN thumbnail_url;
+        ALTER TABLE "song" DROP COLUMN channel_name;
+        ALTER TABLE "song" DROP COLUMN thumbnail_url;
+        `,
+	},
+	{
+		Version:     5,
+		Description: "Create the guild_setting table",
+		Up: `
+        CREATE TABLE IF NOT EXISTS "guild_setting" (
+            client_id VARCHAR NOT NULL,
+            guild_id VARCHAR NOT NULL,
+            name VARCHAR NOT NULL,
+            value VARCHAR NOT NULL,
+            PRIMARY KEY (client_id, guild_id, name)
+        );
+        `,
+		Down: `
+        DROP TABLE IF EXISTS "guild_setting";
+        `,
+	},
+}
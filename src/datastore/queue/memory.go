@@ -0,0 +1,206 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"discord-music-bot/datastore/memory"
+	"discord-music-bot/model"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type MemoryQueueStore struct {
+	log *log.Logger
+	db  *memory.DB
+}
+
+// NewMemoryQueueStore creates an object that handles
+// persisting and removing Queues in an in-memory database.
+func NewMemoryQueueStore(db *memory.DB, log *log.Logger) *MemoryQueueStore {
+	return &MemoryQueueStore{
+		db:  db,
+		log: log,
+	}
+}
+
+// PersistQueue saves the provided queue.
+// Returns error if the queue,
+// identified by the same clientID and guildID, already exists.
+func (store *MemoryQueueStore) PersistQueue(ctx context.Context, queue *model.Queue) error {
+	store.db.Lock()
+
+	key := memory.QueueKey{ClientID: queue.ClientID, GuildID: queue.GuildID}
+	if _, ok := store.db.Queues[key]; ok {
+		store.db.Unlock()
+		return errors.New("memory: Queue already exists")
+	}
+	store.db.Queues[key] = copyQueue(queue)
+	store.db.Unlock()
+
+	return store.PersistQueueOptions(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+		queue.Options...,
+	)
+}
+
+// UpdateQueue updates the provided queue. This does not update
+// the queue's clientID or guildID.
+// NOTE: same as in postgres, updating a queue that
+// does not exist has no effect.
+func (store *MemoryQueueStore) UpdateQueue(ctx context.Context, queue *model.Queue) error {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: queue.ClientID, GuildID: queue.GuildID}
+	if _, ok := store.db.Queues[key]; !ok {
+		return nil
+	}
+	store.db.Queues[key] = copyQueue(queue)
+	return nil
+}
+
+// RemoveQueue removes the queue identified by the clientID and guildID
+// from the store, together with it's options and songs.
+func (store *MemoryQueueStore) RemoveQueue(ctx context.Context, clientID string, guildID string) error {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	store.db.RemoveQueue(memory.QueueKey{ClientID: clientID, GuildID: guildID})
+	return nil
+}
+
+// GetQueue fetches the queue identified by the provided clientID and guildID.
+// Returns error if no such queue exists.
+func (store *MemoryQueueStore) GetQueue(ctx context.Context, clientID string, guildID string) (*model.Queue, error) {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	queue, ok := store.db.Queues[key]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	queue = copyQueue(queue)
+	queue.Options = copyOptions(store.db.QueueOptions[key])
+	return queue, nil
+}
+
+// FindAllQueue returns all queues in the store.
+func (store *MemoryQueueStore) FindAllQueues(ctx context.Context) ([]*model.Queue, error) {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	queues := make([]*model.Queue, 0)
+	for _, queue := range store.db.Queues {
+		queues = append(queues, copyQueue(queue))
+	}
+	return queues, nil
+}
+
+// PersistQueueOptions adds all of the provided queue options to the
+// queue. Options with name equal to some other already
+// persisted option (for the same queue) are not persisted.
+func (store *MemoryQueueStore) PersistQueueOptions(ctx context.Context, clientID string, guildID string, options ...*model.QueueOption) error {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	if _, ok := store.db.Queues[key]; !ok {
+		return nil
+	}
+outerOptionsLoop:
+	for _, o := range options {
+		if o == nil {
+			continue
+		}
+		for _, existing := range store.db.QueueOptions[key] {
+			if existing.Name == o.Name {
+				continue outerOptionsLoop
+			}
+		}
+		store.db.QueueOptions[key] = append(
+			store.db.QueueOptions[key],
+			&model.QueueOption{Name: o.Name},
+		)
+	}
+	return nil
+}
+
+// RemoveQueueOptions removes all the provided options from
+// the queue identified by the provided clientID and guildID.
+// This does not throw error if no such option exists in the store.
+func (store *MemoryQueueStore) RemoveQueueOptions(ctx context.Context, clientID string, guildID string, options ...model.QueueOptionName) error {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	remaining := make([]*model.QueueOption, 0)
+outerOptionsLoop:
+	for _, existing := range store.db.QueueOptions[key] {
+		for _, name := range options {
+			if existing.Name == name {
+				continue outerOptionsLoop
+			}
+		}
+		remaining = append(remaining, existing)
+	}
+	store.db.QueueOptions[key] = remaining
+	return nil
+}
+
+// QueueHasOption checks whether the queue identified by the
+// provided clientID and guildID has the option with the provided name.
+func (store *MemoryQueueStore) QueueHasOption(ctx context.Context, clientID string, guildID string, name model.QueueOptionName) bool {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	for _, o := range store.db.QueueOptions[key] {
+		if o.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetOptionsForQueue returns all queue options that belong to
+// the queue identified by the provided clientID and guildID
+func (store *MemoryQueueStore) GetOptionsForQueue(ctx context.Context, clientID string, guildID string) ([]*model.QueueOption, error) {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	return copyOptions(store.db.QueueOptions[key]), nil
+}
+
+// LockQueue has no effect in the in-memory database, as
+// it runs the units of work one after another.
+func (store *MemoryQueueStore) LockQueue(ctx context.Context, clientID string, guildID string) error {
+	return nil
+}
+
+// copyQueue copies the queue's persisted fields, so the
+// stored queue is not modified together with the provided one.
+func copyQueue(queue *model.Queue) *model.Queue {
+	return &model.Queue{
+		ClientID:         queue.ClientID,
+		GuildID:          queue.GuildID,
+		MessageID:        queue.MessageID,
+		ChannelID:        queue.ChannelID,
+		Offset:           queue.Offset,
+		Limit:            queue.Limit,
+		VoiceChannelID:   queue.VoiceChannelID,
+		PlaybackPosition: queue.PlaybackPosition,
+	}
+}
+
+func copyOptions(options []*model.QueueOption) []*model.QueueOption {
+	copied := make([]*model.QueueOption, 0)
+	for _, o := range options {
+		copied = append(copied, &model.QueueOption{Name: o.Name})
+	}
+	return copied
+}
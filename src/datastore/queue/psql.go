@@ -1,7 +1,8 @@
 package queue
 
 import (
-	"database/sql"
+	"context"
+	"discord-music-bot/datastore/sqldb"
 	"discord-music-bot/model"
 	"fmt"
 	"time"
@@ -12,43 +13,27 @@ import (
 )
 
 type QueueStore struct {
-	log *log.Logger
-	db  *sql.DB
-	idx int
+	log   *log.Logger
+	db    sqldb.Querier
+	trace *sqldb.Tracer
 }
 
 // NewQueueStore creates an object that handles
 // persisting and removing Queues in postgres database.
-func NewQueueStore(db *sql.DB, log *log.Logger) *QueueStore {
+func NewQueueStore(db sqldb.Querier, log *log.Logger, options sqldb.Options) *QueueStore {
 	return &QueueStore{
-		db:  db,
-		log: log,
-		idx: 0,
+		db:    db,
+		log:   log,
+		trace: sqldb.NewTracer(log, "Q", options),
 	}
 }
 
-// Init creates the required tables for the Queue store.
-func (store *QueueStore) Init() error {
-	if err := store.createQueueTable(); err != nil {
-		return err
-	}
-	return store.createQueueOptionTable()
-}
-
-// Destroy drops the created tables for the Queue store.
-func (store *QueueStore) Destroy() error {
-	if err := store.dropQueueTable(); err != nil {
-		return err
-	}
-	return store.dropQueueOptionTable()
-}
-
 // PersistQueue saves the provided queue and returns the inserted queue.
 // Returns error if the queue,
 // identified by the same clientID and guildID, already exists.
-func (store *QueueStore) PersistQueue(queue *model.Queue) error {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *QueueStore) PersistQueue(ctx context.Context, queue *model.Queue) error {
+	i, t, ctx, done := store.trace.Start(ctx, "PersistQueue")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": queue.ClientID,
@@ -57,12 +42,14 @@ func (store *QueueStore) PersistQueue(queue *model.Queue) error {
 
 	newQueue := &model.Queue{}
 
-	if err := store.db.QueryRow(
+	if err := store.db.QueryRowContext(
+		ctx,
 		`
         INSERT INTO "queue" (
-            client_id, guild_id, message_id, channel_id, "offset", "limit"
+            client_id, guild_id, message_id, channel_id, "offset", "limit",
+            voice_channel_id, playback_position
         ) VALUES
-            ($1, $2, $3, $4, $5, $6)
+            ($1, $2, $3, $4, $5, $6, $7, $8)
         RETURNING *;
         `,
 		queue.ClientID,
@@ -71,11 +58,15 @@ func (store *QueueStore) PersistQueue(queue *model.Queue) error {
 		queue.ChannelID,
 		queue.Offset,
 		queue.Limit,
+		queue.VoiceChannelID,
+		queue.PlaybackPosition,
 	).Scan(
 		&newQueue.ClientID, &newQueue.GuildID,
 		&newQueue.MessageID, &newQueue.ChannelID,
 		&newQueue.Offset,
 		&newQueue.Limit,
+		&newQueue.VoiceChannelID,
+		&newQueue.PlaybackPosition,
 	); err != nil {
 		store.log.Tracef(
 			"[Q%d]Error: %v", i, err,
@@ -87,6 +78,7 @@ func (store *QueueStore) PersistQueue(queue *model.Queue) error {
 		).Tracef("[Q%d]Done : persisted the queue", i)
 
 		return store.PersistQueueOptions(
+			ctx,
 			queue.ClientID,
 			queue.GuildID,
 			queue.Options...,
@@ -97,22 +89,25 @@ func (store *QueueStore) PersistQueue(queue *model.Queue) error {
 // UpdateQueue updates the provided queue. This does not update
 // the queue's clientID or guildID.
 // Returns error if the queue does not exist in the databse.
-func (store *QueueStore) UpdateQueue(queue *model.Queue) error {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *QueueStore) UpdateQueue(ctx context.Context, queue *model.Queue) error {
+	i, t, ctx, done := store.trace.Start(ctx, "UpdateQueue")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": queue.ClientID,
 		"GuildID":  queue.GuildID,
 	}).Tracef("[Q%d]Start: Update queue", i)
 
-	if _, err := store.db.Exec(
+	if _, err := store.db.ExecContext(
+		ctx,
 		`
         UPDATE "queue"
         SET "offset" = $3,
             "limit" = $4,
             message_id = $5,
-            channel_id = $6
+            channel_id = $6,
+            voice_channel_id = $7,
+            playback_position = $8
         WHERE "queue".client_id = $1 AND
             "queue".guild_id = $2;
         `,
@@ -122,6 +117,8 @@ func (store *QueueStore) UpdateQueue(queue *model.Queue) error {
 		queue.Limit,
 		queue.MessageID,
 		queue.ChannelID,
+		queue.VoiceChannelID,
+		queue.PlaybackPosition,
 	); err != nil {
 		store.log.Tracef(
 			"[Q%d]Error: %v", i, err,
@@ -136,16 +133,17 @@ func (store *QueueStore) UpdateQueue(queue *model.Queue) error {
 
 // RemoveQueue removes the queue identified by the clientID and guildID
 // from the database. Returns error if no such queue exists.
-func (store *QueueStore) RemoveQueue(clientID string, guildID string) error {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *QueueStore) RemoveQueue(ctx context.Context, clientID string, guildID string) error {
+	i, t, ctx, done := store.trace.Start(ctx, "RemoveQueue")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
 		"GuildID":  guildID,
 	}).Tracef("[Q%d]Start: Remove queue", i)
 
-	if _, err := store.db.Exec(
+	if _, err := store.db.ExecContext(
+		ctx,
 		`
         DELETE FROM "queue"
         WHERE "queue".guild_id = $1 AND
@@ -167,9 +165,9 @@ func (store *QueueStore) RemoveQueue(clientID string, guildID string) error {
 
 // GetQueue fetches the queue identified by the provided clientID and guildID.
 // Returns error if no such queue exists.
-func (store *QueueStore) GetQueue(clientID string, guildID string) (*model.Queue, error) {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *QueueStore) GetQueue(ctx context.Context, clientID string, guildID string) (*model.Queue, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetQueue")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
@@ -178,7 +176,8 @@ func (store *QueueStore) GetQueue(clientID string, guildID string) (*model.Queue
 
 	queue := &model.Queue{}
 
-	if err := store.db.QueryRow(
+	if err := store.db.QueryRowContext(
+		ctx,
 		`
         SELECT * FROM "queue"
         WHERE "queue".guild_id = $1 AND
@@ -190,6 +189,7 @@ func (store *QueueStore) GetQueue(clientID string, guildID string) (*model.Queue
 		&queue.ClientID, &queue.GuildID,
 		&queue.MessageID, &queue.ChannelID,
 		&queue.Offset, &queue.Limit,
+		&queue.VoiceChannelID, &queue.PlaybackPosition,
 	); err != nil {
 		store.log.Tracef(
 			"[Q%d]Error: %v", i, err,
@@ -197,7 +197,7 @@ func (store *QueueStore) GetQueue(clientID string, guildID string) (*model.Queue
 		return nil, err
 	}
 
-	opts, err := store.GetOptionsForQueue(clientID, guildID)
+	opts, err := store.GetOptionsForQueue(ctx, clientID, guildID)
 	if err != nil {
 		return nil, err
 	}
@@ -210,15 +210,16 @@ func (store *QueueStore) GetQueue(clientID string, guildID string) (*model.Queue
 }
 
 // FindAllQueue returns all queues in the store.
-func (store *QueueStore) FindAllQueues() ([]*model.Queue, error) {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *QueueStore) FindAllQueues(ctx context.Context) ([]*model.Queue, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "FindAllQueues")
+	defer done()
 
 	store.log.Tracef("[Q%d]Start: Find all queues", i)
 
 	queues := make([]*model.Queue, 0)
 
-	if rows, err := store.db.Query(
+	if rows, err := store.db.QueryContext(
+		ctx,
 		`SELECT * FROM "queue"`,
 	); err != nil {
 		store.log.Tracef(
@@ -232,6 +233,7 @@ func (store *QueueStore) FindAllQueues() ([]*model.Queue, error) {
 				&queue.ClientID, &queue.GuildID,
 				&queue.MessageID, &queue.ChannelID,
 				&queue.Offset, &queue.Limit,
+				&queue.VoiceChannelID, &queue.PlaybackPosition,
 			); err != nil {
 				store.log.Tracef(
 					"[Q%d]Error: %v", i, err,
@@ -249,15 +251,15 @@ func (store *QueueStore) FindAllQueues() ([]*model.Queue, error) {
 // PersistQueueOptions inserts all of the provided queue options to the
 // database in a single query. Options with name equal to some other
 // already persisted option (for the same queue) are not persisted.
-func (store *QueueStore) PersistQueueOptions(clientID string, guildID string, options ...*model.QueueOption) error {
+func (store *QueueStore) PersistQueueOptions(ctx context.Context, clientID string, guildID string, options ...*model.QueueOption) error {
 	if options == nil || len(options) < 1 {
 		return nil
 	}
-	if _, err := store.GetQueue(clientID, guildID); err != nil {
+	if _, err := store.GetQueue(ctx, clientID, guildID); err != nil {
 		return nil
 	}
-	i, t := store.idx, time.Now()
-	store.idx++
+	i, t, ctx, done := store.trace.Start(ctx, "PersistQueueOptions")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
@@ -287,7 +289,7 @@ func (store *QueueStore) PersistQueueOptions(clientID string, guildID string, op
 	s += `
      ON CONFLICT DO NOTHING;
     `
-	if _, err := store.db.Exec(s); err != nil {
+	if _, err := store.db.ExecContext(ctx, s); err != nil {
 		store.log.Tracef("[Q%d]Error: %v", i, err)
 		return err
 	}
@@ -302,12 +304,12 @@ func (store *QueueStore) PersistQueueOptions(clientID string, guildID string, op
 // RemoveQueueOptions removes all the provided options from
 // the queue identified by the provided clientID and guildID.
 // This does not throw error if no such option exists in the database.
-func (store *QueueStore) RemoveQueueOptions(clientID string, guildID string, options ...model.QueueOptionName) error {
+func (store *QueueStore) RemoveQueueOptions(ctx context.Context, clientID string, guildID string, options ...model.QueueOptionName) error {
 	if options == nil || len(options) < 1 {
 		return nil
 	}
-	i, t := store.idx, time.Now()
-	store.idx++
+	i, t, ctx, done := store.trace.Start(ctx, "RemoveQueueOptions")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
@@ -319,7 +321,8 @@ func (store *QueueStore) RemoveQueueOptions(clientID string, guildID string, opt
 		l = append(l, string(o))
 	}
 
-	if _, err := store.db.Exec(
+	if _, err := store.db.ExecContext(
+		ctx,
 		`
         DELETE FROM "queue_option"
         WHERE "queue_option".name = ANY($1) AND
@@ -343,9 +346,9 @@ func (store *QueueStore) RemoveQueueOptions(clientID string, guildID string, opt
 
 // QueueHasOption checks whether the queue identified by the
 // provided clientID and guildID has the option with the provided name.
-func (store *QueueStore) QueueHasOption(clientID string, guildID string, name model.QueueOptionName) bool {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *QueueStore) QueueHasOption(ctx context.Context, clientID string, guildID string, name model.QueueOptionName) bool {
+	i, t, ctx, done := store.trace.Start(ctx, "QueueHasOption")
+	defer done()
 
 	opt := &model.QueueOption{}
 	var ignore interface{}
@@ -353,7 +356,8 @@ func (store *QueueStore) QueueHasOption(clientID string, guildID string, name mo
 		"ClientID": clientID,
 		"GuildID":  guildID,
 	}).Tracef("[Q%d]Start: Check if queue has option", i)
-	err := store.db.QueryRow(
+	err := store.db.QueryRowContext(
+		ctx,
 		`
         SELECT * FROM "queue_option"
         WHERE "queue_option".queue_client_id = $1 AND
@@ -377,16 +381,17 @@ func (store *QueueStore) QueueHasOption(clientID string, guildID string, name mo
 
 // GetOptionsForQueue returns all queue options that belong to
 // the queue identified by the provided clientID and guildID
-func (store *QueueStore) GetOptionsForQueue(clientID string, guildID string) ([]*model.QueueOption, error) {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *QueueStore) GetOptionsForQueue(ctx context.Context, clientID string, guildID string) ([]*model.QueueOption, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetOptionsForQueue")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
 		"GuildID":  guildID,
 	}).Tracef("[Q%d]Start: Fetch options for queue", i)
 
-	rows, err := store.db.Query(
+	rows, err := store.db.QueryContext(
+		ctx,
 		`
         SELECT * FROM "queue_option"
         WHERE "queue_option".queue_client_id = $1 AND
@@ -421,116 +426,34 @@ func (store *QueueStore) GetOptionsForQueue(clientID string, guildID string) ([]
 	return options, nil
 }
 
-// createQueueTable creates the "queue" table
-// with all it's constraints
-// if it does  not already exist
-func (store *QueueStore) createQueueTable() error {
-	i, t := store.idx, time.Now()
-	store.idx++
-
-	store.log.WithField("TableName", "queue").Tracef(
-		"[Q%d]Start: Create psql table (if not exists)",
-		i,
-	)
+// LockQueue locks the row of the queue identified by the provided
+// clientID and guildID until the end of the current transaction, so
+// the transactions modifying the same queue run one after another.
+// NOTE: this has no effect outside of a transaction.
+func (store *QueueStore) LockQueue(ctx context.Context, clientID string, guildID string) error {
+	i, t, ctx, done := store.trace.Start(ctx, "LockQueue")
+	defer done()
 
-	if _, err := store.db.Exec(
-		`
-        CREATE TABLE IF NOT EXISTS "queue" (
-            client_id VARCHAR,
-            guild_id VARCHAR,
-            message_id VARCHAR NOT NULL,
-            channel_id VARCHAR NOT NULL,
-            "offset" INTEGER NOT NULL DEFAULT '0',
-            "limit" INTEGER NOT NULL DEFAULT '10',
-            UNIQUE (client_id, guild_id),
-            PRIMARY KEY (client_id, guild_id)
-        );
-        `,
-	); err != nil {
-		store.log.Tracef("[Q%d]Error: %v", i, err)
-		return err
-	}
-	store.log.WithField("Latency", time.Since(t)).Tracef(
-		"[Q%d]Done : psql table created", i,
-	)
-	return nil
-}
-
-// createQueueOptionTable creates the "queue_option" table
-// with all it's constraints if it does  not already exist
-func (store *QueueStore) createQueueOptionTable() error {
-	i, t := store.idx, time.Now()
-	store.idx++
-
-	store.log.WithField("TableName", "queue_option").Tracef(
-		"[Q%d]Start: Create psql table (if not exists)",
-		i,
-	)
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[Q%d]Start: Lock queue", i)
 
-	if _, err := store.db.Exec(
+	if _, err := store.db.ExecContext(
+		ctx,
 		`
-        CREATE TABLE IF NOT EXISTS "queue_option" (
-            name VARCHAR,
-            queue_client_id VARCHAR,
-            queue_guild_id VARCHAR,
-            PRIMARY KEY (name, queue_client_id, queue_guild_id),
-            FOREIGN KEY (queue_client_id, queue_guild_id)
-                REFERENCES "queue" (client_id, guild_id)
-                    ON DELETE CASCADE
-        );
+        SELECT client_id FROM "queue"
+        WHERE "queue".client_id = $1 AND "queue".guild_id = $2
+        FOR UPDATE;
         `,
+		clientID,
+		guildID,
 	); err != nil {
 		store.log.Tracef("[Q%d]Error: %v", i, err)
 		return err
 	}
-	store.log.WithField("Latency", time.Since(t)).Tracef(
-		"[Q%d]Done : psql table created", i,
-	)
-	return nil
-}
-
-// dropQueueTable() drops the "queue" table.
-func (store *QueueStore) dropQueueTable() error {
-	i, t := store.idx, time.Now()
-	store.idx++
-
-	store.log.WithField("TableName", "queue").Tracef(
-		"[Q%d]Start: Drop psql table (if exists)", i,
-	)
-
-	if _, err := store.db.Exec(
-		`DROP TABLE IF EXISTS "queue" CASCADE`,
-	); err != nil {
-		store.log.Tracef(
-			"[Q%d]Error: %v", i, err,
-		)
-		return err
-	}
-	store.log.WithField(
-		"Latency", time.Since(t),
-	).Tracef("[Q%d]Done : psql table dropped", i)
-	return nil
-}
-
-// dropQueueOptionTable drops the "queue_option" table.
-func (store *QueueStore) dropQueueOptionTable() error {
-	i, t := store.idx, time.Now()
-	store.idx++
-
-	store.log.WithField("TableName", "queue_option").Tracef(
-		"[Q%d]Start: Drop psql table (if exists)", i,
-	)
-
-	if _, err := store.db.Exec(
-		`DROP TABLE IF EXISTS "queue_option" CASCADE`,
-	); err != nil {
-		store.log.Tracef(
-			"[Q%d]Error: %v", i, err,
-		)
-		return err
-	}
 	store.log.WithField(
 		"Latency", time.Since(t),
-	).Tracef("[Q%d]Done : psql table dropped", i)
+	).Tracef("[Q%d]Done : Queue locked", i)
 	return nil
 }
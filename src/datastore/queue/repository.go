@@ -0,0 +1,49 @@
+package queue
+
+import (
+	"context"
+	"discord-music-bot/model"
+)
+
+// QueueRepository handles persisting and removing
+// Queues and their options in a datastore.
+type QueueRepository interface {
+	// PersistQueue saves the provided queue. Returns error if
+	// the queue, identified by the same clientID and guildID,
+	// already exists.
+	PersistQueue(ctx context.Context, queue *model.Queue) error
+	// UpdateQueue updates the provided queue. This does not update
+	// the queue's clientID or guildID.
+	UpdateQueue(ctx context.Context, queue *model.Queue) error
+	// RemoveQueue removes the queue identified by the clientID and
+	// guildID, together with it's options and songs.
+	RemoveQueue(ctx context.Context, clientID string, guildID string) error
+	// GetQueue fetches the queue identified by the provided clientID
+	// and guildID. Returns error if no such queue exists.
+	GetQueue(ctx context.Context, clientID string, guildID string) (*model.Queue, error)
+	// FindAllQueues returns all queues in the store.
+	FindAllQueues(ctx context.Context) ([]*model.Queue, error)
+	// PersistQueueOptions adds the provided options to the queue
+	// identified by the provided clientID and guildID.
+	// Options already added to the queue are not duplicated.
+	PersistQueueOptions(ctx context.Context, clientID string, guildID string, options ...*model.QueueOption) error
+	// RemoveQueueOptions removes all the provided options from the
+	// queue identified by the provided clientID and guildID.
+	RemoveQueueOptions(ctx context.Context, clientID string, guildID string, options ...model.QueueOptionName) error
+	// QueueHasOption checks whether the queue identified by the
+	// provided clientID and guildID has the option with the provided name.
+	QueueHasOption(ctx context.Context, clientID string, guildID string, name model.QueueOptionName) bool
+	// GetOptionsForQueue returns all queue options that belong to
+	// the queue identified by the provided clientID and guildID.
+	GetOptionsForQueue(ctx context.Context, clientID string, guildID string) ([]*model.QueueOption, error)
+	// LockQueue locks the queue identified by the provided clientID
+	// and guildID until the end of the current unit of work, so the
+	// units of work modifying the same queue run one after another.
+	LockQueue(ctx context.Context, clientID string, guildID string) error
+}
+
+var (
+	_ QueueRepository = (*QueueStore)(nil)
+	_ QueueRepository = (*MemoryQueueStore)(nil)
+	_ QueueRepository = (*SqliteQueueStore)(nil)
+)
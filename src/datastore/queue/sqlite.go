@@ -0,0 +1,417 @@
+package queue
+
+import (
+	"context"
+	"discord-music-bot/datastore/sqldb"
+	"discord-music-bot/model"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type SqliteQueueStore struct {
+	log   *log.Logger
+	db    sqldb.Querier
+	trace *sqldb.Tracer
+}
+
+// NewSqliteQueueStore creates an object that handles
+// persisting and removing Queues in sqlite database.
+func NewSqliteQueueStore(db sqldb.Querier, log *log.Logger, options sqldb.Options) *SqliteQueueStore {
+	return &SqliteQueueStore{
+		db:    db,
+		log:   log,
+		trace: sqldb.NewTracer(log, "Q", options),
+	}
+}
+
+// PersistQueue saves the provided queue and returns the inserted queue.
+// Returns error if the queue,
+// identified by the same clientID and guildID, already exists.
+func (store *SqliteQueueStore) PersistQueue(ctx context.Context, queue *model.Queue) error {
+	i, t, ctx, done := store.trace.Start(ctx, "PersistQueue")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": queue.ClientID,
+		"GuildID":  queue.GuildID,
+	}).Tracef("[Q%d]Start: Persist queue", i)
+
+	if _, err := store.db.ExecContext(
+		ctx,
+		`
+        INSERT INTO "queue" (
+            client_id, guild_id, message_id, channel_id, "offset", "limit",
+            voice_channel_id, playback_position
+        ) VALUES
+            (?, ?, ?, ?, ?, ?, ?, ?);
+        `,
+		queue.ClientID,
+		queue.GuildID,
+		queue.MessageID,
+		queue.ChannelID,
+		queue.Offset,
+		queue.Limit,
+		queue.VoiceChannelID,
+		queue.PlaybackPosition,
+	); err != nil {
+		store.log.Tracef(
+			"[Q%d]Error: %v", i, err,
+		)
+		return err
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[Q%d]Done : persisted the queue", i)
+
+	return store.PersistQueueOptions(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+		queue.Options...,
+	)
+}
+
+// UpdateQueue updates the provided queue. This does not update
+// the queue's clientID or guildID.
+func (store *SqliteQueueStore) UpdateQueue(ctx context.Context, queue *model.Queue) error {
+	i, t, ctx, done := store.trace.Start(ctx, "UpdateQueue")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": queue.ClientID,
+		"GuildID":  queue.GuildID,
+	}).Tracef("[Q%d]Start: Update queue", i)
+
+	if _, err := store.db.ExecContext(
+		ctx,
+		`
+        UPDATE "queue"
+        SET "offset" = ?,
+            "limit" = ?,
+            message_id = ?,
+            channel_id = ?,
+            voice_channel_id = ?,
+            playback_position = ?
+        WHERE "queue".client_id = ? AND
+            "queue".guild_id = ?;
+        `,
+		queue.Offset,
+		queue.Limit,
+		queue.MessageID,
+		queue.ChannelID,
+		queue.VoiceChannelID,
+		queue.PlaybackPosition,
+		queue.ClientID,
+		queue.GuildID,
+	); err != nil {
+		store.log.Tracef(
+			"[Q%d]Error: %v", i, err,
+		)
+		return err
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[Q%d]Done : Queue updated", i)
+	return nil
+}
+
+// RemoveQueue removes the queue identified by the clientID and guildID
+// from the database. The removal cascades to the queue's options
+// and songs.
+func (store *SqliteQueueStore) RemoveQueue(ctx context.Context, clientID string, guildID string) error {
+	i, t, ctx, done := store.trace.Start(ctx, "RemoveQueue")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[Q%d]Start: Remove queue", i)
+
+	if _, err := store.db.ExecContext(
+		ctx,
+		`
+        DELETE FROM "queue"
+        WHERE "queue".guild_id = ? AND
+            "queue".client_id = ?;
+        `,
+		guildID,
+		clientID,
+	); err != nil {
+		store.log.Tracef(
+			"[Q%d]Error: %v", i, err,
+		)
+		return err
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[Q%d]Done : Queue removed", i)
+	return nil
+}
+
+// GetQueue fetches the queue identified by the provided clientID and guildID.
+// Returns error if no such queue exists.
+func (store *SqliteQueueStore) GetQueue(ctx context.Context, clientID string, guildID string) (*model.Queue, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetQueue")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[Q%d]Start: Find queue", i)
+
+	queue := &model.Queue{}
+
+	if err := store.db.QueryRowContext(
+		ctx,
+		`
+        SELECT * FROM "queue"
+        WHERE "queue".guild_id = ? AND
+            "queue".client_id = ?;
+        `,
+		guildID,
+		clientID,
+	).Scan(
+		&queue.ClientID, &queue.GuildID,
+		&queue.MessageID, &queue.ChannelID,
+		&queue.Offset, &queue.Limit,
+		&queue.VoiceChannelID, &queue.PlaybackPosition,
+	); err != nil {
+		store.log.Tracef(
+			"[Q%d]Error: %v", i, err,
+		)
+		return nil, err
+	}
+
+	opts, err := store.GetOptionsForQueue(ctx, clientID, guildID)
+	if err != nil {
+		return nil, err
+	}
+	queue.Options = opts
+
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[Q%d]Done : Queue found", i)
+	return queue, nil
+}
+
+// FindAllQueue returns all queues in the store.
+func (store *SqliteQueueStore) FindAllQueues(ctx context.Context) ([]*model.Queue, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "FindAllQueues")
+	defer done()
+
+	store.log.Tracef("[Q%d]Start: Find all queues", i)
+
+	rows, err := store.db.QueryContext(ctx, `SELECT * FROM "queue"`)
+	if err != nil {
+		store.log.Tracef(
+			"[Q%d]Error: %v", i, err,
+		)
+		return nil, err
+	}
+	defer rows.Close()
+
+	queues := make([]*model.Queue, 0)
+	for rows.Next() {
+		queue := &model.Queue{}
+		if err := rows.Scan(
+			&queue.ClientID, &queue.GuildID,
+			&queue.MessageID, &queue.ChannelID,
+			&queue.Offset, &queue.Limit,
+			&queue.VoiceChannelID, &queue.PlaybackPosition,
+		); err != nil {
+			store.log.Tracef(
+				"[Q%d]Error: %v", i, err,
+			)
+		}
+		queues = append(queues, queue)
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[Q%d]Done : Queues found", i)
+	return queues, nil
+}
+
+// PersistQueueOptions inserts all of the provided queue options to the
+// database in a single query. Options with name equal to some other
+// already persisted option (for the same queue) are not persisted.
+func (store *SqliteQueueStore) PersistQueueOptions(ctx context.Context, clientID string, guildID string, options ...*model.QueueOption) error {
+	if options == nil || len(options) < 1 {
+		return nil
+	}
+	if _, err := store.GetQueue(ctx, clientID, guildID); err != nil {
+		return nil
+	}
+	i, t, ctx, done := store.trace.Start(ctx, "PersistQueueOptions")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[Q%d]Start: Persist %d queue options", i, len(options))
+
+	values := make([]string, 0)
+	params := make([]interface{}, 0)
+	for _, o := range options {
+		if o == nil {
+			continue
+		}
+		values = append(values, "(?, ?, ?)")
+		params = append(params, o.Name, clientID, guildID)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	// NOTE: do not insert duplicated options
+	if _, err := store.db.ExecContext(
+		ctx,
+		`
+        INSERT INTO "queue_option" (
+            name, queue_client_id, queue_guild_id
+        ) VALUES `+strings.Join(values, ", ")+`
+        ON CONFLICT DO NOTHING;
+        `,
+		params...,
+	); err != nil {
+		store.log.Tracef("[Q%d]Error: %v", i, err)
+		return err
+	}
+
+	store.log.WithField(
+		"Latency",
+		time.Since(t),
+	).Tracef("[Q%d]Done : %d queue_options persisted", i, len(options))
+	return nil
+}
+
+// RemoveQueueOptions removes all the provided options from
+// the queue identified by the provided clientID and guildID.
+// This does not throw error if no such option exists in the database.
+func (store *SqliteQueueStore) RemoveQueueOptions(ctx context.Context, clientID string, guildID string, options ...model.QueueOptionName) error {
+	if options == nil || len(options) < 1 {
+		return nil
+	}
+	i, t, ctx, done := store.trace.Start(ctx, "RemoveQueueOptions")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[Q%d]Start: Remove %d queue options", i, len(options))
+
+	placeholders := make([]string, 0)
+	params := make([]interface{}, 0)
+	for _, o := range options {
+		placeholders = append(placeholders, "?")
+		params = append(params, string(o))
+	}
+	params = append(params, clientID, guildID)
+
+	if _, err := store.db.ExecContext(
+		ctx,
+		`
+        DELETE FROM "queue_option"
+        WHERE "queue_option".name IN (`+strings.Join(placeholders, ", ")+`) AND
+            "queue_option".queue_client_id = ? AND
+            "queue_option".queue_guild_id = ?;
+        `,
+		params...,
+	); err != nil {
+		store.log.Tracef("[Q%d]Error: %v", i, err)
+		return err
+	}
+
+	store.log.WithField(
+		"Latency",
+		time.Since(t),
+	).Tracef("[Q%d]Done : queue_options removed", i)
+	return nil
+}
+
+// QueueHasOption checks whether the queue identified by the
+// provided clientID and guildID has the option with the provided name.
+func (store *SqliteQueueStore) QueueHasOption(ctx context.Context, clientID string, guildID string, name model.QueueOptionName) bool {
+	i, t, ctx, done := store.trace.Start(ctx, "QueueHasOption")
+	defer done()
+
+	opt := &model.QueueOption{}
+	var ignore interface{}
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[Q%d]Start: Check if queue has option", i)
+	err := store.db.QueryRowContext(
+		ctx,
+		`
+        SELECT * FROM "queue_option"
+        WHERE "queue_option".queue_client_id = ? AND
+            "queue_option".queue_guild_id = ? AND
+            "queue_option".name = ?;
+        `,
+		clientID,
+		guildID,
+		name,
+	).Scan(&opt.Name, &ignore, &ignore)
+
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[Q%d]Done : checked if queue has option", i)
+
+	return err == nil
+}
+
+// GetOptionsForQueue returns all queue options that belong to
+// the queue identified by the provided clientID and guildID
+func (store *SqliteQueueStore) GetOptionsForQueue(ctx context.Context, clientID string, guildID string) ([]*model.QueueOption, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetOptionsForQueue")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[Q%d]Start: Fetch options for queue", i)
+
+	rows, err := store.db.QueryContext(
+		ctx,
+		`
+        SELECT * FROM "queue_option"
+        WHERE "queue_option".queue_client_id = ? AND
+            "queue_option".queue_guild_id = ?;
+        `,
+		clientID,
+		guildID,
+	)
+	if err != nil {
+		store.log.Tracef("[Q%d]Error: %v", i, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := make([]*model.QueueOption, 0)
+	for rows.Next() {
+		opt := &model.QueueOption{}
+		var ignore interface{}
+		if err := rows.Scan(
+			&opt.Name, &ignore, &ignore,
+		); err != nil {
+			store.log.Tracef(
+				"[Q%d]Error: %v", i, err,
+			)
+			return nil, err
+		}
+		options = append(options, opt)
+	}
+
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[Q%d]Done : %d options for queue fetched", i, len(options))
+
+	return options, nil
+}
+
+// LockQueue has no effect in the sqlite database, as it uses a
+// single connection, so the transactions already run one after another.
+func (store *SqliteQueueStore) LockQueue(ctx context.Context, clientID string, guildID string) error {
+	return nil
+}
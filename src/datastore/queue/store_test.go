@@ -1,48 +1,60 @@
 package queue_test
 
 import (
+	"context"
 	"database/sql"
+	"discord-music-bot/datastore/migration"
 	"discord-music-bot/datastore/queue"
+	"discord-music-bot/datastore/sqldb"
 	"discord-music-bot/model"
 	"fmt"
+	"path/filepath"
 	"testing"
 
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
 )
 
+// ctx is the context passed to the repositories' calls.
+var ctx = context.Background()
+
 type QueueStoreTestSuite struct {
-	db    *sql.DB
-	store *queue.QueueStore
+	driver   string
+	db       *sql.DB
+	migrator *migration.Migrator
+	store    queue.QueueRepository
 	suite.Suite
 }
 
 // SetupSuite runs when the suite is initialized and
-// connects to the database and initialized the queue store.
+// connects to the suite's database and initialized the queue store.
 func (s *QueueStoreTestSuite) SetupSuite() {
-	db, err := sql.Open(
-		"postgres",
-		"host=postgres port=5432 user=postgres password=postgres "+
-			"dbname=discord_bot_test sslmode=disable",
-	)
+	db, err := openDatabase(s.driver, s.T().TempDir())
 	s.NoError(err)
 
 	s.db = db
-	s.store = queue.NewQueueStore(db, logrus.StandardLogger())
+	if s.driver == "sqlite3" {
+		s.migrator = migration.NewMigrator(db, logrus.StandardLogger(), migration.Sqlite)
+		s.store = queue.NewSqliteQueueStore(db, logrus.StandardLogger(), sqldb.Options{})
+	} else {
+		s.migrator = migration.NewMigrator(db, logrus.StandardLogger(), migration.Postgres)
+		s.store = queue.NewQueueStore(db, logrus.StandardLogger(), sqldb.Options{})
+	}
 }
 
-// SetupTest runs before every test and initializes the store.
+// SetupTest runs before every test and recreates the
+// store's tables by reverting and reapplying the migrations.
 func (s *QueueStoreTestSuite) SetupTest() {
-	err := s.store.Destroy()
-	s.NoError(err)
-	err = s.store.Init()
+	err := s.migrator.Reset()
 	s.NoError(err)
 }
 
-// TearDownSuite runs after all tests have been run and destroys
-// the queue store and closes database connection.
+// TearDownSuite runs after all tests have been run, reverts
+// all the migrations and closes database connection.
 func (s *QueueStoreTestSuite) TearDownSuite() {
-	err := s.store.Destroy()
+	_, err := s.migrator.Down(len(s.migrator.Migrations()))
 	s.NoError(err)
 
 	err = s.db.Close()
@@ -62,11 +74,11 @@ func (s *QueueStoreTestSuite) TestIntegrationQueueCRUD() {
 	}
 
 	// First persist the queue
-	err := s.store.PersistQueue(queue)
+	err := s.store.PersistQueue(ctx, queue)
 	s.NoError(err)
 
 	// Should successfully fetch the persisted queue
-	queue2, err := s.store.GetQueue(queue.ClientID, queue.GuildID)
+	queue2, err := s.store.GetQueue(ctx, queue.ClientID, queue.GuildID)
 	s.NoError(err)
 	s.Equal(queue.GuildID, queue2.GuildID)
 	s.Equal(queue.ClientID, queue2.ClientID)
@@ -78,24 +90,31 @@ func (s *QueueStoreTestSuite) TestIntegrationQueueCRUD() {
 	s.Equal(0, queue2.InactiveSize)
 	s.Equal(0, queue2.Size)
 
+	s.Equal("", queue2.VoiceChannelID)
+	s.Equal(0, queue2.PlaybackPosition)
+
 	queue.MessageID = "MESSAGE-ID-TEST2"
 	queue.Limit = 5
+	queue.VoiceChannelID = "VOICE-CHANNEL-ID-TEST"
+	queue.PlaybackPosition = 42
 	// Should successfully update the queue
-	err = s.store.UpdateQueue(queue)
+	err = s.store.UpdateQueue(ctx, queue)
 	s.NoError(err)
 
 	// Should successfully fetch the persisted queue
-	queue2, err = s.store.GetQueue(queue.ClientID, queue.GuildID)
+	queue2, err = s.store.GetQueue(ctx, queue.ClientID, queue.GuildID)
 	s.NoError(err)
 	s.Equal("MESSAGE-ID-TEST2", queue2.MessageID)
 	s.Equal(5, queue.Limit)
+	s.Equal("VOICE-CHANNEL-ID-TEST", queue2.VoiceChannelID)
+	s.Equal(42, queue2.PlaybackPosition)
 
 	// Remove the queue
-	err = s.store.RemoveQueue(queue.ChannelID, queue.GuildID)
+	err = s.store.RemoveQueue(ctx, queue.ChannelID, queue.GuildID)
 	s.NoError(err)
 
 	// Try to fetch the queue again, it should return error
-	_, err = s.store.GetQueue(queue.ChannelID, queue.GuildID)
+	_, err = s.store.GetQueue(ctx, queue.ChannelID, queue.GuildID)
 	s.Error(err)
 	s.Equal("sql: no rows in result set", err.Error())
 }
@@ -104,7 +123,7 @@ func (s *QueueStoreTestSuite) TestIntegrationQueueCRUD() {
 // fetches all of them and checks their data.
 func (s *QueueStoreTestSuite) TestIntegrationFindAllQueues() {
 	// Should not fetch any queues
-	queried_queues, err := s.store.FindAllQueues()
+	queried_queues, err := s.store.FindAllQueues(ctx)
 	s.NoError(err)
 	s.Len(queried_queues, 0)
 
@@ -119,12 +138,12 @@ func (s *QueueStoreTestSuite) TestIntegrationFindAllQueues() {
 			Offset:    0,
 		}
 		queues = append(queues, queue)
-		err := s.store.PersistQueue(queue)
+		err := s.store.PersistQueue(ctx, queue)
 		s.NoError(err)
 	}
 
 	// Should fetch all inserted queues
-	queried_queues, err = s.store.FindAllQueues()
+	queried_queues, err = s.store.FindAllQueues(ctx)
 	s.NoError(err)
 	s.Len(queried_queues, len(queues))
 	for _, queue := range queues {
@@ -156,11 +175,12 @@ func (s *QueueStoreTestSuite) TestIntegrationAddRemoveQueueOptions() {
 		Limit:     10,
 		Offset:    0,
 	}
-	err := s.store.PersistQueue(queue)
+	err := s.store.PersistQueue(ctx, queue)
 	s.NoError(err)
 	s.Len(queue.Options, 0)
 
 	err = s.store.PersistQueueOptions(
+		ctx,
 		queue.ClientID,
 		queue.GuildID,
 		model.LoopOption(),
@@ -168,13 +188,13 @@ func (s *QueueStoreTestSuite) TestIntegrationAddRemoveQueueOptions() {
 	)
 	s.NoError(err)
 
-	v := s.store.QueueHasOption(queue.ClientID, queue.GuildID, model.Loop)
+	v := s.store.QueueHasOption(ctx, queue.ClientID, queue.GuildID, model.Loop)
 	s.Equal(true, v)
-	v = s.store.QueueHasOption(queue.ClientID, queue.GuildID, model.Paused)
+	v = s.store.QueueHasOption(ctx, queue.ClientID, queue.GuildID, model.Paused)
 	s.Equal(true, v)
 
 	// Make sure the added options are there
-	queue, err = s.store.GetQueue(queue.ClientID, queue.GuildID)
+	queue, err = s.store.GetQueue(ctx, queue.ClientID, queue.GuildID)
 	s.NoError(err)
 	s.Len(queue.Options, 2)
 
@@ -188,17 +208,40 @@ func (s *QueueStoreTestSuite) TestIntegrationAddRemoveQueueOptions() {
 	}
 	s.Equal(2, found)
 
-	err = s.store.RemoveQueueOptions(queue.ClientID, queue.GuildID, model.Paused)
+	err = s.store.RemoveQueueOptions(ctx, queue.ClientID, queue.GuildID, model.Paused)
 	s.NoError(err)
 
-	options, err := s.store.GetOptionsForQueue(queue.ClientID, queue.GuildID)
+	options, err := s.store.GetOptionsForQueue(ctx, queue.ClientID, queue.GuildID)
 	s.NoError(err)
 	s.Len(options, 1)
 	s.Equal(options[0].Name, model.Loop)
 }
 
 // TestQueueStorageTestSuite runs all tests under
-// the QueueStoreTestSuite suite.
+// the QueueStoreTestSuite suite against the postgres database.
 func TestQueueStorageTestSuite(t *testing.T) {
-	suite.Run(t, new(QueueStoreTestSuite))
+	suite.Run(t, &QueueStoreTestSuite{driver: "postgres"})
+}
+
+// TestSqliteQueueStorageTestSuite runs all tests under
+// the QueueStoreTestSuite suite against the sqlite database.
+func TestSqliteQueueStorageTestSuite(t *testing.T) {
+	suite.Run(t, &QueueStoreTestSuite{driver: "sqlite3"})
+}
+
+// openDatabase opens the test database for the provided driver.
+// The sqlite database is created in the provided directory.
+func openDatabase(driver string, dir string) (*sql.DB, error) {
+	if driver == "sqlite3" {
+		return sql.Open(
+			"sqlite3",
+			"file:"+filepath.Join(dir, "discord_bot_test.db")+
+				"?_foreign_keys=on",
+		)
+	}
+	return sql.Open(
+		"postgres",
+		"host=postgres port=5432 user=postgres password=postgres "+
+			"dbname=discord_bot_test sslmode=disable",
+	)
 }
@@ -0,0 +1,411 @@
+package song
+
+import (
+	"context"
+	"database/sql"
+	"discord-music-bot/datastore/memory"
+	"discord-music-bot/model"
+	"errors"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type MemorySongStore struct {
+	log             *log.Logger
+	db              *memory.DB
+	inactiveSongTTL time.Duration
+}
+
+// NewMemorySongStore creates an object that handles
+// persisting and removing Songs in an in-memory database.
+func NewMemorySongStore(db *memory.DB, log *log.Logger, inactiveSongTTL time.Duration) *MemorySongStore {
+	return &MemorySongStore{
+		log:             log,
+		db:              db,
+		inactiveSongTTL: inactiveSongTTL,
+	}
+}
+
+// UpdateQueueWithSongs fetches the queue's songs,
+// limited by the queue's offset and limit, the total
+// size of the queue and the durations of it's songs.
+func (store *MemorySongStore) UpdateQueueWithSongs(ctx context.Context, queue *model.Queue) (*model.Queue, error) {
+	queue.InactiveSize = store.GetInactiveSongCountForQueue(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+	)
+	if headSong, err := store.GetHeadSongForQueue(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+	); err == nil {
+		queue.HeadSong = headSong
+	} else {
+		return queue, nil
+	}
+	songs, err := store.GetSongsForQueue(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+		queue.Offset+1,
+		queue.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	queue.Songs = songs
+	queue.Size = store.GetSongCountForQueue(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+	)
+	queue.Duration = store.GetDurationForQueue(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+		0,
+		queue.Size,
+	)
+	queue.DurationBefore = store.GetDurationForQueue(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+		0,
+		queue.Offset+1,
+	)
+	return queue, nil
+}
+
+// PersistSongs saves all of the provided songs to the back
+// of the queue identified by the provided clientID and guildID.
+// Songs with the same name as another of the provided songs
+// are persisted only once.
+func (store *MemorySongStore) PersistSongs(ctx context.Context, clientID string, guildID string, songs ...*model.Song) error {
+	if len(songs) < 1 {
+		return nil
+	}
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	if _, ok := store.db.Queues[key]; !ok {
+		return errors.New("memory: Queue does not exist")
+	}
+	maxPosition, _ := store.positionBounds(key)
+	used := make(map[string]struct{})
+	for _, song := range songs {
+		if song == nil {
+			continue
+		}
+		if _, ok := used[song.Name]; ok {
+			continue
+		}
+		used[song.Name] = struct{}{}
+		maxPosition++
+		s := copySong(song)
+		s.ID = store.db.NextID("song")
+		s.Position = maxPosition
+		store.db.Songs[key] = append(store.db.Songs[key], s)
+	}
+	return nil
+}
+
+// PersistSongToFront saves the provided song to the store.
+// The song's position is set to 1 less than the minimum position of the
+// queue identified with the provided clientID and guildID
+func (store *MemorySongStore) PersistSongToFront(ctx context.Context, clientID string, guildID string, song *model.Song) error {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	if _, ok := store.db.Queues[key]; !ok {
+		return errors.New("memory: Queue does not exist")
+	}
+	_, minPosition := store.positionBounds(key)
+	s := copySong(song)
+	s.ID = store.db.NextID("song")
+	s.Position = minPosition - 1
+	store.db.Songs[key] = append(store.db.Songs[key], s)
+	return nil
+}
+
+// GetHeadSongForQueue returns the songs with the smallest position
+// in the queue identified by the provided clientID and guildID.
+func (store *MemorySongStore) GetHeadSongForQueue(ctx context.Context, clientID string, guildID string) (*model.Song, error) {
+	songs, err := store.GetSongsForQueue(ctx, clientID, guildID, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(songs) == 0 {
+		return nil, errors.New("memory: Found no head song for queue")
+	}
+	return songs[0], nil
+}
+
+// GetSongsForQueue fetches the songs that belong to the queue identified
+// by the provided clientID and guilID,
+// limited by the provided offset and limit.
+func (store *MemorySongStore) GetSongsForQueue(ctx context.Context, clientID string, guildID string, offset int, limit int) ([]*model.Song, error) {
+	songs, err := store.GetAllSongsForQueue(ctx, clientID, guildID)
+	if err != nil {
+		return nil, err
+	}
+	if offset >= len(songs) {
+		return make([]*model.Song, 0), nil
+	}
+	songs = songs[offset:]
+	if limit < len(songs) {
+		songs = songs[:limit]
+	}
+	return songs, nil
+}
+
+// GetSongsForQueue fetches all the songs that belong to the queue identified
+// by the provided clientID and guilID, ordered by their position.
+func (store *MemorySongStore) GetAllSongsForQueue(ctx context.Context, clientID string, guildID string) ([]*model.Song, error) {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	songs := make([]*model.Song, 0)
+	for _, s := range store.db.Songs[key] {
+		songs = append(songs, copySong(s))
+	}
+	sort.SliceStable(songs, func(i, j int) bool {
+		return songs[i].Position < songs[j].Position
+	})
+	return songs, nil
+}
+
+// GetSongCountForQueue returns the number of songs that belong
+// to the queue identified by the provided clientID and guildID
+func (store *MemorySongStore) GetSongCountForQueue(ctx context.Context, clientID string, guildID string) int {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	return len(store.db.Songs[memory.QueueKey{ClientID: clientID, GuildID: guildID}])
+}
+
+// GetDurationForQueue returns the total duration in seconds of the
+// songs that belong to the queue identified by the provided clientID
+// and guildID, limited by the provided offset and limit.
+func (store *MemorySongStore) GetDurationForQueue(ctx context.Context, clientID string, guildID string, offset int, limit int) int {
+	songs, err := store.GetSongsForQueue(ctx, clientID, guildID, offset, limit)
+	if err != nil {
+		return 0
+	}
+	duration := 0
+	for _, s := range songs {
+		duration += s.DurationSeconds
+	}
+	return duration
+}
+
+// RemoveHeadSong removes song with the minimum position belonging to the
+// queue identified with the provided clientID and guildID
+func (store *MemorySongStore) RemoveHeadSong(ctx context.Context, clientID string, guildID string) error {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	_, minPosition := store.positionBounds(key)
+	store.removeSongs(key, func(s *model.Song) bool {
+		return s.Position == minPosition
+	})
+	return nil
+}
+
+// PushHeadSongToBack places the song with the min song position to the back
+// of the queue, by setting it's position 1 more than the song with max position
+func (store *MemorySongStore) PushHeadSongToBack(ctx context.Context, clientID string, guildID string) error {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	maxPosition, minPosition := store.positionBounds(key)
+	for _, s := range store.db.Songs[key] {
+		if s.Position == minPosition {
+			s.Position = maxPosition + 1
+		}
+	}
+	return nil
+}
+
+// PushLastSongToFront places the song with the max song position to the front
+// of the queue, by setting it's position 1 less than the song with min position
+func (store *MemorySongStore) PushLastSongToFront(ctx context.Context, clientID string, guildID string) error {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	maxPosition, minPosition := store.positionBounds(key)
+	for _, s := range store.db.Songs[key] {
+		if s.Position == maxPosition {
+			s.Position = minPosition - 1
+		}
+	}
+	return nil
+}
+
+// RemoveSongs removes songs with ID in the provided ids that belong to the
+// queue, identified by the provided clientID and guildID.
+func (store *MemorySongStore) RemoveSongs(ctx context.Context, clientID string, guildID string, ids ...uint) error {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	store.removeSongs(key, func(s *model.Song) bool {
+		for _, id := range ids {
+			if s.ID == id {
+				return true
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+// PersistInactiveSongs saves all of the provided inactive songs.
+// The persisted inactive songs will be automatically deleted
+// after some time.
+func (store *MemorySongStore) PersistInactiveSongs(ctx context.Context, clientID string, guildID string, songs ...*model.Song) error {
+	if len(songs) < 1 {
+		return nil
+	}
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	if _, ok := store.db.Queues[key]; !ok {
+		return errors.New("memory: Queue does not exist")
+	}
+	for _, song := range songs {
+		if song == nil {
+			continue
+		}
+		s := copySong(song)
+		s.ID = store.db.NextID("inactive_song")
+		s.Position = 0
+		store.db.InactiveSongs[key] = append(
+			store.db.InactiveSongs[key],
+			&memory.InactiveSong{Song: s, Added: time.Now()},
+		)
+	}
+	return nil
+}
+
+// PopLatestInactiveSong deletes the inactive song, belonging to the queue
+// identified with the provided clientID and guildID, that was added last
+// to the store, and returns it
+func (store *MemorySongStore) PopLatestInactiveSong(ctx context.Context, clientID string, guildID string) (*model.Song, error) {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	key := memory.QueueKey{ClientID: clientID, GuildID: guildID}
+	inactive := store.db.InactiveSongs[key]
+	if len(inactive) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	latest := inactive[len(inactive)-1]
+	store.db.InactiveSongs[key] = inactive[:len(inactive)-1]
+	return copySong(latest.Song), nil
+}
+
+// GetInactiveSongCountForQueue returns the number of inactive
+// songs that belong to the queue
+// identified by the provided clientID and guildID
+func (store *MemorySongStore) GetInactiveSongCountForQueue(ctx context.Context, clientID string, guildID string) int {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	return len(store.db.InactiveSongs[memory.QueueKey{ClientID: clientID, GuildID: guildID}])
+}
+
+// runInactiveSongsCleanup is a long lived worker, that cleans up
+// outdated inactive songs from the store at interval.
+func (store *MemorySongStore) RunInactiveSongsCleanup(ctx context.Context) {
+	interval := store.inactiveSongTTL / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	store.log.WithFields(log.Fields{
+		"TTL":      store.inactiveSongTTL,
+		"Interval": interval,
+	}).Debug(
+		"Running inactive songs cleanup",
+	)
+	done := ctx.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	store.removeOutdatedInactiveSongs()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			store.removeOutdatedInactiveSongs()
+		}
+	}
+}
+
+// removeOutdatedInactiveSongs removes all the inactive songs
+// added before the InactiveSongTTL config option.
+func (store *MemorySongStore) removeOutdatedInactiveSongs() {
+	store.db.Lock()
+	defer store.db.Unlock()
+
+	limit := time.Now().Add(store.inactiveSongTTL * (-1))
+	for key, inactive := range store.db.InactiveSongs {
+		remaining := make([]*memory.InactiveSong, 0)
+		for _, s := range inactive {
+			if s.Added.After(limit) {
+				remaining = append(remaining, s)
+			}
+		}
+		store.db.InactiveSongs[key] = remaining
+	}
+}
+
+// positionBounds returns the maximum and the minimum position of
+// the songs that belong to the queue identified by the provided key,
+// or zeros if the queue has no songs.
+// NOTE: the DB should be locked when calling this.
+func (store *MemorySongStore) positionBounds(key memory.QueueKey) (int, int) {
+	songs := store.db.Songs[key]
+	if len(songs) == 0 {
+		return 0, 0
+	}
+	max, min := songs[0].Position, songs[0].Position
+	for _, s := range songs {
+		if s.Position > max {
+			max = s.Position
+		}
+		if s.Position < min {
+			min = s.Position
+		}
+	}
+	return max, min
+}
+
+// removeSongs removes the songs of the queue identified by
+// the provided key, for which the provided function returns true.
+// NOTE: the DB should be locked when calling this.
+func (store *MemorySongStore) removeSongs(key memory.QueueKey, remove func(*model.Song) bool) {
+	remaining := make([]*model.Song, 0)
+	for _, s := range store.db.Songs[key] {
+		if !remove(s) {
+			remaining = append(remaining, s)
+		}
+	}
+	store.db.Songs[key] = remaining
+}
+
+func copySong(song *model.Song) *model.Song {
+	s := *song
+	return &s
+}
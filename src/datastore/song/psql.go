@@ -2,7 +2,7 @@ package song
 
 import (
 	"context"
-	"database/sql"
+	"discord-music-bot/datastore/sqldb"
 	"discord-music-bot/model"
 	"errors"
 	"fmt"
@@ -15,47 +15,33 @@ import (
 
 type SongStore struct {
 	log             *log.Logger
-	db              *sql.DB
+	db              sqldb.Querier
 	inactiveSongTTL time.Duration
-	idx             int
+	trace           *sqldb.Tracer
 }
 
 // NewSongStore creates an object that handles
 // persisting and removing Songs in postgres database.
-func NewSongStore(db *sql.DB, log *log.Logger, inactiveSongTTL time.Duration) *SongStore {
+func NewSongStore(db sqldb.Querier, log *log.Logger, inactiveSongTTL time.Duration, options sqldb.Options) *SongStore {
 	return &SongStore{
 		log:             log,
 		db:              db,
-		idx:             0,
+		trace:           sqldb.NewTracer(log, "S", options),
 		inactiveSongTTL: inactiveSongTTL,
 	}
 }
 
-// Init creates the required tables for the Song store.
-func (store *SongStore) Init() error {
-	if err := store.createSongTable(); err != nil {
-		return err
-	}
-	return store.createInactiveSongTable()
-}
-
-// Destroy drops the created tables for the Song store.
-func (store *SongStore) Destroy() error {
-	if err := store.dropSongTable(); err != nil {
-		return err
-	}
-	return store.dropInactiveSongTable()
-}
-
 // UpdateQueueWithSongs fetches the queue's songs,
-// limited by the queue's offset and limit, and the total
-// size of the queue.
-func (store *SongStore) UpdateQueueWithSongs(queue *model.Queue) (*model.Queue, error) {
+// limited by the queue's offset and limit, the total
+// size of the queue and the durations of it's songs.
+func (store *SongStore) UpdateQueueWithSongs(ctx context.Context, queue *model.Queue) (*model.Queue, error) {
 	queue.InactiveSize = store.GetInactiveSongCountForQueue(
+		ctx,
 		queue.ClientID,
 		queue.GuildID,
 	)
 	if headSong, err := store.GetHeadSongForQueue(
+		ctx,
 		queue.ClientID,
 		queue.GuildID,
 	); err == nil {
@@ -64,6 +50,7 @@ func (store *SongStore) UpdateQueueWithSongs(queue *model.Queue) (*model.Queue,
 		return queue, nil
 	}
 	if songs, err := store.GetSongsForQueue(
+		ctx,
 		queue.ClientID,
 		queue.GuildID,
 		queue.Offset+1,
@@ -71,8 +58,23 @@ func (store *SongStore) UpdateQueueWithSongs(queue *model.Queue) (*model.Queue,
 	); err == nil {
 		queue.Songs = songs
 		queue.Size = store.GetSongCountForQueue(
+			ctx,
+			queue.ClientID,
+			queue.GuildID,
+		)
+		queue.Duration = store.GetDurationForQueue(
+			ctx,
+			queue.ClientID,
+			queue.GuildID,
+			0,
+			queue.Size,
+		)
+		queue.DurationBefore = store.GetDurationForQueue(
+			ctx,
 			queue.ClientID,
 			queue.GuildID,
+			0,
+			queue.Offset+1,
 		)
 	} else {
 		return nil, err
@@ -84,13 +86,13 @@ func (store *SongStore) UpdateQueueWithSongs(queue *model.Queue) (*model.Queue,
 // in a single query.
 // The saved songs belong to the queue identified by the provided
 // clientID and guildID
-func (store *SongStore) PersistSongs(clientID string, guildID string, songs ...*model.Song) error {
+func (store *SongStore) PersistSongs(ctx context.Context, clientID string, guildID string, songs ...*model.Song) error {
 	if len(songs) < 1 {
 		return nil
 	}
 
-	i, t := store.idx, time.Now()
-	store.idx++
+	i, t, ctx, done := store.trace.Start(ctx, "PersistSongs")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
@@ -98,6 +100,7 @@ func (store *SongStore) PersistSongs(clientID string, guildID string, songs ...*
 	}).Tracef("[S%d]Start: Persist %d songs", i, len(songs))
 
 	maxPosition, err := store.getMaxSongPosition(
+		ctx,
 		clientID,
 		guildID,
 	)
@@ -107,7 +110,8 @@ func (store *SongStore) PersistSongs(clientID string, guildID string, songs ...*
 	s := `
     INSERT INTO "song" (
         position, name, short_name, url, duration_seconds,
-        duration_string, color, queue_client_id, queue_guild_id
+        duration_string, color, queue_client_id, queue_guild_id,
+        requester_id, thumbnail_url, channel_name
     ) VALUES
     `
 	used := make(map[string]struct{})
@@ -136,14 +140,17 @@ func (store *SongStore) PersistSongs(clientID string, guildID string, songs ...*
 		params = append(params, song.Color)
 		params = append(params, clientID)
 		params = append(params, guildID)
+		params = append(params, song.RequesterID)
+		params = append(params, song.ThumbnailUrl)
+		params = append(params, song.ChannelName)
 		s += fmt.Sprintf(
-			` ($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)`,
-			p, p+1, p+2, p+3, p+4, p+5, p+6, p+7, p+8,
+			` ($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)`,
+			p, p+1, p+2, p+3, p+4, p+5, p+6, p+7, p+8, p+9, p+10, p+11,
 		)
-		p += 9
+		p += 12
 	}
 	s += ";"
-	if _, err := store.db.Exec(s, params...); err != nil {
+	if _, err := store.db.ExecContext(ctx, s, params...); err != nil {
 		store.log.Tracef("[S%d]Error: %v", i, err)
 		return err
 	}
@@ -157,9 +164,9 @@ func (store *SongStore) PersistSongs(clientID string, guildID string, songs ...*
 // PersistSongToFront saves the provided song to the database.
 // The song's position is set to 1 less than the minimum position of the
 // queue identified with the provided clientID and guildID
-func (store *SongStore) PersistSongToFront(clientID string, guildID string, song *model.Song) error {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *SongStore) PersistSongToFront(ctx context.Context, clientID string, guildID string, song *model.Song) error {
+	i, t, ctx, done := store.trace.Start(ctx, "PersistSongToFront")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
@@ -167,6 +174,7 @@ func (store *SongStore) PersistSongToFront(clientID string, guildID string, song
 	}).Tracef("[S%d]Start: Persist song to front", i)
 
 	minPosition, err := store.getMinSongPosition(
+		ctx,
 		clientID,
 		guildID,
 	)
@@ -175,12 +183,14 @@ func (store *SongStore) PersistSongToFront(clientID string, guildID string, song
 		return err
 	}
 
-	if _, err := store.db.Exec(
+	if _, err := store.db.ExecContext(
+		ctx,
 		`
     INSERT INTO "song" (
         position, name, short_name, url, duration_seconds,
-        duration_string, color, queue_client_id, queue_guild_id
-    ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        duration_string, color, queue_client_id, queue_guild_id,
+        requester_id, thumbnail_url, channel_name
+    ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
     `,
 		minPosition-1,
 		song.Name,
@@ -191,6 +201,9 @@ func (store *SongStore) PersistSongToFront(clientID string, guildID string, song
 		song.Color,
 		clientID,
 		guildID,
+		song.RequesterID,
+		song.ThumbnailUrl,
+		song.ChannelName,
 	); err != nil {
 		store.log.Tracef("[S%d]Error: %v", i, err)
 		return err
@@ -205,8 +218,8 @@ func (store *SongStore) PersistSongToFront(clientID string, guildID string, song
 
 // GetHeadSongForQueue returns the songs with the smallest position
 // in the queue identified by the provided clientID and guildID.
-func (store *SongStore) GetHeadSongForQueue(clientID string, guildID string) (*model.Song, error) {
-	songs, err := store.GetSongsForQueue(clientID, guildID, 0, 1)
+func (store *SongStore) GetHeadSongForQueue(ctx context.Context, clientID string, guildID string) (*model.Song, error) {
+	songs, err := store.GetSongsForQueue(ctx, clientID, guildID, 0, 1)
 	if err != nil {
 		return nil, err
 	}
@@ -219,9 +232,9 @@ func (store *SongStore) GetHeadSongForQueue(clientID string, guildID string) (*m
 // GetSongsForQueue fetches the songs that belong to the queue identified
 // by the provided clientID and guilID,
 // limited by the provided offset and limit.
-func (store *SongStore) GetSongsForQueue(clientID string, guildID string, offset int, limit int) ([]*model.Song, error) {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *SongStore) GetSongsForQueue(ctx context.Context, clientID string, guildID string, offset int, limit int) ([]*model.Song, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetSongsForQueue")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
@@ -229,7 +242,8 @@ func (store *SongStore) GetSongsForQueue(clientID string, guildID string, offset
 		"Offset":   offset,
 	}).Tracef("[S%d]Start: Fetch %d songs for queue", i, limit)
 
-	if rows, err := store.db.Query(
+	if rows, err := store.db.QueryContext(
+		ctx,
 		`
         SELECT * FROM "song"
         WHERE "song".queue_client_id = $1 AND
@@ -256,6 +270,8 @@ func (store *SongStore) GetSongsForQueue(clientID string, guildID string, offset
 				&song.DurationSeconds,
 				&song.DurationString,
 				&song.Color, &ignore, &ignore,
+				&song.RequesterID,
+				&song.ThumbnailUrl, &song.ChannelName,
 			); err != nil {
 				store.log.Tracef(
 					"[S%d]Error: %v", i, err,
@@ -274,16 +290,17 @@ func (store *SongStore) GetSongsForQueue(clientID string, guildID string, offset
 
 // GetSongsForQueue fetches all the songs that belong to the queue identified
 // by the provided clientID and guilID.
-func (store *SongStore) GetAllSongsForQueue(clientID string, guildID string) ([]*model.Song, error) {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *SongStore) GetAllSongsForQueue(ctx context.Context, clientID string, guildID string) ([]*model.Song, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetAllSongsForQueue")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
 		"GuildID":  guildID,
 	}).Tracef("[S%d]Start: Fetch all songs for queue", i)
 
-	if rows, err := store.db.Query(
+	if rows, err := store.db.QueryContext(
+		ctx,
 		`
         SELECT * FROM "song"
         WHERE "song".queue_client_id = $1 AND
@@ -306,6 +323,8 @@ func (store *SongStore) GetAllSongsForQueue(clientID string, guildID string) ([]
 				&song.DurationSeconds,
 				&song.DurationString,
 				&song.Color, &ignore, &ignore,
+				&song.RequesterID,
+				&song.ThumbnailUrl, &song.ChannelName,
 			); err != nil {
 				store.log.Tracef(
 					"[S%d]Error: %v", i, err,
@@ -324,9 +343,9 @@ func (store *SongStore) GetAllSongsForQueue(clientID string, guildID string) ([]
 
 // GetSongCountForQueue returns the number of songs that belong
 // to the queue identified by the provided clientID and guildID
-func (store *SongStore) GetSongCountForQueue(clientID string, guildID string) int {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *SongStore) GetSongCountForQueue(ctx context.Context, clientID string, guildID string) int {
+	i, t, ctx, done := store.trace.Start(ctx, "GetSongCountForQueue")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
@@ -334,7 +353,8 @@ func (store *SongStore) GetSongCountForQueue(clientID string, guildID string) in
 	}).Tracef("[S%d]Start: Fetch song count for queue", i)
 
 	var count int
-	if err := store.db.QueryRow(
+	if err := store.db.QueryRowContext(
+		ctx,
 		`
         SELECT COUNT(*) FROM "song"
         WHERE "song".queue_client_id = $1 AND
@@ -354,23 +374,66 @@ func (store *SongStore) GetSongCountForQueue(clientID string, guildID string) in
 	return count
 }
 
+// GetDurationForQueue returns the total duration in seconds of the
+// songs that belong to the queue identified by the provided clientID
+// and guildID, limited by the provided offset and limit.
+func (store *SongStore) GetDurationForQueue(ctx context.Context, clientID string, guildID string, offset int, limit int) int {
+	i, t, ctx, done := store.trace.Start(ctx, "GetDurationForQueue")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+		"Offset":   offset,
+	}).Tracef("[S%d]Start: Fetch duration of %d songs for queue", i, limit)
+
+	var duration int
+	if err := store.db.QueryRowContext(
+		ctx,
+		`
+        SELECT COALESCE(SUM(s.duration_seconds), 0) FROM (
+            SELECT "song".duration_seconds FROM "song"
+            WHERE "song".queue_client_id = $1 AND
+                "song".queue_guild_id = $2
+            ORDER BY position ASC
+            LIMIT $3
+            OFFSET $4
+        ) s
+        `,
+		clientID,
+		guildID,
+		limit,
+		offset,
+	).Scan(&duration); err != nil {
+		store.log.Tracef(
+			"[S%d]Error: %v", i, err,
+		)
+		duration = 0
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[S%d]Done : Duration for queue fetched (%d)", i, duration)
+	return duration
+}
+
 // RemoveHeadSong removes song with the minimum position belonging to the
 // queue identified with the provided clientID and guildID
-func (store *SongStore) RemoveHeadSong(clientID string, guildID string) error {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *SongStore) RemoveHeadSong(ctx context.Context, clientID string, guildID string) error {
+	i, t, ctx, done := store.trace.Start(ctx, "RemoveHeadSong")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
 		"GuildID":  guildID,
 	}).Tracef("[S%d]Start: Remove head song", i)
 
-	minPosition, err := store.getMinSongPosition(clientID, guildID)
+	minPosition, err := store.getMinSongPosition(ctx, clientID, guildID)
 	if err != nil {
 		store.log.Tracef("[S%d]Error: %v", i, err)
 		return err
 	}
-	if _, err := store.db.Exec(
+	if _, err := store.db.ExecContext(
+		ctx,
 		`
 
         DELETE FROM "song"
@@ -393,26 +456,27 @@ func (store *SongStore) RemoveHeadSong(clientID string, guildID string) error {
 
 // PushHeadSongToBack places the song with the min song position to the back
 // of the queue, by setting it's position 1 more than the song with max position
-func (store *SongStore) PushHeadSongToBack(clientID string, guildID string) error {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *SongStore) PushHeadSongToBack(ctx context.Context, clientID string, guildID string) error {
+	i, t, ctx, done := store.trace.Start(ctx, "PushHeadSongToBack")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
 		"GuildID":  guildID,
 	}).Tracef("[S%d]Start: Push head song to back", i)
 
-	minPosition, err := store.getMinSongPosition(clientID, guildID)
+	minPosition, err := store.getMinSongPosition(ctx, clientID, guildID)
 	if err != nil {
 		store.log.Tracef("[S%d]Error: %v", i, err)
 		return err
 	}
-	maxPosition, err := store.getMaxSongPosition(clientID, guildID)
+	maxPosition, err := store.getMaxSongPosition(ctx, clientID, guildID)
 	if err != nil {
 		store.log.Tracef("[S%d]Error: %v", i, err)
 		return err
 	}
-	if _, err := store.db.Exec(
+	if _, err := store.db.ExecContext(
+		ctx,
 		`
         UPDATE "song" SET
         position = $1
@@ -436,26 +500,27 @@ func (store *SongStore) PushHeadSongToBack(clientID string, guildID string) erro
 
 // PushLastSongToFront places the song with the max song position to the front
 // of the queue, by setting it's position 1 less than the song with min position
-func (store *SongStore) PushLastSongToFront(clientID string, guildID string) error {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *SongStore) PushLastSongToFront(ctx context.Context, clientID string, guildID string) error {
+	i, t, ctx, done := store.trace.Start(ctx, "PushLastSongToFront")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
 		"GuildID":  guildID,
 	}).Tracef("[S%d]Start: Push last song to front", i)
 
-	minPosition, err := store.getMinSongPosition(clientID, guildID)
+	minPosition, err := store.getMinSongPosition(ctx, clientID, guildID)
 	if err != nil {
 		store.log.Tracef("[S%d]Error: %v", i, err)
 		return err
 	}
-	maxPosition, err := store.getMaxSongPosition(clientID, guildID)
+	maxPosition, err := store.getMaxSongPosition(ctx, clientID, guildID)
 	if err != nil {
 		store.log.Tracef("[S%d]Error: %v", i, err)
 		return err
 	}
-	if _, err := store.db.Exec(
+	if _, err := store.db.ExecContext(
+		ctx,
 		`
         UPDATE "song" SET
         position = $1
@@ -481,16 +546,17 @@ func (store *SongStore) PushLastSongToFront(clientID string, guildID string) err
 // queue, identified by the provided clientID and guildID.
 // If force is true, the songs are deleted, else they are moved
 // to the 'inactive_song' table.
-func (store *SongStore) RemoveSongs(clientID string, guildID string, ids ...uint) error {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *SongStore) RemoveSongs(ctx context.Context, clientID string, guildID string, ids ...uint) error {
+	i, t, ctx, done := store.trace.Start(ctx, "RemoveSongs")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
 		"GuildID":  guildID,
 	}).Tracef("[S%d]Start: Remove %d songs from queue", i, len(ids))
 
-	if _, err := store.db.Exec(
+	if _, err := store.db.ExecContext(
+		ctx,
 		`
         DELETE FROM "song"
         WHERE "song".id = ANY($1) AND
@@ -513,9 +579,9 @@ func (store *SongStore) RemoveSongs(clientID string, guildID string, ids ...uint
 
 // getMaxSongPosition returns the maximum position of a song
 // that belongs to the queue identified with the provided clientID and guildID
-func (store *SongStore) getMaxSongPosition(clientID string, guildID string) (int, error) {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *SongStore) getMaxSongPosition(ctx context.Context, clientID string, guildID string) (int, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "getMaxSongPosition")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
@@ -523,7 +589,8 @@ func (store *SongStore) getMaxSongPosition(clientID string, guildID string) (int
 	}).Tracef("[S%d]Start: Fetch max song position for queue", i)
 
 	var position int = 0
-	if err := store.db.QueryRow(
+	if err := store.db.QueryRowContext(
+		ctx,
 		`
         SELECT COALESCE(MAX(s.position), 0)
         FROM "song" s
@@ -546,9 +613,9 @@ func (store *SongStore) getMaxSongPosition(clientID string, guildID string) (int
 
 // getMaxSongPosition returns the minimum position of a song
 // that belongs to the queue identified with the provided clientID and guildID
-func (store *SongStore) getMinSongPosition(clientID string, guildID string) (int, error) {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *SongStore) getMinSongPosition(ctx context.Context, clientID string, guildID string) (int, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "getMinSongPosition")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
@@ -556,7 +623,8 @@ func (store *SongStore) getMinSongPosition(clientID string, guildID string) (int
 	}).Tracef("[S%d]Start: Fetch min song position for queue", i)
 
 	var position int = 0
-	if err := store.db.QueryRow(
+	if err := store.db.QueryRowContext(
+		ctx,
 		`
         SELECT COALESCE(MIN(s.position), 0)
         FROM "song" s
@@ -581,13 +649,13 @@ func (store *SongStore) getMinSongPosition(clientID string, guildID string) (int
 // the database in a single query.
 // The persisted inactive songs will be automatically deleted
 // after some time.
-func (store *SongStore) PersistInactiveSongs(clientID string, guildID string, songs ...*model.Song) error {
+func (store *SongStore) PersistInactiveSongs(ctx context.Context, clientID string, guildID string, songs ...*model.Song) error {
 	if len(songs) < 1 {
 		return nil
 	}
 
-	i, t := store.idx, time.Now()
-	store.idx++
+	i, t, ctx, done := store.trace.Start(ctx, "PersistInactiveSongs")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
@@ -597,7 +665,8 @@ func (store *SongStore) PersistInactiveSongs(clientID string, guildID string, so
 	s := `
     INSERT INTO "inactive_song" (
         name, short_name, url, duration_seconds,
-        duration_string, color, queue_client_id, queue_guild_id
+        duration_string, color, queue_client_id, queue_guild_id,
+        requester_id, thumbnail_url, channel_name
     ) VALUES
     `
 	idx := 0
@@ -619,13 +688,16 @@ func (store *SongStore) PersistInactiveSongs(clientID string, guildID string, so
 		params = append(params, song.Color)
 		params = append(params, clientID)
 		params = append(params, guildID)
+		params = append(params, song.RequesterID)
+		params = append(params, song.ThumbnailUrl)
+		params = append(params, song.ChannelName)
 		s += fmt.Sprintf(
-			` ($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)`,
-			p, p+1, p+2, p+3, p+4, p+5, p+6, p+7,
+			` ($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)`,
+			p, p+1, p+2, p+3, p+4, p+5, p+6, p+7, p+8, p+9, p+10,
 		)
-		p += 8
+		p += 11
 	}
-	if _, err := store.db.Exec(s, params...); err != nil {
+	if _, err := store.db.ExecContext(ctx, s, params...); err != nil {
 		store.log.Tracef("[S%d]Error: %v", i, err)
 		return err
 	}
@@ -639,9 +711,9 @@ func (store *SongStore) PersistInactiveSongs(clientID string, guildID string, so
 // PopLatestInactiveSong deletes the inactive song, belonging to the queue
 // identified with the provided clientID and guildID, that was added last
 // to the database, and returns it
-func (store *SongStore) PopLatestInactiveSong(clientID string, guildID string) (*model.Song, error) {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *SongStore) PopLatestInactiveSong(ctx context.Context, clientID string, guildID string) (*model.Song, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "PopLatestInactiveSong")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
@@ -651,7 +723,8 @@ func (store *SongStore) PopLatestInactiveSong(clientID string, guildID string) (
 	song := &model.Song{}
 	var ignore string
 
-	if err := store.db.QueryRow(
+	if err := store.db.QueryRowContext(
+		ctx,
 		`
         DELETE FROM "inactive_song"
         WHERE "inactive_song".queue_client_id = $1 AND
@@ -672,6 +745,8 @@ func (store *SongStore) PopLatestInactiveSong(clientID string, guildID string) (
 		&song.Name, &song.ShortName, &song.Url,
 		&song.DurationSeconds, &song.DurationString,
 		&song.Color, &ignore, &ignore, &ignore,
+		&song.RequesterID,
+		&song.ThumbnailUrl, &song.ChannelName,
 	); err != nil {
 		store.log.Tracef("[S%d]Error: %v", i, err)
 		return nil, err
@@ -687,9 +762,9 @@ func (store *SongStore) PopLatestInactiveSong(clientID string, guildID string) (
 // GetInactiveSongCountForQueue returns the number of inactive
 // songs that belong to the queue
 // identified by the provided clientID and guildID
-func (store *SongStore) GetInactiveSongCountForQueue(clientID string, guildID string) int {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *SongStore) GetInactiveSongCountForQueue(ctx context.Context, clientID string, guildID string) int {
+	i, t, ctx, done := store.trace.Start(ctx, "GetInactiveSongCountForQueue")
+	defer done()
 
 	store.log.WithFields(log.Fields{
 		"ClientID": clientID,
@@ -697,7 +772,8 @@ func (store *SongStore) GetInactiveSongCountForQueue(clientID string, guildID st
 	}).Tracef("[S%d]Start: Fetch inactive song count for queue", i)
 
 	var count int
-	if err := store.db.QueryRow(
+	if err := store.db.QueryRowContext(
+		ctx,
 		`
         SELECT COUNT(*) FROM "inactive_song"
         WHERE "inactive_song".queue_client_id = $1 AND
@@ -733,29 +809,30 @@ func (store *SongStore) RunInactiveSongsCleanup(ctx context.Context) {
 	done := ctx.Done()
 	ticker := time.NewTicker(store.inactiveSongTTL)
 
-	store.removeOutdatedInactiveSongs()
+	store.removeOutdatedInactiveSongs(ctx)
 
 	for {
 		select {
 		case <-done:
 			return
 		case <-ticker.C:
-			store.removeOutdatedInactiveSongs()
+			store.removeOutdatedInactiveSongs(ctx)
 		}
 	}
 }
 
 // removeOutdatedInactiveSongs removes all the inactive songs
 // with "added" column older than the InactiveSongTTL cofnig option.
-func (store *SongStore) removeOutdatedInactiveSongs() {
-	i, t := store.idx, time.Now()
-	store.idx++
+func (store *SongStore) removeOutdatedInactiveSongs(ctx context.Context) {
+	i, t, ctx, done := store.trace.Start(ctx, "removeOutdatedInactiveSongs")
+	defer done()
 
 	store.log.Tracef(
 		"[S%d]Start: Remove outdated inactive songs", i,
 	)
 
-	if _, err := store.db.Exec(
+	if _, err := store.db.ExecContext(
+		ctx,
 		`
         DELETE FROM "inactive_song"
         WHERE "inactive_song".added <= $1;
@@ -773,167 +850,3 @@ func (store *SongStore) removeOutdatedInactiveSongs() {
 	).Tracef("[S%d]Done : Outdated inactive songs removed", i)
 
 }
-
-// createSongTable creates the "song" table
-// with all it's constraints
-// if it does  not already exist
-func (store *SongStore) createSongTable() error {
-	i, t := store.idx, time.Now()
-	store.idx++
-
-	store.log.WithField("TableName", "song").Tracef(
-		"[S%d]Start: Create psql table (if not exists)", i,
-	)
-
-	if _, err := store.db.Exec(
-		`
-        CREATE TABLE IF NOT EXISTS "song" (
-            id SERIAL,
-            position INTEGER NOT NULL DEFAULT '0',
-            name VARCHAR NOT NULL,
-            short_name VARCHAR NOT NULL,
-            url VARCHAR NOT NULL,
-            duration_seconds INTEGER NOT NULL,
-            duration_string VARCHAR NOT NULL,
-            color INTEGER NOT NULL,
-            queue_client_id VARCHAR,
-            queue_guild_id VARCHAR,
-            PRIMARY KEY (id)
-        );
-
-        DO $$
-        DECLARE
-            info_table information_schema.tables%rowtype;
-        BEGIN
-        SELECT *
-        FROM information_schema.tables
-        INTO info_table
-        WHERE table_name = 'queue';
-
-        IF FOUND THEN
-            ALTER TABLE "song" DROP CONSTRAINT
-                IF EXISTS "song_queue_fkey";
-            ALTER TABLE "song"
-            ADD CONSTRAINT  "song_queue_fkey"
-            FOREIGN KEY (queue_client_id, queue_guild_id)
-                REFERENCES "queue" (client_id, guild_id)
-                    ON DELETE CASCADE;
-        END IF;
-        END $$
-        `,
-	); err != nil {
-		store.log.Tracef(
-			"[S%d]Error: %v", i, err,
-		)
-		return err
-	}
-	store.log.WithField(
-		"Latency", time.Since(t),
-	).Tracef("[S%d]Done : psql table created", i)
-	return nil
-}
-
-// createInactiveSongTable creates the "inactive_song" table
-// with all it's constraints
-// if it does  not already exist
-func (store *SongStore) createInactiveSongTable() error {
-	i, t := store.idx, time.Now()
-	store.idx++
-
-	store.log.WithField("TableName", "inactive_song").Tracef(
-		"[S%d]Start: Create psql table (if not exists)", i,
-	)
-
-	if _, err := store.db.Exec(
-		`
-        CREATE TABLE IF NOT EXISTS "inactive_song" (
-            id SERIAL,
-            name VARCHAR NOT NULL,
-            short_name VARCHAR NOT NULL,
-            url VARCHAR NOT NULL,
-            duration_seconds INTEGER NOT NULL,
-            duration_string VARCHAR NOT NULL,
-            color INTEGER NOT NULL,
-            queue_client_id VARCHAR,
-            queue_guild_id VARCHAR,
-            added timestamp DEFAULT ((CURRENT_TIMESTAMP)),
-            PRIMARY KEY (id)
-        );
-
-        DO $$
-        DECLARE
-            info_table information_schema.tables%rowtype;
-        BEGIN
-        SELECT *
-        FROM information_schema.tables
-        INTO info_table
-        WHERE table_name = 'queue';
-
-        IF FOUND THEN
-            ALTER TABLE "inactive_song" DROP CONSTRAINT
-                IF EXISTS "inactive_song_queue_fkey";
-            ALTER TABLE "inactive_song"
-            ADD CONSTRAINT  "inactive_song_queue_fkey"
-            FOREIGN KEY (queue_client_id, queue_guild_id)
-                REFERENCES "queue" (client_id, guild_id)
-                    ON DELETE CASCADE;
-        END IF;
-        END $$
-        `,
-	); err != nil {
-		store.log.Tracef(
-			"[S%d]Error: %v", i, err,
-		)
-		return err
-	}
-	store.log.WithField(
-		"Latency", time.Since(t),
-	).Tracef("[S%d]Done : psql table created", i)
-	return nil
-}
-
-// dropSongTable drops the "song" table.
-func (store *SongStore) dropSongTable() error {
-	i, t := store.idx, time.Now()
-	store.idx++
-
-	store.log.WithField("TableName", "song").Tracef(
-		"[S%d]Start: Drop psql table (if exists)", i,
-	)
-
-	if _, err := store.db.Exec(
-		`DROP TABLE IF EXISTS "song" CASCADE`,
-	); err != nil {
-		store.log.Tracef(
-			"[S%d]Error: %v", i, err,
-		)
-		return err
-	}
-	store.log.WithField(
-		"Latency", time.Since(t),
-	).Tracef("[S%d]Done : psql table dropped", i)
-	return nil
-}
-
-// dropInactiveSongTable drops the "inactive_song" table.
-func (store *SongStore) dropInactiveSongTable() error {
-	i, t := store.idx, time.Now()
-	store.idx++
-
-	store.log.WithField("TableName", "inactive_song").Tracef(
-		"[S%d]Start: Drop psql table (if exists)", i,
-	)
-
-	if _, err := store.db.Exec(
-		`DROP TABLE IF EXISTS "inactive_song" CASCADE`,
-	); err != nil {
-		store.log.Tracef(
-			"[S%d]Error: %v", i, err,
-		)
-		return err
-	}
-	store.log.WithField(
-		"Latency", time.Since(t),
-	).Tracef("[S%d]Done : psql table dropped", i)
-	return nil
-}
@@ -0,0 +1,69 @@
+package song
+
+import (
+	"context"
+	"discord-music-bot/model"
+)
+
+// SongRepository handles persisting and removing
+// the queues' songs and inactive songs in a datastore.
+type SongRepository interface {
+	// UpdateQueueWithSongs fetches the queue's songs, limited by
+	// the queue's offset and limit, the total size of the queue
+	// and the durations of it's songs.
+	UpdateQueueWithSongs(ctx context.Context, queue *model.Queue) (*model.Queue, error)
+	// PersistSongs saves the provided songs to the back of the
+	// queue identified by the provided clientID and guildID.
+	PersistSongs(ctx context.Context, clientID string, guildID string, songs ...*model.Song) error
+	// PersistSongToFront saves the provided song to the front of the
+	// queue identified by the provided clientID and guildID.
+	PersistSongToFront(ctx context.Context, clientID string, guildID string, song *model.Song) error
+	// GetHeadSongForQueue returns the song with the smallest position
+	// in the queue identified by the provided clientID and guildID.
+	GetHeadSongForQueue(ctx context.Context, clientID string, guildID string) (*model.Song, error)
+	// GetSongsForQueue fetches the songs that belong to the queue
+	// identified by the provided clientID and guildID, ordered by
+	// their position and limited by the provided offset and limit.
+	GetSongsForQueue(ctx context.Context, clientID string, guildID string, offset int, limit int) ([]*model.Song, error)
+	// GetAllSongsForQueue fetches all the songs that belong to the
+	// queue identified by the provided clientID and guildID.
+	GetAllSongsForQueue(ctx context.Context, clientID string, guildID string) ([]*model.Song, error)
+	// GetSongCountForQueue returns the number of songs that belong
+	// to the queue identified by the provided clientID and guildID.
+	GetSongCountForQueue(ctx context.Context, clientID string, guildID string) int
+	// GetDurationForQueue returns the total duration in seconds of the
+	// songs that belong to the queue identified by the provided clientID
+	// and guildID, ordered by their position and limited by the
+	// provided offset and limit.
+	GetDurationForQueue(ctx context.Context, clientID string, guildID string, offset int, limit int) int
+	// RemoveHeadSong removes the song with the minimum position from
+	// the queue identified by the provided clientID and guildID.
+	RemoveHeadSong(ctx context.Context, clientID string, guildID string) error
+	// PushHeadSongToBack places the song with the minimum position
+	// to the back of the queue.
+	PushHeadSongToBack(ctx context.Context, clientID string, guildID string) error
+	// PushLastSongToFront places the song with the maximum position
+	// to the front of the queue.
+	PushLastSongToFront(ctx context.Context, clientID string, guildID string) error
+	// RemoveSongs removes the songs with the provided ids from the
+	// queue identified by the provided clientID and guildID.
+	RemoveSongs(ctx context.Context, clientID string, guildID string, ids ...uint) error
+	// PersistInactiveSongs saves the provided songs as inactive
+	// songs of the queue. They are automatically removed after some time.
+	PersistInactiveSongs(ctx context.Context, clientID string, guildID string, songs ...*model.Song) error
+	// PopLatestInactiveSong removes the latest added inactive song of the
+	// queue identified by the provided clientID and guildID and returns it.
+	PopLatestInactiveSong(ctx context.Context, clientID string, guildID string) (*model.Song, error)
+	// GetInactiveSongCountForQueue returns the number of inactive songs
+	// that belong to the queue identified by the provided clientID and guildID.
+	GetInactiveSongCountForQueue(ctx context.Context, clientID string, guildID string) int
+	// RunInactiveSongsCleanup is a long lived worker, that removes
+	// the outdated inactive songs at interval.
+	RunInactiveSongsCleanup(ctx context.Context)
+}
+
+var (
+	_ SongRepository = (*SongStore)(nil)
+	_ SongRepository = (*MemorySongStore)(nil)
+	_ SongRepository = (*SqliteSongStore)(nil)
+)
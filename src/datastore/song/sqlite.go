@@ -0,0 +1,805 @@
+package song
+
+import (
+	"context"
+	"database/sql"
+	"discord-music-bot/datastore/sqldb"
+	"discord-music-bot/model"
+	"errors"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type SqliteSongStore struct {
+	log             *log.Logger
+	db              sqldb.Querier
+	inactiveSongTTL time.Duration
+	trace           *sqldb.Tracer
+}
+
+// NewSqliteSongStore creates an object that handles
+// persisting and removing Songs in sqlite database.
+func NewSqliteSongStore(db sqldb.Querier, log *log.Logger, inactiveSongTTL time.Duration, options sqldb.Options) *SqliteSongStore {
+	return &SqliteSongStore{
+		log:             log,
+		db:              db,
+		trace:           sqldb.NewTracer(log, "S", options),
+		inactiveSongTTL: inactiveSongTTL,
+	}
+}
+
+// UpdateQueueWithSongs fetches the queue's songs,
+// limited by the queue's offset and limit, the total
+// size of the queue and the durations of it's songs.
+func (store *SqliteSongStore) UpdateQueueWithSongs(ctx context.Context, queue *model.Queue) (*model.Queue, error) {
+	queue.InactiveSize = store.GetInactiveSongCountForQueue(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+	)
+	if headSong, err := store.GetHeadSongForQueue(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+	); err == nil {
+		queue.HeadSong = headSong
+	} else {
+		return queue, nil
+	}
+	if songs, err := store.GetSongsForQueue(
+		ctx,
+		queue.ClientID,
+		queue.GuildID,
+		queue.Offset+1,
+		queue.Limit,
+	); err == nil {
+		queue.Songs = songs
+		queue.Size = store.GetSongCountForQueue(
+			ctx,
+			queue.ClientID,
+			queue.GuildID,
+		)
+		queue.Duration = store.GetDurationForQueue(
+			ctx,
+			queue.ClientID,
+			queue.GuildID,
+			0,
+			queue.Size,
+		)
+		queue.DurationBefore = store.GetDurationForQueue(
+			ctx,
+			queue.ClientID,
+			queue.GuildID,
+			0,
+			queue.Offset+1,
+		)
+	} else {
+		return nil, err
+	}
+	return queue, nil
+}
+
+// PersistSongs inserts all of the provided songs to the database
+// in a single query.
+// The saved songs belong to the queue identified by the provided
+// clientID and guildID
+func (store *SqliteSongStore) PersistSongs(ctx context.Context, clientID string, guildID string, songs ...*model.Song) error {
+	if len(songs) < 1 {
+		return nil
+	}
+
+	i, t, ctx, done := store.trace.Start(ctx, "PersistSongs")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[S%d]Start: Persist %d songs", i, len(songs))
+
+	maxPosition, err := store.getMaxSongPosition(
+		ctx,
+		clientID,
+		guildID,
+	)
+	if err != nil {
+		return err
+	}
+	used := make(map[string]struct{})
+	values := make([]string, 0)
+	params := make([]interface{}, 0)
+	for _, song := range songs {
+		if song == nil {
+			continue
+		}
+		if _, ok := used[song.Name]; ok {
+			continue
+		}
+		maxPosition++
+		used[song.Name] = struct{}{}
+		values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		params = append(params,
+			maxPosition, song.Name, song.ShortName, song.Url,
+			song.DurationSeconds, song.DurationString, song.Color,
+			clientID, guildID, song.RequesterID,
+			song.ThumbnailUrl, song.ChannelName,
+		)
+	}
+	if _, err := store.db.ExecContext(
+		ctx,
+		`
+        INSERT INTO "song" (
+            position, name, short_name, url, duration_seconds,
+            duration_string, color, queue_client_id, queue_guild_id,
+            requester_id, thumbnail_url, channel_name
+        ) VALUES `+strings.Join(values, ", ")+`;
+        `,
+		params...,
+	); err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return err
+	}
+	store.log.WithField(
+		"Latency",
+		time.Since(t),
+	).Tracef("[S%d]Done : %d songs persisted", i, len(songs))
+	return nil
+}
+
+// PersistSongToFront saves the provided song to the database.
+// The song's position is set to 1 less than the minimum position of the
+// queue identified with the provided clientID and guildID
+func (store *SqliteSongStore) PersistSongToFront(ctx context.Context, clientID string, guildID string, song *model.Song) error {
+	i, t, ctx, done := store.trace.Start(ctx, "PersistSongToFront")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[S%d]Start: Persist song to front", i)
+
+	minPosition, err := store.getMinSongPosition(
+		ctx,
+		clientID,
+		guildID,
+	)
+	if err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return err
+	}
+
+	if _, err := store.db.ExecContext(
+		ctx,
+		`
+        INSERT INTO "song" (
+            position, name, short_name, url, duration_seconds,
+            duration_string, color, queue_client_id, queue_guild_id,
+            requester_id, thumbnail_url, channel_name
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+        `,
+		minPosition-1,
+		song.Name,
+		song.ShortName,
+		song.Url,
+		song.DurationSeconds,
+		song.DurationString,
+		song.Color,
+		clientID,
+		guildID,
+		song.RequesterID,
+		song.ThumbnailUrl,
+		song.ChannelName,
+	); err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return err
+	}
+
+	store.log.WithField(
+		"Latency",
+		time.Since(t),
+	).Tracef("[S%d]Done : song persisted to front", i)
+	return nil
+}
+
+// GetHeadSongForQueue returns the songs with the smallest position
+// in the queue identified by the provided clientID and guildID.
+func (store *SqliteSongStore) GetHeadSongForQueue(ctx context.Context, clientID string, guildID string) (*model.Song, error) {
+	songs, err := store.GetSongsForQueue(ctx, clientID, guildID, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(songs) == 0 {
+		return nil, errors.New("sqlite: Found no head song for queue")
+	}
+	return songs[0], nil
+}
+
+// GetSongsForQueue fetches the songs that belong to the queue identified
+// by the provided clientID and guilID,
+// limited by the provided offset and limit.
+func (store *SqliteSongStore) GetSongsForQueue(ctx context.Context, clientID string, guildID string, offset int, limit int) ([]*model.Song, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetSongsForQueue")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+		"Offset":   offset,
+	}).Tracef("[S%d]Start: Fetch %d songs for queue", i, limit)
+
+	rows, err := store.db.QueryContext(
+		ctx,
+		`
+        SELECT * FROM "song"
+        WHERE "song".queue_client_id = ? AND
+            "song".queue_guild_id = ?
+        ORDER BY position ASC
+        LIMIT ?
+        OFFSET ?;
+        `,
+		clientID,
+		guildID,
+		limit,
+		offset,
+	)
+	if err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return nil, err
+	}
+	songs, err := store.scanSongs(rows)
+	if err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return nil, err
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[S%d]Done : %d songs for queue fetched", i, len(songs))
+	return songs, nil
+}
+
+// GetSongsForQueue fetches all the songs that belong to the queue identified
+// by the provided clientID and guilID.
+func (store *SqliteSongStore) GetAllSongsForQueue(ctx context.Context, clientID string, guildID string) ([]*model.Song, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "GetAllSongsForQueue")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[S%d]Start: Fetch all songs for queue", i)
+
+	rows, err := store.db.QueryContext(
+		ctx,
+		`
+        SELECT * FROM "song"
+        WHERE "song".queue_client_id = ? AND
+            "song".queue_guild_id = ?
+        ORDER BY position;
+        `,
+		clientID,
+		guildID,
+	)
+	if err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return nil, err
+	}
+	songs, err := store.scanSongs(rows)
+	if err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return nil, err
+	}
+	store.log.WithField("Latency", time.Since(t)).Tracef(
+		"[S%d]Done : %d songs fetched for queue", i, len(songs),
+	)
+	return songs, nil
+}
+
+// GetSongCountForQueue returns the number of songs that belong
+// to the queue identified by the provided clientID and guildID
+func (store *SqliteSongStore) GetSongCountForQueue(ctx context.Context, clientID string, guildID string) int {
+	i, t, ctx, done := store.trace.Start(ctx, "GetSongCountForQueue")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[S%d]Start: Fetch song count for queue", i)
+
+	var count int
+	if err := store.db.QueryRowContext(
+		ctx,
+		`
+        SELECT COUNT(*) FROM "song"
+        WHERE "song".queue_client_id = ? AND
+            "song".queue_guild_id = ?
+        `,
+		clientID,
+		guildID,
+	).Scan(&count); err != nil {
+		store.log.Tracef(
+			"[S%d]Error: %v", i, err,
+		)
+		count = 0
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[S%d]Done : Song count for queue fetched (%d)", i, count)
+	return count
+}
+
+// GetDurationForQueue returns the total duration in seconds of the
+// songs that belong to the queue identified by the provided clientID
+// and guildID, limited by the provided offset and limit.
+func (store *SqliteSongStore) GetDurationForQueue(ctx context.Context, clientID string, guildID string, offset int, limit int) int {
+	i, t, ctx, done := store.trace.Start(ctx, "GetDurationForQueue")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+		"Offset":   offset,
+	}).Tracef("[S%d]Start: Fetch duration of %d songs for queue", i, limit)
+
+	var duration int
+	if err := store.db.QueryRowContext(
+		ctx,
+		`
+        SELECT COALESCE(SUM(s.duration_seconds), 0) FROM (
+            SELECT "song".duration_seconds FROM "song"
+            WHERE "song".queue_client_id = ? AND
+                "song".queue_guild_id = ?
+            ORDER BY position ASC
+            LIMIT ?
+            OFFSET ?
+        ) s
+        `,
+		clientID,
+		guildID,
+		limit,
+		offset,
+	).Scan(&duration); err != nil {
+		store.log.Tracef(
+			"[S%d]Error: %v", i, err,
+		)
+		duration = 0
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[S%d]Done : Duration for queue fetched (%d)", i, duration)
+	return duration
+}
+
+// RemoveHeadSong removes song with the minimum position belonging to the
+// queue identified with the provided clientID and guildID
+func (store *SqliteSongStore) RemoveHeadSong(ctx context.Context, clientID string, guildID string) error {
+	i, t, ctx, done := store.trace.Start(ctx, "RemoveHeadSong")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[S%d]Start: Remove head song", i)
+
+	minPosition, err := store.getMinSongPosition(ctx, clientID, guildID)
+	if err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return err
+	}
+	if _, err := store.db.ExecContext(
+		ctx,
+		`
+        DELETE FROM "song"
+        WHERE "song".position = ? AND
+        "song".queue_client_id = ? AND
+        "song".queue_guild_id = ?;
+        `,
+		minPosition,
+		clientID,
+		guildID,
+	); err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return err
+	}
+	store.log.WithField("Latency", time.Since(t)).Tracef(
+		"[S%d]Done : Removed head song", i,
+	)
+	return nil
+}
+
+// PushHeadSongToBack places the song with the min song position to the back
+// of the queue, by setting it's position 1 more than the song with max position
+func (store *SqliteSongStore) PushHeadSongToBack(ctx context.Context, clientID string, guildID string) error {
+	i, t, ctx, done := store.trace.Start(ctx, "PushHeadSongToBack")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[S%d]Start: Push head song to back", i)
+
+	minPosition, err := store.getMinSongPosition(ctx, clientID, guildID)
+	if err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return err
+	}
+	maxPosition, err := store.getMaxSongPosition(ctx, clientID, guildID)
+	if err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return err
+	}
+	if err := store.updateSongPosition(
+		ctx,
+		clientID, guildID, minPosition, maxPosition+1,
+	); err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return err
+	}
+	store.log.WithField("Latency", time.Since(t)).Tracef(
+		"[S%d]Done : Pushed head song to back", i,
+	)
+	return nil
+}
+
+// PushLastSongToFront places the song with the max song position to the front
+// of the queue, by setting it's position 1 less than the song with min position
+func (store *SqliteSongStore) PushLastSongToFront(ctx context.Context, clientID string, guildID string) error {
+	i, t, ctx, done := store.trace.Start(ctx, "PushLastSongToFront")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[S%d]Start: Push last song to front", i)
+
+	minPosition, err := store.getMinSongPosition(ctx, clientID, guildID)
+	if err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return err
+	}
+	maxPosition, err := store.getMaxSongPosition(ctx, clientID, guildID)
+	if err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return err
+	}
+	if err := store.updateSongPosition(
+		ctx,
+		clientID, guildID, maxPosition, minPosition-1,
+	); err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return err
+	}
+	store.log.WithField("Latency", time.Since(t)).Tracef(
+		"[S%d]Done : Pushed last song to front", i,
+	)
+	return nil
+}
+
+// RemoveSongs removes songs with ID in the provided ids that belong to the
+// queue, identified by the provided clientID and guildID.
+func (store *SqliteSongStore) RemoveSongs(ctx context.Context, clientID string, guildID string, ids ...uint) error {
+	if len(ids) < 1 {
+		return nil
+	}
+	i, t, ctx, done := store.trace.Start(ctx, "RemoveSongs")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[S%d]Start: Remove %d songs from queue", i, len(ids))
+
+	placeholders := make([]string, 0)
+	params := make([]interface{}, 0)
+	for _, id := range ids {
+		placeholders = append(placeholders, "?")
+		params = append(params, id)
+	}
+	params = append(params, clientID, guildID)
+
+	if _, err := store.db.ExecContext(
+		ctx,
+		`
+        DELETE FROM "song"
+        WHERE "song".id IN (`+strings.Join(placeholders, ", ")+`) AND
+            "song".queue_client_id = ? AND
+            "song".queue_guild_id = ?
+        `,
+		params...,
+	); err != nil {
+		store.log.Tracef(
+			"[S%d]Error: %v", i, err,
+		)
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[S%d]Done : Removed songs from queeu", i)
+	return nil
+}
+
+// PersistInactiveSongs inserts all of the provided inactive songs to
+// the database in a single query.
+// The persisted inactive songs will be automatically deleted
+// after some time.
+func (store *SqliteSongStore) PersistInactiveSongs(ctx context.Context, clientID string, guildID string, songs ...*model.Song) error {
+	if len(songs) < 1 {
+		return nil
+	}
+
+	i, t, ctx, done := store.trace.Start(ctx, "PersistInactiveSongs")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[S%d]Start: Persist %d inactive songs", i, len(songs))
+
+	added := time.Now().UTC()
+	values := make([]string, 0)
+	params := make([]interface{}, 0)
+	for _, song := range songs {
+		if song == nil {
+			continue
+		}
+		values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		params = append(params,
+			song.Name, song.ShortName, song.Url,
+			song.DurationSeconds, song.DurationString, song.Color,
+			clientID, guildID, added, song.RequesterID,
+			song.ThumbnailUrl, song.ChannelName,
+		)
+	}
+	if _, err := store.db.ExecContext(
+		ctx,
+		`
+        INSERT INTO "inactive_song" (
+            name, short_name, url, duration_seconds,
+            duration_string, color, queue_client_id, queue_guild_id,
+            added, requester_id, thumbnail_url, channel_name
+        ) VALUES `+strings.Join(values, ", ")+`;
+        `,
+		params...,
+	); err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return err
+	}
+	store.log.WithField(
+		"Latency",
+		time.Since(t),
+	).Tracef("[S%d]Done : %d inactive songs persisted", i, len(songs))
+	return nil
+}
+
+// PopLatestInactiveSong deletes the inactive song, belonging to the queue
+// identified with the provided clientID and guildID, that was added last
+// to the database, and returns it
+func (store *SqliteSongStore) PopLatestInactiveSong(ctx context.Context, clientID string, guildID string) (*model.Song, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "PopLatestInactiveSong")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[S%d]Start: Pop latest inactive song", i)
+
+	song := &model.Song{}
+	var ignore interface{}
+
+	if err := store.db.QueryRowContext(
+		ctx,
+		`
+        DELETE FROM "inactive_song"
+        WHERE "inactive_song".id = (
+            SELECT id FROM "inactive_song"
+            WHERE "inactive_song".queue_client_id = ? AND
+                "inactive_song".queue_guild_id = ?
+            ORDER BY id DESC
+            LIMIT 1
+        )
+        RETURNING *
+        `,
+		clientID,
+		guildID,
+	).Scan(
+		&song.ID,
+		&song.Name, &song.ShortName, &song.Url,
+		&song.DurationSeconds, &song.DurationString,
+		&song.Color, &ignore, &ignore, &ignore,
+		&song.RequesterID,
+		&song.ThumbnailUrl, &song.ChannelName,
+	); err != nil {
+		store.log.Tracef("[S%d]Error: %v", i, err)
+		return nil, err
+	}
+	store.log.WithField(
+		"Latency",
+		time.Since(t),
+	).Tracef("[S%d]Done : Popped latest inactive song", i)
+
+	return song, nil
+}
+
+// GetInactiveSongCountForQueue returns the number of inactive
+// songs that belong to the queue
+// identified by the provided clientID and guildID
+func (store *SqliteSongStore) GetInactiveSongCountForQueue(ctx context.Context, clientID string, guildID string) int {
+	i, t, ctx, done := store.trace.Start(ctx, "GetInactiveSongCountForQueue")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[S%d]Start: Fetch inactive song count for queue", i)
+
+	var count int
+	if err := store.db.QueryRowContext(
+		ctx,
+		`
+        SELECT COUNT(*) FROM "inactive_song"
+        WHERE "inactive_song".queue_client_id = ? AND
+            "inactive_song".queue_guild_id = ?
+        `,
+		clientID,
+		guildID,
+	).Scan(&count); err != nil {
+		store.log.Tracef(
+			"[S%d]Error: %v", i, err,
+		)
+		count = 0
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[S%d]Done : Inactive song count for queue fetched (%d)", i, count)
+	return count
+}
+
+// runInactiveSongsCleanup is a long lived worker, that cleans up
+// outdated inactive songs from the store at interval.
+func (store *SqliteSongStore) RunInactiveSongsCleanup(ctx context.Context) {
+	interval := store.inactiveSongTTL / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	store.log.WithFields(log.Fields{
+		"TTL":      store.inactiveSongTTL,
+		"Interval": interval,
+	}).Debug(
+		"Running inactive songs cleanup",
+	)
+	done := ctx.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	store.removeOutdatedInactiveSongs(ctx)
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			store.removeOutdatedInactiveSongs(ctx)
+		}
+	}
+}
+
+// removeOutdatedInactiveSongs removes all the inactive songs
+// with "added" column older than the InactiveSongTTL cofnig option.
+func (store *SqliteSongStore) removeOutdatedInactiveSongs(ctx context.Context) {
+	i, t, ctx, done := store.trace.Start(ctx, "removeOutdatedInactiveSongs")
+	defer done()
+
+	store.log.Tracef(
+		"[S%d]Start: Remove outdated inactive songs", i,
+	)
+
+	if _, err := store.db.ExecContext(
+		ctx,
+		`
+        DELETE FROM "inactive_song"
+        WHERE "inactive_song".added <= ?;
+        `,
+		time.Now().UTC().Add(store.inactiveSongTTL*(-1)),
+	); err != nil {
+		store.log.Tracef(
+			"[S%d]Error: %v", i, err,
+		)
+		return
+	}
+
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[S%d]Done : Outdated inactive songs removed", i)
+}
+
+// getMaxSongPosition returns the maximum position of a song
+// that belongs to the queue identified with the provided clientID and guildID
+func (store *SqliteSongStore) getMaxSongPosition(ctx context.Context, clientID string, guildID string) (int, error) {
+	return store.getSongPosition(ctx, "MAX", clientID, guildID)
+}
+
+// getMinSongPosition returns the minimum position of a song
+// that belongs to the queue identified with the provided clientID and guildID
+func (store *SqliteSongStore) getMinSongPosition(ctx context.Context, clientID string, guildID string) (int, error) {
+	return store.getSongPosition(ctx, "MIN", clientID, guildID)
+}
+
+// getSongPosition returns the result of the provided aggregate
+// function (MIN or MAX) over the positions of the songs that belong
+// to the queue identified with the provided clientID and guildID.
+func (store *SqliteSongStore) getSongPosition(ctx context.Context, aggregate string, clientID string, guildID string) (int, error) {
+	i, t, ctx, done := store.trace.Start(ctx, "getSongPosition")
+	defer done()
+
+	store.log.WithFields(log.Fields{
+		"ClientID": clientID,
+		"GuildID":  guildID,
+	}).Tracef("[S%d]Start: Fetch %s song position for queue", i, aggregate)
+
+	var position int = 0
+	if err := store.db.QueryRowContext(
+		ctx,
+		`
+        SELECT COALESCE(`+aggregate+`(s.position), 0)
+        FROM "song" s
+        WHERE s.queue_guild_id = ? AND
+            s.queue_client_id = ?
+        `,
+		guildID,
+		clientID,
+	).Scan(&position); err != nil {
+		store.log.Tracef(
+			"[S%d]Error: %v", i, err,
+		)
+		return 0, err
+	}
+	store.log.WithField(
+		"Latency", time.Since(t),
+	).Tracef("[S%d]Done : Fetched %s song position (%d)", i, aggregate, position)
+	return position, nil
+}
+
+// updateSongPosition moves the song at the provided position in the
+// queue identified with the provided clientID and guildID to the
+// provided new position.
+func (store *SqliteSongStore) updateSongPosition(ctx context.Context, clientID string, guildID string, position int, newPosition int) error {
+	_, err := store.db.ExecContext(
+		ctx,
+		`
+        UPDATE "song" SET
+        position = ?
+        WHERE "song".position = ? AND
+        "song".queue_client_id = ? AND
+        "song".queue_guild_id = ?;
+        `,
+		newPosition,
+		position,
+		clientID,
+		guildID,
+	)
+	return err
+}
+
+// scanSongs scans all the provided rows from the "song" table
+// into songs and closes the rows.
+func (store *SqliteSongStore) scanSongs(rows *sql.Rows) ([]*model.Song, error) {
+	defer rows.Close()
+
+	songs := make([]*model.Song, 0)
+	for rows.Next() {
+		song := &model.Song{}
+		var ignore interface{}
+		if err := rows.Scan(
+			&song.ID, &song.Position,
+			&song.Name, &song.ShortName, &song.Url,
+			&song.DurationSeconds,
+			&song.DurationString,
+			&song.Color, &ignore, &ignore,
+			&song.RequesterID,
+			&song.ThumbnailUrl, &song.ChannelName,
+		); err != nil {
+			return nil, err
+		}
+		songs = append(songs, song)
+	}
+	return songs, rows.Err()
+}
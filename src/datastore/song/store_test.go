@@ -1,50 +1,78 @@
 package song_test
 
 import (
+	"context"
 	"database/sql"
+	"discord-music-bot/datastore/migration"
+	"discord-music-bot/datastore/queue"
 	"discord-music-bot/datastore/song"
+	"discord-music-bot/datastore/sqldb"
 	"discord-music-bot/model"
+	"path/filepath"
 	"testing"
 	"time"
 
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
 )
 
+// ctx is the context passed to the repositories' calls.
+var ctx = context.Background()
+
 type SongStoreTestSuite struct {
+	driver          string
 	db              *sql.DB
-	store           *song.SongStore
+	migrator        *migration.Migrator
+	store           song.SongRepository
+	queueStore      queue.QueueRepository
 	inactiveSongTTL time.Duration
 	suite.Suite
 }
 
 // SetupSuite runs when the suite is initialized and
-// connects to the database and initialized the song store.
+// connects to the suite's database and initialized the song store.
 func (s *SongStoreTestSuite) SetupSuite() {
-	db, err := sql.Open(
-		"postgres",
-		"host=postgres port=5432 user=postgres password=postgres "+
-			"dbname=discord_bot_test sslmode=disable",
-	)
+	db, err := openDatabase(s.driver, s.T().TempDir())
 	s.NoError(err)
 
 	s.db = db
 	s.inactiveSongTTL = 2 * time.Second
-	s.store = song.NewSongStore(db, logrus.StandardLogger(), s.inactiveSongTTL)
+	if s.driver == "sqlite3" {
+		s.migrator = migration.NewMigrator(db, logrus.StandardLogger(), migration.Sqlite)
+		s.store = song.NewSqliteSongStore(db, logrus.StandardLogger(), s.inactiveSongTTL, sqldb.Options{})
+		s.queueStore = queue.NewSqliteQueueStore(db, logrus.StandardLogger(), sqldb.Options{})
+	} else {
+		s.migrator = migration.NewMigrator(db, logrus.StandardLogger(), migration.Postgres)
+		s.store = song.NewSongStore(db, logrus.StandardLogger(), s.inactiveSongTTL, sqldb.Options{})
+		s.queueStore = queue.NewQueueStore(db, logrus.StandardLogger(), sqldb.Options{})
+	}
 }
 
-// SetupTest runs before every test and initializes the store.
+// SetupTest runs before every test and recreates the
+// store's tables by reverting and reapplying the migrations.
 func (s *SongStoreTestSuite) SetupTest() {
-	err := s.store.Destroy()
-	s.NoError(err)
-	err = s.store.Init()
+	err := s.migrator.Reset()
 	s.NoError(err)
+
+	// NOTE: the songs may only be persisted to existing queues
+	for _, id := range []string{"TEST", "TEST2"} {
+		err = s.queueStore.PersistQueue(ctx, &model.Queue{
+			ClientID:  "CLIENT-ID-" + id,
+			GuildID:   "GUILD-ID-" + id,
+			MessageID: "MESSAGE-ID-" + id,
+			ChannelID: "CHANNEL-ID-" + id,
+			Limit:     10,
+		})
+		s.NoError(err)
+	}
 }
 
-// TearDownSuite runs after all tests have been run and destroys
-// the song store and closes database connection.
+// TearDownSuite runs after all tests have been run, reverts
+// all the migrations and closes database connection.
 func (s *SongStoreTestSuite) TearDownSuite() {
-	err := s.store.Destroy()
+	_, err := s.migrator.Down(len(s.migrator.Migrations()))
 	s.NoError(err)
 
 	err = s.db.Close()
@@ -59,6 +87,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsCRUD() {
 	// First insert 3 songs normally into
 	// the store
 	err := s.store.PersistSongs(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 		&model.Song{
@@ -95,6 +124,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsCRUD() {
 	// It's Position should then be the
 	// smallest of the added songs
 	err = s.store.PersistSongToFront(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 		&model.Song{
@@ -111,6 +141,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsCRUD() {
 
 	// Should get that there are 4 songs
 	count := s.store.GetSongCountForQueue(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
@@ -120,6 +151,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsCRUD() {
 	// are ordered by position and song with ID 4 has
 	// been added to front
 	songs, err := s.store.GetSongsForQueue(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 		2,
@@ -136,6 +168,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsCRUD() {
 	// Others should have id and position equal
 	// to their index in the slice.
 	songs, err = s.store.GetAllSongsForQueue(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
@@ -156,11 +189,13 @@ func (s *SongStoreTestSuite) TestIntegrationSongsCRUD() {
 	// In this context song pushed back should be the one
 	// with id=4. It should then have the largest position.
 	s.store.PushHeadSongToBack(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
 	s.NoError(err)
 	songs, err = s.store.GetSongsForQueue(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 		0,
@@ -175,11 +210,13 @@ func (s *SongStoreTestSuite) TestIntegrationSongsCRUD() {
 
 	// Not the song with ID=4 should be in front again
 	err = s.store.PushLastSongToFront(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
 	s.NoError(err)
 	songs, err = s.store.GetSongsForQueue(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 		0,
@@ -194,11 +231,13 @@ func (s *SongStoreTestSuite) TestIntegrationSongsCRUD() {
 
 	// Not the song with ID=1 should be in front again
 	err = s.store.PushHeadSongToBack(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
 	s.NoError(err)
 	songs, err = s.store.GetAllSongsForQueue(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
@@ -210,6 +249,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsCRUD() {
 	s.Equal(4, songs[3].Position)
 
 	err = s.store.RemoveHeadSong(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
@@ -217,6 +257,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsCRUD() {
 	// Now the song with position 1 and id=1
 	// should be removed
 	songs, err = s.store.GetAllSongsForQueue(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
@@ -226,6 +267,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsCRUD() {
 	s.Equal(uint(2), songs[0].ID)
 
 	err = s.store.RemoveSongs(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 		2, 3,
@@ -233,6 +275,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsCRUD() {
 	s.NoError(err)
 	// Now only the song with id=4 should remain
 	songs, err = s.store.GetAllSongsForQueue(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
@@ -247,6 +290,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsCRUD() {
 // not has the correct songs fields.
 func (s *SongStoreTestSuite) TestIntegrationSongsForQueue() {
 	err := s.store.PersistSongs(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 		&model.Song{
@@ -279,6 +323,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsForQueue() {
 	)
 	s.NoError(err)
 	err = s.store.PersistSongs(
+		ctx,
 		"CLIENT-ID-TEST2",
 		"GUILD-ID-TEST2",
 		&model.Song{
@@ -294,6 +339,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsForQueue() {
 	s.NoError(err)
 
 	songs, err := s.store.GetSongsForQueue(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 		0, 3,
@@ -305,6 +351,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsForQueue() {
 	s.Equal(uint(3), songs[2].ID)
 
 	songs, err = s.store.GetSongsForQueue(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 		1, 2,
@@ -315,6 +362,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsForQueue() {
 	s.Equal(uint(3), songs[1].ID)
 
 	songs, err = s.store.GetSongsForQueue(
+		ctx,
 		"CLIENT-ID-TEST2",
 		"GUILD-ID-TEST2",
 		0, 3,
@@ -323,7 +371,7 @@ func (s *SongStoreTestSuite) TestIntegrationSongsForQueue() {
 	s.Len(songs, 1)
 	s.Equal(uint(4), songs[0].ID)
 
-	queue, err := s.store.UpdateQueueWithSongs(&model.Queue{
+	queue, err := s.store.UpdateQueueWithSongs(ctx, &model.Queue{
 		ClientID: "CLIENT-ID-TEST",
 		GuildID:  "GUILD-ID-TEST",
 		Offset:   0,
@@ -343,6 +391,7 @@ func (s *SongStoreTestSuite) TestIntegrationInactiveSongsCRUD() {
 	// First insert 3 songs normally into
 	// the store
 	err := s.store.PersistInactiveSongs(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 		&model.Song{
@@ -366,6 +415,7 @@ func (s *SongStoreTestSuite) TestIntegrationInactiveSongsCRUD() {
 	)
 	s.NoError(err)
 	err = s.store.PersistInactiveSongs(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 		&model.Song{
@@ -376,18 +426,21 @@ func (s *SongStoreTestSuite) TestIntegrationInactiveSongsCRUD() {
 			DurationSeconds: 10,
 			DurationString:  "00:10",
 			Color:           0,
+			RequesterID:     "USER-ID-TEST",
 		},
 	)
 	s.NoError(err)
 
 	// Should get that there are 2 songs
 	count := s.store.GetInactiveSongCountForQueue(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
 	s.Equal(count, 3)
 
 	song, err := s.store.PopLatestInactiveSong(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
@@ -397,15 +450,18 @@ func (s *SongStoreTestSuite) TestIntegrationInactiveSongsCRUD() {
 	s.Equal("Song3", song.Name)
 	s.Equal("Song3", song.ShortName)
 	s.Equal("SongUrl3", song.Url)
+	s.Equal("USER-ID-TEST", song.RequesterID)
 
 	// Should get that there is now a single song
 	count = s.store.GetInactiveSongCountForQueue(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
 	s.Equal(count, 2)
 
 	song, err = s.store.PopLatestInactiveSong(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
@@ -417,6 +473,7 @@ func (s *SongStoreTestSuite) TestIntegrationInactiveSongsCRUD() {
 
 	// Should get that there are now no songs
 	count = s.store.GetInactiveSongCountForQueue(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
@@ -428,6 +485,7 @@ func (s *SongStoreTestSuite) TestIntegrationInactiveSongsCRUD() {
 // it's InactiveSize is correct.
 func (s *SongStoreTestSuite) TestIntegrationInactiveSongsForQueue() {
 	err := s.store.PersistInactiveSongs(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 		&model.Song{
@@ -450,13 +508,14 @@ func (s *SongStoreTestSuite) TestIntegrationInactiveSongsForQueue() {
 		},
 	)
 	inactiveSize := s.store.GetInactiveSongCountForQueue(
+		ctx,
 		"CLIENT-ID-TEST",
 		"GUILD-ID-TEST",
 	)
 	s.Equal(2, inactiveSize)
 
 	s.NoError(err)
-	queue, err := s.store.UpdateQueueWithSongs(&model.Queue{
+	queue, err := s.store.UpdateQueueWithSongs(ctx, &model.Queue{
 		ClientID: "CLIENT-ID-TEST",
 		GuildID:  "GUILD-ID-TEST",
 		Offset:   0,
@@ -467,7 +526,30 @@ func (s *SongStoreTestSuite) TestIntegrationInactiveSongsForQueue() {
 }
 
 // TestSongStorageTestSuite runs all tests under
-// the SongStoreTestSuite suite.
+// the SongStoreTestSuite suite against the postgres database.
 func TestSongStorageTestSuite(t *testing.T) {
-	suite.Run(t, new(SongStoreTestSuite))
+	suite.Run(t, &SongStoreTestSuite{driver: "postgres"})
+}
+
+// TestSqliteSongStorageTestSuite runs all tests under
+// the SongStoreTestSuite suite against the sqlite database.
+func TestSqliteSongStorageTestSuite(t *testing.T) {
+	suite.Run(t, &SongStoreTestSuite{driver: "sqlite3"})
+}
+
+// openDatabase opens the test database for the provided driver.
+// The sqlite database is created in the provided directory.
+func openDatabase(driver string, dir string) (*sql.DB, error) {
+	if driver == "sqlite3" {
+		return sql.Open(
+			"sqlite3",
+			"file:"+filepath.Join(dir, "discord_bot_test.db")+
+				"?_foreign_keys=on",
+		)
+	}
+	return sql.Open(
+		"postgres",
+		"host=postgres port=5432 user=postgres password=postgres "+
+			"dbname=discord_bot_test sslmode=disable",
+	)
 }
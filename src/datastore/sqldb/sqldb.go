@@ -0,0 +1,21 @@
+// Package sqldb holds what is shared between the sql datastores.
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Querier runs the queries of the sql stores. It is implemented by
+// both *sql.DB and *sql.Tx, so the same store may run it's queries
+// directly on the database or in a transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+var (
+	_ Querier = (*sql.DB)(nil)
+	_ Querier = (*sql.Tx)(nil)
+)
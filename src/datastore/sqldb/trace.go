@@ -0,0 +1,66 @@
+package sqldb
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Options configures the calls of the sql stores.
+type Options struct {
+	// QueryTimeout limits the duration of a single store
+	// call, there is no limit when it is 0.
+	QueryTimeout time.Duration
+	// SlowQueryThreshold is the latency above which the store
+	// calls are logged as slow, nothing is logged when it is 0.
+	SlowQueryThreshold time.Duration
+}
+
+// Tracer numbers the calls of a sql store, limits their
+// duration and logs the slow ones.
+type Tracer struct {
+	log     *log.Logger
+	prefix  string
+	options Options
+	idx     uint64
+}
+
+// NewTracer creates an object that numbers the calls of a sql
+// store. The provided prefix is prepended to the calls' numbers
+// in the logs, so the calls of different stores are told apart.
+func NewTracer(log *log.Logger, prefix string, options Options) *Tracer {
+	return &Tracer{
+		log:     log,
+		prefix:  prefix,
+		options: options,
+		idx:     0,
+	}
+}
+
+// Start returns the number of a new store call, the time it started
+// at and the ctx limited by the query timeout. The returned function
+// must be called once the store call is done, it releases the ctx
+// and logs the store call, identified by the provided name, if it
+// was slow.
+func (tracer *Tracer) Start(ctx context.Context, name string) (uint64, time.Time, context.Context, func()) {
+	// NOTE: a tracer is shared by the store's calls
+	// from all goroutines, so it's number is atomic
+	i, t := atomic.AddUint64(&tracer.idx, 1)-1, time.Now()
+
+	var cancel context.CancelFunc = func() {}
+	if tracer.options.QueryTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, tracer.options.QueryTimeout)
+	}
+	return i, t, ctx, func() {
+		cancel()
+		latency := time.Since(t)
+		if tracer.options.SlowQueryThreshold > 0 &&
+			latency >= tracer.options.SlowQueryThreshold {
+			tracer.log.WithField("Latency", latency).Warnf(
+				"[%s%d]Slow : %s", tracer.prefix, i, name,
+			)
+		}
+	}
+}
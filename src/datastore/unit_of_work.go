@@ -0,0 +1,85 @@
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"discord-music-bot/datastore/guild"
+	"discord-music-bot/datastore/history"
+	"discord-music-bot/datastore/queue"
+	"discord-music-bot/datastore/song"
+	"discord-music-bot/datastore/sqldb"
+)
+
+// UnitOfWork holds the datastore's repositories, that run all of
+// their queries in a single transaction, so either all the changes
+// made through them are saved or none.
+type UnitOfWork struct {
+	queue   queue.QueueRepository
+	song    song.SongRepository
+	history history.HistoryRepository
+	guild   guild.GuildSettingsRepository
+}
+
+// Queue returns the object that handles persisting and
+// removing Queues in the unit of work.
+func (uow *UnitOfWork) Queue() queue.QueueRepository {
+	return uow.queue
+}
+
+// Song returns the object that handles persisting and
+// removing Songs in the unit of work.
+func (uow *UnitOfWork) Song() song.SongRepository {
+	return uow.song
+}
+
+// History returns the object that handles persisting and
+// fetching the played songs' history in the unit of work.
+func (uow *UnitOfWork) History() history.HistoryRepository {
+	return uow.history
+}
+
+// GuildSettings returns the object that handles persisting
+// and removing the guilds' settings in the unit of work.
+func (uow *UnitOfWork) GuildSettings() guild.GuildSettingsRepository {
+	return uow.guild
+}
+
+// RunUnitOfWork runs the provided function in a single transaction,
+// that is rolled back if the provided ctx is done before it is committed.
+// The changes made through the unit of work's repositories are saved
+// only if the function returns no error, otherwise they are discarded
+// and the function's error is returned.
+// NOTE: only the unit of work's repositories should be used in the
+// function, as the datastore's repositories may wait for the
+// transaction to finish.
+func (datastore *Datastore) RunUnitOfWork(ctx context.Context, fn func(uow *UnitOfWork) error) error {
+	return datastore.runUnitOfWork(ctx, fn)
+}
+
+// useRepositories sets the datastore's repositories to the ones
+// created by the provided function for the database, and runs the
+// units of work with the repositories created for a transaction.
+func (datastore *Datastore) useRepositories(db *sql.DB, repositories func(db sqldb.Querier) *UnitOfWork) {
+	uow := repositories(db)
+	datastore.queue = uow.queue
+	datastore.song = uow.song
+	datastore.history = uow.history
+	datastore.guild = uow.guild
+
+	datastore.runUnitOfWork = func(ctx context.Context, fn func(uow *UnitOfWork) error) error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := fn(repositories(tx)); err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				datastore.Errorf(
+					"Error when rolling back unit of work: %v",
+					rollbackErr,
+				)
+			}
+			return err
+		}
+		return tx.Commit()
+	}
+}
@@ -0,0 +1,129 @@
+package datastore_test
+
+import (
+	"context"
+	"discord-music-bot/datastore"
+	"discord-music-bot/model"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// ctx is the context passed to the repositories' calls.
+var ctx = context.Background()
+
+type UnitOfWorkTestSuite struct {
+	memory    bool
+	datastore *datastore.Datastore
+	cancel    context.CancelFunc
+	suite.Suite
+}
+
+// SetupTest runs before every test and connects
+// to a new empty datastore.
+func (s *UnitOfWorkTestSuite) SetupTest() {
+	config := &datastore.Configuration{
+		LogLevel:        logrus.InfoLevel,
+		InactiveSongTTL: time.Hour,
+		Sqlite: &datastore.SqliteConfig{
+			Path: filepath.Join(s.T().TempDir(), "discord_bot_test.db"),
+		},
+	}
+	s.datastore = datastore.NewDatastore(config)
+	if s.memory {
+		s.NoError(s.datastore.ConnectMemory())
+	} else {
+		s.NoError(s.datastore.Connect())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.NoError(s.datastore.Init(ctx))
+
+	s.NoError(s.datastore.Queue().PersistQueue(ctx, &model.Queue{
+		ClientID:  "CLIENT-ID-TEST",
+		GuildID:   "GUILD-ID-TEST",
+		MessageID: "MESSAGE-ID-TEST",
+		ChannelID: "CHANNEL-ID-TEST",
+		Limit:     10,
+	}))
+	s.NoError(s.datastore.Song().PersistSongs(
+		ctx,
+		"CLIENT-ID-TEST",
+		"GUILD-ID-TEST",
+		&model.Song{Name: "Song1", ShortName: "Song1", Url: "SongUrl1"},
+		&model.Song{Name: "Song2", ShortName: "Song2", Url: "SongUrl2"},
+	))
+}
+
+// TearDownTest runs after every test and stops
+// the datastore's workers.
+func (s *UnitOfWorkTestSuite) TearDownTest() {
+	s.cancel()
+}
+
+// TestCommit checks that all the changes made in a successful
+// unit of work are saved.
+func (s *UnitOfWorkTestSuite) TestCommit() {
+	err := s.datastore.RunUnitOfWork(ctx, func(uow *datastore.UnitOfWork) error {
+		s.NoError(uow.Queue().LockQueue(ctx, "CLIENT-ID-TEST", "GUILD-ID-TEST"))
+
+		song, err := uow.Song().GetHeadSongForQueue(ctx, "CLIENT-ID-TEST", "GUILD-ID-TEST")
+		s.NoError(err)
+		s.NoError(uow.Song().PersistInactiveSongs(ctx, "CLIENT-ID-TEST", "GUILD-ID-TEST", song))
+		return uow.Song().RemoveHeadSong(ctx, "CLIENT-ID-TEST", "GUILD-ID-TEST")
+	})
+	s.NoError(err)
+
+	s.Equal(1, s.datastore.Song().GetSongCountForQueue(ctx, "CLIENT-ID-TEST", "GUILD-ID-TEST"))
+	s.Equal(1, s.datastore.Song().GetInactiveSongCountForQueue(ctx, "CLIENT-ID-TEST", "GUILD-ID-TEST"))
+}
+
+// TestRollback checks that none of the changes made in a
+// failed unit of work are saved.
+func (s *UnitOfWorkTestSuite) TestRollback() {
+	failed := errors.New("failed")
+	err := s.datastore.RunUnitOfWork(ctx, func(uow *datastore.UnitOfWork) error {
+		song, err := uow.Song().GetHeadSongForQueue(ctx, "CLIENT-ID-TEST", "GUILD-ID-TEST")
+		s.NoError(err)
+		s.NoError(uow.Song().PersistInactiveSongs(ctx, "CLIENT-ID-TEST", "GUILD-ID-TEST", song))
+		s.NoError(uow.Song().RemoveHeadSong(ctx, "CLIENT-ID-TEST", "GUILD-ID-TEST"))
+		return failed
+	})
+	s.Equal(failed, err)
+
+	s.Equal(2, s.datastore.Song().GetSongCountForQueue(ctx, "CLIENT-ID-TEST", "GUILD-ID-TEST"))
+	s.Equal(0, s.datastore.Song().GetInactiveSongCountForQueue(ctx, "CLIENT-ID-TEST", "GUILD-ID-TEST"))
+
+	song, err := s.datastore.Song().GetHeadSongForQueue(ctx, "CLIENT-ID-TEST", "GUILD-ID-TEST")
+	s.NoError(err)
+	s.Equal("Song1", song.Name)
+}
+
+// TestCancelledContext checks that a unit of work is not
+// run when the provided context is already done.
+func (s *UnitOfWorkTestSuite) TestCancelledContext() {
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err := s.datastore.RunUnitOfWork(cancelled, func(uow *datastore.UnitOfWork) error {
+		return uow.Song().RemoveHeadSong(cancelled, "CLIENT-ID-TEST", "GUILD-ID-TEST")
+	})
+	s.ErrorIs(err, context.Canceled)
+
+	s.Equal(2, s.datastore.Song().GetSongCountForQueue(ctx, "CLIENT-ID-TEST", "GUILD-ID-TEST"))
+}
+
+// TestSqliteUnitOfWorkTestSuite runs all tests under
+// the UnitOfWorkTestSuite suite against the sqlite datastore.
+func TestSqliteUnitOfWorkTestSuite(t *testing.T) {
+	suite.Run(t, &UnitOfWorkTestSuite{memory: false})
+}
+
+// TestMemoryUnitOfWorkTestSuite runs all tests under
+// the UnitOfWorkTestSuite suite against the in-memory datastore.
+func TestMemoryUnitOfWorkTestSuite(t *testing.T) {
+	suite.Run(t, &UnitOfWorkTestSuite{memory: true})
+}
@@ -6,6 +6,7 @@ require (
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
 	github.com/jonas747/dca v0.0.0-20210930103944-155f5e5f0cc7
 	github.com/lib/pq v1.10.6
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/stretchr/testify v1.8.1
 	golang.org/x/net v0.0.0-20220708220712-1185a9018129
 )
@@ -0,0 +1,211 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultLanguage is the language used for the messages
+// that are not translated to the requested language.
+const DefaultLanguage = "en"
+
+//go:embed catalogs
+var catalogs embed.FS
+
+type Configuration struct {
+	Path string `yaml:"Path"` // Directory with additional catalogs, named after their languages (e.g. de.yaml or de.json), that override the built in ones
+}
+
+// Catalog maps the keys of the messages to
+// their translations in a single language.
+type Catalog map[string]string
+
+type Translator struct {
+	catalogs map[string]Catalog
+}
+
+type Localizer struct {
+	language string
+	catalogs []Catalog
+}
+
+// NewTranslator constructs an object that holds the message catalogs
+// of all the supported languages, initially the built in ones.
+func NewTranslator() *Translator {
+	t := &Translator{catalogs: make(map[string]Catalog)}
+	entries, err := catalogs.ReadDir("catalogs")
+	if err != nil {
+		panic(err)
+	}
+	for _, e := range entries {
+		data, err := catalogs.ReadFile("catalogs/" + e.Name())
+		if err != nil {
+			panic(err)
+		}
+		// NOTE: the built in catalogs are tested,
+		// so they are expected to always be valid
+		if err := t.add(e.Name(), data); err != nil {
+			panic(err)
+		}
+	}
+	return t
+}
+
+// Load reads the catalogs from the yaml and json files in the
+// directory at the provided path. Their messages are added to the
+// catalogs of the languages that are already supported, or a new
+// language is added for every catalog that has no built in one.
+func (t *Translator) Load(path string) error {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return fmt.Errorf("Could not read catalogs: %v", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(path, e.Name()))
+		if err != nil {
+			return fmt.Errorf("Could not read catalog %s: %v", e.Name(), err)
+		}
+		if err := t.add(e.Name(), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Languages returns the sorted languages that have a catalog.
+func (t *Translator) Languages() []string {
+	languages := make([]string, 0, len(t.catalogs))
+	for l := range t.catalogs {
+		languages = append(languages, l)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Localizer returns an object that translates the messages to the
+// first of the provided languages that has a catalog. Languages may
+// be discord's locales, so "en-US" is matched by the "en" catalog.
+// The messages missing in that language are taken from the
+// default language.
+func (t *Translator) Localizer(languages ...string) *Localizer {
+	l := &Localizer{language: DefaultLanguage}
+	for _, language := range languages {
+		if match := t.match(language); len(match) > 0 {
+			l.language = match
+			break
+		}
+	}
+	l.catalogs = append(l.catalogs, t.catalogs[l.language])
+	if l.language != DefaultLanguage {
+		l.catalogs = append(l.catalogs, t.catalogs[DefaultLanguage])
+	}
+	return l
+}
+
+// Localizations returns the translations of the message identified by
+// the provided key, mapped by all of the discord's locales that match
+// a language other than the default one. Returns nil if the message
+// is not translated to any such language.
+func (t *Translator) Localizations(key string) map[discordgo.Locale]string {
+	var localizations map[discordgo.Locale]string
+	for locale := range discordgo.Locales {
+		language := t.match(string(locale))
+		if len(language) == 0 || language == DefaultLanguage {
+			continue
+		}
+		message, ok := t.catalogs[language][key]
+		if !ok {
+			continue
+		}
+		if localizations == nil {
+			localizations = make(map[discordgo.Locale]string)
+		}
+		localizations[locale] = message
+	}
+	return localizations
+}
+
+// Language returns the language to which the messages are translated.
+func (l *Localizer) Language() string {
+	return l.language
+}
+
+// T returns the message identified by the provided key, formatted
+// with the provided args. The key is returned when no catalog
+// has the message.
+func (l *Localizer) T(key string, args ...interface{}) string {
+	message := l.Or(key, key)
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Or returns the message identified by the provided key, or the
+// provided fallback when no catalog has the message. This is used
+// for the configured texts, that are translated only to some of the
+// languages.
+func (l *Localizer) Or(key string, fallback string) string {
+	for _, c := range l.catalogs {
+		if message, ok := c[key]; ok {
+			return message
+		}
+	}
+	return fallback
+}
+
+// match returns the name of the catalog that matches
+// the provided language, or an empty string if none does.
+func (t *Translator) match(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if len(language) == 0 {
+		return ""
+	}
+	if _, ok := t.catalogs[language]; ok {
+		return language
+	}
+	base := strings.SplitN(language, "-", 2)[0]
+	if _, ok := t.catalogs[base]; ok {
+		return base
+	}
+	return ""
+}
+
+// add parses the catalog from the provided data, read from
+// the file with the provided name, and adds it's messages
+// to the catalog of the language named after the file.
+func (t *Translator) add(name string, data []byte) error {
+	ext := filepath.Ext(name)
+	language := strings.ToLower(strings.TrimSuffix(name, ext))
+	catalog := make(Catalog)
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &catalog); err != nil {
+			return fmt.Errorf("Invalid catalog %s: %v", name, err)
+		}
+	case ".json":
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return fmt.Errorf("Invalid catalog %s: %v", name, err)
+		}
+	default:
+		return nil
+	}
+	if _, ok := t.catalogs[language]; !ok {
+		t.catalogs[language] = make(Catalog)
+	}
+	for k, v := range catalog {
+		t.catalogs[language][k] = v
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package i18n_test
+
+import (
+	"discord-music-bot/i18n"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/suite"
+)
+
+type I18nTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitBuiltInCatalogs checks that the built in catalogs
+// are valid and that the messages of the default language
+// are translated to the other built in languages.
+func (s *I18nTestSuite) TestUnitBuiltInCatalogs() {
+	t := i18n.NewTranslator()
+	s.Equal([]string{"de", "en"}, t.Languages())
+
+	en := t.Localizer(i18n.DefaultLanguage)
+	for _, key := range []string{
+		"queue.none", "queue.next", "voice.required",
+		"songs.playing", "history.added", "error.generic",
+		"stats.total", "stats.top_songs", "stats.busiest_hours",
+		"settings.forbidden", "command.settings.setting.theme",
+	} {
+		s.NotEqual(key, en.T(key))
+		s.NotEqual(en.T(key), t.Localizer("de").T(key))
+	}
+}
+
+// TestUnitLocalizer checks that the localizer matches discord's
+// locales by their base language, and falls back to the default
+// language for the unsupported languages and missing messages.
+func (s *I18nTestSuite) TestUnitLocalizer() {
+	t := i18n.NewTranslator()
+
+	s.Equal("de", t.Localizer("", "de").Language())
+	s.Equal("en", t.Localizer("en-US").Language())
+	s.Equal("en", t.Localizer("fr").Language())
+	s.Equal("de", t.Localizer("fr", "de").Language())
+	s.Equal("en", t.Localizer("en", "de").Language())
+
+	l := t.Localizer("de")
+	s.Equal("Es gibt keine aktive Musikwarteschlange!", l.T("queue.none"))
+	s.Equal("Seite", l.Or("queue.buttons.page", "Page"))
+	s.Equal("Page", t.Localizer("en").Or("queue.buttons.page", "Page"))
+	s.Equal("Serverstatistik", l.Or("stats.title", "Server Statistics"))
+	s.Equal("missing.key", l.T("missing.key"))
+	s.Equal(
+		"Added **Song** to the queue.",
+		t.Localizer("en-GB").T("history.added", "Song"),
+	)
+}
+
+// TestUnitLoad checks that the loaded catalogs override the
+// built in messages and add the new languages.
+func (s *I18nTestSuite) TestUnitLoad() {
+	dir := s.T().TempDir()
+	s.Require().NoError(os.WriteFile(
+		filepath.Join(dir, "de.yaml"),
+		[]byte("queue.none: Keine Warteschlange!\n"),
+		0644,
+	))
+	s.Require().NoError(os.WriteFile(
+		filepath.Join(dir, "fr.json"),
+		[]byte(`{"queue.none": "Aucune file d'attente !"}`),
+		0644,
+	))
+
+	t := i18n.NewTranslator()
+	s.Require().NoError(t.Load(dir))
+	s.Equal([]string{"de", "en", "fr"}, t.Languages())
+	s.Equal("Keine Warteschlange!", t.Localizer("de").T("queue.none"))
+	s.Equal("Jetzt", t.Localizer("de").T("queue.now"))
+	s.Equal("Aucune file d'attente !", t.Localizer("fr").T("queue.none"))
+	s.Equal("Now", t.Localizer("fr").T("queue.now"))
+
+	s.Require().NoError(os.WriteFile(
+		filepath.Join(dir, "it.yaml"),
+		[]byte("queue.none: [invalid"),
+		0644,
+	))
+	s.Error(t.Load(dir))
+	s.Error(t.Load(filepath.Join(dir, "missing")))
+}
+
+// TestUnitLocalizations checks that the localizations hold
+// only the translations to the languages other than the
+// default one, mapped by all of their discord's locales.
+func (s *I18nTestSuite) TestUnitLocalizations() {
+	t := i18n.NewTranslator()
+
+	l := t.Localizations("command.music.name")
+	s.Equal(map[discordgo.Locale]string{discordgo.German: "musik"}, l)
+
+	s.Nil(t.Localizations("missing.key"))
+
+	l = t.Localizations("queue.none")
+	s.NotContains(l, discordgo.EnglishUS)
+	s.NotContains(l, discordgo.EnglishGB)
+	s.Contains(l, discordgo.German)
+}
+
+// TestI18nTestSuite runs all tests under
+// the I18nTestSuite suite.
+func TestI18nTestSuite(t *testing.T) {
+	suite.Run(t, new(I18nTestSuite))
+}
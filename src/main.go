@@ -4,7 +4,10 @@ import (
 	"context"
 	"discord-music-bot/bot"
 	"discord-music-bot/config"
+	"discord-music-bot/datastore"
+	"errors"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"os/signal"
@@ -46,6 +49,58 @@ func loadConfig(configFiles []string) *Configuration {
 	return &configuration
 }
 
+// errNoSchema is returned when migrating a datastore
+// that has no schema, e.g. the in-memory datastore.
+var errNoSchema = errors.New("the memory datastore has no schema to migrate")
+
+// migrate runs the provided migration command against the
+// configured datastore: "up" applies all the pending migrations,
+// "down" reverts the last applied migration and "status"
+// logs the status of all the migrations.
+func migrate(configuration *Configuration, command string) error {
+	store := datastore.NewDatastore(configuration.MusicBot.Datastore)
+	if err := store.Connect(); err != nil {
+		return err
+	}
+	migrator := store.Migrator()
+	if migrator == nil {
+		return errNoSchema
+	}
+
+	switch command {
+	case "up":
+		count, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		log.WithField("Count", count).Info("Applied pending migrations")
+	case "down":
+		count, err := migrator.Down(1)
+		if err != nil {
+			return err
+		}
+		log.WithField("Count", count).Info("Reverted last migration")
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			fields := log.Fields{
+				"Version": status.Version,
+				"Applied": status.Applied,
+			}
+			if status.Applied {
+				fields["AppliedAt"] = status.AppliedAt
+			}
+			log.WithFields(fields).Info(status.Description)
+		}
+	default:
+		return fmt.Errorf("Unknown migrate command: %s", command)
+	}
+	return nil
+}
+
 // loadHelp loads the content from the provided help
 // files, that holds the information about using the bot
 func loadHelp(helpFiles []string) string {
@@ -74,12 +129,23 @@ func main() {
 		"../README.md",
 		"File with information about the commands",
 	)
+	migrateParam := flag.String(
+		"migrate",
+		"",
+		"Migrate the datastore (up, down or status) and exit",
+	)
 	flag.Parse()
 	ctx, cancel := context.WithCancel(context.Background())
 	shutdownSignal := make(chan os.Signal, 2)
 	signal.Notify(shutdownSignal, syscall.SIGTERM, syscall.SIGINT)
 
 	configuration := loadConfig(strings.Split(*configFileParam, ","))
+	if len(*migrateParam) > 0 {
+		if err := migrate(configuration, *migrateParam); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	help := loadHelp(strings.Split(*helpFileParam, ","))
 
 	bot := initBot(ctx, configuration, help)
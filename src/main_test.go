@@ -0,0 +1,33 @@
+package main
+
+import (
+	"discord-music-bot/bot"
+	"discord-music-bot/datastore"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MainTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitMigrateMemory checks that migrating the in-memory
+// datastore fails with an error instead of panicking, as
+// it has no schema to migrate.
+func (s *MainTestSuite) TestUnitMigrateMemory() {
+	configuration := &Configuration{
+		MusicBot: &bot.Configuration{
+			Datastore: &datastore.Configuration{Memory: true},
+		},
+	}
+	for _, command := range []string{"up", "down", "status"} {
+		s.ErrorIs(migrate(configuration, command), errNoSchema)
+	}
+}
+
+// TestMainTestSuite runs all tests under
+// the MainTestSuite suite.
+func TestMainTestSuite(t *testing.T) {
+	suite.Run(t, new(MainTestSuite))
+}
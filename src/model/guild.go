@@ -0,0 +1,8 @@
+package model
+
+type GuildSetting struct {
+	ClientID string `json:"client_id"` // Id of the bot to which the setting applies
+	GuildID  string `json:"guild_id"`  // Id of the discord server that has changed the setting
+	Name     string `json:"name"`      // Name of the setting, e.g. language or theme
+	Value    string `json:"value"`     // Value of the setting, that overrides the configured one
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+type HistoryEntry struct {
+	ID              uint      `json:"id"`               // Serial ID automatically added when the entry is persisted
+	ClientID        string    `json:"client_id"`        // Id of the bot that played the song
+	GuildID         string    `json:"guild_id"`         // Id of the discord server in which the song has been played
+	RequesterID     string    `json:"requester_id"`     // Id of the user that added the song to the queue
+	Name            string    `json:"name"`             // Trimmed name of the Youtube song
+	ShortName       string    `json:"short_name"`       // Shortened name, so that all songs' names are displayed with equal lengths
+	Url             string    `json:"url"`              // Url of the Youtube song
+	DurationSeconds int       `json:"duration_seconds"` // Duration of the song in seconds
+	DurationString  string    `json:"duration_string"`  // A string representing the duration of the song in format hh:mm::ss
+	StartedAt       time.Time `json:"started_at"`       // Time at which the song started playing
+	PlayedSeconds   int       `json:"played_seconds"`   // Number of seconds the song has actually been played
+}
+
+type History struct {
+	ClientID string          `json:"client_id"` // Id of the bot that played the songs
+	GuildID  string          `json:"guild_id"`  // Id of the discord server in which the songs have been played
+	Offset   int             `json:"offset"`    // Current offset of the displayed history entries
+	Limit    int             `json:"limit"`     // Number of history entries displayed at once
+	Entries  []*HistoryEntry `json:"entries"`   // Currently displayed history entries
+	Size     int             `json:"size"`      // Total number of history entries in the discord server
+}
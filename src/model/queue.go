@@ -12,17 +12,22 @@ type QueueOption struct {
 }
 
 type Queue struct {
-	ClientID     string         `json:"client_id"`     // Id of the bot that created the queue
-	GuildID      string         `json:"guild_id"`      // Id of the discord server in which the queue has been created
-	MessageID    string         `json:"message_id"`    // Id of the queue's message in a discord channel
-	ChannelID    string         `json:"channel_id"`    // Id of the channel in which the queue has been created
-	Offset       int            `json:"offset"`        // Current offset of the displayed songs in the queue
-	Limit        int            `json:"limit"`         // Number of songs displayed at once
-	Options      []*QueueOption `json:"options"`       // A list of options currently added to the queue
-	Songs        []*Song        `json:"songs"`         // Currently displayed songs
-	HeadSong     *Song          `json:"head_song"`     // A queue's song with the minimum position
-	InactiveSize int            `json:"inactive_size"` // Total number of inactive songs (removed from the queue) that belong to the queue
-	Size         int            `json:"size"`          // Total number of songs that belong to the queue
+	ClientID         string         `json:"client_id"`         // Id of the bot that created the queue
+	GuildID          string         `json:"guild_id"`          // Id of the discord server in which the queue has been created
+	MessageID        string         `json:"message_id"`        // Id of the queue's message in a discord channel
+	ChannelID        string         `json:"channel_id"`        // Id of the channel in which the queue has been created
+	Offset           int            `json:"offset"`            // Current offset of the displayed songs in the queue
+	Limit            int            `json:"limit"`             // Number of songs displayed at once
+	VoiceChannelID   string         `json:"voice_channel_id"`  // Id of the voice channel in which the playback should be resumed after a restart
+	PlaybackPosition int            `json:"playback_position"` // Position (in seconds) in the head song from which the playback should be resumed after a restart
+	Options          []*QueueOption `json:"options"`           // A list of options currently added to the queue
+	Songs            []*Song        `json:"songs"`             // Currently displayed songs
+	HeadSong         *Song          `json:"head_song"`         // A queue's song with the minimum position
+	Elapsed          int            `json:"elapsed"`           // Seconds of the head song that have already been played
+	Duration         int            `json:"duration"`          // Total duration (in seconds) of all the queue's songs
+	DurationBefore   int            `json:"duration_before"`   // Total duration (in seconds) of the head song and the songs before the displayed ones
+	InactiveSize     int            `json:"inactive_size"`     // Total number of inactive songs (removed from the queue) that belong to the queue
+	Size             int            `json:"size"`              // Total number of songs that belong to the queue
 }
 
 func LoopOption() *QueueOption {
@@ -9,6 +9,9 @@ type Song struct {
 	DurationSeconds int    `json:"duration_seconds"` // Duration of the song in seconds
 	DurationString  string `json:"duration_string"`  // A string representing the duration of the song in format hh:mm::ss
 	Color           int    `json:"color"`            // The color of the discord embed, when this song is playing
+	RequesterID     string `json:"requester_id"`     // Id of the user that added the song to the queue
+	ThumbnailUrl    string `json:"thumbnail_url"`    // Url of the Youtube song's thumbnail, empty if unknown
+	ChannelName     string `json:"channel_name"`     // Name of the Youtube channel that uploaded the song, empty if unknown
 }
 
 type SongInfo struct {
@@ -16,4 +19,8 @@ type SongInfo struct {
 	Name          string `json:"name"`
 	Url           string `json:"url"`
 	LengthSeconds int    `json:"duration_seconds"`
+	ChannelName   string `json:"channel_name"`   // Name of the channel that uploaded the video
+	ThumbnailUrl  string `json:"thumbnail_url"`  // Url of the video's largest thumbnail
+	Live          bool   `json:"live"`           // Whether the video is a live stream that is currently live
+	AgeRestricted bool   `json:"age_restricted"` // Whether youtube requires a sign in to confirm the viewer's age
 }
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+type StatsPeriod string
+
+const (
+	WeekPeriod  StatsPeriod = "7d"  // Statistics for the last 7 days
+	MonthPeriod StatsPeriod = "30d" // Statistics for the last 30 days
+	AllPeriod   StatsPeriod = "all" // Statistics for the whole history
+)
+
+type SongStats struct {
+	ShortName     string `json:"short_name"`     // Shortened name of the played song
+	Url           string `json:"url"`            // Url of the Youtube song, songs are grouped by it
+	PlayCount     int    `json:"play_count"`     // Number of times the song has been played
+	PlayedSeconds int    `json:"played_seconds"` // Total number of seconds the song has been played
+}
+
+type RequesterStats struct {
+	RequesterID   string `json:"requester_id"`   // Id of the user that requested the songs
+	PlayCount     int    `json:"play_count"`     // Number of played songs requested by the user
+	PlayedSeconds int    `json:"played_seconds"` // Total number of seconds of the user's songs played
+}
+
+type HourStats struct {
+	Hour      int `json:"hour"`       // Hour of the day (UTC) in which the songs started playing
+	PlayCount int `json:"play_count"` // Number of songs that started playing in the hour
+}
+
+type Stats struct {
+	ClientID           string            `json:"client_id"`            // Id of the bot that played the songs
+	GuildID            string            `json:"guild_id"`             // Id of the discord server in which the songs have been played
+	Period             StatsPeriod       `json:"period"`               // The period the statistics are computed for
+	Since              time.Time         `json:"since"`                // Only songs started after this time are included in the statistics
+	Limit              int               `json:"limit"`                // Max number of displayed songs, requesters and hours
+	PlayCount          int               `json:"play_count"`           // Total number of played songs
+	TotalPlayedSeconds int               `json:"total_played_seconds"` // Total number of seconds the songs have been played
+	TopSongs           []*SongStats      `json:"top_songs"`            // Most played songs
+	TopRequesters      []*RequesterStats `json:"top_requesters"`       // Users with the most played requested songs
+	BusiestHours       []*HourStats      `json:"busiest_hours"`        // Hours of the day in which most songs started playing
+}
@@ -0,0 +1,42 @@
+package history
+
+import (
+	"discord-music-bot/model"
+)
+
+type HistoryService struct{}
+
+// NewHistoryService constructs an object that holds some
+// logic for manipulating the played songs' history.
+func NewHistoryService() *HistoryService {
+	return &HistoryService{}
+}
+
+// IncrementHistoryOffset increments the provided history's
+// offset by it's limit. If the new offset is larger than
+// the size of the history, the offset is wrapped back to 0.
+// The provided history is expected to have all the data fetched.
+func (service *HistoryService) IncrementHistoryOffset(history *model.History) {
+	history.Offset += history.Limit
+	if history.Offset >= history.Size {
+		history.Offset = 0
+	}
+}
+
+// DecrementHistoryOffset decrements the provided history's
+// offset by it's limit. If the new offset is less than 0,
+// the offset is maximized.
+func (service *HistoryService) DecrementHistoryOffset(history *model.History) {
+	history.Offset -= history.Limit
+	if history.Offset < 0 {
+		if history.Size == 0 {
+			history.Offset = 0
+			return
+		}
+		j := history.Size % history.Limit
+		if j == 0 {
+			j = history.Limit
+		}
+		history.Offset = history.Size - j
+	}
+}
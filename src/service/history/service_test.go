@@ -0,0 +1,90 @@
+package history_test
+
+import (
+	"discord-music-bot/model"
+	"discord-music-bot/service/history"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HistoryServiceTestSuite struct {
+	suite.Suite
+	service *history.HistoryService
+}
+
+// SetupSuite runs on suit init and creates
+// the history service.
+func (s *HistoryServiceTestSuite) SetupSuite() {
+	s.service = history.NewHistoryService()
+}
+
+// TestUnitIncrementHistoryOffset tests that
+// IncrementHistoryOffset() properly increments the history's offset.
+func (s *HistoryServiceTestSuite) TestUnitIncrementHistoryOffset() {
+	history := &model.History{
+		ClientID: "CLIENT-ID-TEST",
+		GuildID:  "GUILD-ID-TEST",
+		Size:     12,
+		Limit:    5,
+		Offset:   0,
+	}
+	s.service.IncrementHistoryOffset(history)
+	// No other fields should be changed
+	s.Equal(history.ClientID, "CLIENT-ID-TEST")
+	s.Equal(history.GuildID, "GUILD-ID-TEST")
+
+	s.Equal(5, history.Offset)
+	s.service.IncrementHistoryOffset(history)
+	s.Equal(10, history.Offset)
+	s.service.IncrementHistoryOffset(history)
+	s.Equal(0, history.Offset)
+
+	// Unlike the queue, history has no head song,
+	// so it wraps exactly on it's size
+	history.Size = 10
+	s.service.IncrementHistoryOffset(history)
+	s.service.IncrementHistoryOffset(history)
+	s.Equal(0, history.Offset)
+
+	history.Size = 0
+	s.service.IncrementHistoryOffset(history)
+	s.Equal(0, history.Offset)
+}
+
+// TestUnitDecrementHistoryOffset tests that
+// DecrementHistoryOffset() properly decrements the history's offset.
+func (s *HistoryServiceTestSuite) TestUnitDecrementHistoryOffset() {
+	history := &model.History{
+		ClientID: "CLIENT-ID-TEST",
+		GuildID:  "GUILD-ID-TEST",
+		Size:     12,
+		Limit:    5,
+		Offset:   0,
+	}
+	s.service.DecrementHistoryOffset(history)
+	// No other fields should be changed
+	s.Equal(history.ClientID, "CLIENT-ID-TEST")
+	s.Equal(history.GuildID, "GUILD-ID-TEST")
+	s.Equal(10, history.Offset)
+
+	s.service.DecrementHistoryOffset(history)
+	s.Equal(5, history.Offset)
+	s.service.DecrementHistoryOffset(history)
+	s.Equal(0, history.Offset)
+
+	history.Size = 10
+	s.service.DecrementHistoryOffset(history)
+	s.Equal(5, history.Offset)
+
+	history.Size = 0
+	history.Offset = 0
+	s.service.DecrementHistoryOffset(history)
+	s.Equal(0, history.Offset)
+}
+
+// TestHistoryServiceTestSuite runs all tests under
+// the HistoryServiceTestSuite
+func TestHistoryServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(HistoryServiceTestSuite))
+}
@@ -2,6 +2,7 @@ package queue
 
 import (
 	"discord-music-bot/model"
+	"strings"
 )
 
 type QueueService struct{}
@@ -37,3 +38,50 @@ func (service *QueueService) DecrementQueueOffset(queue *model.Queue) {
 		queue.Offset = i - j
 	}
 }
+
+// GetQueuePages returns the number of pages of the provided queue's
+// songs, that are displayed after the head song. The queue always
+// has at least one page.
+func (service *QueueService) GetQueuePages(queue *model.Queue) int {
+	if queue.Size <= 1 || queue.Limit <= 0 {
+		return 1
+	}
+	return (queue.Size - 2 + queue.Limit) / queue.Limit
+}
+
+// SetQueuePage sets the provided queue's offset, so that the
+// page with the provided number (starting with 1) is displayed.
+// Pages out of the queue's range are limited to the first or the
+// last page.
+func (service *QueueService) SetQueuePage(queue *model.Queue, page int) {
+	if pages := service.GetQueuePages(queue); page > pages {
+		page = pages
+	}
+	if page < 1 {
+		page = 1
+	}
+	queue.Offset = (page - 1) * queue.Limit
+}
+
+// SearchQueue finds the first of the provided songs, whose name
+// contains the provided query, after the provided queue's page,
+// and sets the queue's offset to the page with that song. The search
+// wraps to the queue's start, so searching again shows the next match.
+// The provided songs are all of the queue's songs, including the
+// head song, that is never searched as it is displayed on every page.
+// Returns false, without changing the offset, if no song matches.
+func (service *QueueService) SearchQueue(queue *model.Queue, songs []*model.Song, query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if len(query) == 0 || len(songs) <= 1 || queue.Limit <= 0 {
+		return false
+	}
+	start := queue.Offset + queue.Limit
+	for i := 0; i < len(songs)-1; i++ {
+		j := (start+i)%(len(songs)-1) + 1
+		if strings.Contains(strings.ToLower(songs[j].Name), query) {
+			queue.Offset = ((j - 1) / queue.Limit) * queue.Limit
+			return true
+		}
+	}
+	return false
+}
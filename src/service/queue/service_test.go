@@ -108,6 +108,64 @@ func (s *QueueServiceTestSuite) TestUnitDecrementQueueOffset() {
 
 }
 
+// TestUnitSetQueuePage tests that SetQueuePage() sets
+// the queue's offset to the requested page, limited by
+// the number of the queue's pages.
+func (s *QueueServiceTestSuite) TestUnitSetQueuePage() {
+	queue := &model.Queue{
+		Size:   22,
+		Limit:  10,
+		Offset: 0,
+	}
+	// Head song + 21 songs are displayed on 3 pages
+	s.Equal(3, s.service.GetQueuePages(queue))
+
+	s.service.SetQueuePage(queue, 2)
+	s.Equal(10, queue.Offset)
+
+	s.service.SetQueuePage(queue, 5)
+	s.Equal(20, queue.Offset)
+
+	s.service.SetQueuePage(queue, 0)
+	s.Equal(0, queue.Offset)
+
+	queue.Size = 1
+	s.Equal(1, s.service.GetQueuePages(queue))
+	s.service.SetQueuePage(queue, 2)
+	s.Equal(0, queue.Offset)
+}
+
+// TestUnitSearchQueue tests that SearchQueue() moves the
+// queue's offset to the page with the next matching song.
+func (s *QueueServiceTestSuite) TestUnitSearchQueue() {
+	songs := make([]*model.Song, 0)
+	for _, name := range []string{
+		"Head Song", "Snow", "Otherside", "Californication",
+		"Can't Stop", "Dani California",
+	} {
+		songs = append(songs, &model.Song{Name: name})
+	}
+	queue := &model.Queue{
+		Size:   len(songs),
+		Limit:  2,
+		Offset: 0,
+	}
+	s.True(s.service.SearchQueue(queue, songs, "californi"))
+	s.Equal(2, queue.Offset)
+
+	// Searching again shows the next match
+	s.True(s.service.SearchQueue(queue, songs, "californi"))
+	s.Equal(4, queue.Offset)
+
+	// And then wraps to the start of the queue
+	s.True(s.service.SearchQueue(queue, songs, "SNOW"))
+	s.Equal(0, queue.Offset)
+
+	// The head song is not searched
+	s.False(s.service.SearchQueue(queue, songs, "head"))
+	s.Equal(0, queue.Offset)
+}
+
 // TestQueueServiceTestSuite runs all tests under
 // the QueueServiceTestSuite
 func TestQueueServiceTestSuite(t *testing.T) {
@@ -1,21 +1,24 @@
 package service
 
 import (
+	"discord-music-bot/service/history"
 	"discord-music-bot/service/queue"
 	"discord-music-bot/service/song"
 )
 
 type Service struct {
-	queue *queue.QueueService
-	song  *song.SongService
+	queue   *queue.QueueService
+	song    *song.SongService
+	history *history.HistoryService
 }
 
 // NewService constructs an object that holds different
 // services for handling some of the logic.
 func NewService() *Service {
 	return &Service{
-		queue: queue.NewQueueService(),
-		song:  song.NewSongService(),
+		queue:   queue.NewQueueService(),
+		song:    song.NewSongService(),
+		history: history.NewHistoryService(),
 	}
 }
 
@@ -30,3 +33,9 @@ func (service *Service) Queue() *queue.QueueService {
 func (service *Service) Song() *song.SongService {
 	return service.song
 }
+
+// History returns the service for handling the logic
+// behind paging through the played songs' history.
+func (service *Service) History() *history.HistoryService {
+	return service.history
+}
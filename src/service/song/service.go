@@ -3,6 +3,9 @@ package song
 import (
 	"discord-music-bot/model"
 	"math/rand"
+	"net/url"
+	"regexp"
+	"strings"
 )
 
 type SongService struct{}
@@ -44,3 +47,54 @@ func (service *SongService) ShuffleSongs(songs []*model.Song) []*model.Song {
 	}
 	return songs
 }
+
+// urlRegexp matches the urls in a message's text, discord's
+// <url> syntax for suppressing the url's embed included.
+var urlRegexp = regexp.MustCompile(`https?://[^\s<>]+`)
+
+// supportedHosts are the hosts of the urls from which the songs
+// may be added to the queue.
+var supportedHosts = map[string]struct{}{
+	"youtube.com":       {},
+	"www.youtube.com":   {},
+	"m.youtube.com":     {},
+	"music.youtube.com": {},
+	"youtu.be":          {},
+}
+
+// ExtractSongUrls returns all of the supported songs' and playlists'
+// urls found in the provided texts, in the order in which
+// they appear, each of them only once. The shortened youtu.be
+// urls are expanded to the youtube's /watch urls.
+func (service *SongService) ExtractSongUrls(texts ...string) []string {
+	urls := make([]string, 0)
+	found := make(map[string]struct{})
+	for _, text := range texts {
+		for _, match := range urlRegexp.FindAllString(text, -1) {
+			// NOTE: the punctuation directly after the url
+			// is usually not a part of it
+			match = strings.TrimRight(match, ".,;:!?)]*_~|'\"")
+			u, err := url.Parse(match)
+			if err != nil {
+				continue
+			}
+			host := strings.ToLower(u.Host)
+			if _, ok := supportedHosts[host]; !ok {
+				continue
+			}
+			if host == "youtu.be" {
+				videoID := strings.Trim(u.Path, "/")
+				if len(videoID) == 0 {
+					continue
+				}
+				match = "https://www.youtube.com/watch?v=" + videoID
+			}
+			if _, ok := found[match]; ok {
+				continue
+			}
+			found[match] = struct{}{}
+			urls = append(urls, match)
+		}
+	}
+	return urls
+}
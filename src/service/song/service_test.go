@@ -63,6 +63,26 @@ func (s *SongServiceTestSuite) TestUnitShuffleSongs() {
 	}
 }
 
+// TestUnitExtractSongUrls checks that only the supported urls
+// are extracted from the texts, without the surrounding
+// punctuation and without duplicates.
+func (s *SongServiceTestSuite) TestUnitExtractSongUrls() {
+	urls := s.service.ExtractSongUrls(
+		"Listen to https://www.youtube.com/watch?v=abc, it's great!",
+		"<https://youtu.be/def> and (https://example.com/watch?v=ghi)",
+		"https://music.youtube.com/playlist?list=jkl\nhttps://www.youtube.com/watch?v=abc",
+		"https://youtu.be/ http://m.youtube.com/watch?v=mno&t=10",
+	)
+	s.Equal([]string{
+		"https://www.youtube.com/watch?v=abc",
+		"https://www.youtube.com/watch?v=def",
+		"https://music.youtube.com/playlist?list=jkl",
+		"http://m.youtube.com/watch?v=mno&t=10",
+	}, urls)
+
+	s.Empty(s.service.ExtractSongUrls("no urls here", ""))
+}
+
 // TestSongServiceTestSuite runs all tests under
 // the SongServiceTestSuite
 func TestSongServiceTestSuite(t *testing.T) {
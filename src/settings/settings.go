@@ -0,0 +1,366 @@
+package settings
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultProgressInterval is the interval at which the progress
+	// is refreshed, when no other interval is configured.
+	DefaultProgressInterval = 15 * time.Second
+	// MinProgressInterval limits how often the progress is refreshed,
+	// so the queue messages' updates do not exceed discord's rate limits.
+	MinProgressInterval = 5 * time.Second
+)
+
+const (
+	// DetailedTheme shows the queue message with the playing song's
+	// thumbnail, channel and progress. This is the default theme.
+	DetailedTheme = "detailed"
+	// CompactTheme shows the queue message with only the
+	// names and durations of the songs, each in a single line.
+	CompactTheme = "compact"
+)
+
+// The names of the settings, that the guilds' admins
+// may change for their own guild.
+const (
+	ProgressSetting             = "progress"
+	ProgressIntervalSetting     = "progress_interval"
+	AnnouncementsSetting        = "announcements"
+	AnnouncementsChannelSetting = "announcements_channel"
+	RecreateSetting             = "recreate"
+	StickySetting               = "sticky"
+	ThemeSetting                = "theme"
+	LanguageSetting             = "language"
+)
+
+// Names lists the names of all the settings,
+// that may be changed for a single guild.
+var Names = []string{
+	ProgressSetting,
+	ProgressIntervalSetting,
+	AnnouncementsSetting,
+	AnnouncementsChannelSetting,
+	RecreateSetting,
+	StickySetting,
+	ThemeSetting,
+	LanguageSetting,
+}
+
+var (
+	ErrUnknownSetting = errors.New("unknown setting")
+	ErrInvalidValue   = errors.New("invalid setting value")
+)
+
+// channelMention matches a channel's ID or a mention of the channel.
+var channelMention = regexp.MustCompile(`^(?:<#)?([0-9]+)>?$`)
+
+type Configuration struct {
+	Default *Settings            `yaml:"Default"` // Settings of all the guilds without their own settings
+	Guilds  map[string]*Settings `yaml:"Guilds"`  // Settings by guild ID, the omitted ones are taken from the default settings
+}
+
+type Settings struct {
+	Progress      *ProgressSettings     `yaml:"Progress"`
+	Announcements *AnnouncementSettings `yaml:"Announcements"`
+	QueueMessage  *QueueMessageSettings `yaml:"QueueMessage"`
+	Language      string                `yaml:"Language"` // Language of the bot's messages (e.g. en or de), the user's discord language is used when omitted
+}
+
+type ProgressSettings struct {
+	Show     *bool         `yaml:"Show"`     // Show the playing song's progress in the queue message
+	Interval time.Duration `yaml:"Interval"` // Interval at which the progress is refreshed while the song is playing
+}
+
+type AnnouncementSettings struct {
+	Show      *bool  `yaml:"Show"`      // Post a message whenever a new song starts playing
+	ChannelID string `yaml:"ChannelID"` // Channel to which the messages are posted, the queue message's channel when omitted
+}
+
+type QueueMessageSettings struct {
+	Recreate *bool  `yaml:"Recreate"` // Post a new queue message when it is deleted, instead of stopping the music
+	Sticky   int    `yaml:"Sticky"`   // Number of new messages in the queue's channel, after which the queue message is reposted below them, never reposted when 0
+	Theme    string `yaml:"Theme"`    // Appearance of the queue message, either detailed or compact, detailed when omitted
+}
+
+type GuildSettings struct {
+	config *Configuration
+	mutex  sync.RWMutex
+	// NOTE: the settings changed by the guilds' admins, by guild ID
+	// and setting name, applied over the configured settings
+	stored map[string]map[string]string
+}
+
+// NewGuildSettings constructs an object that resolves the settings
+// of every guild, from the guild's own settings and the default ones.
+// An empty configuration is used when the provided config is nil.
+func NewGuildSettings(config *Configuration) *GuildSettings {
+	if config == nil {
+		config = &Configuration{}
+	}
+	return &GuildSettings{
+		config: config,
+		stored: make(map[string]map[string]string),
+	}
+}
+
+// Set changes the setting with the provided name for the guild
+// identified by the provided guildID, so it overrides the configured
+// setting. The provided value is normalized before it is stored.
+func (s *GuildSettings) Set(guildID string, name string, value string) error {
+	value, err := Normalize(name, value)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.stored[guildID]; !ok {
+		s.stored[guildID] = make(map[string]string)
+	}
+	s.stored[guildID][name] = value
+	return nil
+}
+
+// Remove resets the setting with the provided name for the guild
+// identified by the provided guildID, back to the configured setting.
+func (s *GuildSettings) Remove(guildID string, name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.stored[guildID], name)
+	if len(s.stored[guildID]) == 0 {
+		delete(s.stored, guildID)
+	}
+}
+
+// IsSet returns true if the setting with the provided name has
+// been changed for the guild identified by the provided guildID.
+func (s *GuildSettings) IsSet(guildID string, name string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	_, ok := s.stored[guildID][name]
+	return ok
+}
+
+// Get returns the settings of the guild identified by the provided
+// guildID. The settings the guild does not have are taken from the
+// default settings, and those that neither has are left at their
+// default values, so the returned settings are always complete.
+func (s *GuildSettings) Get(guildID string) *Settings {
+	settings := &Settings{
+		Progress:      &ProgressSettings{},
+		Announcements: &AnnouncementSettings{},
+		QueueMessage:  &QueueMessageSettings{},
+	}
+	settings.merge(s.config.Default)
+	settings.merge(s.config.Guilds[guildID])
+	s.mutex.RLock()
+	for name, value := range s.stored[guildID] {
+		settings.set(name, value)
+	}
+	s.mutex.RUnlock()
+	if settings.Progress.Show == nil {
+		show := false
+		settings.Progress.Show = &show
+	}
+	if settings.Progress.Interval == 0 {
+		settings.Progress.Interval = DefaultProgressInterval
+	}
+	if settings.Progress.Interval < MinProgressInterval {
+		settings.Progress.Interval = MinProgressInterval
+	}
+	if settings.Announcements.Show == nil {
+		show := false
+		settings.Announcements.Show = &show
+	}
+	if settings.QueueMessage.Recreate == nil {
+		recreate := false
+		settings.QueueMessage.Recreate = &recreate
+	}
+	if settings.QueueMessage.Sticky < 0 {
+		settings.QueueMessage.Sticky = 0
+	}
+	if settings.QueueMessage.Theme != CompactTheme {
+		settings.QueueMessage.Theme = DetailedTheme
+	}
+	return settings
+}
+
+// merge overrides the settings with the ones
+// that are set in the provided other settings.
+func (settings *Settings) merge(other *Settings) {
+	if other == nil {
+		return
+	}
+	if p := other.Progress; p != nil {
+		if p.Show != nil {
+			settings.Progress.Show = p.Show
+		}
+		if p.Interval != 0 {
+			settings.Progress.Interval = p.Interval
+		}
+	}
+	if a := other.Announcements; a != nil {
+		if a.Show != nil {
+			settings.Announcements.Show = a.Show
+		}
+		if len(a.ChannelID) > 0 {
+			settings.Announcements.ChannelID = a.ChannelID
+		}
+	}
+	if q := other.QueueMessage; q != nil {
+		if q.Recreate != nil {
+			settings.QueueMessage.Recreate = q.Recreate
+		}
+		if q.Sticky != 0 {
+			settings.QueueMessage.Sticky = q.Sticky
+		}
+		if len(q.Theme) > 0 {
+			settings.QueueMessage.Theme = q.Theme
+		}
+	}
+	if len(other.Language) > 0 {
+		settings.Language = other.Language
+	}
+}
+
+// set overrides the setting with the provided name,
+// with the provided normalized value.
+func (settings *Settings) set(name string, value string) {
+	switch name {
+	case ProgressSetting:
+		show := value == "on"
+		settings.Progress.Show = &show
+	case ProgressIntervalSetting:
+		settings.Progress.Interval, _ = time.ParseDuration(value)
+	case AnnouncementsSetting:
+		show := value == "on"
+		settings.Announcements.Show = &show
+	case AnnouncementsChannelSetting:
+		settings.Announcements.ChannelID = value
+	case RecreateSetting:
+		recreate := value == "on"
+		settings.QueueMessage.Recreate = &recreate
+	case StickySetting:
+		settings.QueueMessage.Sticky, _ = strconv.Atoi(value)
+	case ThemeSetting:
+		settings.QueueMessage.Theme = value
+	case LanguageSetting:
+		settings.Language = value
+	}
+}
+
+// Value returns the value of the setting with the provided
+// name, in the same form as it is accepted by Normalize.
+func (settings *Settings) Value(name string) string {
+	onOff := func(enabled bool) string {
+		if enabled {
+			return "on"
+		}
+		return "off"
+	}
+	switch name {
+	case ProgressSetting:
+		return onOff(settings.Progress.Enabled())
+	case ProgressIntervalSetting:
+		return settings.Progress.Interval.String()
+	case AnnouncementsSetting:
+		return onOff(settings.Announcements.Enabled())
+	case AnnouncementsChannelSetting:
+		return settings.Announcements.ChannelID
+	case RecreateSetting:
+		return onOff(settings.QueueMessage.Recreated())
+	case StickySetting:
+		return strconv.Itoa(settings.QueueMessage.Sticky)
+	case ThemeSetting:
+		return settings.QueueMessage.Theme
+	case LanguageSetting:
+		return settings.Language
+	}
+	return ""
+}
+
+// Normalize checks whether the provided value is valid for the
+// setting with the provided name, and returns it in the form in
+// which it is stored. The switches accept on, off and the boolean
+// values, the announcements' channel accepts a channel's mention.
+// The language is not checked against the available languages.
+func Normalize(name string, value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch name {
+	case ProgressSetting, AnnouncementsSetting, RecreateSetting:
+		switch value {
+		case "on":
+			return "on", nil
+		case "off":
+			return "off", nil
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", ErrInvalidValue
+		}
+		if enabled {
+			return "on", nil
+		}
+		return "off", nil
+	case ProgressIntervalSetting:
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < MinProgressInterval {
+			return "", ErrInvalidValue
+		}
+		return interval.String(), nil
+	case AnnouncementsChannelSetting:
+		match := channelMention.FindStringSubmatch(value)
+		if match == nil {
+			return "", ErrInvalidValue
+		}
+		return match[1], nil
+	case StickySetting:
+		sticky, err := strconv.Atoi(value)
+		if err != nil || sticky < 0 {
+			return "", ErrInvalidValue
+		}
+		return strconv.Itoa(sticky), nil
+	case ThemeSetting:
+		if value != DetailedTheme && value != CompactTheme {
+			return "", ErrInvalidValue
+		}
+		return value, nil
+	case LanguageSetting:
+		if len(value) == 0 {
+			return "", ErrInvalidValue
+		}
+		return value, nil
+	}
+	return "", ErrUnknownSetting
+}
+
+// Enabled returns true if the progress should be shown.
+func (progress *ProgressSettings) Enabled() bool {
+	return progress.Show != nil && *progress.Show
+}
+
+// Enabled returns true if the songs should be announced.
+func (announcements *AnnouncementSettings) Enabled() bool {
+	return announcements.Show != nil && *announcements.Show
+}
+
+// Recreated returns true if a new queue message should
+// be posted when the queue message is deleted.
+func (queueMessage *QueueMessageSettings) Recreated() bool {
+	return queueMessage.Recreate != nil && *queueMessage.Recreate
+}
+
+// Compact returns true if the queue message
+// should be shown with the compact theme.
+func (queueMessage *QueueMessageSettings) Compact() bool {
+	return queueMessage.Theme == CompactTheme
+}
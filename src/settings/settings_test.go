@@ -0,0 +1,206 @@
+package settings_test
+
+import (
+	"discord-music-bot/settings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SettingsTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitGetDefaults checks that the settings of a guild are
+// complete even when nothing has been configured.
+func (s *SettingsTestSuite) TestUnitGetDefaults() {
+	guilds := settings.NewGuildSettings(nil)
+
+	guild := guilds.Get("GUILD-ID-TEST")
+	s.Require().NotNil(guild.Progress)
+	s.False(guild.Progress.Enabled())
+	s.Equal(settings.DefaultProgressInterval, guild.Progress.Interval)
+	s.Require().NotNil(guild.Announcements)
+	s.False(guild.Announcements.Enabled())
+	s.Empty(guild.Announcements.ChannelID)
+	s.Require().NotNil(guild.QueueMessage)
+	s.False(guild.QueueMessage.Recreated())
+	s.Zero(guild.QueueMessage.Sticky)
+}
+
+// TestUnitGetOverrides checks that a guild's own settings override
+// the default ones, while the ones it does not set are inherited.
+func (s *SettingsTestSuite) TestUnitGetOverrides() {
+	show, hide := true, false
+	guilds := settings.NewGuildSettings(&settings.Configuration{
+		Default: &settings.Settings{
+			Progress: &settings.ProgressSettings{
+				Show:     &show,
+				Interval: 20 * time.Second,
+			},
+		},
+		Guilds: map[string]*settings.Settings{
+			"GUILD-ID-1": {
+				Progress: &settings.ProgressSettings{Show: &hide},
+			},
+			"GUILD-ID-2": {
+				Progress: &settings.ProgressSettings{Interval: 10 * time.Second},
+			},
+		},
+	})
+
+	guild := guilds.Get("GUILD-ID-1")
+	s.False(guild.Progress.Enabled())
+	s.Equal(20*time.Second, guild.Progress.Interval)
+
+	guild = guilds.Get("GUILD-ID-2")
+	s.True(guild.Progress.Enabled())
+	s.Equal(10*time.Second, guild.Progress.Interval)
+
+	guild = guilds.Get("GUILD-ID-3")
+	s.True(guild.Progress.Enabled())
+	s.Equal(20*time.Second, guild.Progress.Interval)
+}
+
+// TestUnitGetAnnouncements checks that the announcements' channel
+// is inherited from the default settings, when a guild only
+// enables the announcements.
+func (s *SettingsTestSuite) TestUnitGetAnnouncements() {
+	show := true
+	guilds := settings.NewGuildSettings(&settings.Configuration{
+		Default: &settings.Settings{
+			Announcements: &settings.AnnouncementSettings{
+				ChannelID: "CHANNEL-ID-TEST",
+			},
+		},
+		Guilds: map[string]*settings.Settings{
+			"GUILD-ID-1": {
+				Announcements: &settings.AnnouncementSettings{Show: &show},
+			},
+		},
+	})
+
+	guild := guilds.Get("GUILD-ID-1")
+	s.True(guild.Announcements.Enabled())
+	s.Equal("CHANNEL-ID-TEST", guild.Announcements.ChannelID)
+
+	guild = guilds.Get("GUILD-ID-2")
+	s.False(guild.Announcements.Enabled())
+}
+
+// TestUnitGetLanguage checks that a guild's language overrides the
+// default one, and that it is empty when neither is set.
+func (s *SettingsTestSuite) TestUnitGetLanguage() {
+	guilds := settings.NewGuildSettings(&settings.Configuration{
+		Default: &settings.Settings{Language: "en"},
+		Guilds: map[string]*settings.Settings{
+			"GUILD-ID-1": {Language: "de"},
+		},
+	})
+	s.Equal("de", guilds.Get("GUILD-ID-1").Language)
+	s.Equal("en", guilds.Get("GUILD-ID-2").Language)
+
+	s.Empty(settings.NewGuildSettings(nil).Get("GUILD-ID-1").Language)
+}
+
+// TestUnitGetTheme checks that a guild's theme overrides the
+// default one, and that the unknown themes are replaced
+// with the detailed theme.
+func (s *SettingsTestSuite) TestUnitGetTheme() {
+	guilds := settings.NewGuildSettings(&settings.Configuration{
+		Default: &settings.Settings{
+			QueueMessage: &settings.QueueMessageSettings{Theme: settings.CompactTheme},
+		},
+		Guilds: map[string]*settings.Settings{
+			"GUILD-ID-1": {
+				QueueMessage: &settings.QueueMessageSettings{Theme: "unknown"},
+			},
+		},
+	})
+	s.False(guilds.Get("GUILD-ID-1").QueueMessage.Compact())
+	s.Equal(settings.DetailedTheme, guilds.Get("GUILD-ID-1").QueueMessage.Theme)
+	s.True(guilds.Get("GUILD-ID-2").QueueMessage.Compact())
+
+	s.Equal(
+		settings.DetailedTheme,
+		settings.NewGuildSettings(nil).Get("GUILD-ID-1").QueueMessage.Theme,
+	)
+}
+
+// TestUnitGetMinInterval checks that the progress is never
+// refreshed more often than the minimum interval allows.
+func (s *SettingsTestSuite) TestUnitGetMinInterval() {
+	guilds := settings.NewGuildSettings(&settings.Configuration{
+		Default: &settings.Settings{
+			Progress: &settings.ProgressSettings{Interval: time.Second},
+		},
+	})
+	s.Equal(
+		settings.MinProgressInterval,
+		guilds.Get("GUILD-ID-TEST").Progress.Interval,
+	)
+}
+
+// TestUnitSetOverrides checks that the settings changed for a
+// guild override the configured ones, even when they are
+// switched off, and that removing them restores the configured ones.
+func (s *SettingsTestSuite) TestUnitSetOverrides() {
+	show := true
+	guilds := settings.NewGuildSettings(&settings.Configuration{
+		Default: &settings.Settings{
+			Progress:     &settings.ProgressSettings{Show: &show},
+			QueueMessage: &settings.QueueMessageSettings{Sticky: 3},
+		},
+	})
+	s.NoError(guilds.Set("GUILD-ID-1", settings.ProgressSetting, "false"))
+	s.NoError(guilds.Set("GUILD-ID-1", settings.StickySetting, "0"))
+	s.NoError(guilds.Set("GUILD-ID-1", settings.ThemeSetting, "Compact"))
+	s.NoError(guilds.Set("GUILD-ID-1", settings.AnnouncementsChannelSetting, "<#123>"))
+
+	guild := guilds.Get("GUILD-ID-1")
+	s.False(guild.Progress.Enabled())
+	s.Zero(guild.QueueMessage.Sticky)
+	s.True(guild.QueueMessage.Compact())
+	s.Equal("123", guild.Announcements.ChannelID)
+	s.Equal("off", guild.Value(settings.ProgressSetting))
+	s.True(guilds.IsSet("GUILD-ID-1", settings.ThemeSetting))
+
+	// Other guilds should not be affected
+	guild = guilds.Get("GUILD-ID-2")
+	s.True(guild.Progress.Enabled())
+	s.Equal(3, guild.QueueMessage.Sticky)
+
+	guilds.Remove("GUILD-ID-1", settings.ProgressSetting)
+	s.False(guilds.IsSet("GUILD-ID-1", settings.ProgressSetting))
+	s.True(guilds.Get("GUILD-ID-1").Progress.Enabled())
+}
+
+// TestUnitSetInvalid checks that the unknown settings and the
+// invalid values are rejected and do not change the settings.
+func (s *SettingsTestSuite) TestUnitSetInvalid() {
+	guilds := settings.NewGuildSettings(nil)
+
+	s.ErrorIs(guilds.Set("GUILD-ID-1", "unknown", "on"), settings.ErrUnknownSetting)
+	for name, value := range map[string]string{
+		settings.ProgressSetting:             "maybe",
+		settings.ProgressIntervalSetting:     "1s",
+		settings.AnnouncementsChannelSetting: "general",
+		settings.StickySetting:               "-1",
+		settings.ThemeSetting:                "unknown",
+		settings.LanguageSetting:             " ",
+	} {
+		s.ErrorIs(guilds.Set("GUILD-ID-1", name, value), settings.ErrInvalidValue, name)
+		s.False(guilds.IsSet("GUILD-ID-1", name), name)
+	}
+	s.Equal(
+		settings.DefaultProgressInterval,
+		guilds.Get("GUILD-ID-1").Progress.Interval,
+	)
+}
+
+// TestSettingsTestSuite runs all tests under
+// the SettingsTestSuite suite.
+func TestSettingsTestSuite(t *testing.T) {
+	suite.Run(t, new(SettingsTestSuite))
+}
@@ -1,17 +1,53 @@
 package client
 
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBaseUrl is the url to which the requests
+// are sent, when no other base url is configured.
+const DefaultBaseUrl = "https://www.youtube.com"
+
+type Configuration struct {
+	BaseUrl string            `yaml:"BaseUrl"` // Url of youtube, or of a server that mimics it
+	Timeout time.Duration     `yaml:"Timeout"` // Duration after which a request is cancelled, requests are not limited when omitted
+	Headers map[string]string `yaml:"Headers"` // Headers added to every request, they override the default headers
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client `yaml:"-"`
+}
+
 type YoutubeClient struct {
-	baseUrl string
-	headers map[string]string
+	baseUrl    string
+	headers    map[string]string
+	timeout    time.Duration
+	httpClient *http.Client
 }
 
 // NewYoutubeClient construct a new object that handles
-// youtube http requests.
-func NewYoutubeClient() *YoutubeClient {
+// youtube http requests. The default configuration is
+// used when the provided config is nil.
+func NewYoutubeClient(config *Configuration) *YoutubeClient {
+	if config == nil {
+		config = &Configuration{}
+	}
 	c := &YoutubeClient{
-		baseUrl: "https://www.youtube.com",
-		headers: make(map[string]string),
+		baseUrl:    config.BaseUrl,
+		headers:    make(map[string]string),
+		timeout:    config.Timeout,
+		httpClient: config.HTTPClient,
+	}
+	if len(c.baseUrl) == 0 {
+		c.baseUrl = DefaultBaseUrl
+	}
+	c.baseUrl = strings.TrimSuffix(c.baseUrl, "/")
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
 	}
 	c.headers["Content-Type"] = "application/json"
+	for k, v := range config.Headers {
+		c.headers[k] = v
+	}
 	return c
 }
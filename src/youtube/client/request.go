@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 
 type Request struct {
 	*http.Request
+	client *YoutubeClient
 }
 
 type QueryParam string
@@ -44,6 +46,24 @@ func (client *YoutubeClient) NewSearchRequest(query string) ([]byte, string, err
 
 }
 
+// NewPlaylistRequest creates a get request to youtube's /playlist
+// endpoint with list=?playlistID where playlistID is the provided ID.
+// Returns response bytes, request's url and error (if any).
+func (client *YoutubeClient) NewPlaylistRequest(playlistID string) ([]byte, string, error) {
+	req, _ := client.newRequest("GET", "/playlist")
+	req.AddQueryParam("list", playlistID)
+	url := req.url()
+	b, err := req.doAndRead()
+	return b, url, err
+}
+
+// WatchUrl returns the canonical url of youtube's /watch endpoint
+// for the video identified by the provided videoID, regardless
+// of the client's base url.
+func (client *YoutubeClient) WatchUrl(videoID string) string {
+	return DefaultBaseUrl + "/watch?" + url.Values{"v": {videoID}}.Encode()
+}
+
 // newRequest constructs a new http request with url
 // Returns error if invalid pathParams provided.
 // equal to client's baseUrl + the provided endpoint.
@@ -59,7 +79,7 @@ func (client *YoutubeClient) newRequest(method string, endpoint string, pathPara
 	); err != nil {
 		return nil, err
 	} else {
-		req := &Request{r}
+		req := &Request{r, client}
 		for k, v := range client.headers {
 			req = req.AddHeader(k, v)
 		}
@@ -106,15 +126,31 @@ func (r *Request) AddPathParam(k string, v string) *Request {
 
 // Do sends a http request and returns the response
 func (r *Request) do() (*http.Response, error) {
-	return http.DefaultClient.Do(r.Request)
+	return r.client.httpClient.Do(r.Request)
 }
 
-// DoAndRead sends a http request and reads the response's body
+// DoAndRead sends a http request and reads the response's body,
+// the request is cancelled after the client's timeout, if any.
+// Returns error if the response's status is not successful.
 func (r *Request) doAndRead() ([]byte, error) {
-	resp, err := http.DefaultClient.Do(r.Request)
+	if r.client.timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), r.client.timeout)
+		defer cancel()
+		r.Request = r.WithContext(ctx)
+	}
+	resp, err := r.do()
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf(
+			"Request to '%s' failed with status: %s",
+			r.url(),
+			resp.Status,
+		)
+	}
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
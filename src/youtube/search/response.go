@@ -0,0 +1,189 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+)
+
+// playerResponse is the part of the ytInitialPlayerResponse object,
+// embedded in youtube's /watch page, that describes the video.
+type playerResponse struct {
+	PlayabilityStatus struct {
+		Status                     string `json:"status"`
+		Reason                     string `json:"reason"`
+		DesktopLegacyAgeGateReason int    `json:"desktopLegacyAgeGateReason"`
+	} `json:"playabilityStatus"`
+	VideoDetails struct {
+		VideoID       string     `json:"videoId"`
+		Title         string     `json:"title"`
+		LengthSeconds string     `json:"lengthSeconds"`
+		ChannelID     string     `json:"channelId"`
+		Author        string     `json:"author"`
+		Thumbnail     thumbnails `json:"thumbnail"`
+		IsLive        bool       `json:"isLive"`
+		IsLiveContent bool       `json:"isLiveContent"`
+	} `json:"videoDetails"`
+	Microformat struct {
+		PlayerMicroformatRenderer struct {
+			// NOTE: a pointer, so a missing field is not
+			// mistaken for an age restriction
+			IsFamilySafe         *bool `json:"isFamilySafe"`
+			LiveBroadcastDetails *struct {
+				IsLiveNow bool `json:"isLiveNow"`
+			} `json:"liveBroadcastDetails"`
+		} `json:"playerMicroformatRenderer"`
+	} `json:"microformat"`
+}
+
+// searchData is the part of the ytInitialData object, embedded
+// in youtube's /results page, that contains the search results.
+type searchData struct {
+	Contents struct {
+		TwoColumnSearchResultsRenderer struct {
+			PrimaryContents struct {
+				SectionListRenderer sectionList `json:"sectionListRenderer"`
+			} `json:"primaryContents"`
+		} `json:"twoColumnSearchResultsRenderer"`
+	} `json:"contents"`
+}
+
+// playlistData is the part of the ytInitialData object, embedded
+// in youtube's /playlist page, that contains the playlist's videos.
+type playlistData struct {
+	Contents struct {
+		TwoColumnBrowseResultsRenderer struct {
+			Tabs []struct {
+				TabRenderer struct {
+					Content struct {
+						SectionListRenderer sectionList `json:"sectionListRenderer"`
+					} `json:"content"`
+				} `json:"tabRenderer"`
+			} `json:"tabs"`
+		} `json:"twoColumnBrowseResultsRenderer"`
+	} `json:"contents"`
+}
+
+type sectionList struct {
+	Contents []struct {
+		ItemSectionRenderer struct {
+			Contents []struct {
+				VideoRenderer *struct {
+					VideoID string `json:"videoId"`
+				} `json:"videoRenderer"`
+				PlaylistVideoListRenderer *struct {
+					Contents []struct {
+						PlaylistVideoRenderer *playlistVideo `json:"playlistVideoRenderer"`
+					} `json:"contents"`
+				} `json:"playlistVideoListRenderer"`
+			} `json:"contents"`
+		} `json:"itemSectionRenderer"`
+	} `json:"contents"`
+}
+
+type playlistVideo struct {
+	VideoID         string     `json:"videoId"`
+	Title           text       `json:"title"`
+	ShortBylineText text       `json:"shortBylineText"`
+	Thumbnail       thumbnails `json:"thumbnail"`
+	LengthSeconds   string     `json:"lengthSeconds"`
+	IsPlayable      bool       `json:"isPlayable"`
+}
+
+// text is youtube's representation of a displayed text,
+// either a simple text or a list of differently styled runs.
+type text struct {
+	SimpleText string `json:"simpleText"`
+	Runs       []struct {
+		Text string `json:"text"`
+	} `json:"runs"`
+}
+
+type thumbnails struct {
+	Thumbnails []struct {
+		Url    string `json:"url"`
+		Width  int    `json:"width"`
+		Height int    `json:"height"`
+	} `json:"thumbnails"`
+}
+
+// String joins the text's runs, or returns the simple text
+// when there are no runs.
+func (t text) String() string {
+	if len(t.Runs) == 0 {
+		return t.SimpleText
+	}
+	b := new(bytes.Buffer)
+	for _, r := range t.Runs {
+		b.WriteString(r.Text)
+	}
+	return b.String()
+}
+
+// largest returns the url of the widest thumbnail,
+// or an empty string if there are no thumbnails.
+func (t thumbnails) largest() string {
+	url, width := "", -1
+	for _, v := range t.Thumbnails {
+		if v.Width > width {
+			url, width = v.Url, v.Width
+		}
+	}
+	return url
+}
+
+// videoIDs returns the ids of the videos in the search
+// results, ordered as they are displayed.
+func (d *searchData) videoIDs() []string {
+	ids := make([]string, 0)
+	for _, c := range d.Contents.TwoColumnSearchResultsRenderer.PrimaryContents.SectionListRenderer.Contents {
+		for _, item := range c.ItemSectionRenderer.Contents {
+			if item.VideoRenderer != nil && len(item.VideoRenderer.VideoID) > 0 {
+				ids = append(ids, item.VideoRenderer.VideoID)
+			}
+		}
+	}
+	return ids
+}
+
+// videos returns the playlist's videos, ordered as
+// they are in the playlist.
+func (d *playlistData) videos() []*playlistVideo {
+	videos := make([]*playlistVideo, 0)
+	for _, tab := range d.Contents.TwoColumnBrowseResultsRenderer.Tabs {
+		for _, c := range tab.TabRenderer.Content.SectionListRenderer.Contents {
+			for _, item := range c.ItemSectionRenderer.Contents {
+				if item.PlaylistVideoListRenderer == nil {
+					continue
+				}
+				for _, v := range item.PlaylistVideoListRenderer.Contents {
+					if v.PlaylistVideoRenderer != nil {
+						videos = append(videos, v.PlaylistVideoRenderer)
+					}
+				}
+			}
+		}
+	}
+	return videos
+}
+
+var (
+	playerResponseRegexp = regexp.MustCompile(`ytInitialPlayerResponse\s*=\s*{`)
+	initialDataRegexp    = regexp.MustCompile(`ytInitialData\s*=\s*{`)
+)
+
+// decodeEmbeddedObject finds the javascript object assigned
+// to a variable matched by the provided regexp, in the provided
+// page, and decodes it into the provided interface.
+func decodeEmbeddedObject(page []byte, re *regexp.Regexp, v interface{}) error {
+	loc := re.FindIndex(page)
+	if loc == nil {
+		return errors.New("Embedded object not found in the page")
+	}
+	// NOTE: the decoder stops after the first complete
+	// json value, so the rest of the script is ignored
+	return json.NewDecoder(
+		bytes.NewReader(page[loc[1]-1:]),
+	).Decode(v)
+}
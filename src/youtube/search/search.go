@@ -1,7 +1,6 @@
 package search
 
 import (
-	"bytes"
 	"discord-music-bot/model"
 	"discord-music-bot/youtube/client"
 	"errors"
@@ -10,83 +9,85 @@ import (
 	"sync"
 )
 
+// MaxSongs is the maximum number of songs returned for the queries
+// at once, so a pasted playlist's url cannot add an unbounded
+// number of songs, same as at most 100 songs may be queried at once.
+const MaxSongs = 100
+
 type Search struct {
 	client *client.YoutubeClient
 }
 
 // NewSearch constructs an object that handles
-// searching songs on youtube either by url or query
-func NewSearch() *Search {
+// searching songs on youtube either by url or query.
+// The youtube requests are sent based on the provided
+// config, the default one is used when it is nil.
+func NewSearch(config *client.Configuration) *Search {
 	return &Search{
-		client: client.NewYoutubeClient(),
+		client: client.NewYoutubeClient(config),
 	}
 }
 
 // GetSongs searches the provided queries on the youtube and
 // recieved the found videos' information. Always returns the first
 // search result. If the query is a youtube video url, the url is used
-// for fetching the info.
+// for fetching the info, and if it is a youtube playlist url, all
+// the playlist's videos are returned. At most MaxSongs songs are
+// returned, the ones over the limit are dropped.
 func (s *Search) GetSongs(queries []string) []*model.SongInfo {
 	added := make(map[string]struct{})
-	songBuffer := make(chan *model.SongInfo, len(queries))
-	var wg sync.WaitGroup
-
-	var prevWG *sync.WaitGroup = nil
-
-	// NOTE: run all queries in parallel, as each query may take
-	// more than a second to complete
+	unique := make([]string, 0, len(queries))
 	for _, query := range queries {
 		if _, ok := added[query]; ok {
 			continue
 		}
-		// NOTE: all queries except the first one
-		// need to wait for the previous query to complete
-		// the search, so they are added to the channel in order
-		wg2 := &sync.WaitGroup{}
-		wg.Add(1)
-		wg2.Add(1)
 		added[query] = struct{}{}
-		go func(query string, prevWG *sync.WaitGroup) {
-			defer func() {
-				wg.Done()
-				wg2.Done()
-			}()
-			info, err := s.getSong(query)
+		unique = append(unique, query)
+	}
+	results := make([][]*model.SongInfo, len(unique))
+	var wg sync.WaitGroup
+
+	// NOTE: run all queries in parallel, as each query may take
+	// more than a second to complete, the results are stored
+	// by the queries' indices so they are returned in order
+	for i, query := range unique {
+		wg.Add(1)
+		go func(i int, query string) {
+			defer wg.Done()
+			songs, err := s.getSongs(query)
 			if err != nil {
 				return
 			}
-			if prevWG != nil {
-				prevWG.Wait()
-			}
-			select {
-			case songBuffer <- info:
-			default:
-			}
-		}(query, prevWG)
-
-		prevWG = wg2
+			results[i] = songs
+		}(i, query)
 	}
 
 	// NOTE: wait for all the queries to complete
 	wg.Wait()
 
-	// NOTE: build a slice from the songs
-	// in the channel
-	cnt := len(songBuffer)
-	if cnt > 0 {
-		songs := make([]*model.SongInfo, 0, len(songBuffer))
-	songLoop:
-		for i := 0; i < cnt; i++ {
-			select {
-			case song := <-songBuffer:
-				songs = append(songs, song)
-			default:
-				break songLoop
-			}
+	songs := make([]*model.SongInfo, 0, len(unique))
+	for _, r := range results {
+		songs = append(songs, r...)
+	}
+	if len(songs) > MaxSongs {
+		songs = songs[:MaxSongs]
+	}
+	return songs
+}
+
+// getSongs returns the songs found for the provided query,
+// that is either a song's name or url, or a playlist's url.
+func (s *Search) getSongs(q string) ([]*model.SongInfo, error) {
+	if _, ok := s.extractYoutubeVideoID(q); !ok {
+		if id, ok := s.extractYoutubePlaylistID(q); ok {
+			return s.getPlaylistSongs(id)
 		}
-		return songs
 	}
-	return []*model.SongInfo{}
+	song, err := s.getSong(q)
+	if err != nil {
+		return nil, err
+	}
+	return []*model.SongInfo{song}, nil
 }
 
 func (s *Search) getSong(q string) (*model.SongInfo, error) {
@@ -102,44 +103,45 @@ func (s *Search) getSong(q string) (*model.SongInfo, error) {
 		videoID = id
 	}
 
-	b, url, err := s.client.NewWatchEndpointRequest(videoID)
+	b, _, err := s.client.NewWatchEndpointRequest(videoID)
 	if err != nil {
 		return nil, err
 	}
-
-	info := new(model.SongInfo)
-	info.Url = url
-
-	str := string(b)
-	content := s.getRegExpGroupValues(
-		`"videoDetails":{.*?`+
-			`("videoId":"(?P<videoID>.*?)")|`+
-			`("title":"(?P<title>.*?)")|`+
-			`("lengthSeconds":"(?P<lengthSeconds>.*?)")`,
-		str,
-		[]string{"videoID", "title", "lengthSeconds"},
-	)
-	if v, ok := content["title"]; ok && len(v) > 0 {
-		info.Name = v
-	} else {
-		return nil, errors.New("Failed to extract title for song query: " + q)
+	response := new(playerResponse)
+	if err := decodeEmbeddedObject(b, playerResponseRegexp, response); err != nil {
+		return nil, errors.New(
+			"Failed to decode player response for song query: " + q,
+		)
 	}
-	if v, ok := content["videoID"]; ok && len(v) > 0 {
-		info.VideoID = v
-	} else {
-		return nil, errors.New("Failed to extract videoID for song query: " + q)
+	details := response.VideoDetails
+	microformat := response.Microformat.PlayerMicroformatRenderer
+	if len(details.VideoID) == 0 {
+		// NOTE: unavailable videos have no details,
+		// only the reason why they are unavailable
+		return nil, errors.New(
+			"Video unavailable for song query: " + q +
+				" (" + response.PlayabilityStatus.Reason + ")",
+		)
 	}
-	if v, ok := content["lengthSeconds"]; ok && len(v) > 0 {
-		if i, err := strconv.Atoi(v); err == nil {
-			info.LengthSeconds = i
-		} else {
-			return nil, errors.New("Failed to extract duration for song query: " + q)
-		}
-	} else {
+	if len(details.Title) == 0 {
+		return nil, errors.New("Failed to extract title for song query: " + q)
+	}
+	length, err := strconv.Atoi(details.LengthSeconds)
+	if err != nil {
 		return nil, errors.New("Failed to extract duration for song query: " + q)
 	}
-	info.Name = s.unescapeHTML(info.Name)
-	return info, nil
+	return &model.SongInfo{
+		VideoID:       details.VideoID,
+		Name:          details.Title,
+		Url:           s.client.WatchUrl(details.VideoID),
+		LengthSeconds: length,
+		ChannelName:   details.Author,
+		ThumbnailUrl:  details.Thumbnail.largest(),
+		Live: details.IsLive || (microformat.LiveBroadcastDetails != nil &&
+			microformat.LiveBroadcastDetails.IsLiveNow),
+		AgeRestricted: response.PlayabilityStatus.DesktopLegacyAgeGateReason > 0 ||
+			(microformat.IsFamilySafe != nil && !*microformat.IsFamilySafe),
+	}, nil
 }
 
 func (s *Search) getVideoIDFromQuery(query string) (string, error) {
@@ -147,15 +149,12 @@ func (s *Search) getVideoIDFromQuery(query string) (string, error) {
 	if err != nil {
 		return query, err
 	}
-
-	str := string(body)
-	content := s.getRegExpGroupValues(
-		`"videoId":"(?P<videoID>.*?)"`,
-		str,
-		[]string{"videoID"},
-	)
-	if v, ok := content["videoID"]; ok {
-		return v, nil
+	data := new(searchData)
+	if err := decodeEmbeddedObject(body, initialDataRegexp, data); err != nil {
+		return query, errors.New("Failed to decode search results: " + query)
+	}
+	if ids := data.videoIDs(); len(ids) > 0 {
+		return ids[0], nil
 	}
 	return query, errors.New("Invalid query param: " + query)
 }
@@ -170,6 +169,53 @@ func (s *Search) extractYoutubeVideoID(url string) (string, bool) {
 	return v, ok
 }
 
+func (s *Search) extractYoutubePlaylistID(url string) (string, bool) {
+	content := s.getRegExpGroupValues(
+		`youtube.*[?&]list=(?P<playlistID>[^&\/]*)`,
+		url,
+		[]string{"playlistID"},
+	)
+	v, ok := content["playlistID"]
+	return v, ok
+}
+
+// getPlaylistSongs returns the playable songs in the
+// playlist identified by the provided playlistID.
+func (s *Search) getPlaylistSongs(playlistID string) ([]*model.SongInfo, error) {
+	body, _, err := s.client.NewPlaylistRequest(playlistID)
+	if err != nil {
+		return nil, err
+	}
+	data := new(playlistData)
+	if err := decodeEmbeddedObject(body, initialDataRegexp, data); err != nil {
+		return nil, errors.New("Failed to decode playlist: " + playlistID)
+	}
+	songs := make([]*model.SongInfo, 0)
+	for _, v := range data.videos() {
+		// NOTE: deleted and private videos remain in
+		// the playlist, but are not playable
+		if !v.IsPlayable {
+			continue
+		}
+		length, err := strconv.Atoi(v.LengthSeconds)
+		if err != nil {
+			continue
+		}
+		songs = append(songs, &model.SongInfo{
+			VideoID:       v.VideoID,
+			Name:          v.Title.String(),
+			Url:           s.client.WatchUrl(v.VideoID),
+			LengthSeconds: length,
+			ChannelName:   v.ShortBylineText.String(),
+			ThumbnailUrl:  v.Thumbnail.largest(),
+		})
+	}
+	if len(songs) == 0 {
+		return nil, errors.New("No songs found in playlist: " + playlistID)
+	}
+	return songs, nil
+}
+
 func (s *Search) getRegExpGroupValues(reString string, str string, groups []string) map[string]string {
 	re := regexp.MustCompile(reString)
 	matches := re.FindAllStringSubmatch(string(str), len(groups))
@@ -187,12 +233,3 @@ func (s *Search) getRegExpGroupValues(reString string, str string, groups []stri
 	}
 	return values
 }
-
-// unescapeHTML replaces \u0026 with &, \u003e with > and \u003c with <
-func (s *Search) unescapeHTML(str string) string {
-	b := []byte(str)
-	b = bytes.Replace(b, []byte("\\u003c"), []byte("<"), -1)
-	b = bytes.Replace(b, []byte("\\u003e"), []byte(">"), -1)
-	b = bytes.Replace(b, []byte("\\u0026"), []byte("&"), -1)
-	return string(b)
-}
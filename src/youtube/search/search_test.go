@@ -1,26 +1,41 @@
 package search_test
 
 import (
+	"discord-music-bot/youtube/client"
 	"discord-music-bot/youtube/search"
+	"discord-music-bot/youtube/stub"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 )
 
 type YoutubeSearchTestSuite struct {
 	suite.Suite
+	server *stub.Server
 	search *search.Search
 }
 
-// SetupSuite reuns on suite init and creates the
-// search object.
-func (s *YoutubeSearchTestSuite) SetupSuite() {
-	s.search = search.NewSearch()
+// SetupTest runs before every test and creates the search
+// object, that sends the requests to a new stub server.
+func (s *YoutubeSearchTestSuite) SetupTest() {
+	s.server = stub.NewServer()
+	s.search = search.NewSearch(&client.Configuration{
+		BaseUrl: s.server.URL,
+		Timeout: 5 * time.Second,
+	})
 }
 
-// TestIntegrationGetSongs gets songs by queries and urls and
-// checks whether the correct number of results was returned.
-func (s *YoutubeSearchTestSuite) TestIntegrationGetSongs() {
+// TearDownTest runs after every test and
+// closes the stub server.
+func (s *YoutubeSearchTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+// TestUnitGetSongs gets songs by queries and urls and
+// checks whether the correct results were returned in order.
+func (s *YoutubeSearchTestSuite) TestUnitGetSongs() {
 	queries := []string{
 		"red hot chili peppers snow",
 		"rhcp snow",
@@ -31,12 +46,27 @@ func (s *YoutubeSearchTestSuite) TestIntegrationGetSongs() {
 		"https://www.youtube.com/watch?v=yuFI5KSPAt4",
 	}
 	songs := s.search.GetSongs(queries)
-	s.Len(songs, len(queries))
+	s.Require().Len(songs, len(queries))
+
+	videoIDs := make([]string, len(songs))
+	for i, song := range songs {
+		videoIDs[i] = song.VideoID
+	}
+	s.Equal([]string{
+		"yuFI5KSPAt4",
+		"yuFI5KSPAt4",
+		"wsrvmNtWU4E",
+		"Sb5aq5HcS1A",
+		"wsrvmNtWU4E",
+		"z0NfI2NeDHI",
+		"yuFI5KSPAt4",
+	}, videoIDs)
+	s.Equal("Metallica & Rammstein - Live Mix", songs[3].Name)
 }
 
-// TestIntegrationGetSongsVerifyUrlResults gets songs by urls and
+// TestUnitGetSongsVerifyUrlResults gets songs by urls and
 // checks the correctness of the returned results.
-func (s *YoutubeSearchTestSuite) TestIntegrationGetSongsVerifyUrlResults() {
+func (s *YoutubeSearchTestSuite) TestUnitGetSongsVerifyUrlResults() {
 	queries := []string{
 		"https://www.youtube.com/watch?v=D-BhsIEzp64",
 		"https://www.youtube.com/watch?v=z0NfI2NeDHI",
@@ -44,7 +74,7 @@ func (s *YoutubeSearchTestSuite) TestIntegrationGetSongsVerifyUrlResults() {
 
 	songs := s.search.GetSongs(queries)
 
-	s.Len(songs, len(queries))
+	s.Require().Len(songs, len(queries))
 
 	s.Equal(
 		"Red Hot Chili Peppers best songs",
@@ -65,6 +95,149 @@ func (s *YoutubeSearchTestSuite) TestIntegrationGetSongsVerifyUrlResults() {
 	)
 }
 
+// TestUnitGetSongsPlaylist gets songs by a playlist url and
+// checks that all the playable songs of the playlist are returned.
+func (s *YoutubeSearchTestSuite) TestUnitGetSongsPlaylist() {
+	songs := s.search.GetSongs([]string{
+		"https://www.youtube.com/playlist?list=PLr0ckCl4ss1cs",
+	})
+	s.Require().Len(songs, 3)
+
+	s.Equal("z0NfI2NeDHI", songs[0].VideoID)
+	s.Equal("Rammstein - Radio (Official Video)", songs[0].Name)
+	s.Equal(290, songs[0].LengthSeconds)
+	s.Equal(
+		"https://www.youtube.com/watch?v=z0NfI2NeDHI",
+		songs[0].Url,
+	)
+	s.Equal("Rammstein Official", songs[0].ChannelName)
+	s.Equal(
+		"https://i.ytimg.com/vi/z0NfI2NeDHI/hqdefault.jpg",
+		songs[0].ThumbnailUrl,
+	)
+	s.Equal("yuFI5KSPAt4", songs[1].VideoID)
+	s.Equal(334, songs[1].LengthSeconds)
+	s.Equal("wsrvmNtWU4E", songs[2].VideoID)
+	s.Equal(305, songs[2].LengthSeconds)
+}
+
+// TestUnitGetSongsLimit gets songs by many playlist urls and
+// checks that the expanded songs are limited to MaxSongs.
+func (s *YoutubeSearchTestSuite) TestUnitGetSongsLimit() {
+	queries := make([]string, 0)
+	for i := 0; i < search.MaxSongs/3+1; i++ {
+		queries = append(queries, fmt.Sprintf(
+			"https://www.youtube.com/playlist?list=PLr0ckCl4ss1cs&index=%d", i,
+		))
+	}
+	songs := s.search.GetSongs(queries)
+	s.Len(songs, search.MaxSongs)
+	s.Equal("z0NfI2NeDHI", songs[0].VideoID)
+}
+
+// TestUnitGetSongsMetadata gets a song by url and checks
+// that its channel, thumbnail and flags are returned.
+func (s *YoutubeSearchTestSuite) TestUnitGetSongsMetadata() {
+	songs := s.search.GetSongs([]string{
+		"https://www.youtube.com/watch?v=yuFI5KSPAt4",
+		"https://www.youtube.com/watch?v=jfKfPfyJRdk",
+		"https://www.youtube.com/watch?v=Ag3R3str1ct",
+	})
+	s.Require().Len(songs, 3)
+
+	s.Equal("Red Hot Chili Peppers", songs[0].ChannelName)
+	s.Equal(
+		"https://i.ytimg.com/vi/yuFI5KSPAt4/maxresdefault.jpg",
+		songs[0].ThumbnailUrl,
+	)
+	s.False(songs[0].Live)
+	s.False(songs[0].AgeRestricted)
+
+	s.Equal("Lofi Girl", songs[1].ChannelName)
+	s.Equal("lofi hip hop radio 📚 - beats to relax/study to", songs[1].Name)
+	s.True(songs[1].Live)
+	s.False(songs[1].AgeRestricted)
+
+	s.Equal("Age Restricted Concert", songs[2].Name)
+	s.Equal(421, songs[2].LengthSeconds)
+	s.False(songs[2].Live)
+	s.True(songs[2].AgeRestricted)
+}
+
+// TestUnitGetSongsEscapedTitle gets a song whose title contains
+// escaped quotes, backslashes and html characters and checks
+// that the title is fully unescaped.
+func (s *YoutubeSearchTestSuite) TestUnitGetSongsEscapedTitle() {
+	songs := s.search.GetSongs([]string{
+		"https://www.youtube.com/watch?v=Esc4p3dQu0t",
+	})
+	s.Require().Len(songs, 1)
+	s.Equal(`AC/DC - "Back In Black" (Live \ Remastered) <HD>`, songs[0].Name)
+	s.Equal(262, songs[0].LengthSeconds)
+}
+
+// TestUnitGetSongsSkipsOtherResults checks that channels and
+// playlists in the search results are skipped and the first
+// video is returned.
+func (s *YoutubeSearchTestSuite) TestUnitGetSongsSkipsOtherResults() {
+	songs := s.search.GetSongs([]string{"rock classics"})
+	s.Require().Len(songs, 1)
+	s.Equal("D-BhsIEzp64", songs[0].VideoID)
+	s.Equal("Red Hot Chili Peppers best songs", songs[0].Name)
+}
+
+// TestUnitGetSongsUnknown checks that no songs are returned for
+// the videos and search queries that do not exist, nor for the
+// videos that youtube reports as unavailable.
+func (s *YoutubeSearchTestSuite) TestUnitGetSongsUnknown() {
+	songs := s.search.GetSongs([]string{
+		"https://www.youtube.com/watch?v=Unkn0wnV1d0",
+		"https://www.youtube.com/watch?v=Unav4ilabl3",
+		"a query without results",
+		"https://www.youtube.com/playlist?list=PLunkn0wn",
+	})
+	s.Empty(songs)
+}
+
+// TestUnitGetSongsHeaders checks that the configured headers
+// are sent with every request.
+func (s *YoutubeSearchTestSuite) TestUnitGetSongsHeaders() {
+	search := search.NewSearch(&client.Configuration{
+		BaseUrl: s.server.URL + "/",
+		Headers: map[string]string{
+			"Accept-Language": "en-US",
+			"Content-Type":    "text/html",
+		},
+	})
+	songs := search.GetSongs([]string{"rammstein radio"})
+	s.Require().Len(songs, 1)
+
+	requests := s.server.Requests()
+	s.Require().Len(requests, 2)
+	s.Equal("/results", requests[0].URL.Path)
+	s.Equal("/watch", requests[1].URL.Path)
+	for _, r := range requests {
+		s.Equal("en-US", r.Header.Get("Accept-Language"))
+		s.Equal("text/html", r.Header.Get("Content-Type"))
+	}
+}
+
+// TestUnitGetSongsTimeout checks that the requests are
+// cancelled once the configured timeout is exceeded.
+func (s *YoutubeSearchTestSuite) TestUnitGetSongsTimeout() {
+	s.server.SetDelay(time.Second)
+	search := search.NewSearch(&client.Configuration{
+		BaseUrl: s.server.URL,
+		Timeout: 50 * time.Millisecond,
+	})
+	start := time.Now()
+	songs := search.GetSongs([]string{
+		"https://www.youtube.com/watch?v=z0NfI2NeDHI",
+	})
+	s.Empty(songs)
+	s.Less(time.Since(start), time.Second)
+}
+
 // TestYoutubeSearchTestSuite runs all tests under
 // the YoutubeSearchTestSuite
 func TestYoutubeSearchTestSuite(t *testing.T) {
@@ -30,8 +30,8 @@ func NewStream() *Stream {
 }
 
 // GetSession creates a new streaming session from
-// the provided url
-func (s *Stream) GetSession(url string, vc *discordgo.VoiceConnection) (*Session, error) {
+// the provided url, that starts streaming at the provided position.
+func (s *Stream) GetSession(url string, vc *discordgo.VoiceConnection, start time.Duration) (*Session, error) {
 	streamUrl, err := s.getStreamUrl(url)
 	if err != nil {
 		return nil, err
@@ -40,6 +40,7 @@ func (s *Stream) GetSession(url string, vc *discordgo.VoiceConnection) (*Session
 	options.RawOutput = true
 	options.Bitrate = 96
 	options.Application = "lowdelay"
+	options.StartTime = int(start.Seconds())
 
 	encodingSession, err := dca.EncodeFile(streamUrl, options)
 	if err != nil {
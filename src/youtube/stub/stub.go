@@ -0,0 +1,116 @@
+package stub
+
+import (
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NOTE: the fixtures are trimmed copies of youtube's pages, they
+// keep only the embedded ytInitialPlayerResponse and ytInitialData
+// objects, with the fields that are relevant for the search.
+//
+//go:embed fixtures
+var fixtures embed.FS
+
+// Server is a http server that mimics youtube's /watch,
+// /results and /playlist endpoints, by serving the pages from
+// the fixtures directory. It responds with 404 to everything
+// that does not have a fixture.
+type Server struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	requests []*http.Request
+	delay    time.Duration
+}
+
+// NewServer starts a new stub server, the server should
+// be closed once it is no longer needed.
+func NewServer() *Server {
+	s := &Server{
+		requests: make([]*http.Request, 0),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// SetDelay sets the duration for which the server waits
+// before responding to a request.
+func (s *Server) SetDelay(delay time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.delay = delay
+}
+
+// Requests returns all the requests the server has received.
+func (s *Server) Requests() []*http.Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	requests := make([]*http.Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+// fixturePath returns the path of the fixture served for the
+// provided request's url, or false if the url has no fixture.
+func fixturePath(r *http.Request) (string, bool) {
+	q := r.URL.Query()
+	switch r.URL.Path {
+	case "/watch":
+		if v := q.Get("v"); len(v) > 0 {
+			return path.Join("fixtures", "watch", v+".html"), true
+		}
+	case "/results":
+		if v := q.Get("search_query"); len(v) > 0 {
+			return path.Join("fixtures", "search", slug(v)+".html"), true
+		}
+	case "/playlist":
+		if v := q.Get("list"); len(v) > 0 {
+			return path.Join("fixtures", "playlist", v+".html"), true
+		}
+	}
+	return "", false
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.requests = append(s.requests, r.Clone(r.Context()))
+	delay := s.delay
+	s.mutex.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+	p, ok := fixturePath(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	b, err := fixtures.ReadFile(p)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(b)
+}
+
+var slugRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug converts the provided search query to the name
+// of its fixture, lowercase words separated by dashes.
+func slug(query string) string {
+	return strings.Trim(
+		slugRegexp.ReplaceAllString(strings.ToLower(query), "-"),
+		"-",
+	)
+}
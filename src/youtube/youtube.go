@@ -1,6 +1,7 @@
 package youtube
 
 import (
+	"discord-music-bot/youtube/client"
 	"discord-music-bot/youtube/search"
 	"discord-music-bot/youtube/stream"
 )
@@ -11,10 +12,11 @@ type Youtube struct {
 }
 
 // NewYoutube constructs an object that handles
-// youtube integration
-func NewYoutube() *Youtube {
+// youtube integration. The youtube requests are sent based
+// on the provided config, the default one is used when it is nil.
+func NewYoutube(config *client.Configuration) *Youtube {
 	return &Youtube{
-		search: search.NewSearch(),
+		search: search.NewSearch(config),
 		stream: stream.NewStream(),
 	}
 }